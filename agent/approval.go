@@ -0,0 +1,77 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+)
+
+const (
+	runGraphName = "agent_run"
+	reactNode    = "react"
+)
+
+// ErrNoCheckPoints 需要审批或恢复运行，但没有配置检查点存储与运行 ID
+var ErrNoCheckPoints = errors.New("agent approval requires a checkpoint store and run id")
+
+// Decision 对一次等待审批的工具调用的决定
+type Decision struct {
+	Approved bool   `json:"approved"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+func init() {
+	// 中断信息与状态随检查点序列化
+	schema.RegisterName[*Event]("_go_agent_event")
+}
+
+// approve 在执行需要审批的工具前调用：首次到达时中断运行；恢复时按审批结果放行或拒绝
+// 返回 approved 为 false 时，output 与 err 即工具调用的结果
+func (t *tracer) approve(ctx context.Context, input *compose.ToolInput) (approved bool, output *compose.ToolOutput, err error) {
+	req := &Event{Type: EventApproval, Iteration: t.iteration(), Tool: input.Name, CallID: input.CallID, Arguments: input.Arguments}
+
+	wasInterrupted, hasState, state := compose.GetInterruptState[*Event](ctx)
+	if !wasInterrupted {
+		return false, nil, compose.StatefulInterrupt(ctx, req, req)
+	}
+	if hasState {
+		req = state
+	}
+
+	// 本次恢复没有指定这个调用，继续等待审批
+	isResume, hasData, d := compose.GetResumeContext[*Decision](ctx)
+	if !isResume {
+		return false, nil, compose.StatefulInterrupt(ctx, req, req)
+	}
+	// 只恢复不带数据视为同意
+	if !hasData || d.Approved {
+		return true, nil, nil
+	}
+
+	msg := "tool call rejected by user"
+	if d.Reason != "" {
+		msg += ": " + d.Reason
+	}
+	output = &compose.ToolOutput{Result: "error: " + msg}
+	t.toolResult(input.Name, input.CallID, output.Result, msg)
+	return false, output, nil
+}
+
+// pendingApprovals 从中断信息中取出等待审批的工具调用，按调用 ID 排序
+func pendingApprovals(info *compose.InterruptInfo) []Event {
+	var pending []Event
+	for _, ic := range info.InterruptContexts {
+		req, ok := ic.Info.(*Event)
+		if !ok || !ic.IsRootCause {
+			continue
+		}
+		e := *req
+		e.InterruptID = ic.ID
+		pending = append(pending, e)
+	}
+	sort.Slice(pending, func(i, j int) bool { return pending[i].CallID < pending[j].CallID })
+	return pending
+}
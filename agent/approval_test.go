@@ -0,0 +1,157 @@
+package agent
+
+import (
+	"context"
+	"go-agent/model/chat_model"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/schema"
+)
+
+func newApprovalConfig(t *testing.T, fake *chat_model.FakeChatModel) Config {
+	t.Helper()
+	store, err := NewRunStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Config{
+		Model:         fake,
+		Tools:         []tool.BaseTool{newAddTool(t)},
+		NeedsApproval: func(name string) bool { return name == "add" },
+		CheckPoints:   store,
+		RunID:         NewRunID(),
+	}
+}
+
+func TestRunPausesForApproval(t *testing.T) {
+	fake := chat_model.NewFakeChatModel(
+		toolCall("c1", "add", `{"a":1,"b":2}`),
+		schema.AssistantMessage("the sum is 3", nil),
+	)
+	conf := newApprovalConfig(t, fake)
+
+	result, err := Run(context.Background(), conf, []*schema.Message{schema.UserMessage("1+2?")}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Answer != nil || len(result.Pending) != 1 {
+		t.Fatalf("result = %+v, want one pending approval", result)
+	}
+	pending := result.Pending[0]
+	if pending.Tool != "add" || pending.CallID != "c1" || pending.InterruptID == "" {
+		t.Fatalf("pending = %+v", pending)
+	}
+	if got := eventTypes(result.Trace); got != "iteration,tool_call,approval_required" {
+		t.Fatalf("trace = %s", got)
+	}
+
+	// 恢复时只运行工具与后续轮次，轮数接着暂停前计算
+	conf.Iteration = result.Iterations
+	result, err = Resume(context.Background(), conf, nil, map[string]*Decision{pending.InterruptID: {Approved: true}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Answer == nil || result.Answer.Content != "the sum is 3" || result.Iterations != 2 {
+		t.Fatalf("result = %+v", result)
+	}
+	if got := eventTypes(result.Trace); got != "tool_result,iteration,answer" {
+		t.Fatalf("resumed trace = %s", got)
+	}
+	inputs := fake.Inputs()
+	last := inputs[len(inputs)-1]
+	if len(last) != 3 || last[0].Content != "1+2?" || last[2].Content != "3" {
+		t.Fatalf("model input after resume = %+v", last)
+	}
+}
+
+func TestResumeRejectsToolCall(t *testing.T) {
+	fake := chat_model.NewFakeChatModel(
+		toolCall("c1", "add", `{"a":1,"b":2}`),
+		schema.AssistantMessage("ok, not adding", nil),
+	)
+	conf := newApprovalConfig(t, fake)
+
+	result, err := Run(context.Background(), conf, []*schema.Message{schema.UserMessage("1+2?")}, nil)
+	if err != nil || len(result.Pending) != 1 {
+		t.Fatalf("result = %+v, err = %v", result, err)
+	}
+
+	decisions := map[string]*Decision{result.Pending[0].InterruptID: {Reason: "not now"}}
+	result, err = Resume(context.Background(), conf, nil, decisions, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Answer == nil || result.Answer.Content != "ok, not adding" {
+		t.Fatalf("result = %+v", result)
+	}
+	if r := result.Trace[0]; r.Type != EventToolResult || !strings.Contains(r.Error, "not now") {
+		t.Fatalf("rejection = %+v", r)
+	}
+	inputs := fake.Inputs()
+	last := inputs[len(inputs)-1]
+	if msg := last[len(last)-1]; msg.Role != schema.Tool || !strings.Contains(msg.Content, "rejected") {
+		t.Fatalf("tool message = %+v", msg)
+	}
+}
+
+func TestResumeLeavesUndecidedCallsPending(t *testing.T) {
+	fake := chat_model.NewFakeChatModel(
+		schema.AssistantMessage("", []schema.ToolCall{
+			{ID: "c1", Function: schema.FunctionCall{Name: "add", Arguments: `{"a":1,"b":2}`}},
+			{ID: "c2", Function: schema.FunctionCall{Name: "add", Arguments: `{"a":3,"b":4}`}},
+		}),
+		schema.AssistantMessage("3 and 7", nil),
+	)
+	conf := newApprovalConfig(t, fake)
+
+	result, err := Run(context.Background(), conf, []*schema.Message{schema.UserMessage("sums?")}, nil)
+	if err != nil || len(result.Pending) != 2 {
+		t.Fatalf("result = %+v, err = %v", result, err)
+	}
+
+	// 每次暂停都会生成新的 InterruptID
+	result, err = Resume(context.Background(), conf, nil, map[string]*Decision{result.Pending[0].InterruptID: {Approved: true}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Pending) != 1 || result.Pending[0].CallID != "c2" {
+		t.Fatalf("pending = %+v", result.Pending)
+	}
+
+	result, err = Resume(context.Background(), conf, nil, map[string]*Decision{result.Pending[0].InterruptID: {Approved: true}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Answer == nil || result.Answer.Content != "3 and 7" {
+		t.Fatalf("result = %+v", result)
+	}
+	inputs := fake.Inputs()
+	last := inputs[len(inputs)-1]
+	if len(last) != 4 || last[2].Content != "3" || last[3].Content != "7" {
+		t.Fatalf("model input = %+v", last)
+	}
+}
+
+func TestRunStoreClaim(t *testing.T) {
+	store, err := NewRunStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Load("missing"); err == nil {
+		t.Fatal("load missing run succeeded")
+	}
+	if err := store.Save(&RunRecord{ID: "abc", Status: RunWaitingApproval}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Claim("abc", RunWaitingApproval); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Claim("abc", RunWaitingApproval); err == nil {
+		t.Fatal("second claim succeeded")
+	}
+	if err := store.Save(&RunRecord{ID: "../x"}); err == nil {
+		t.Fatal("saved run with invalid id")
+	}
+}
@@ -0,0 +1,124 @@
+package mcpclient
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// 传输方式
+const (
+	TransportStdio = "stdio"
+	TransportHTTP  = "http" // Streamable HTTP
+)
+
+var serverNameValid = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// ServerConfig 一个 MCP 服务器
+type ServerConfig struct {
+	Name string `json:"-"`
+	// Type 传输方式 stdio 或 http，为空时有 command 视为 stdio、有 url 视为 http
+	Type string `json:"type,omitempty"`
+
+	// stdio：启动子进程，通过标准输入输出通信
+	Command string            `json:"command,omitempty"`
+	Args    []string          `json:"args,omitempty"`
+	Env     map[string]string `json:"env,omitempty"`
+
+	// http：Streamable HTTP 端点
+	URL     string            `json:"url,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+
+	// Tools 允许注册的工具（服务器端名称），为空时注册服务器提供的全部工具
+	Tools []string `json:"tools,omitempty"`
+	// Disabled 暂时停用该服务器
+	Disabled bool `json:"disabled,omitempty"`
+}
+
+// LoadServers 读取服务器配置文件：{"mcpServers":{"名称":{...}}}，按名称排序返回启用的服务器
+func LoadServers(path string) ([]ServerConfig, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read mcp servers file failed: %w", err)
+	}
+	return ParseServers(b)
+}
+
+// ParseServers 解析服务器配置
+func ParseServers(b []byte) ([]ServerConfig, error) {
+	var file struct {
+		Servers map[string]ServerConfig `json:"mcpServers"`
+	}
+	if err := json.Unmarshal(b, &file); err != nil {
+		return nil, fmt.Errorf("invalid mcp servers config: %w", err)
+	}
+
+	servers := make([]ServerConfig, 0, len(file.Servers))
+	for name, conf := range file.Servers {
+		if conf.Disabled {
+			continue
+		}
+		conf.Name = name
+		if err := conf.validate(); err != nil {
+			return nil, fmt.Errorf("mcp server %s: %w", name, err)
+		}
+		servers = append(servers, conf)
+	}
+	sort.Slice(servers, func(i, j int) bool { return servers[i].Name < servers[j].Name })
+	return servers, nil
+}
+
+// validate 校验配置并补全传输方式
+func (c *ServerConfig) validate() error {
+	if !serverNameValid.MatchString(c.Name) {
+		return fmt.Errorf("name may only contain letters, digits, '_' and '-'")
+	}
+	if c.Type == "" {
+		switch {
+		case c.Command != "":
+			c.Type = TransportStdio
+		case c.URL != "":
+			c.Type = TransportHTTP
+		}
+	}
+
+	switch c.Type {
+	case TransportStdio:
+		if c.Command == "" {
+			return fmt.Errorf("stdio transport requires command")
+		}
+	case TransportHTTP:
+		if !strings.HasPrefix(c.URL, "http://") && !strings.HasPrefix(c.URL, "https://") {
+			return fmt.Errorf("http transport requires an http(s) url")
+		}
+	default:
+		return fmt.Errorf("unsupported transport %q, expected stdio or http", c.Type)
+	}
+	return nil
+}
+
+// allowed 判断服务器端工具是否在允许列表中
+func (c *ServerConfig) allowed(tool string) bool {
+	if len(c.Tools) == 0 {
+		return true
+	}
+	for _, name := range c.Tools {
+		if name == tool {
+			return true
+		}
+	}
+	return false
+}
+
+// environ 把 env 转换为子进程环境变量，按键排序
+func (c *ServerConfig) environ() []string {
+	env := make([]string, 0, len(c.Env))
+	for k, v := range c.Env {
+		env = append(env, k+"="+v)
+	}
+	sort.Strings(env)
+	return env
+}
@@ -0,0 +1,395 @@
+package mcpclient
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-agent/agent/toolbox"
+	"go-agent/config"
+	"log"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/schema"
+	"github.com/eino-contrib/jsonschema"
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Clients 全局 MCP 客户端，未配置 MCP_SERVERS_FILE 时为 nil
+var Clients *Manager
+
+const (
+	DefaultTimeout           = 30 * time.Second
+	DefaultReconnectInterval = 30 * time.Second
+
+	maxToolNameLen = 64
+)
+
+var toolNameInvalid = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+// Options 连接选项
+type Options struct {
+	Timeout           time.Duration // 连接与单次工具调用的超时
+	ReconnectInterval time.Duration // 检查连接与重连的间隔，<=0 时不在后台重连（调用工具时仍会按需重连）
+}
+
+// Manager 管理全部 MCP 服务器的连接，把服务器提供的工具注册到 Agent 工具注册表
+// 工具以 "<服务器>_<工具>" 命名；连接断开后工具保留，调用时或后台检查时重新连接
+type Manager struct {
+	ctx      context.Context // 子进程与长连接的生命周期
+	registry *toolbox.Registry
+	opts     Options
+	servers  []*serverConn
+}
+
+// NewManagerFromConfig 按 MCP_SERVERS_FILE 创建并连接，未配置时返回 nil
+func NewManagerFromConfig(ctx context.Context, registry *toolbox.Registry) (*Manager, error) {
+	conf := config.Cfg.MCPConf
+	if strings.TrimSpace(conf.ServersFile) == "" {
+		return nil, nil
+	}
+	servers, err := LoadServers(conf.ServersFile)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := Options{Timeout: DefaultTimeout, ReconnectInterval: DefaultReconnectInterval}
+	if d, err := time.ParseDuration(conf.Timeout); err == nil && d > 0 {
+		opts.Timeout = d
+	}
+	if d, err := time.ParseDuration(conf.ReconnectInterval); err == nil {
+		opts.ReconnectInterval = d
+	}
+	return NewManager(ctx, servers, registry, opts), nil
+}
+
+// NewManager 连接全部服务器并注册工具，单个服务器连接失败只记录日志，由后台重连
+func NewManager(ctx context.Context, servers []ServerConfig, registry *toolbox.Registry, opts Options) *Manager {
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
+
+	m := &Manager{ctx: ctx, registry: registry, opts: opts}
+	for _, conf := range servers {
+		s := &serverConn{conf: conf, m: m}
+		m.servers = append(m.servers, s)
+		if err := s.connect(); err != nil {
+			log.Printf("警告: 连接 MCP 服务器 %s 失败，稍后重试: %v", conf.Name, err)
+			continue
+		}
+		log.Printf("MCP 服务器 %s 已连接，注册工具: %s", conf.Name, strings.Join(s.registered(), ", "))
+	}
+	return m
+}
+
+// Run 按间隔检查连接：断开的服务器重新连接并刷新工具，已连接的服务器发送 ping，ctx 结束时停止
+func (m *Manager) Run(ctx context.Context) {
+	if m.opts.ReconnectInterval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(m.opts.ReconnectInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, s := range m.servers {
+				s.check()
+			}
+		}
+	}
+}
+
+// Close 断开全部服务器，stdio 服务器的子进程随之退出
+func (m *Manager) Close() {
+	for _, s := range m.servers {
+		s.disconnect(nil)
+	}
+}
+
+// serverConn 单个服务器的连接状态
+type serverConn struct {
+	conf ServerConfig
+	m    *Manager
+
+	mu     sync.Mutex
+	client *client.Client
+	tools  []string // 已注册到注册表的工具名称
+}
+
+// connect 建立连接、发现工具并同步到注册表，已连接时直接返回
+func (s *serverConn) connect() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.client != nil {
+		return nil
+	}
+
+	c, err := s.newClient()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(s.m.ctx, s.m.opts.Timeout)
+	defer cancel()
+
+	req := mcp.InitializeRequest{}
+	req.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
+	req.Params.ClientInfo = mcp.Implementation{Name: "go-agent", Version: "1.0.0"}
+	if _, err := c.Initialize(ctx, req); err != nil {
+		c.Close()
+		return fmt.Errorf("initialize failed: %w", err)
+	}
+
+	list, err := c.ListTools(ctx, mcp.ListToolsRequest{})
+	if err != nil {
+		c.Close()
+		return fmt.Errorf("list tools failed: %w", err)
+	}
+	c.OnConnectionLost(func(err error) { s.disconnect(err) })
+
+	s.client = c
+	s.sync(list.Tools)
+	return nil
+}
+
+// newClient 按传输方式创建并启动客户端
+func (s *serverConn) newClient() (*client.Client, error) {
+	switch s.conf.Type {
+	case TransportStdio:
+		t := transport.NewStdio(s.conf.Command, s.conf.environ(), s.conf.Args...)
+		// 子进程的生命周期跟随 Manager，而不是单次连接的超时
+		if err := t.Start(s.m.ctx); err != nil {
+			return nil, fmt.Errorf("start %s failed: %w", s.conf.Command, err)
+		}
+		go s.logStderr(t)
+		return startClient(s.m.ctx, t)
+	case TransportHTTP:
+		t, err := transport.NewStreamableHTTP(s.conf.URL, transport.WithHTTPHeaders(s.conf.Headers), transport.WithHTTPTimeout(s.m.opts.Timeout))
+		if err != nil {
+			return nil, err
+		}
+		return startClient(s.m.ctx, t)
+	}
+	return nil, fmt.Errorf("unsupported transport %q", s.conf.Type)
+}
+
+func startClient(ctx context.Context, t transport.Interface) (*client.Client, error) {
+	c := client.NewClient(t)
+	if err := c.Start(ctx); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// logStderr 转发子进程的错误输出，同时避免管道写满阻塞子进程
+func (s *serverConn) logStderr(t *transport.Stdio) {
+	scanner := bufio.NewScanner(t.Stderr())
+	for scanner.Scan() {
+		log.Printf("[mcp %s] %s", s.conf.Name, scanner.Text())
+	}
+}
+
+// sync 按发现的工具重新注册：先移除本服务器旧的工具，再注册允许列表中的工具，调用方需持有锁
+func (s *serverConn) sync(tools []mcp.Tool) {
+	for _, name := range s.tools {
+		s.m.registry.Unregister(name)
+	}
+	s.tools = nil
+
+	for _, t := range tools {
+		if !s.conf.allowed(t.Name) {
+			continue
+		}
+		name := ToolName(s.conf.Name, t.Name)
+		if s.m.registry.Has(name) {
+			log.Printf("警告: MCP 服务器 %s 的工具 %s 与已注册的工具 %s 重名，已跳过", s.conf.Name, t.Name, name)
+			continue
+		}
+		info, err := toolInfo(name, t)
+		if err != nil {
+			log.Printf("警告: MCP 服务器 %s 的工具 %s 参数定义无效，已跳过: %v", s.conf.Name, t.Name, err)
+			continue
+		}
+		if err := s.m.registry.Register(s.m.ctx, &mcpTool{server: s, remote: t.Name, info: info}); err != nil {
+			log.Printf("警告: 注册 MCP 工具 %s 失败: %v", name, err)
+			continue
+		}
+		s.tools = append(s.tools, name)
+	}
+}
+
+func (s *serverConn) registered() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.tools...)
+}
+
+// disconnect 关闭连接，工具保留在注册表中，下次调用或后台检查时重连
+func (s *serverConn) disconnect(cause error) {
+	s.mu.Lock()
+	c := s.client
+	s.client = nil
+	s.mu.Unlock()
+
+	if c == nil {
+		return
+	}
+	if cause != nil {
+		log.Printf("MCP 服务器 %s 连接断开: %v", s.conf.Name, cause)
+	}
+	c.Close()
+}
+
+// check 后台检查：未连接时重连并刷新工具，已连接时 ping，失败则断开等待下一轮重连
+func (s *serverConn) check() {
+	s.mu.Lock()
+	c := s.client
+	s.mu.Unlock()
+
+	if c == nil {
+		if err := s.connect(); err != nil {
+			log.Printf("重连 MCP 服务器 %s 失败: %v", s.conf.Name, err)
+			return
+		}
+		log.Printf("MCP 服务器 %s 已重新连接，注册工具: %s", s.conf.Name, strings.Join(s.registered(), ", "))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(s.m.ctx, s.m.opts.Timeout)
+	defer cancel()
+	if err := c.Ping(ctx); err != nil {
+		s.disconnect(err)
+	}
+}
+
+// call 调用服务器端工具，未连接时先重连；传输层错误会断开连接，但不自动重试，避免重复执行有副作用的工具
+func (s *serverConn) call(ctx context.Context, name string, arguments map[string]any) (*mcp.CallToolResult, error) {
+	if err := s.connect(); err != nil {
+		return nil, fmt.Errorf("mcp server %s unavailable: %w", s.conf.Name, err)
+	}
+	s.mu.Lock()
+	c := s.client
+	s.mu.Unlock()
+	if c == nil {
+		return nil, fmt.Errorf("mcp server %s unavailable", s.conf.Name)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.m.opts.Timeout)
+	defer cancel()
+
+	req := mcp.CallToolRequest{}
+	req.Params.Name = name
+	req.Params.Arguments = arguments
+	res, err := c.CallTool(ctx, req)
+	if err != nil {
+		var transportErr *transport.Error
+		if errors.As(err, &transportErr) && !errors.Is(err, context.Canceled) {
+			s.disconnect(err)
+		}
+		return nil, err
+	}
+	return res, nil
+}
+
+// ToolName 注册到 Agent 的工具名称："<服务器>_<工具>"，非法字符替换为下划线，最长 64 个字符
+func ToolName(serverName, toolName string) string {
+	name := serverName + "_" + toolNameInvalid.ReplaceAllString(toolName, "_")
+	if len(name) > maxToolNameLen {
+		name = name[:maxToolNameLen]
+	}
+	return name
+}
+
+// toolInfo 把 MCP 工具的输入 JSON Schema 转换为 eino 工具描述
+func toolInfo(name string, t mcp.Tool) (*schema.ToolInfo, error) {
+	raw := t.RawInputSchema
+	if len(raw) == 0 {
+		b, err := json.Marshal(t.InputSchema)
+		if err != nil {
+			return nil, err
+		}
+		raw = b
+	}
+	var s jsonschema.Schema
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return nil, err
+	}
+	if s.Type == "" {
+		s.Type = "object"
+	}
+
+	return &schema.ToolInfo{
+		Name:        name,
+		Desc:        t.Description,
+		ParamsOneOf: schema.NewParamsOneOfByJSONSchema(&s),
+	}, nil
+}
+
+// mcpTool 转发到 MCP 服务器的 eino 工具
+type mcpTool struct {
+	server *serverConn
+	remote string // 服务器端工具名称
+	info   *schema.ToolInfo
+}
+
+func (t *mcpTool) Info(ctx context.Context) (*schema.ToolInfo, error) {
+	return t.info, nil
+}
+
+func (t *mcpTool) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	var arguments map[string]any
+	if strings.TrimSpace(argumentsInJSON) != "" {
+		if err := json.Unmarshal([]byte(argumentsInJSON), &arguments); err != nil {
+			return "", fmt.Errorf("invalid arguments: %w", err)
+		}
+	}
+
+	res, err := t.server.call(ctx, t.remote, arguments)
+	if err != nil {
+		return "", err
+	}
+	text := resultText(res)
+	if res.IsError {
+		return "", errors.New(text)
+	}
+	return text, nil
+}
+
+// resultText 拼接工具结果中的文本，非文本内容以占位说明代替；只有结构化结果时返回其 JSON
+func resultText(res *mcp.CallToolResult) string {
+	var parts []string
+	for _, content := range res.Content {
+		switch c := content.(type) {
+		case mcp.TextContent:
+			parts = append(parts, c.Text)
+		case mcp.ImageContent:
+			parts = append(parts, fmt.Sprintf("[image %s]", c.MIMEType))
+		case mcp.AudioContent:
+			parts = append(parts, fmt.Sprintf("[audio %s]", c.MIMEType))
+		case mcp.EmbeddedResource:
+			if r, ok := c.Resource.(mcp.TextResourceContents); ok {
+				parts = append(parts, r.Text)
+			} else {
+				parts = append(parts, "[resource]")
+			}
+		case mcp.ResourceLink:
+			parts = append(parts, fmt.Sprintf("[resource %s]", c.URI))
+		}
+	}
+	if len(parts) == 0 && res.StructuredContent != nil {
+		if b, err := json.Marshal(res.StructuredContent); err == nil {
+			return string(b)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
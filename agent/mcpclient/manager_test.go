@@ -0,0 +1,175 @@
+package mcpclient
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"go-agent/agent/toolbox"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// 测试进程以 GO_AGENT_MCP_STUB=1 启动时作为 stdio MCP 服务器运行
+func TestMain(m *testing.M) {
+	if os.Getenv("GO_AGENT_MCP_STUB") == "1" {
+		if err := server.ServeStdio(newStubServer()); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func newStubServer() *server.MCPServer {
+	s := server.NewMCPServer("stub", "1.0.0")
+	s.AddTool(mcp.NewTool("echo", mcp.WithDescription("echo text"), mcp.WithString("text", mcp.Required())),
+		func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return mcp.NewToolResultText(req.GetString("text", "")), nil
+		})
+	s.AddTool(mcp.NewTool("add", mcp.WithNumber("a", mcp.Required()), mcp.WithNumber("b", mcp.Required())),
+		func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return mcp.NewToolResultText(fmt.Sprint(req.GetFloat("a", 0) + req.GetFloat("b", 0))), nil
+		})
+	s.AddTool(mcp.NewTool("fail"),
+		func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return mcp.NewToolResultError("something went wrong"), nil
+		})
+	s.AddTool(mcp.NewTool("exit"),
+		func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			os.Exit(1)
+			return nil, nil
+		})
+	s.AddTool(mcp.NewTool("secret"),
+		func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return mcp.NewToolResultText("should not be reachable"), nil
+		})
+	return s
+}
+
+func newTestRegistry(t *testing.T) *toolbox.Registry {
+	t.Helper()
+	r, err := toolbox.NewRegistry(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func invoke(t *testing.T, r *toolbox.Registry, name, args string) (string, error) {
+	t.Helper()
+	tools, err := r.Get([]string{name})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tools[0].(tool.InvokableTool).InvokableRun(context.Background(), args)
+}
+
+func TestStdioServerToolsAndReconnect(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r := newTestRegistry(t)
+
+	m := NewManager(ctx, []ServerConfig{{
+		Name:    "stub",
+		Type:    TransportStdio,
+		Command: os.Args[0],
+		Env:     map[string]string{"GO_AGENT_MCP_STUB": "1"},
+		Tools:   []string{"echo", "fail", "exit"},
+	}}, r, Options{Timeout: 10 * time.Second})
+	defer m.Close()
+
+	for _, name := range []string{"stub_echo", "stub_fail", "stub_exit"} {
+		if !r.Has(name) {
+			t.Fatalf("tool %s not registered", name)
+		}
+	}
+	if r.Has("stub_secret") || r.Has("stub_add") {
+		t.Fatal("tools outside the allowlist were registered")
+	}
+
+	out, err := invoke(t, r, "stub_echo", `{"text":"hello"}`)
+	if err != nil || out != "hello" {
+		t.Fatalf("echo = %q, %v", out, err)
+	}
+	if _, err := invoke(t, r, "stub_fail", `{}`); err == nil || !strings.Contains(err.Error(), "something went wrong") {
+		t.Fatalf("fail err = %v", err)
+	}
+
+	// 服务器进程退出后调用失败，下一次调用重新启动服务器
+	if _, err := invoke(t, r, "stub_exit", `{}`); err == nil {
+		t.Fatal("exit succeeded, want error")
+	}
+	out, err = invoke(t, r, "stub_echo", `{"text":"again"}`)
+	if err != nil || out != "again" {
+		t.Fatalf("echo after reconnect = %q, %v", out, err)
+	}
+}
+
+func TestHTTPServerTools(t *testing.T) {
+	srv := httptest.NewServer(server.NewStreamableHTTPServer(newStubServer()))
+	defer srv.Close()
+	r := newTestRegistry(t)
+
+	m := NewManager(context.Background(), []ServerConfig{{
+		Name:  "remote",
+		Type:  TransportHTTP,
+		URL:   srv.URL + "/mcp",
+		Tools: []string{"add"},
+	}}, r, Options{Timeout: 10 * time.Second})
+	defer m.Close()
+
+	tools, err := r.Get([]string{"remote_add"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := tools[0].Info(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	js, err := info.ParamsOneOf.ToJSONSchema()
+	if err != nil || js.Properties.Len() != 2 || len(js.Required) != 2 {
+		t.Fatalf("schema = %+v, err = %v", js, err)
+	}
+
+	out, err := invoke(t, r, "remote_add", `{"a":2,"b":3.5}`)
+	if err != nil || out != "5.5" {
+		t.Fatalf("add = %q, %v", out, err)
+	}
+}
+
+func TestParseServers(t *testing.T) {
+	servers, err := ParseServers([]byte(`{"mcpServers":{
+		"files":{"command":"npx","args":["-y","server"],"tools":["read"]},
+		"web":{"url":"https://mcp.example.com/mcp","headers":{"Authorization":"Bearer x"}},
+		"off":{"command":"x","disabled":true}
+	}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(servers) != 2 || servers[0].Name != "files" || servers[0].Type != TransportStdio || servers[1].Type != TransportHTTP {
+		t.Fatalf("servers = %+v", servers)
+	}
+
+	for _, raw := range []string{
+		`{"mcpServers":{"bad name":{"command":"x"}}}`,
+		`{"mcpServers":{"a":{}}}`,
+		`{"mcpServers":{"a":{"type":"http","url":"ftp://x"}}}`,
+		`{"mcpServers":{"a":{"type":"sse","url":"http://x"}}}`,
+	} {
+		if _, err := ParseServers([]byte(raw)); err == nil {
+			t.Errorf("config %s accepted, want error", raw)
+		}
+	}
+
+	if got := ToolName("srv", "get.weather/v2"); got != "srv_get_weather_v2" {
+		t.Fatalf("tool name = %q", got)
+	}
+}
@@ -0,0 +1,340 @@
+package plan
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-agent/agent"
+	"strings"
+	"sync"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/schema"
+)
+
+// 计划轨迹事件类型，步骤内的事件沿用 agent 包的类型并以 Step 字段区分所属步骤
+const (
+	EventPlan       = "plan"        // 初始计划，Plan 为全部步骤
+	EventReplan     = "replan"      // 步骤失败后重新规划，Plan 为替换后的完整计划，Content 为失败原因
+	EventStepStart  = "step_start"  // 开始执行步骤，Content 为步骤任务
+	EventStepResult = "step_result" // 步骤结束，Result 为步骤产出，失败时 Error 不为空
+)
+
+// 步骤状态
+const (
+	StepPending = "pending"
+	StepDone    = "done"
+	StepFailed  = "failed"
+)
+
+// 默认限制
+const (
+	DefaultMaxSteps   = 8
+	DefaultMaxReplans = 2
+)
+
+// ErrTooManyReplans 重新规划次数用尽后仍有步骤失败
+var ErrTooManyReplans = errors.New("plan exceeded max replans")
+
+// stepFailedPrefix 执行步骤的模型无法完成步骤时以此开头回答，视为步骤失败
+const stepFailedPrefix = "STEP FAILED:"
+
+const plannerPrompt = `You are the planner of a plan-and-execute agent.
+Break the user's request into a short sequence of concrete steps. Each step is carried out by an executor that can use the tools below and sees the results of earlier steps, but not the conversation.
+Every step must be self-contained, name the information it needs, and produce a result that later steps or the final answer can use. Use at most %d steps; a simple request may need only one.
+
+Tools available to the executor:
+%s
+Reply with JSON only, no prose and no code fences:
+{"steps":[{"task":"what to do in this step","tools":["names of tools the step will likely use"]}]}`
+
+const replanPrompt = `
+
+A step of the current plan failed. Completed steps and their results:
+%s
+Failed step: %s
+Failure: %s
+
+Write a new plan for the remaining work only, avoiding the approach that failed. Do not repeat completed steps. Reply with the same JSON format.`
+
+const executorPrompt = `You are the executor of a plan-and-execute agent working towards this goal:
+%s
+
+Carry out only the current step. Use tools when they help, and answer with the step's result: the facts, figures or text later steps need, without addressing the user.
+If the step cannot be completed, answer with "` + stepFailedPrefix + `" followed by the reason.`
+
+const synthesisPrompt = `You are answering the user's request using the results of a plan that has been carried out step by step.
+Base the answer on the step results below; say so when they do not contain what the user asked for.
+
+Step results:
+%s`
+
+// Step 计划中的一个步骤
+type Step struct {
+	ID     int      `json:"id"`
+	Task   string   `json:"task"`
+	Tools  []string `json:"tools,omitempty"` // 规划时建议使用的工具，执行时全部工具均可用
+	Status string   `json:"status,omitempty"`
+	Result string   `json:"result,omitempty"`
+	Error  string   `json:"error,omitempty"`
+}
+
+// Event 一条计划轨迹，Step 为事件所属的步骤，规划与最终回答事件为 0
+type Event struct {
+	agent.Event
+	Step int    `json:"step,omitempty"`
+	Plan []Step `json:"plan,omitempty"`
+}
+
+// Config 单次运行的配置
+type Config struct {
+	Model         model.ToolCallingChatModel
+	Tools         []tool.BaseTool
+	MaxSteps      int // 计划最多包含的步骤数
+	MaxReplans    int // 步骤失败后最多重新规划的次数，为 0 时使用默认值，小于 0 时不重新规划
+	MaxIterations int // 每个步骤内最多调用模型的轮数
+	ModelOptions  []model.Option
+}
+
+// Result 运行结果，Plan 为最终执行的计划及每一步的结果
+type Result struct {
+	Answer  *schema.Message
+	Plan    []Step
+	Replans int
+	Trace   []Event
+}
+
+// Run 先让模型生成 JSON 步骤计划，再逐步以 ReAct 方式调用工具或检索执行；步骤失败时带着已完成步骤的结果重新规划剩余部分，
+// 全部完成后根据各步结果生成最终回答。messages 为对话历史与用户问题，不含系统提示词；每条轨迹产生时通过 emit 回调（可为 nil）
+func Run(ctx context.Context, conf Config, messages []*schema.Message, emit func(Event)) (*Result, error) {
+	if conf.MaxSteps <= 0 {
+		conf.MaxSteps = DefaultMaxSteps
+	}
+	switch {
+	case conf.MaxReplans == 0:
+		conf.MaxReplans = DefaultMaxReplans
+	case conf.MaxReplans < 0:
+		conf.MaxReplans = 0
+	}
+
+	r := &runner{conf: conf, messages: messages, tr: &tracer{emit: emit}}
+	result := &Result{}
+	defer func() { result.Trace = r.tr.events() }()
+
+	tools, err := describeTools(ctx, conf.Tools)
+	if err != nil {
+		return result, err
+	}
+	r.tools = tools
+
+	steps, err := r.plan(ctx, "")
+	if err != nil {
+		return result, err
+	}
+	result.Plan = steps
+	r.tr.add(Event{Event: agent.Event{Type: EventPlan}, Plan: cloneSteps(steps)})
+
+	for i := 0; i < len(result.Plan); i++ {
+		step := &result.Plan[i]
+		err := r.execute(ctx, result.Plan[:i], step)
+		if err == nil {
+			continue
+		}
+		if ctx.Err() != nil {
+			return result, err
+		}
+
+		if result.Replans >= conf.MaxReplans {
+			return result, fmt.Errorf("%w: step %d: %s", ErrTooManyReplans, step.ID, step.Error)
+		}
+		result.Replans++
+		steps, err := r.plan(ctx, fmt.Sprintf(replanPrompt, formatResults(result.Plan[:i]), step.Task, step.Error))
+		if err != nil {
+			return result, err
+		}
+		// 失败的步骤保留在计划中，新步骤接在其后编号
+		for j := range steps {
+			steps[j].ID = step.ID + 1 + j
+		}
+		result.Plan = append(result.Plan[:i+1], steps...)
+		r.tr.add(Event{Event: agent.Event{Type: EventReplan, Content: step.Error}, Plan: cloneSteps(result.Plan)})
+	}
+
+	answer, err := r.synthesize(ctx, result.Plan)
+	if err != nil {
+		return result, err
+	}
+	result.Answer = answer
+	r.tr.add(Event{Event: agent.Event{Type: agent.EventAnswer, Content: answer.Content}})
+	return result, nil
+}
+
+type runner struct {
+	conf     Config
+	messages []*schema.Message
+	tools    string
+	tr       *tracer
+}
+
+// plan 生成计划；extra 不为空时为重新规划，追加在规划提示词之后
+func (r *runner) plan(ctx context.Context, extra string) ([]Step, error) {
+	input := append([]*schema.Message{schema.SystemMessage(fmt.Sprintf(plannerPrompt, r.conf.MaxSteps, r.tools) + extra)}, r.messages...)
+	out, err := r.conf.Model.Generate(ctx, input, r.conf.ModelOptions...)
+	if err != nil {
+		return nil, fmt.Errorf("plan failed: %w", err)
+	}
+	steps, err := ParsePlan(out.Content, r.conf.MaxSteps)
+	if err != nil {
+		return nil, fmt.Errorf("plan failed: %w", err)
+	}
+	return steps, nil
+}
+
+// execute 以 ReAct 方式执行一个步骤，done 为此前的步骤（含失败后被替换的步骤）
+func (r *runner) execute(ctx context.Context, done []Step, step *Step) error {
+	r.tr.add(Event{Event: agent.Event{Type: EventStepStart, Content: step.Task}, Step: step.ID})
+
+	var task strings.Builder
+	if results := formatResults(done); results != "" {
+		fmt.Fprintf(&task, "Results of earlier steps:\n%s\n", results)
+	}
+	fmt.Fprintf(&task, "Current step: %s", step.Task)
+	if len(step.Tools) > 0 {
+		fmt.Fprintf(&task, "\nSuggested tools: %s", strings.Join(step.Tools, ", "))
+	}
+	input := []*schema.Message{
+		schema.SystemMessage(fmt.Sprintf(executorPrompt, goal(r.messages))),
+		schema.UserMessage(task.String()),
+	}
+
+	res, err := agent.Run(ctx, agent.Config{
+		Model:         r.conf.Model,
+		Tools:         r.conf.Tools,
+		MaxIterations: r.conf.MaxIterations,
+		ModelOptions:  r.conf.ModelOptions,
+	}, input, func(e agent.Event) {
+		// 步骤的回答由 step_result 给出
+		if e.Type != agent.EventAnswer {
+			r.tr.add(Event{Event: e, Step: step.ID})
+		}
+	})
+	if err == nil {
+		if reason, failed := strings.CutPrefix(strings.TrimSpace(res.Answer.Content), stepFailedPrefix); failed {
+			err = errors.New(strings.TrimSpace(reason))
+		}
+	}
+
+	if err != nil {
+		step.Status, step.Error = StepFailed, err.Error()
+		r.tr.add(Event{Event: agent.Event{Type: EventStepResult, Error: step.Error}, Step: step.ID})
+		return err
+	}
+	step.Status, step.Result = StepDone, res.Answer.Content
+	r.tr.add(Event{Event: agent.Event{Type: EventStepResult, Result: step.Result}, Step: step.ID})
+	return nil
+}
+
+// synthesize 根据各步结果生成最终回答
+func (r *runner) synthesize(ctx context.Context, steps []Step) (*schema.Message, error) {
+	input := append([]*schema.Message{schema.SystemMessage(fmt.Sprintf(synthesisPrompt, formatResults(steps)))}, r.messages...)
+	out, err := r.conf.Model.Generate(ctx, input, r.conf.ModelOptions...)
+	if err != nil {
+		return nil, fmt.Errorf("answer failed: %w", err)
+	}
+	return out, nil
+}
+
+// ParsePlan 解析模型输出的计划，容忍代码块与前后的说明文字，步骤从 1 开始编号
+func ParsePlan(content string, maxSteps int) ([]Step, error) {
+	start, end := strings.Index(content, "{"), strings.LastIndex(content, "}")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("no JSON plan in model output: %q", content)
+	}
+	var out struct {
+		Steps []Step `json:"steps"`
+	}
+	if err := json.Unmarshal([]byte(content[start:end+1]), &out); err != nil {
+		return nil, fmt.Errorf("invalid JSON plan: %w", err)
+	}
+	if len(out.Steps) == 0 {
+		return nil, errors.New("plan has no steps")
+	}
+	if maxSteps > 0 && len(out.Steps) > maxSteps {
+		return nil, fmt.Errorf("plan has %d steps, at most %d allowed", len(out.Steps), maxSteps)
+	}
+
+	steps := make([]Step, len(out.Steps))
+	for i, s := range out.Steps {
+		if strings.TrimSpace(s.Task) == "" {
+			return nil, fmt.Errorf("step %d has no task", i+1)
+		}
+		steps[i] = Step{ID: i + 1, Task: strings.TrimSpace(s.Task), Tools: s.Tools, Status: StepPending}
+	}
+	return steps, nil
+}
+
+func describeTools(ctx context.Context, tools []tool.BaseTool) (string, error) {
+	if len(tools) == 0 {
+		return "(none; steps can only reason over earlier results)\n", nil
+	}
+	var b strings.Builder
+	for _, t := range tools {
+		info, err := t.Info(ctx)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&b, "- %s: %s\n", info.Name, info.Desc)
+	}
+	return b.String(), nil
+}
+
+func formatResults(steps []Step) string {
+	var b strings.Builder
+	for _, s := range steps {
+		switch s.Status {
+		case StepDone:
+			fmt.Fprintf(&b, "%d. %s\nResult: %s\n", s.ID, s.Task, s.Result)
+		case StepFailed:
+			fmt.Fprintf(&b, "%d. %s\nFailed: %s\n", s.ID, s.Task, s.Error)
+		}
+	}
+	return b.String()
+}
+
+// goal 用户最后一条消息即本次要完成的目标
+func goal(messages []*schema.Message) string {
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == schema.User {
+			return messages[i].Content
+		}
+	}
+	return ""
+}
+
+func cloneSteps(steps []Step) []Step {
+	return append([]Step(nil), steps...)
+}
+
+// tracer 汇总规划与各步骤的轨迹，步骤内的工具可能并行调用，需要加锁
+type tracer struct {
+	mu    sync.Mutex
+	emit  func(Event)
+	trace []Event
+}
+
+func (t *tracer) add(e Event) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.trace = append(t.trace, e)
+	if t.emit != nil {
+		t.emit(e)
+	}
+}
+
+func (t *tracer) events() []Event {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]Event(nil), t.trace...)
+}
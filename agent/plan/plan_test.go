@@ -0,0 +1,138 @@
+package plan
+
+import (
+	"context"
+	"errors"
+	"go-agent/model/chat_model"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/components/tool/utils"
+	"github.com/cloudwego/eino/schema"
+)
+
+func toolCall(id, name, args string) *schema.Message {
+	return schema.AssistantMessage("", []schema.ToolCall{{ID: id, Function: schema.FunctionCall{Name: name, Arguments: args}}})
+}
+
+func newLookupTool(t *testing.T) tool.InvokableTool {
+	t.Helper()
+	lookup, err := utils.InferTool("lookup", "look up a fact", func(ctx context.Context, in *struct {
+		Key string `json:"key"`
+	}) (string, error) {
+		return "value of " + in.Key, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return lookup
+}
+
+func stepTypes(trace []Event) string {
+	var types []string
+	for _, e := range trace {
+		if e.Step > 0 {
+			types = append(types, e.Type+"@"+strconv.Itoa(e.Step))
+		} else {
+			types = append(types, e.Type)
+		}
+	}
+	return strings.Join(types, ",")
+}
+
+func TestRunExecutesPlan(t *testing.T) {
+	fake := chat_model.NewFakeChatModel(
+		schema.AssistantMessage("```json\n{\"steps\":[{\"task\":\"look up revenue\",\"tools\":[\"lookup\"]},{\"task\":\"compare with last year\"}]}\n```", nil),
+		toolCall("c1", "lookup", `{"key":"revenue"}`),
+		schema.AssistantMessage("revenue is 10", nil),
+		schema.AssistantMessage("up 25%", nil),
+		schema.AssistantMessage("Revenue is 10, up 25%.", nil),
+	)
+
+	var streamed []Event
+	result, err := Run(context.Background(), Config{Model: fake, Tools: []tool.BaseTool{newLookupTool(t)}},
+		[]*schema.Message{schema.UserMessage("how did revenue change?")}, func(e Event) { streamed = append(streamed, e) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Answer.Content != "Revenue is 10, up 25%." || result.Replans != 0 || len(streamed) != len(result.Trace) {
+		t.Fatalf("result = %+v", result)
+	}
+	want := "plan,step_start@1,iteration@1,tool_call@1,tool_result@1,iteration@1,step_result@1," +
+		"step_start@2,iteration@2,step_result@2,answer"
+	if got := stepTypes(result.Trace); got != want {
+		t.Fatalf("trace = %s", got)
+	}
+	if p := result.Plan; len(p) != 2 || p[0].Status != StepDone || p[0].Result != "revenue is 10" || p[1].Result != "up 25%" {
+		t.Fatalf("plan = %+v", p)
+	}
+	// 后续步骤看到此前步骤的结果，最终回答看到全部结果
+	inputs := fake.Inputs()
+	if in := inputs[3][1].Content; !strings.Contains(in, "revenue is 10") || !strings.Contains(in, "Current step: compare with last year") {
+		t.Fatalf("step 2 input = %q", in)
+	}
+	if sys := inputs[4][0].Content; !strings.Contains(sys, "Result: up 25%") {
+		t.Fatalf("synthesis prompt = %q", sys)
+	}
+}
+
+func TestRunReplansAfterFailedStep(t *testing.T) {
+	fake := chat_model.NewFakeChatModel(
+		schema.AssistantMessage(`{"steps":[{"task":"read the report"},{"task":"summarise it"}]}`, nil),
+		schema.AssistantMessage("STEP FAILED: the report is not available", nil),
+		schema.AssistantMessage(`{"steps":[{"task":"search the knowledge base instead"}]}`, nil),
+		schema.AssistantMessage("found a summary", nil),
+		schema.AssistantMessage("Here is the summary.", nil),
+	)
+
+	result, err := Run(context.Background(), Config{Model: fake}, []*schema.Message{schema.UserMessage("summarise the report")}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Replans != 1 || result.Answer.Content != "Here is the summary." {
+		t.Fatalf("result = %+v", result)
+	}
+	p := result.Plan
+	if len(p) != 2 || p[0].Status != StepFailed || p[0].Error != "the report is not available" || p[1].ID != 2 || p[1].Status != StepDone {
+		t.Fatalf("plan = %+v", p)
+	}
+	if got := stepTypes(result.Trace); got != "plan,step_start@1,iteration@1,step_result@1,replan,step_start@2,iteration@2,step_result@2,answer" {
+		t.Fatalf("trace = %s", got)
+	}
+	if sys := fake.Inputs()[2][0].Content; !strings.Contains(sys, "Failure: the report is not available") {
+		t.Fatalf("replan prompt = %q", sys)
+	}
+
+	// 重新规划次数用尽后返回错误
+	fake = chat_model.NewFakeChatModel(
+		schema.AssistantMessage(`{"steps":[{"task":"read the report"}]}`, nil),
+		schema.AssistantMessage("STEP FAILED: still missing", nil),
+	)
+	_, err = Run(context.Background(), Config{Model: fake, MaxReplans: -1}, []*schema.Message{schema.UserMessage("summarise")}, nil)
+	if !errors.Is(err, ErrTooManyReplans) {
+		t.Fatalf("err = %v, want ErrTooManyReplans", err)
+	}
+}
+
+func TestParsePlan(t *testing.T) {
+	steps, err := ParsePlan("Here is the plan:\n{\"steps\":[{\"task\":\" a \"},{\"task\":\"b\",\"tools\":[\"calculator\"]}]}", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(steps) != 2 || steps[0].ID != 1 || steps[0].Task != "a" || steps[1].Tools[0] != "calculator" || steps[1].Status != StepPending {
+		t.Fatalf("steps = %+v", steps)
+	}
+
+	for _, content := range []string{
+		"no plan",
+		`{"steps":[]}`,
+		`{"steps":[{"task":""}]}`,
+		`{"steps":[{"task":"a"},{"task":"b"},{"task":"c"},{"task":"d"}]}`,
+	} {
+		if _, err := ParsePlan(content, 3); err == nil {
+			t.Errorf("plan %q accepted, want error", content)
+		}
+	}
+}
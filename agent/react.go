@@ -0,0 +1,431 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/flow/agent/react"
+	"github.com/cloudwego/eino/schema"
+)
+
+// 执行轨迹事件类型
+const (
+	EventIteration  = "iteration"         // 开始第 N 轮模型调用
+	EventThought    = "thought"           // 模型在调用工具前输出的文本
+	EventToolCall   = "tool_call"         // 模型请求调用工具
+	EventToolResult = "tool_result"       // 工具返回结果或错误
+	EventAnswer     = "answer"            // 最终回答
+	EventApproval   = "approval_required" // 工具调用等待人工审批，运行暂停
+)
+
+// DefaultMaxIterations 未配置时的最大模型调用轮数
+const DefaultMaxIterations = 8
+
+// ErrMaxIterations 超过最大迭代次数仍未得到最终回答
+var ErrMaxIterations = errors.New("agent exceeded max iterations")
+
+// Event 一条执行轨迹
+type Event struct {
+	Type string `json:"type"`
+	// Agent 多 Agent 运行时产生事件的 Agent 名称
+	Agent     string `json:"agent,omitempty"`
+	Iteration int    `json:"iteration,omitempty"`
+	Content   string `json:"content,omitempty"`
+	Tool      string `json:"tool,omitempty"`
+	CallID    string `json:"call_id,omitempty"`
+	Arguments string `json:"arguments,omitempty"`
+	Result    string `json:"result,omitempty"`
+	Error     string `json:"error,omitempty"`
+	// InterruptID 等待审批的工具调用在检查点中的位置，恢复运行时用来指定审批结果
+	InterruptID string `json:"interrupt_id,omitempty"`
+}
+
+// Config 单次运行的配置
+type Config struct {
+	Model         model.ToolCallingChatModel
+	Tools         []tool.BaseTool
+	MaxIterations int // 最多调用模型的轮数，<=0 时使用 DefaultMaxIterations
+	ModelOptions  []model.Option
+
+	// NeedsApproval 返回 true 的工具在执行前暂停运行等待审批，需要同时设置 CheckPoints 与 RunID
+	NeedsApproval func(tool string) bool
+	CheckPoints   compose.CheckPointStore
+	RunID         string
+	// Iteration 恢复运行时此前已完成的轮数，轮数上限包含这些轮
+	Iteration int
+	// OnMessages 对话每增长一步（模型输出、每个工具结果）时以完整消息回调，用于持久化，可为 nil
+	OnMessages func(messages []*schema.Message)
+}
+
+// Result 运行结果，Pending 不为空时运行已暂停，Answer 为 nil
+type Result struct {
+	Answer     *schema.Message
+	Iterations int
+	Trace      []Event
+	Pending    []Event
+}
+
+// Run 以 ReAct 循环运行 Agent：模型决定调用哪些工具，工具结果回填后继续推理，直到模型给出最终回答
+// 每条轨迹在产生时通过 emit 回调（可为 nil），工具执行出错时把错误作为结果交给模型而不是中断运行
+// 调用需要审批的工具时运行暂停，状态写入检查点，返回的 Result.Pending 列出等待审批的调用
+func Run(ctx context.Context, conf Config, messages []*schema.Message, emit func(Event)) (*Result, error) {
+	return run(ctx, conf, messages, newTracer(conf, emit))
+}
+
+// Resume 从检查点恢复暂停的运行，decisions 以 InterruptID 为键给出审批结果，未给出结果的调用继续等待审批
+// messages 为暂停时的对话，仅用于 OnMessages 继续记录，运行状态以检查点为准
+func Resume(ctx context.Context, conf Config, messages []*schema.Message, decisions map[string]*Decision, emit func(Event)) (*Result, error) {
+	if conf.CheckPoints == nil || conf.RunID == "" {
+		return nil, ErrNoCheckPoints
+	}
+	data := make(map[string]any, len(decisions))
+	for id, d := range decisions {
+		data[id] = d
+	}
+	tr := newTracer(conf, emit)
+	tr.messages = slices.Clone(messages)
+	return run(compose.BatchResumeWithData(ctx, data), conf, nil, tr)
+}
+
+// Continue 从持久化的对话消息继续运行，用于进程重启或连接断开后从最后完成的一步恢复
+// 最后一轮模型输出中还没有结果的工具调用先补齐执行再回到 ReAct 循环；其中有需要审批的工具时丢弃这一轮，由模型重新决定
+func Continue(ctx context.Context, conf Config, messages []*schema.Message, emit func(Event)) (*Result, error) {
+	tr := newTracer(conf, emit)
+	tr.messages = slices.Clone(messages)
+
+	last, missing := unfinishedToolCalls(messages)
+	needsApproval := false
+	for _, call := range missing {
+		needsApproval = needsApproval || (conf.NeedsApproval != nil && conf.NeedsApproval(call.Function.Name))
+	}
+	switch {
+	case needsApproval:
+		messages = messages[:last]
+		tr.messages = slices.Clone(messages)
+		tr.round = max(tr.round-1, 0)
+	case len(missing) > 0:
+		if err := tr.runToolCalls(ctx, conf.Tools, missing); err != nil {
+			return &Result{Iterations: tr.iteration(), Trace: tr.events()}, err
+		}
+		messages = tr.conversation()
+	}
+	return run(ctx, conf, messages, tr)
+}
+
+func run(ctx context.Context, conf Config, messages []*schema.Message, tr *tracer) (*Result, error) {
+	maxIterations := conf.MaxIterations
+	if maxIterations <= 0 {
+		maxIterations = DefaultMaxIterations
+	}
+	if conf.NeedsApproval != nil && (conf.CheckPoints == nil || conf.RunID == "") {
+		return nil, ErrNoCheckPoints
+	}
+
+	ra, err := react.NewAgent(ctx, &react.AgentConfig{
+		ToolCallingModel: &tracingModel{inner: conf.Model, tracer: tr, maxIterations: maxIterations},
+		ToolsConfig: compose.ToolsNodeConfig{
+			Tools:               conf.Tools,
+			ToolCallMiddlewares: []compose.ToolMiddleware{tr.middleware()},
+			UnknownToolsHandler: func(ctx context.Context, name, input string) (string, error) {
+				return tr.unknownTool(name, compose.GetToolCallID(ctx)), nil
+			},
+		},
+		// 每轮包含模型与工具两个节点，多留一步给最终回答，实际轮数由 tracingModel 控制
+		MaxStep: 2*maxIterations + 2,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create react agent failed: %w", err)
+	}
+
+	// 把 ReAct 图嵌入外层图编译，以便启用检查点
+	g, nodeOpts := ra.ExportGraph()
+	outer := compose.NewGraph[[]*schema.Message, *schema.Message]()
+	_ = outer.AddGraphNode(reactNode, g, nodeOpts...)
+	_ = outer.AddEdge(compose.START, reactNode)
+	_ = outer.AddEdge(reactNode, compose.END)
+
+	compileOpts := []compose.GraphCompileOption{compose.WithGraphName(runGraphName)}
+	var runOpts []compose.Option
+	if conf.CheckPoints != nil {
+		compileOpts = append(compileOpts, compose.WithCheckPointStore(conf.CheckPoints))
+		runOpts = append(runOpts, compose.WithCheckPointID(conf.RunID))
+		// 从消息开始（新运行或断点续跑）时忽略此前暂停留下的检查点
+		if messages != nil {
+			runOpts = append(runOpts, compose.WithForceNewRun())
+		}
+	}
+	runnable, err := outer.Compile(ctx, compileOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("compile agent graph failed: %w", err)
+	}
+
+	runOpts = append(runOpts, compose.WithChatModelOption(conf.ModelOptions...))
+	answer, err := runnable.Invoke(ctx, messages, runOpts...)
+	result := &Result{Iterations: tr.iteration()}
+	if err != nil {
+		if info, ok := compose.ExtractInterruptInfo(err); ok {
+			if pending := pendingApprovals(info); len(pending) > 0 {
+				for _, e := range pending {
+					tr.add(e)
+				}
+				result.Trace, result.Pending = tr.events(), pending
+				return result, nil
+			}
+		}
+		result.Trace = tr.events()
+		if tr.exceeded || errors.Is(err, compose.ErrExceedMaxSteps) {
+			return result, ErrMaxIterations
+		}
+		return result, err
+	}
+
+	tr.add(Event{Type: EventAnswer, Iteration: tr.iteration(), Content: answer.Content})
+	result.Answer = answer
+	result.Trace = tr.events()
+	return result, nil
+}
+
+// tracer 收集轨迹并按产生顺序回调，工具可能并发执行，需要加锁
+type tracer struct {
+	mu    sync.Mutex
+	emit  func(Event)
+	trace []Event
+	round int
+
+	exceeded bool // 达到最大轮数后模型仍要求继续
+
+	needsApproval func(tool string) bool
+
+	messages   []*schema.Message // 当前完整对话
+	onMessages func([]*schema.Message)
+}
+
+func newTracer(conf Config, emit func(Event)) *tracer {
+	return &tracer{emit: emit, round: conf.Iteration, needsApproval: conf.NeedsApproval, onMessages: conf.OnMessages}
+}
+
+func (t *tracer) add(e Event) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.trace = append(t.trace, e)
+	if t.emit != nil {
+		t.emit(e)
+	}
+}
+
+// next 开始新一轮，已达到 max 轮时返回 false
+func (t *tracer) next(max int) (int, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.round >= max {
+		t.exceeded = true
+		return t.round, false
+	}
+	t.round++
+	return t.round, true
+}
+
+func (t *tracer) iteration() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.round
+}
+
+func (t *tracer) events() []Event {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]Event(nil), t.trace...)
+}
+
+// setMessages 以模型的输入与输出作为当前对话
+func (t *tracer) setMessages(input []*schema.Message, out *schema.Message) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.messages = append(slices.Clone(input), out)
+	t.notifyMessages()
+}
+
+func (t *tracer) addMessage(msg *schema.Message) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.messages = append(t.messages, msg)
+	t.notifyMessages()
+}
+
+func (t *tracer) notifyMessages() {
+	if t.onMessages != nil {
+		t.onMessages(slices.Clone(t.messages))
+	}
+}
+
+func (t *tracer) conversation() []*schema.Message {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return slices.Clone(t.messages)
+}
+
+// toolResult 记录工具结果：轨迹事件与交给模型的工具消息，errMsg 不为空时 content 为错误文本
+func (t *tracer) toolResult(name, callID, content, errMsg string) {
+	e := Event{Type: EventToolResult, Iteration: t.iteration(), Tool: name, CallID: callID, Result: content}
+	if errMsg != "" {
+		e.Result, e.Error = "", errMsg
+	}
+	t.add(e)
+	t.addMessage(schema.ToolMessage(content, callID, schema.WithToolName(name)))
+}
+
+func (t *tracer) unknownTool(name, callID string) string {
+	content := fmt.Sprintf("error: tool %s does not exist", name)
+	t.toolResult(name, callID, content, "unknown tool")
+	return content
+}
+
+// runToolCalls 在 ReAct 图之外依次执行工具调用，结果同样记入轨迹与对话
+func (t *tracer) runToolCalls(ctx context.Context, tools []tool.BaseTool, calls []schema.ToolCall) error {
+	byName := make(map[string]tool.InvokableTool, len(tools))
+	for _, bt := range tools {
+		info, err := bt.Info(ctx)
+		if err != nil {
+			return err
+		}
+		if it, ok := bt.(tool.InvokableTool); ok {
+			byName[info.Name] = it
+		}
+	}
+
+	for _, call := range calls {
+		it, ok := byName[call.Function.Name]
+		if !ok {
+			t.unknownTool(call.Function.Name, call.ID)
+			continue
+		}
+		out, err := it.InvokableRun(ctx, call.Function.Arguments)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			t.toolResult(call.Function.Name, call.ID, "error: "+err.Error(), err.Error())
+			continue
+		}
+		t.toolResult(call.Function.Name, call.ID, out, "")
+	}
+	return nil
+}
+
+// unfinishedToolCalls 返回最后一条模型消息的位置及其中还没有工具结果的调用
+func unfinishedToolCalls(messages []*schema.Message) (int, []schema.ToolCall) {
+	last := -1
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == schema.Assistant {
+			last = i
+			break
+		}
+	}
+	if last < 0 {
+		return last, nil
+	}
+
+	answered := make(map[string]bool)
+	for _, msg := range messages[last+1:] {
+		if msg.Role == schema.Tool {
+			answered[msg.ToolCallID] = true
+		}
+	}
+	var missing []schema.ToolCall
+	for _, call := range messages[last].ToolCalls {
+		if !answered[call.ID] {
+			missing = append(missing, call)
+		}
+	}
+	return last, missing
+}
+
+// middleware 记录工具结果，并把工具错误转换为结果文本交给模型
+func (t *tracer) middleware() compose.ToolMiddleware {
+	return compose.ToolMiddleware{
+		Invokable: func(next compose.InvokableToolEndpoint) compose.InvokableToolEndpoint {
+			return func(ctx context.Context, input *compose.ToolInput) (*compose.ToolOutput, error) {
+				if t.needsApproval != nil && t.needsApproval(input.Name) {
+					approved, output, err := t.approve(ctx, input)
+					if !approved {
+						return output, err
+					}
+				}
+				output, err := next(ctx, input)
+				if err != nil {
+					if ctx.Err() != nil {
+						return nil, err
+					}
+					output = &compose.ToolOutput{Result: "error: " + err.Error()}
+					t.toolResult(input.Name, input.CallID, output.Result, err.Error())
+					return output, nil
+				}
+				t.toolResult(input.Name, input.CallID, output.Result, "")
+				return output, nil
+			}
+		},
+	}
+}
+
+// tracingModel 包装工具调用模型：统计迭代轮数、限制最大轮数，并记录模型的思考与工具调用
+type tracingModel struct {
+	inner         model.ToolCallingChatModel
+	tracer        *tracer
+	maxIterations int
+}
+
+func (m *tracingModel) Generate(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	round, err := m.begin()
+	if err != nil {
+		return nil, err
+	}
+	out, err := m.inner.Generate(ctx, input, opts...)
+	if err != nil {
+		return nil, err
+	}
+	m.record(round, out)
+	m.tracer.setMessages(input, out)
+	return out, nil
+}
+
+func (m *tracingModel) Stream(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	if _, err := m.begin(); err != nil {
+		return nil, err
+	}
+	return m.inner.Stream(ctx, input, opts...)
+}
+
+func (m *tracingModel) WithTools(tools []*schema.ToolInfo) (model.ToolCallingChatModel, error) {
+	inner, err := m.inner.WithTools(tools)
+	if err != nil {
+		return nil, err
+	}
+	return &tracingModel{inner: inner, tracer: m.tracer, maxIterations: m.maxIterations}, nil
+}
+
+func (m *tracingModel) begin() (int, error) {
+	round, ok := m.tracer.next(m.maxIterations)
+	if !ok {
+		return 0, ErrMaxIterations
+	}
+	m.tracer.add(Event{Type: EventIteration, Iteration: round})
+	return round, nil
+}
+
+func (m *tracingModel) record(round int, out *schema.Message) {
+	if len(out.ToolCalls) == 0 {
+		return
+	}
+	if out.Content != "" {
+		m.tracer.add(Event{Type: EventThought, Iteration: round, Content: out.Content})
+	}
+	for _, call := range out.ToolCalls {
+		m.tracer.add(Event{Type: EventToolCall, Iteration: round, Tool: call.Function.Name, CallID: call.ID, Arguments: call.Function.Arguments})
+	}
+}
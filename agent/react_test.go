@@ -0,0 +1,164 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"go-agent/model/chat_model"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/components/tool/utils"
+	"github.com/cloudwego/eino/schema"
+)
+
+type addInput struct {
+	A int `json:"a"`
+	B int `json:"b"`
+}
+
+func newAddTool(t *testing.T) tool.InvokableTool {
+	t.Helper()
+	add, err := utils.InferTool("add", "add two integers", func(ctx context.Context, in *addInput) (int, error) {
+		if in.A < 0 {
+			return 0, errors.New("negative input")
+		}
+		return in.A + in.B, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return add
+}
+
+func toolCall(id, name, args string) *schema.Message {
+	return schema.AssistantMessage("", []schema.ToolCall{{ID: id, Function: schema.FunctionCall{Name: name, Arguments: args}}})
+}
+
+func eventTypes(trace []Event) string {
+	types := make([]string, len(trace))
+	for i, e := range trace {
+		types[i] = e.Type
+	}
+	return strings.Join(types, ",")
+}
+
+func TestRunToolLoop(t *testing.T) {
+	fake := chat_model.NewFakeChatModel(
+		toolCall("c1", "add", `{"a":1,"b":2}`),
+		schema.AssistantMessage("the sum is 3", nil),
+	)
+
+	var streamed []Event
+	result, err := Run(context.Background(), Config{Model: fake, Tools: []tool.BaseTool{newAddTool(t)}},
+		[]*schema.Message{schema.UserMessage("1+2?")}, func(e Event) { streamed = append(streamed, e) })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Answer.Content != "the sum is 3" || result.Iterations != 2 {
+		t.Fatalf("answer = %q, iterations = %d", result.Answer.Content, result.Iterations)
+	}
+	if got := eventTypes(streamed); got != "iteration,tool_call,tool_result,iteration,answer" {
+		t.Fatalf("trace = %s", got)
+	}
+	if r := streamed[2]; r.Tool != "add" || r.CallID != "c1" || r.Result != "3" {
+		t.Fatalf("tool result = %+v", r)
+	}
+
+	// 第二轮模型输入包含工具结果
+	inputs := fake.Inputs()
+	last := inputs[len(inputs)-1]
+	if msg := last[len(last)-1]; msg.Role != schema.Tool || msg.Content != "3" {
+		t.Fatalf("last input = %+v", msg)
+	}
+}
+
+func TestRunToolErrorsAreReturnedToModel(t *testing.T) {
+	fake := chat_model.NewFakeChatModel(
+		toolCall("c1", "add", `{"a":-1,"b":2}`),
+		toolCall("c2", "missing", `{}`),
+		schema.AssistantMessage("gave up", nil),
+	)
+
+	result, err := Run(context.Background(), Config{Model: fake, Tools: []tool.BaseTool{newAddTool(t)}},
+		[]*schema.Message{schema.UserMessage("?")}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var errs []string
+	for _, e := range result.Trace {
+		if e.Type == EventToolResult {
+			errs = append(errs, e.Error)
+		}
+	}
+	if len(errs) != 2 || !strings.Contains(errs[0], "negative input") || errs[1] != "unknown tool" {
+		t.Fatalf("tool errors = %q", errs)
+	}
+}
+
+func TestRunMaxIterations(t *testing.T) {
+	script := make([]*schema.Message, 5)
+	for i := range script {
+		script[i] = toolCall("c", "add", `{"a":1,"b":1}`)
+	}
+	fake := chat_model.NewFakeChatModel(script...)
+
+	result, err := Run(context.Background(), Config{Model: fake, Tools: []tool.BaseTool{newAddTool(t)}, MaxIterations: 2},
+		[]*schema.Message{schema.UserMessage("loop")}, nil)
+	if !errors.Is(err, ErrMaxIterations) {
+		t.Fatalf("err = %v, want ErrMaxIterations", err)
+	}
+	if result.Iterations != 2 || len(fake.Inputs()) != 2 {
+		t.Fatalf("iterations = %d, model calls = %d", result.Iterations, len(fake.Inputs()))
+	}
+}
+
+func TestContinueRunsUnfinishedToolCalls(t *testing.T) {
+	fake := chat_model.NewFakeChatModel(schema.AssistantMessage("the sum is 3", nil))
+	// 中断前模型已经要求调用工具，但工具结果还没有写入
+	messages := []*schema.Message{schema.UserMessage("1+2?"), toolCall("c1", "add", `{"a":1,"b":2}`)}
+
+	var saved []*schema.Message
+	conf := Config{Model: fake, Tools: []tool.BaseTool{newAddTool(t)}, Iteration: 1,
+		OnMessages: func(msgs []*schema.Message) { saved = msgs }}
+	result, err := Continue(context.Background(), conf, messages, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Answer.Content != "the sum is 3" || result.Iterations != 2 {
+		t.Fatalf("result = %+v", result)
+	}
+	if got := eventTypes(result.Trace); got != "tool_result,iteration,answer" {
+		t.Fatalf("trace = %s", got)
+	}
+	if in := fake.Inputs()[0]; len(in) != 3 || in[2].Role != schema.Tool || in[2].Content != "3" {
+		t.Fatalf("model input = %+v", in)
+	}
+	if len(saved) != 4 || saved[3].Content != "the sum is 3" {
+		t.Fatalf("saved messages = %+v", saved)
+	}
+}
+
+func TestContinueDropsRoundNeedingApproval(t *testing.T) {
+	fake := chat_model.NewFakeChatModel(
+		toolCall("c2", "add", `{"a":1,"b":2}`),
+		schema.AssistantMessage("the sum is 3", nil),
+	)
+	conf := newApprovalConfig(t, fake)
+	conf.Iteration = 1
+	messages := []*schema.Message{schema.UserMessage("1+2?"), toolCall("c1", "add", `{"a":1,"b":2}`)}
+
+	// 未经审批的调用不能直接补齐执行，丢弃这一轮后由模型重新决定，并照常等待审批
+	result, err := Continue(context.Background(), conf, messages, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Pending) != 1 || result.Pending[0].CallID != "c2" || result.Iterations != 1 {
+		t.Fatalf("result = %+v", result)
+	}
+	if in := fake.Inputs()[0]; len(in) != 1 {
+		t.Fatalf("model input = %+v", in)
+	}
+}
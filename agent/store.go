@@ -0,0 +1,218 @@
+package agent
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+// 运行状态
+const (
+	RunRunning         = "running"
+	RunWaitingApproval = "waiting_approval"
+	RunCompleted       = "completed"
+	RunFailed          = "failed"
+	RunInterrupted     = "interrupted" // 连接断开或进程重启时未完成，可以从最后完成的一步继续
+)
+
+// Runs 全局运行存储，未初始化时运行不落盘，也不能使用需要审批的工具
+var Runs *RunStore
+
+var (
+	ErrRunNotFound = errors.New("agent run not found")
+	ErrRunStatus   = errors.New("agent run status does not allow this operation")
+)
+
+var runIDValid = regexp.MustCompile(`^[A-Za-z0-9]+$`)
+
+// RunRecord 一次运行的持久化记录
+type RunRecord struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+	// Request 创建运行的原始请求，恢复时用来重建模型与工具配置
+	Request    json.RawMessage `json:"request,omitempty"`
+	Prompt     string          `json:"prompt,omitempty"`
+	Iterations int             `json:"iterations"`
+	// Messages 最后完成的一步之后的完整对话；前 Input 条是首轮输入（系统提示词、历史与问题）
+	Messages  []*schema.Message `json:"messages,omitempty"`
+	Input     int               `json:"input"`
+	Trace     []Event           `json:"trace,omitempty"`
+	Pending   []Event           `json:"pending,omitempty"`
+	Answer    string            `json:"answer,omitempty"`
+	Provider  string            `json:"provider,omitempty"`
+	Error     string            `json:"error,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
+
+// CompletedIterations 对话中已完成的模型轮数
+func (r *RunRecord) CompletedIterations() int {
+	n := 0
+	for _, msg := range r.Messages[min(r.Input, len(r.Messages)):] {
+		if msg.Role == schema.Assistant {
+			n++
+		}
+	}
+	return n
+}
+
+// RunStore 本地运行存储：<dir>/<ID>.json 保存运行记录，<ID>.checkpoint 保存图检查点
+// 同时实现 compose.CheckPointStore
+type RunStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewRunStore 创建运行存储
+func NewRunStore(dir string) (*RunStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("create agent run dir failed: %w", err)
+	}
+	return &RunStore{dir: dir}, nil
+}
+
+// NewRunID 生成运行 ID
+func NewRunID() string {
+	return rand.Text()
+}
+
+// Get 读取检查点
+func (s *RunStore) Get(ctx context.Context, id string) ([]byte, bool, error) {
+	if !runIDValid.MatchString(id) {
+		return nil, false, fmt.Errorf("invalid run id %q", id)
+	}
+	b, err := os.ReadFile(s.path(id, ".checkpoint"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return b, true, nil
+}
+
+// Set 写入检查点
+func (s *RunStore) Set(ctx context.Context, id string, checkPoint []byte) error {
+	if !runIDValid.MatchString(id) {
+		return fmt.Errorf("invalid run id %q", id)
+	}
+	return writeFile(s.path(id, ".checkpoint"), checkPoint)
+}
+
+// Save 写入运行记录
+func (s *RunStore) Save(r *RunRecord) error {
+	if !runIDValid.MatchString(r.ID) {
+		return fmt.Errorf("invalid run id %q", r.ID)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.save(r)
+}
+
+// Load 读取运行记录
+func (s *RunStore) Load(id string) (*RunRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.load(id)
+}
+
+// Claim 把处于 from 状态之一的运行标记为运行中，保证同一次暂停或中断只被恢复一次
+func (s *RunStore) Claim(id string, from ...string) (*RunRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, err := s.load(id)
+	if err != nil {
+		return nil, err
+	}
+	if !slices.Contains(from, r.Status) {
+		return nil, fmt.Errorf("%w: status %s", ErrRunStatus, r.Status)
+	}
+	r.Status = RunRunning
+	if err := s.save(r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// MarkInterrupted 启动时把上次进程退出时仍在运行的记录标记为中断，返回这些运行的 ID
+func (s *RunStore) MarkInterrupted() ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	paths, err := filepath.Glob(filepath.Join(s.dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, path := range paths {
+		r, err := s.load(strings.TrimSuffix(filepath.Base(path), ".json"))
+		if err != nil {
+			return ids, err
+		}
+		if r.Status != RunRunning {
+			continue
+		}
+		r.Status = RunInterrupted
+		if err := s.save(r); err != nil {
+			return ids, err
+		}
+		ids = append(ids, r.ID)
+	}
+	return ids, nil
+}
+
+func (s *RunStore) load(id string) (*RunRecord, error) {
+	if !runIDValid.MatchString(id) {
+		return nil, fmt.Errorf("%w: %s", ErrRunNotFound, id)
+	}
+	b, err := os.ReadFile(s.path(id, ".json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrRunNotFound, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var r RunRecord
+	if err := json.Unmarshal(b, &r); err != nil {
+		return nil, fmt.Errorf("decode agent run %s failed: %w", id, err)
+	}
+	return &r, nil
+}
+
+func (s *RunStore) save(r *RunRecord) error {
+	now := time.Now()
+	if r.CreatedAt.IsZero() {
+		r.CreatedAt = now
+	}
+	r.UpdatedAt = now
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFile(s.path(r.ID, ".json"), b)
+}
+
+func (s *RunStore) path(id, ext string) string {
+	return filepath.Join(s.dir, id+ext)
+}
+
+// writeFile 先写临时文件再重命名，避免留下写了一半的文件
+func writeFile(path string, b []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,87 @@
+package team
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+)
+
+// Default 配置文件中的团队，未配置 AGENT_TEAM_FILE 时为 nil
+var Default *Config
+
+var agentNameValid = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_-]{0,63}$`)
+
+// Spec 一个 Agent 的配置
+type Spec struct {
+	// Description 成员的能力说明，主管据此决定把子任务交给谁
+	Description string `json:"description,omitempty"`
+	// Prompt 系统提示词，主管为空时使用内置提示词
+	Prompt string `json:"prompt,omitempty"`
+	// Model 聊天模型名称（见 GET /api/models），为空时使用默认故障转移链
+	Model string `json:"model,omitempty"`
+	// Tools 可用的工具名称（见 GET /api/agent/tools），为空时不使用工具；主管的工具固定为各成员
+	Tools []string `json:"tools,omitempty"`
+	// Collection search_knowledge_base 检索的集合，为空时使用 MILVUS_COLLECTION_NAME
+	Collection string `json:"collection,omitempty"`
+	// MaxIterations 最多调用模型的轮数，为空时使用 AGENT_MAX_ITERATIONS
+	MaxIterations int `json:"max_iterations,omitempty"`
+}
+
+// Config 团队配置：一个主管与若干成员，Members 按名称排序
+type Config struct {
+	Supervisor Spec
+	Members    []Member
+}
+
+// Member 一个成员
+type Member struct {
+	Name string
+	Spec
+}
+
+// LoadConfig 读取团队配置文件：{"supervisor":{...},"agents":{"名称":{...}}}
+func LoadConfig(path string) (*Config, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read agent team file failed: %w", err)
+	}
+	return ParseConfig(b)
+}
+
+// ParseConfig 解析团队配置
+func ParseConfig(b []byte) (*Config, error) {
+	var file struct {
+		Supervisor Spec            `json:"supervisor"`
+		Agents     map[string]Spec `json:"agents"`
+	}
+	if err := json.Unmarshal(b, &file); err != nil {
+		return nil, fmt.Errorf("invalid agent team config: %w", err)
+	}
+	if len(file.Agents) == 0 {
+		return nil, fmt.Errorf("agent team config requires at least one agent")
+	}
+	if len(file.Supervisor.Tools) > 0 || file.Supervisor.Collection != "" {
+		return nil, fmt.Errorf("supervisor can only hand off to agents, tools and collection are not allowed")
+	}
+	if file.Supervisor.MaxIterations < 0 {
+		return nil, fmt.Errorf("supervisor: max_iterations must not be negative")
+	}
+
+	conf := &Config{Supervisor: file.Supervisor}
+	for name, spec := range file.Agents {
+		if !agentNameValid.MatchString(name) || name == SupervisorName {
+			return nil, fmt.Errorf("agent %q: name must start with a letter and contain only letters, digits, '_' and '-', and must not be %q", name, SupervisorName)
+		}
+		if spec.Description == "" {
+			return nil, fmt.Errorf("agent %s: description is required for the supervisor to route tasks", name)
+		}
+		if spec.MaxIterations < 0 {
+			return nil, fmt.Errorf("agent %s: max_iterations must not be negative", name)
+		}
+		conf.Members = append(conf.Members, Member{Name: name, Spec: spec})
+	}
+	sort.Slice(conf.Members, func(i, j int) bool { return conf.Members[i].Name < conf.Members[j].Name })
+	return conf, nil
+}
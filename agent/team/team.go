@@ -0,0 +1,295 @@
+package team
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go-agent/agent"
+	"go-agent/agent/toolbox"
+	"go-agent/config"
+	"go-agent/model/chat_model"
+	"go-agent/rag/tools/db"
+	"go-agent/rag/tools/retriever"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/schema"
+)
+
+// 交接轨迹事件类型，其余事件沿用 agent 包的类型并以 Agent 字段区分来源
+const (
+	EventHandoff       = "handoff"        // 主管把子任务交给成员，Content 为任务描述
+	EventHandoffResult = "handoff_result" // 成员完成子任务，Result 为交回主管的结果
+)
+
+// SupervisorName 轨迹中主管的名称
+const SupervisorName = "supervisor"
+
+// DefaultSupervisorPrompt 主管未配置提示词时使用
+const DefaultSupervisorPrompt = `You are the supervisor of a team of specialist agents.
+Break the user's request into sub-tasks and delegate each one by calling the tool named after the agent best suited for it.
+The agents cannot see the conversation, so every task must be self-contained and include all details they need.
+Combine their results into the final answer for the user. Answer directly only when no agent is needed.`
+
+// Agent 解析完成、可以运行的 Agent
+type Agent struct {
+	Name          string
+	Description   string
+	Prompt        string
+	Model         model.ToolCallingChatModel
+	Tools         []tool.BaseTool
+	MaxIterations int
+}
+
+// Team 主管与成员，主管以调用工具的方式把子任务交给成员
+type Team struct {
+	Supervisor Agent
+	Members    []Agent
+}
+
+// Result 运行结果，Iterations 为主管调用模型的轮数
+type Result struct {
+	Answer     *schema.Message
+	Iterations int
+	Handoffs   int
+	Trace      []agent.Event
+}
+
+// Build 按配置解析各 Agent 的模型与工具，maxIterations 为未配置轮数时的默认值
+func Build(ctx context.Context, conf *Config, maxIterations int) (*Team, error) {
+	supervisor, err := buildAgent(ctx, SupervisorName, conf.Supervisor, maxIterations)
+	if err != nil {
+		return nil, err
+	}
+	if supervisor.Prompt == "" {
+		supervisor.Prompt = DefaultSupervisorPrompt
+	}
+
+	t := &Team{Supervisor: supervisor}
+	for _, m := range conf.Members {
+		member, err := buildAgent(ctx, m.Name, m.Spec, maxIterations)
+		if err != nil {
+			return nil, err
+		}
+		t.Members = append(t.Members, member)
+	}
+	return t, nil
+}
+
+func buildAgent(ctx context.Context, name string, spec Spec, maxIterations int) (Agent, error) {
+	a := Agent{Name: name, Description: spec.Description, Prompt: spec.Prompt, MaxIterations: maxIterations}
+	if spec.MaxIterations > 0 {
+		a.MaxIterations = spec.MaxIterations
+	}
+
+	cm, err := chat_model.Get(spec.Model)
+	if err != nil {
+		return a, fmt.Errorf("agent %s: %w", name, err)
+	}
+	tcm, ok := cm.(model.ToolCallingChatModel)
+	if !ok {
+		return a, fmt.Errorf("agent %s: 聊天模型 %s 不支持工具调用", name, spec.Model)
+	}
+	a.Model = tcm
+
+	if len(spec.Tools) == 0 {
+		return a, nil
+	}
+	if toolbox.Tools == nil {
+		return a, fmt.Errorf("工具注册表未初始化")
+	}
+	// 成员运行没有暂停点，不能使用需要审批的工具
+	for _, toolName := range spec.Tools {
+		if toolbox.Tools.RequiresApproval(toolName) {
+			return a, fmt.Errorf("agent %s: 工具 %s 需要审批，多 Agent 运行暂不支持", name, toolName)
+		}
+	}
+	if a.Tools, err = toolbox.Tools.Get(spec.Tools); err != nil {
+		return a, fmt.Errorf("agent %s: %w", name, err)
+	}
+
+	if spec.Collection != "" && spec.Collection != config.Cfg.MilvusConf.CollectionName {
+		if a.Tools, err = withCollection(ctx, a.Tools, spec.Collection); err != nil {
+			return a, fmt.Errorf("agent %s: %w", name, err)
+		}
+	}
+	return a, nil
+}
+
+// withCollection 把 search_knowledge_base 换成检索指定集合的版本
+func withCollection(ctx context.Context, tools []tool.BaseTool, collection string) ([]tool.BaseTool, error) {
+	if db.Milvus == nil {
+		return nil, fmt.Errorf("memory 模式只有默认集合，不能检索集合 %s", collection)
+	}
+	topK, err := strconv.Atoi(config.Cfg.MilvusConf.TopK)
+	if err != nil || topK <= 0 {
+		topK = 10
+	}
+
+	out := make([]tool.BaseTool, len(tools))
+	replaced := false
+	for i, t := range tools {
+		info, err := t.Info(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if info.Name != toolbox.ToolSearchKnowledgeBase {
+			out[i] = t
+			continue
+		}
+		r, err := retriever.NewCollectionRetriever(ctx, collection, topK)
+		if err != nil {
+			return nil, fmt.Errorf("create retriever for collection %s failed: %w", collection, err)
+		}
+		if out[i], err = toolbox.NewKnowledgeBaseTool(r); err != nil {
+			return nil, err
+		}
+		replaced = true
+	}
+	if !replaced {
+		return nil, fmt.Errorf("collection 需要同时启用 %s 工具", toolbox.ToolSearchKnowledgeBase)
+	}
+	return out, nil
+}
+
+// Run 运行团队：主管决定把哪些子任务交给哪个成员，成员以各自的提示词、模型与工具完成后把结果交回主管，
+// 由主管给出最终回答。messages 为对话历史与用户问题，不含系统提示词；每条轨迹产生时通过 emit 回调（可为 nil）
+func (t *Team) Run(ctx context.Context, messages []*schema.Message, emit func(agent.Event)) (*Result, error) {
+	tr := &tracer{emit: emit, members: make(map[string]bool, len(t.Members))}
+
+	handoffs := make([]tool.BaseTool, 0, len(t.Members))
+	for i := range t.Members {
+		tr.members[t.Members[i].Name] = true
+		handoffs = append(handoffs, &handoffTool{member: &t.Members[i], tracer: tr})
+	}
+
+	input := append([]*schema.Message{schema.SystemMessage(t.supervisorPrompt())}, messages...)
+	res, err := agent.Run(ctx, agent.Config{
+		Model:         t.Supervisor.Model,
+		Tools:         handoffs,
+		MaxIterations: t.Supervisor.MaxIterations,
+	}, input, tr.supervisor)
+
+	result := &Result{Trace: tr.events(), Handoffs: tr.handoffs()}
+	if res != nil {
+		result.Iterations = res.Iterations
+		result.Answer = res.Answer
+	}
+	return result, err
+}
+
+// supervisorPrompt 主管提示词后附上成员名单
+func (t *Team) supervisorPrompt() string {
+	var b strings.Builder
+	b.WriteString(t.Supervisor.Prompt)
+	b.WriteString("\n\nAgents you can delegate to:\n")
+	for _, m := range t.Members {
+		fmt.Fprintf(&b, "- %s: %s\n", m.Name, m.Description)
+	}
+	return b.String()
+}
+
+// tracer 汇总主管与成员的轨迹，成员可能被并行调用，需要加锁
+type tracer struct {
+	mu      sync.Mutex
+	emit    func(agent.Event)
+	trace   []agent.Event
+	members map[string]bool
+}
+
+func (t *tracer) add(e agent.Event) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.trace = append(t.trace, e)
+	if t.emit != nil {
+		t.emit(e)
+	}
+}
+
+// supervisor 记录主管的事件，对成员的工具调用与结果记为交接
+func (t *tracer) supervisor(e agent.Event) {
+	e.Agent = SupervisorName
+	if t.members[e.Tool] {
+		switch e.Type {
+		case agent.EventToolCall:
+			e.Type = EventHandoff
+			var in handoffInput
+			if json.Unmarshal([]byte(e.Arguments), &in) == nil {
+				e.Content = in.Task
+			}
+		case agent.EventToolResult:
+			e.Type = EventHandoffResult
+		}
+	}
+	t.add(e)
+}
+
+func (t *tracer) events() []agent.Event {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]agent.Event(nil), t.trace...)
+}
+
+func (t *tracer) handoffs() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	n := 0
+	for _, e := range t.trace {
+		if e.Type == EventHandoff {
+			n++
+		}
+	}
+	return n
+}
+
+type handoffInput struct {
+	Task string `json:"task"`
+}
+
+// handoffTool 主管看到的成员：调用即把任务交给成员独立运行，成员的最终回答作为工具结果
+type handoffTool struct {
+	member *Agent
+	tracer *tracer
+}
+
+func (h *handoffTool) Info(ctx context.Context) (*schema.ToolInfo, error) {
+	return &schema.ToolInfo{
+		Name: h.member.Name,
+		Desc: h.member.Description,
+		ParamsOneOf: schema.NewParamsOneOfByParams(map[string]*schema.ParameterInfo{
+			"task": {Type: schema.String, Desc: "self-contained description of the sub-task, including all context the agent needs", Required: true},
+		}),
+	}, nil
+}
+
+func (h *handoffTool) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	var in handoffInput
+	if err := json.Unmarshal([]byte(argumentsInJSON), &in); err != nil {
+		return "", fmt.Errorf("invalid arguments: %w", err)
+	}
+	if strings.TrimSpace(in.Task) == "" {
+		return "", fmt.Errorf("task is required")
+	}
+
+	var messages []*schema.Message
+	if h.member.Prompt != "" {
+		messages = append(messages, schema.SystemMessage(h.member.Prompt))
+	}
+	messages = append(messages, schema.UserMessage(in.Task))
+
+	res, err := agent.Run(ctx, agent.Config{
+		Model:         h.member.Model,
+		Tools:         h.member.Tools,
+		MaxIterations: h.member.MaxIterations,
+	}, messages, func(e agent.Event) {
+		e.Agent = h.member.Name
+		h.tracer.add(e)
+	})
+	if err != nil {
+		return "", fmt.Errorf("agent %s failed: %w", h.member.Name, err)
+	}
+	return res.Answer.Content, nil
+}
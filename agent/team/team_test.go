@@ -0,0 +1,139 @@
+package team
+
+import (
+	"context"
+	"go-agent/agent"
+	"go-agent/model/chat_model"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/components/tool/utils"
+	"github.com/cloudwego/eino/schema"
+)
+
+func toolCall(id, name, args string) *schema.Message {
+	return schema.AssistantMessage("", []schema.ToolCall{{ID: id, Function: schema.FunctionCall{Name: name, Arguments: args}}})
+}
+
+func newLookupTool(t *testing.T) tool.InvokableTool {
+	t.Helper()
+	lookup, err := utils.InferTool("lookup", "look up a fact", func(ctx context.Context, in *struct {
+		Key string `json:"key"`
+	}) (string, error) {
+		return "value of " + in.Key, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return lookup
+}
+
+func TestTeamHandsOffToMembers(t *testing.T) {
+	supervisor := chat_model.NewFakeChatModel(
+		toolCall("h1", "researcher", `{"task":"find the launch date"}`),
+		toolCall("h2", "writer", `{"task":"write one sentence about value of launch"}`),
+		schema.AssistantMessage("Launch is covered.", nil),
+	)
+	researcher := chat_model.NewFakeChatModel(
+		toolCall("c1", "lookup", `{"key":"launch"}`),
+		schema.AssistantMessage("value of launch", nil),
+	)
+	writer := chat_model.NewFakeChatModel(schema.AssistantMessage("A fine sentence.", nil))
+
+	team := &Team{
+		Supervisor: Agent{Name: SupervisorName, Prompt: DefaultSupervisorPrompt, Model: supervisor},
+		Members: []Agent{
+			{Name: "researcher", Description: "finds facts", Prompt: "You research.", Model: researcher, Tools: []tool.BaseTool{newLookupTool(t)}},
+			{Name: "writer", Description: "writes prose", Model: writer},
+		},
+	}
+
+	var streamed []agent.Event
+	result, err := team.Run(context.Background(), []*schema.Message{schema.UserMessage("tell me about the launch")},
+		func(e agent.Event) { streamed = append(streamed, e) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Answer.Content != "Launch is covered." || result.Handoffs != 2 || result.Iterations != 3 {
+		t.Fatalf("result = %+v", result)
+	}
+	if len(streamed) != len(result.Trace) {
+		t.Fatalf("streamed %d events, trace has %d", len(streamed), len(result.Trace))
+	}
+
+	var steps []string
+	for _, e := range result.Trace {
+		steps = append(steps, e.Agent+":"+e.Type)
+	}
+	want := "supervisor:iteration,supervisor:handoff," +
+		"researcher:iteration,researcher:tool_call,researcher:tool_result,researcher:iteration,researcher:answer," +
+		"supervisor:handoff_result,supervisor:iteration,supervisor:handoff," +
+		"writer:iteration,writer:answer," +
+		"supervisor:handoff_result,supervisor:iteration,supervisor:answer"
+	if got := strings.Join(steps, ","); got != want {
+		t.Fatalf("trace = %s", got)
+	}
+	if h := result.Trace[1]; h.Tool != "researcher" || h.Content != "find the launch date" {
+		t.Fatalf("handoff = %+v", h)
+	}
+	if r := result.Trace[7]; r.Result != "value of launch" {
+		t.Fatalf("handoff result = %+v", r)
+	}
+
+	// 成员只看到自己的提示词与任务，主管看到成员名单
+	if in := researcher.Inputs()[0]; len(in) != 2 || in[0].Content != "You research." || in[1].Content != "find the launch date" {
+		t.Fatalf("researcher input = %+v", in)
+	}
+	if sys := supervisor.Inputs()[0][0].Content; !strings.Contains(sys, "- researcher: finds facts") || !strings.Contains(sys, "- writer: writes prose") {
+		t.Fatalf("supervisor prompt = %q", sys)
+	}
+}
+
+func TestTeamMemberFailureIsReturnedToSupervisor(t *testing.T) {
+	supervisor := chat_model.NewFakeChatModel(
+		toolCall("h1", "writer", `{"task":""}`),
+		schema.AssistantMessage("Could not write.", nil),
+	)
+	team := &Team{
+		Supervisor: Agent{Name: SupervisorName, Model: supervisor},
+		Members:    []Agent{{Name: "writer", Description: "writes", Model: chat_model.NewFakeChatModel()}},
+	}
+
+	result, err := team.Run(context.Background(), []*schema.Message{schema.UserMessage("write")}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := result.Trace[2]
+	if r.Type != EventHandoffResult || !strings.Contains(r.Error, "task is required") {
+		t.Fatalf("handoff result = %+v", r)
+	}
+}
+
+func TestParseConfig(t *testing.T) {
+	conf, err := ParseConfig([]byte(`{
+		"supervisor": {"model": "ark", "max_iterations": 4},
+		"agents": {
+			"writer": {"description": "writes", "prompt": "Write."},
+			"analyst": {"description": "analyses tables", "tools": ["sql_query"]}
+		}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conf.Supervisor.Model != "ark" || len(conf.Members) != 2 || conf.Members[0].Name != "analyst" || conf.Members[0].Tools[0] != "sql_query" {
+		t.Fatalf("conf = %+v", conf)
+	}
+
+	for _, raw := range []string{
+		`{"agents":{}}`,
+		`{"agents":{"bad name":{"description":"x"}}}`,
+		`{"agents":{"supervisor":{"description":"x"}}}`,
+		`{"agents":{"writer":{}}}`,
+		`{"supervisor":{"tools":["calculator"]},"agents":{"writer":{"description":"x"}}}`,
+	} {
+		if _, err := ParseConfig([]byte(raw)); err == nil {
+			t.Errorf("config %s accepted, want error", raw)
+		}
+	}
+}
@@ -0,0 +1,292 @@
+package toolbox
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"unicode"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/components/tool/utils"
+)
+
+// ToolCalculator 计算器工具名称
+const ToolCalculator = "calculator"
+
+// maxExpressionLen 表达式最大长度
+const maxExpressionLen = 1000
+
+type calculatorInput struct {
+	Expression string `json:"expression" jsonschema:"description=arithmetic expression such as (1+2)*3/4 or sqrt(2)^2; supports + - * / % ^ and functions abs sqrt pow exp log log10 log2 sin cos tan floor ceil round min max and constants pi e"`
+}
+
+type calculatorOutput struct {
+	Expression string  `json:"expression"`
+	Result     float64 `json:"result"`
+}
+
+// calcFuncs 允许调用的函数，值为参数个数（-1 表示至少一个）
+var calcFuncs = map[string]struct {
+	arity int
+	fn    func(args []float64) float64
+}{
+	"abs":   {1, func(a []float64) float64 { return math.Abs(a[0]) }},
+	"sqrt":  {1, func(a []float64) float64 { return math.Sqrt(a[0]) }},
+	"pow":   {2, func(a []float64) float64 { return math.Pow(a[0], a[1]) }},
+	"exp":   {1, func(a []float64) float64 { return math.Exp(a[0]) }},
+	"log":   {1, func(a []float64) float64 { return math.Log(a[0]) }},
+	"log10": {1, func(a []float64) float64 { return math.Log10(a[0]) }},
+	"log2":  {1, func(a []float64) float64 { return math.Log2(a[0]) }},
+	"sin":   {1, func(a []float64) float64 { return math.Sin(a[0]) }},
+	"cos":   {1, func(a []float64) float64 { return math.Cos(a[0]) }},
+	"tan":   {1, func(a []float64) float64 { return math.Tan(a[0]) }},
+	"floor": {1, func(a []float64) float64 { return math.Floor(a[0]) }},
+	"ceil":  {1, func(a []float64) float64 { return math.Ceil(a[0]) }},
+	"round": {1, func(a []float64) float64 { return math.Round(a[0]) }},
+	"min": {-1, func(a []float64) float64 {
+		m := a[0]
+		for _, v := range a[1:] {
+			m = math.Min(m, v)
+		}
+		return m
+	}},
+	"max": {-1, func(a []float64) float64 {
+		m := a[0]
+		for _, v := range a[1:] {
+			m = math.Max(m, v)
+		}
+		return m
+	}},
+}
+
+var calcConsts = map[string]float64{"pi": math.Pi, "e": math.E}
+
+func newCalculatorTool(ctx context.Context) (tool.InvokableTool, error) {
+	return utils.InferTool(ToolCalculator,
+		"Evaluate an arithmetic expression exactly instead of doing math in your head.",
+		func(ctx context.Context, in *calculatorInput) (*calculatorOutput, error) {
+			result, err := Calculate(in.Expression)
+			if err != nil {
+				return nil, err
+			}
+			return &calculatorOutput{Expression: in.Expression, Result: result}, nil
+		})
+}
+
+// Calculate 计算算术表达式，只解析数字、运算符、白名单函数与常量，不执行任意代码
+// ^ 表示乘方（右结合，优先级高于乘除与负号）
+func Calculate(expression string) (float64, error) {
+	if len(expression) > maxExpressionLen {
+		return 0, fmt.Errorf("expression too long (max %d characters)", maxExpressionLen)
+	}
+
+	p := &calcParser{src: expression}
+	p.next()
+	result, err := p.expr()
+	if err != nil {
+		return 0, err
+	}
+	if p.tok != "" {
+		return 0, fmt.Errorf("unexpected %q at position %d", p.tok, p.start)
+	}
+	if math.IsNaN(result) || math.IsInf(result, 0) {
+		return 0, fmt.Errorf("result is not a finite number")
+	}
+	return result, nil
+}
+
+// calcParser 递归下降解析：
+// expr = term {(+|-) term}; term = unary {(*|/|%) unary}; unary = (+|-) unary | power
+// power = primary [^ unary]; primary = number | const | func(args) | (expr)
+type calcParser struct {
+	src   string
+	pos   int
+	start int
+	tok   string // 当前记号，结束时为空
+}
+
+func (p *calcParser) next() {
+	for p.pos < len(p.src) && unicode.IsSpace(rune(p.src[p.pos])) {
+		p.pos++
+	}
+	p.start = p.pos
+	if p.pos >= len(p.src) {
+		p.tok = ""
+		return
+	}
+
+	c := p.src[p.pos]
+	switch {
+	case c >= '0' && c <= '9' || c == '.':
+		for p.pos < len(p.src) && (isDigit(p.src[p.pos]) || p.src[p.pos] == '.') {
+			p.pos++
+		}
+		// 科学计数法，如 1e-3
+		if p.pos < len(p.src) && (p.src[p.pos] == 'e' || p.src[p.pos] == 'E') {
+			end := p.pos + 1
+			if end < len(p.src) && (p.src[end] == '+' || p.src[end] == '-') {
+				end++
+			}
+			if end < len(p.src) && isDigit(p.src[end]) {
+				for end < len(p.src) && isDigit(p.src[end]) {
+					end++
+				}
+				p.pos = end
+			}
+		}
+	case isLetter(c):
+		for p.pos < len(p.src) && (isLetter(p.src[p.pos]) || isDigit(p.src[p.pos])) {
+			p.pos++
+		}
+	default:
+		p.pos++
+	}
+	p.tok = p.src[p.start:p.pos]
+}
+
+func (p *calcParser) expr() (float64, error) {
+	x, err := p.term()
+	if err != nil {
+		return 0, err
+	}
+	for p.tok == "+" || p.tok == "-" {
+		op := p.tok
+		p.next()
+		y, err := p.term()
+		if err != nil {
+			return 0, err
+		}
+		if op == "+" {
+			x += y
+		} else {
+			x -= y
+		}
+	}
+	return x, nil
+}
+
+func (p *calcParser) term() (float64, error) {
+	x, err := p.unary()
+	if err != nil {
+		return 0, err
+	}
+	for p.tok == "*" || p.tok == "/" || p.tok == "%" {
+		op := p.tok
+		p.next()
+		y, err := p.unary()
+		if err != nil {
+			return 0, err
+		}
+		switch {
+		case op == "*":
+			x *= y
+		case y == 0:
+			return 0, fmt.Errorf("division by zero")
+		case op == "/":
+			x /= y
+		default:
+			x = math.Mod(x, y)
+		}
+	}
+	return x, nil
+}
+
+func (p *calcParser) unary() (float64, error) {
+	if p.tok == "+" || p.tok == "-" {
+		op := p.tok
+		p.next()
+		x, err := p.unary()
+		if op == "-" {
+			x = -x
+		}
+		return x, err
+	}
+	return p.power()
+}
+
+func (p *calcParser) power() (float64, error) {
+	x, err := p.primary()
+	if err != nil {
+		return 0, err
+	}
+	if p.tok == "^" {
+		p.next()
+		y, err := p.unary()
+		if err != nil {
+			return 0, err
+		}
+		x = math.Pow(x, y)
+	}
+	return x, nil
+}
+
+func (p *calcParser) primary() (float64, error) {
+	tok := p.tok
+	switch {
+	case tok == "":
+		return 0, fmt.Errorf("unexpected end of expression")
+
+	case tok == "(":
+		p.next()
+		x, err := p.expr()
+		if err != nil {
+			return 0, err
+		}
+		if p.tok != ")" {
+			return 0, fmt.Errorf("missing closing parenthesis")
+		}
+		p.next()
+		return x, nil
+
+	case isDigit(tok[0]) || tok[0] == '.':
+		v, err := strconv.ParseFloat(tok, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid number %q", tok)
+		}
+		p.next()
+		return v, nil
+
+	case isLetter(tok[0]):
+		p.next()
+		if p.tok != "(" {
+			if v, ok := calcConsts[tok]; ok {
+				return v, nil
+			}
+			return 0, fmt.Errorf("unknown identifier %s", tok)
+		}
+
+		f, ok := calcFuncs[tok]
+		if !ok {
+			return 0, fmt.Errorf("unknown function %s", tok)
+		}
+		p.next()
+		var args []float64
+		for p.tok != ")" {
+			v, err := p.expr()
+			if err != nil {
+				return 0, err
+			}
+			args = append(args, v)
+			if p.tok == "," {
+				p.next()
+			} else if p.tok != ")" {
+				return 0, fmt.Errorf("expected ',' or ')' in call to %s", tok)
+			}
+		}
+		p.next()
+		if f.arity > 0 && len(args) != f.arity || f.arity < 0 && len(args) == 0 {
+			return 0, fmt.Errorf("wrong number of arguments for %s", tok)
+		}
+		return f.fn(args), nil
+	}
+	return 0, fmt.Errorf("unexpected %q at position %d", tok, p.start)
+}
+
+func isDigit(c byte) bool { return c >= '0' && c <= '9' }
+
+func isLetter(c byte) bool { return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_' }
+
+func initCalculator() {
+	registerTool(ToolCalculator, newCalculatorTool)
+}
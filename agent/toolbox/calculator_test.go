@@ -0,0 +1,33 @@
+package toolbox
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCalculate(t *testing.T) {
+	cases := map[string]float64{
+		"1 + 2 * 3":          7,
+		"(1 + 2) * 3":        9,
+		"2 * 3 ^ 2":          18,
+		"2 ^ 3 ^ 2":          512,
+		"-2 ^ 2":             -4,
+		"10 % 4":             2,
+		"sqrt(16) + abs(-1)": 5,
+		"max(1, 7, 3)":       7,
+		"round(pi * 100)":    314,
+		"1.5e3 / 3":          500,
+	}
+	for expr, want := range cases {
+		got, err := Calculate(expr)
+		if err != nil || math.Abs(got-want) > 1e-9 {
+			t.Errorf("Calculate(%q) = %v, %v; want %v", expr, got, err, want)
+		}
+	}
+
+	for _, expr := range []string{"1 / 0", "os.Exit(1)", "unknown(1)", "1 +", "(1", "pow(1)", "sqrt(-1)", `"a"`} {
+		if _, err := Calculate(expr); err == nil {
+			t.Errorf("Calculate(%q) succeeded, want error", expr)
+		}
+	}
+}
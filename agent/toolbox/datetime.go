@@ -0,0 +1,115 @@
+package toolbox
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/components/tool/utils"
+)
+
+// ToolDatetime 时间与日期计算工具名称
+const ToolDatetime = "datetime"
+
+// 支持的日期格式，按顺序尝试
+var dateLayouts = []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02"}
+
+type datetimeInput struct {
+	Operation string `json:"operation" jsonschema:"enum=now,enum=add,enum=diff,description=now: current time; add: shift date by the given amounts; diff: difference from date to end_date"`
+	Timezone  string `json:"timezone,omitempty" jsonschema:"description=IANA time zone such as Asia/Shanghai (default: server local time)"`
+	Date      string `json:"date,omitempty" jsonschema:"description=start date for add/diff in RFC3339 or YYYY-MM-DD [HH:MM[:SS]] format (default: now)"`
+	EndDate   string `json:"end_date,omitempty" jsonschema:"description=end date for diff (default: now)"`
+	Years     int    `json:"years,omitempty"`
+	Months    int    `json:"months,omitempty"`
+	Days      int    `json:"days,omitempty"`
+	Hours     int    `json:"hours,omitempty"`
+	Minutes   int    `json:"minutes,omitempty"`
+}
+
+type datetimeOutput struct {
+	Time     string   `json:"time,omitempty"`
+	Date     string   `json:"date,omitempty"`
+	Weekday  string   `json:"weekday,omitempty"`
+	Timezone string   `json:"timezone,omitempty"`
+	Unix     int64    `json:"unix,omitempty"`
+	Diff     *timeGap `json:"diff,omitempty"`
+}
+
+type timeGap struct {
+	Days    float64 `json:"days"`
+	Hours   float64 `json:"hours"`
+	Seconds float64 `json:"seconds"`
+}
+
+func newDatetimeTool(ctx context.Context) (tool.InvokableTool, error) {
+	return utils.InferTool(ToolDatetime,
+		"Get the current date and time, add or subtract years/months/days/hours/minutes from a date, or compute the difference between two dates.",
+		func(ctx context.Context, in *datetimeInput) (*datetimeOutput, error) {
+			return datetime(time.Now(), in)
+		})
+}
+
+func datetime(now time.Time, in *datetimeInput) (*datetimeOutput, error) {
+	loc := time.Local
+	if in.Timezone != "" {
+		var err error
+		if loc, err = time.LoadLocation(in.Timezone); err != nil {
+			return nil, fmt.Errorf("unknown timezone %q", in.Timezone)
+		}
+	}
+	now = now.In(loc)
+
+	start, err := parseDate(in.Date, now, loc)
+	if err != nil {
+		return nil, err
+	}
+
+	switch in.Operation {
+	case "now", "":
+		return describeTime(now), nil
+	case "add":
+		t := start.AddDate(in.Years, in.Months, in.Days).
+			Add(time.Duration(in.Hours)*time.Hour + time.Duration(in.Minutes)*time.Minute)
+		return describeTime(t), nil
+	case "diff":
+		end, err := parseDate(in.EndDate, now, loc)
+		if err != nil {
+			return nil, err
+		}
+		d := end.Sub(start)
+		return &datetimeOutput{Diff: &timeGap{
+			Days:    math.Round(d.Hours()/24*100) / 100,
+			Hours:   math.Round(d.Hours()*100) / 100,
+			Seconds: d.Seconds(),
+		}}, nil
+	}
+	return nil, fmt.Errorf("unsupported operation %q (use now, add or diff)", in.Operation)
+}
+
+func parseDate(value string, now time.Time, loc *time.Location) (time.Time, error) {
+	if value == "" {
+		return now, nil
+	}
+	for _, layout := range dateLayouts {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", value)
+}
+
+func describeTime(t time.Time) *datetimeOutput {
+	return &datetimeOutput{
+		Time:     t.Format(time.RFC3339),
+		Date:     t.Format("2006-01-02"),
+		Weekday:  t.Weekday().String(),
+		Timezone: t.Location().String(),
+		Unix:     t.Unix(),
+	}
+}
+
+func initDatetime() {
+	registerTool(ToolDatetime, newDatetimeTool)
+}
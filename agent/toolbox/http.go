@@ -0,0 +1,134 @@
+package toolbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-agent/config"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/components/tool/utils"
+)
+
+// ToolHTTPGet HTTP GET 工具名称
+const ToolHTTPGet = "http_get"
+
+const (
+	httpGetTimeout      = 10 * time.Second
+	httpGetMaxBodyBytes = 64 << 10 // 返回给模型的正文上限
+	httpGetMaxRedirects = 5
+)
+
+type httpGetInput struct {
+	URL string `json:"url" jsonschema:"description=absolute http or https URL; the host must be on the allowlist"`
+}
+
+type httpGetOutput struct {
+	Status      int    `json:"status"`
+	ContentType string `json:"content_type,omitempty"`
+	Body        string `json:"body"`
+	Truncated   bool   `json:"truncated,omitempty"`
+}
+
+// HostAllowlist 允许访问的主机，"example.com" 精确匹配，"*.example.com" 匹配其全部子域名
+type HostAllowlist []string
+
+// ParseAllowlist 解析逗号分隔的主机列表
+func ParseAllowlist(raw string) HostAllowlist {
+	var list HostAllowlist
+	for _, host := range strings.Split(raw, ",") {
+		if host = strings.ToLower(strings.TrimSpace(host)); host != "" {
+			list = append(list, host)
+		}
+	}
+	return list
+}
+
+// Allowed 判断主机（不含端口）是否在允许列表中
+func (l HostAllowlist) Allowed(host string) bool {
+	host = strings.ToLower(host)
+	for _, pattern := range l {
+		if suffix, ok := strings.CutPrefix(pattern, "*."); ok {
+			if strings.HasSuffix(host, "."+suffix) {
+				return true
+			}
+		} else if host == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+func newHTTPGetTool(ctx context.Context) (tool.InvokableTool, error) {
+	allowlist := ParseAllowlist(config.Cfg.AgentConf.HTTPAllowlist)
+	if len(allowlist) == 0 {
+		return nil, fmt.Errorf("AGENT_HTTP_ALLOWLIST is required when %s is enabled", ToolHTTPGet)
+	}
+	return NewHTTPGetTool(allowlist)
+}
+
+// NewHTTPGetTool 创建只能访问允许列表中主机的 HTTP GET 工具，重定向目标同样需要在列表中
+func NewHTTPGetTool(allowlist HostAllowlist) (tool.InvokableTool, error) {
+	client := &http.Client{
+		Timeout: httpGetTimeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= httpGetMaxRedirects {
+				return errors.New("too many redirects")
+			}
+			return checkURL(req.URL, allowlist)
+		},
+	}
+
+	return utils.InferTool(ToolHTTPGet,
+		fmt.Sprintf("Fetch a web page or API with HTTP GET and return the status and up to %d KB of the body. Allowed hosts: %s.",
+			httpGetMaxBodyBytes>>10, strings.Join(allowlist, ", ")),
+		func(ctx context.Context, in *httpGetInput) (*httpGetOutput, error) {
+			u, err := url.Parse(in.URL)
+			if err != nil {
+				return nil, fmt.Errorf("invalid url: %w", err)
+			}
+			if err := checkURL(u, allowlist); err != nil {
+				return nil, err
+			}
+
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+			if err != nil {
+				return nil, err
+			}
+			resp, err := client.Do(req)
+			if err != nil {
+				return nil, err
+			}
+			defer resp.Body.Close()
+
+			body, err := io.ReadAll(io.LimitReader(resp.Body, httpGetMaxBodyBytes+1))
+			if err != nil {
+				return nil, err
+			}
+			out := &httpGetOutput{Status: resp.StatusCode, ContentType: resp.Header.Get("Content-Type")}
+			if len(body) > httpGetMaxBodyBytes {
+				body, out.Truncated = body[:httpGetMaxBodyBytes], true
+			}
+			out.Body = strings.ToValidUTF8(string(body), "")
+			return out, nil
+		})
+}
+
+func checkURL(u *url.URL, allowlist HostAllowlist) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("unsupported scheme %q", u.Scheme)
+	}
+	if !allowlist.Allowed(u.Hostname()) {
+		return fmt.Errorf("host %s is not on the allowlist", u.Hostname())
+	}
+	return nil
+}
+
+func initHTTPGet() {
+	registerTool(ToolHTTPGet, newHTTPGetTool)
+}
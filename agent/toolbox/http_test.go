@@ -0,0 +1,56 @@
+package toolbox
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHostAllowlist(t *testing.T) {
+	l := ParseAllowlist(" api.example.com, *.docs.io ")
+	for host, want := range map[string]bool{
+		"api.example.com":  true,
+		"API.EXAMPLE.COM":  true,
+		"evil.example.com": false,
+		"a.docs.io":        true,
+		"docs.io":          false,
+		"docs.io.evil.com": false,
+	} {
+		if got := l.Allowed(host); got != want {
+			t.Errorf("Allowed(%q) = %v, want %v", host, got, want)
+		}
+	}
+}
+
+func TestHTTPGetTool(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, "http://localhost.invalid/", http.StatusFound)
+			return
+		}
+		w.Write([]byte(strings.Repeat("x", httpGetMaxBodyBytes+10)))
+	}))
+	defer srv.Close()
+
+	get, err := NewHTTPGetTool(HostAllowlist{"127.0.0.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	out, err := get.InvokableRun(ctx, `{"url":"`+srv.URL+`/page"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, `"status":200`) || !strings.Contains(out, `"truncated":true`) {
+		t.Fatalf("output = %.100s", out)
+	}
+
+	for _, url := range []string{"http://example.com/", "file:///etc/passwd", srv.URL + "/redirect"} {
+		if _, err := get.InvokableRun(ctx, `{"url":"`+url+`"}`); err == nil {
+			t.Errorf("fetch %s succeeded, want error", url)
+		}
+	}
+}
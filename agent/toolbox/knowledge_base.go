@@ -0,0 +1,87 @@
+package toolbox
+
+import (
+	"context"
+	"go-agent/rag/tools/db"
+	"go-agent/rag/tools/retriever"
+
+	einoretriever "github.com/cloudwego/eino/components/retriever"
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/components/tool/utils"
+	"github.com/cloudwego/eino/schema"
+)
+
+// ToolSearchKnowledgeBase 知识库检索工具名称
+const ToolSearchKnowledgeBase = "search_knowledge_base"
+
+// maxSearchTopK 单次检索最多返回的文档块数量
+const maxSearchTopK = 20
+
+type searchKnowledgeBaseInput struct {
+	Query string `json:"query" jsonschema:"description=the search query; use the key terms of the user question"`
+	TopK  int    `json:"top_k,omitempty" jsonschema:"description=number of chunks to return (default: configured top k)"`
+}
+
+type searchKnowledgeBaseOutput struct {
+	Results []searchResult `json:"results"`
+}
+
+type searchResult struct {
+	Content string  `json:"content"`
+	Score   float64 `json:"score"`
+	Source  string  `json:"source,omitempty"`
+}
+
+// newKnowledgeBaseTool 把已入库文档的向量检索暴露为工具，每次调用使用当前的全局召回器
+func newKnowledgeBaseTool(ctx context.Context) (tool.InvokableTool, error) {
+	return knowledgeBaseTool(func() einoretriever.Retriever { return retriever.Retriever })
+}
+
+// NewKnowledgeBaseTool 创建在指定召回器（如另一个集合的召回器）上检索的知识库工具
+func NewKnowledgeBaseTool(r einoretriever.Retriever) (tool.InvokableTool, error) {
+	return knowledgeBaseTool(func() einoretriever.Retriever { return r })
+}
+
+func knowledgeBaseTool(get func() einoretriever.Retriever) (tool.InvokableTool, error) {
+	return utils.InferTool(ToolSearchKnowledgeBase,
+		"Search the document knowledge base and return the most relevant chunks with similarity scores. Use it for questions about uploaded documents.",
+		func(ctx context.Context, in *searchKnowledgeBaseInput) (*searchKnowledgeBaseOutput, error) {
+			return searchKnowledgeBase(ctx, get(), in)
+		})
+}
+
+func searchKnowledgeBase(ctx context.Context, r einoretriever.Retriever, in *searchKnowledgeBaseInput) (*searchKnowledgeBaseOutput, error) {
+	var opts []einoretriever.Option
+	if in.TopK > 0 {
+		opts = append(opts, einoretriever.WithTopK(min(in.TopK, maxSearchTopK)))
+	}
+
+	docs, err := r.Retrieve(ctx, in.Query, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &searchKnowledgeBaseOutput{Results: make([]searchResult, 0, len(docs))}
+	for _, doc := range docs {
+		source, _ := doc.MetaData[db.MetaKeySource].(string)
+		out.Results = append(out.Results, searchResult{
+			Content: doc.Content,
+			Score:   documentScore(doc),
+			Source:  source,
+		})
+	}
+	return out, nil
+}
+
+// documentScore 读取文档相似度，兼容部分召回器把分数放在 metadata 的情况
+func documentScore(doc *schema.Document) float64 {
+	if score := doc.Score(); score != 0 {
+		return score
+	}
+	score, _ := doc.MetaData["score"].(float64)
+	return score
+}
+
+func initKnowledgeBase() {
+	registerTool(ToolSearchKnowledgeBase, newKnowledgeBaseTool)
+}
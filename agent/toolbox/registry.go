@@ -0,0 +1,216 @@
+package toolbox
+
+import (
+	"context"
+	"fmt"
+	"go-agent/config"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/schema"
+)
+
+// ToolFactory 创建内置工具
+type ToolFactory func(ctx context.Context) (tool.InvokableTool, error)
+
+var toolRegistry = make(map[string]ToolFactory)
+
+// Tools 全局工具注册表，供 Agent 按名称选择工具
+var Tools *Registry
+
+// 工具审批策略
+const (
+	ApprovalNever  = "never"  // 直接执行
+	ApprovalAlways = "always" // 每次执行前暂停运行，等待人工审批
+)
+
+// Registry 按名称管理可供 Agent 调用的工具，运行期间可增删（如 MCP 工具）
+type Registry struct {
+	mu    sync.RWMutex
+	tools map[string]tool.BaseTool
+	infos map[string]*schema.ToolInfo
+	// approvals 工具名称或以 * 结尾的前缀 -> 审批策略，与工具是否已注册无关
+	approvals map[string]string
+}
+
+// DefaultTools 未配置 AGENT_TOOLS 时启用的内置工具
+var DefaultTools = []string{ToolSearchKnowledgeBase, ToolCalculator, ToolDatetime}
+
+// NewRegistry 创建注册表并加载配置中启用的内置工具
+func NewRegistry(ctx context.Context) (*Registry, error) {
+	initKnowledgeBase()
+	initCalculator()
+	initDatetime()
+	initHTTPGet()
+	initSQLQuery()
+	initRunStarlark()
+
+	enabled := DefaultTools
+	if config.Cfg != nil && strings.TrimSpace(config.Cfg.AgentConf.Tools) != "" {
+		enabled = nil
+		for _, name := range strings.Split(config.Cfg.AgentConf.Tools, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				enabled = append(enabled, name)
+			}
+		}
+	}
+
+	r := &Registry{
+		tools:     make(map[string]tool.BaseTool),
+		infos:     make(map[string]*schema.ToolInfo),
+		approvals: make(map[string]string),
+	}
+	if config.Cfg != nil {
+		approvals, err := ParseApprovals(config.Cfg.AgentConf.ToolApproval)
+		if err != nil {
+			return nil, err
+		}
+		r.approvals = approvals
+	}
+	for _, name := range enabled {
+		create, ok := toolRegistry[name]
+		if !ok {
+			return nil, fmt.Errorf("未知的内置工具: %s", name)
+		}
+		t, err := create(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("create tool %s failed: %w", name, err)
+		}
+		if err := r.Register(ctx, t); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// Register 注册工具，名称以工具自身的 Info 为准
+func (r *Registry) Register(ctx context.Context, t tool.BaseTool) error {
+	info, err := t.Info(ctx)
+	if err != nil {
+		return fmt.Errorf("get tool info failed: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.tools[info.Name]; ok {
+		return fmt.Errorf("tool %s already registered", info.Name)
+	}
+	r.tools[info.Name] = t
+	r.infos[info.Name] = info
+	return nil
+}
+
+// Has 判断工具是否已注册
+func (r *Registry) Has(name string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	_, ok := r.tools[name]
+	return ok
+}
+
+// Unregister 移除工具
+func (r *Registry) Unregister(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.tools, name)
+	delete(r.infos, name)
+}
+
+// Get 按名称选择工具，names 为空时返回全部工具
+func (r *Registry) Get(names []string) ([]tool.BaseTool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if len(names) == 0 {
+		names = r.names()
+	}
+	tools := make([]tool.BaseTool, 0, len(names))
+	for _, name := range names {
+		t, ok := r.tools[name]
+		if !ok {
+			return nil, fmt.Errorf("未注册的工具: %s", name)
+		}
+		tools = append(tools, t)
+	}
+	return tools, nil
+}
+
+// List 返回全部工具的描述，按名称排序
+func (r *Registry) List() []*schema.ToolInfo {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	infos := make([]*schema.ToolInfo, 0, len(r.infos))
+	for _, name := range r.names() {
+		infos = append(infos, r.infos[name])
+	}
+	return infos
+}
+
+// SetApproval 设置工具的审批策略，pattern 为工具名称或以 * 结尾的前缀（如 github_*）
+func (r *Registry) SetApproval(pattern, policy string) error {
+	if policy != ApprovalNever && policy != ApprovalAlways {
+		return fmt.Errorf("未知的审批策略 %q，可选 %s、%s", policy, ApprovalAlways, ApprovalNever)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.approvals == nil {
+		r.approvals = make(map[string]string)
+	}
+	r.approvals[pattern] = policy
+	return nil
+}
+
+// RequiresApproval 判断工具执行前是否需要人工审批：精确名称优先，其次是最长的前缀
+func (r *Registry) RequiresApproval(name string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if policy, ok := r.approvals[name]; ok {
+		return policy == ApprovalAlways
+	}
+	policy, longest := ApprovalNever, -1
+	for pattern, p := range r.approvals {
+		prefix, ok := strings.CutSuffix(pattern, "*")
+		if ok && strings.HasPrefix(name, prefix) && len(prefix) > longest {
+			policy, longest = p, len(prefix)
+		}
+	}
+	return policy == ApprovalAlways
+}
+
+// ParseApprovals 解析审批策略配置："http_get=always,github_*=always,github_search=never"
+func ParseApprovals(s string) (map[string]string, error) {
+	approvals := make(map[string]string)
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+		pattern, policy, ok := strings.Cut(item, "=")
+		pattern, policy = strings.TrimSpace(pattern), strings.TrimSpace(policy)
+		if !ok || pattern == "" {
+			return nil, fmt.Errorf("审批策略格式错误 %q，应为 工具名=always|never", item)
+		}
+		if policy != ApprovalNever && policy != ApprovalAlways {
+			return nil, fmt.Errorf("工具 %s 的审批策略 %q 无效，可选 %s、%s", pattern, policy, ApprovalAlways, ApprovalNever)
+		}
+		approvals[pattern] = policy
+	}
+	return approvals, nil
+}
+
+func (r *Registry) names() []string {
+	names := make([]string, 0, len(r.tools))
+	for name := range r.tools {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// registerTool 用于具体工具在 init 时注册自己
+func registerTool(name string, factory ToolFactory) {
+	toolRegistry[name] = factory
+}
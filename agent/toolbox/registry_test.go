@@ -0,0 +1,33 @@
+package toolbox
+
+import "testing"
+
+func TestRequiresApproval(t *testing.T) {
+	approvals, err := ParseApprovals("http_get=always, github_*=always, github_search=never, git*=never")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &Registry{approvals: approvals}
+
+	cases := map[string]bool{
+		"http_get":      true,
+		"calculator":    false,
+		"github_create": true,  // github_* 比 git* 更长
+		"github_search": false, // 精确名称优先
+		"gitlab_merge":  false,
+	}
+	for name, want := range cases {
+		if got := r.RequiresApproval(name); got != want {
+			t.Errorf("RequiresApproval(%s) = %v, want %v", name, got, want)
+		}
+	}
+
+	if err := r.SetApproval("calculator", ApprovalAlways); err != nil || !r.RequiresApproval("calculator") {
+		t.Fatalf("SetApproval err = %v", err)
+	}
+	for _, bad := range []string{"http_get", "=always", "http_get=sometimes"} {
+		if _, err := ParseApprovals(bad); err == nil {
+			t.Errorf("ParseApprovals(%q) succeeded", bad)
+		}
+	}
+}
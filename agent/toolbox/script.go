@@ -0,0 +1,360 @@
+package toolbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-agent/config"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/components/tool/utils"
+	"go.starlark.net/lib/math"
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkjson"
+	"go.starlark.net/syntax"
+)
+
+// ToolRunStarlark 沙箱脚本工具名称
+const ToolRunStarlark = "run_starlark"
+
+const (
+	maxScriptTableRows    = 100000 // load_table 单表最多读取的行数
+	maxScriptResultTables = 10     // 单次运行最多产出的表
+)
+
+// ScriptLimits 脚本运行限制
+type ScriptLimits struct {
+	MaxSteps       uint64        `json:"max_steps"`        // 最多执行的 Starlark 指令数，限制 CPU 时间
+	Timeout        time.Duration `json:"timeout"`          // 墙钟超时
+	MaxOutputBytes int           `json:"max_output_bytes"` // print 输出上限，超出部分丢弃
+	MaxMemoryBytes uint64        `json:"max_memory_bytes"` // 脚本子进程可新增的内存上限，超出时子进程被终止
+	MaxResultRows  int           `json:"max_result_rows"`  // 每张产出表返回的行数上限
+}
+
+// DefaultScriptLimits 默认运行限制
+var DefaultScriptLimits = ScriptLimits{
+	MaxSteps:       10_000_000,
+	Timeout:        10 * time.Second,
+	MaxOutputBytes: 16 << 10,
+	MaxMemoryBytes: 256 << 20,
+	MaxResultRows:  defaultSQLMaxRows,
+}
+
+type runStarlarkInput struct {
+	Script string `json:"script" jsonschema:"description=Starlark (Python-like) source code; use print() for output and result_table() to return tables"`
+}
+
+// ScriptResult 脚本运行结果
+type ScriptResult struct {
+	Stdout          string        `json:"stdout"`
+	StdoutTruncated bool          `json:"stdout_truncated,omitempty"`
+	Tables          []ResultTable `json:"tables,omitempty"`
+	Steps           uint64        `json:"steps"`
+}
+
+// ResultTable 脚本通过 result_table 产出的表
+type ResultTable struct {
+	Name      string   `json:"name"`
+	Columns   []string `json:"columns"`
+	Rows      [][]any  `json:"rows"`
+	Truncated bool     `json:"truncated,omitempty"`
+}
+
+const runStarlarkDesc = `Run a Starlark script (a Python dialect without imports, file or network access) to analyse uploaded CSV tables.
+Available functions: tables() -> list of table names; load_table(name) -> list of dicts (one per row);
+result_table(name, rows, columns=None) returns a table (rows are dicts or lists); print(...) writes to stdout.
+Modules: math (sqrt, floor, log, ...), json (encode, decode). Use sorted(), sum via loops, dict and list comprehensions.`
+
+func newRunStarlarkTool(ctx context.Context) (tool.InvokableTool, error) {
+	store, err := sharedTableStore()
+	if err != nil {
+		return nil, err
+	}
+
+	limits := DefaultScriptLimits
+	conf := config.Cfg.AgentConf
+	if n, err := strconv.ParseUint(conf.ScriptMaxSteps, 10, 64); err == nil && n > 0 {
+		limits.MaxSteps = n
+	}
+	if d, err := time.ParseDuration(conf.ScriptTimeout); err == nil && d > 0 {
+		limits.Timeout = d
+	}
+	if n, err := strconv.Atoi(conf.ScriptMaxOutput); err == nil && n > 0 {
+		limits.MaxOutputBytes = n
+	}
+	if n, err := strconv.ParseUint(conf.ScriptMaxMemoryMB, 10, 64); err == nil && n > 0 {
+		limits.MaxMemoryBytes = n << 20
+	}
+	limits.MaxResultRows = store.maxRows
+	return NewRunStarlarkTool(store, limits)
+}
+
+// NewRunStarlarkTool 创建在沙箱中运行 Starlark 脚本的工具，脚本只能读取表存储中的表
+func NewRunStarlarkTool(store *TableStore, limits ScriptLimits) (tool.InvokableTool, error) {
+	return utils.InferTool(ToolRunStarlark, runStarlarkDesc,
+		func(ctx context.Context, in *runStarlarkInput) (*ScriptResult, error) {
+			return RunScript(ctx, store, in.Script, limits)
+		})
+}
+
+// runScript 在当前进程的独立 Starlark 线程中运行脚本，由脚本子进程调用
+// 指令数与墙钟超时限制 CPU；脚本没有 load、文件或网络能力
+func runScript(ctx context.Context, store *TableStore, script string, limits ScriptLimits) (*ScriptResult, error) {
+	run := &scriptRun{ctx: ctx, store: store, limits: limits, result: &ScriptResult{}}
+
+	thread := &starlark.Thread{
+		Name:  ToolRunStarlark,
+		Print: run.print,
+		Load: func(*starlark.Thread, string) (starlark.StringDict, error) {
+			return nil, errors.New("load is not allowed")
+		},
+	}
+	thread.SetMaxExecutionSteps(limits.MaxSteps)
+
+	ctx, cancel := context.WithTimeout(ctx, limits.Timeout)
+	defer cancel()
+	stop := run.watch(ctx, thread)
+	defer stop()
+
+	predeclared := starlark.StringDict{
+		"tables":       starlark.NewBuiltin("tables", run.tables),
+		"load_table":   starlark.NewBuiltin("load_table", run.loadTable),
+		"result_table": starlark.NewBuiltin("result_table", run.resultTable),
+		"math":         math.Module,
+		"json":         starlarkjson.Module,
+	}
+	opts := &syntax.FileOptions{Set: true, While: true, TopLevelControl: true, GlobalReassign: true, Recursion: true}
+	_, err := starlark.ExecFileOptions(opts, thread, "script.star", script, predeclared)
+
+	run.result.Steps = thread.ExecutionSteps()
+	if err != nil {
+		if reason := run.cancelReason(); reason != "" {
+			return nil, errors.New(reason)
+		}
+		if evalErr, ok := err.(*starlark.EvalError); ok {
+			return nil, errors.New(evalErr.Backtrace())
+		}
+		return nil, err
+	}
+	return run.result, nil
+}
+
+// scriptRun 单次运行的状态
+type scriptRun struct {
+	ctx    context.Context
+	store  *TableStore
+	limits ScriptLimits
+	result *ScriptResult
+
+	mu     sync.Mutex
+	stdout strings.Builder
+	reason string
+}
+
+func (r *scriptRun) print(_ *starlark.Thread, msg string) {
+	if r.result.StdoutTruncated {
+		return
+	}
+	if r.stdout.Len()+len(msg)+1 > r.limits.MaxOutputBytes {
+		r.stdout.WriteString(msg[:max(0, min(len(msg), r.limits.MaxOutputBytes-r.stdout.Len()))])
+		r.result.StdoutTruncated = true
+	} else {
+		r.stdout.WriteString(msg)
+		r.stdout.WriteByte('\n')
+	}
+	r.result.Stdout = r.stdout.String()
+}
+
+// watch 超时或取消时中止线程，返回停止监控的函数
+func (r *scriptRun) watch(ctx context.Context, thread *starlark.Thread) func() {
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-done:
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				r.cancel(thread, timeLimitMessage(r.limits))
+			} else {
+				r.cancel(thread, "script canceled")
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+func timeLimitMessage(limits ScriptLimits) string {
+	return fmt.Sprintf("script exceeded time limit of %s", limits.Timeout)
+}
+
+func (r *scriptRun) cancel(thread *starlark.Thread, reason string) {
+	r.mu.Lock()
+	if r.reason == "" {
+		r.reason = reason
+	}
+	r.mu.Unlock()
+	thread.Cancel(reason)
+}
+
+func (r *scriptRun) cancelReason() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.reason
+}
+
+func (r *scriptRun) tables(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 0); err != nil {
+		return nil, err
+	}
+	infos, err := r.store.List()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]starlark.Value, len(infos))
+	for i, info := range infos {
+		names[i] = starlark.String(info.Name)
+	}
+	return starlark.NewList(names), nil
+}
+
+func (r *scriptRun) loadTable(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var name string
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "name", &name); err != nil {
+		return nil, err
+	}
+	res, err := r.store.ReadTable(r.ctx, name, maxScriptTableRows)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([]starlark.Value, len(res.Rows))
+	for i, row := range res.Rows {
+		d := starlark.NewDict(len(res.Columns))
+		for j, col := range res.Columns {
+			if err := d.SetKey(starlark.String(col), toStarlark(row[j])); err != nil {
+				return nil, err
+			}
+		}
+		rows[i] = d
+	}
+	return starlark.NewList(rows), nil
+}
+
+func (r *scriptRun) resultTable(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var (
+		name    string
+		rows    starlark.Iterable
+		columns *starlark.List
+	)
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "name", &name, "rows", &rows, "columns?", &columns); err != nil {
+		return nil, err
+	}
+	if len(r.result.Tables) >= maxScriptResultTables {
+		return nil, fmt.Errorf("%s: at most %d tables can be returned", b.Name(), maxScriptResultTables)
+	}
+
+	table := ResultTable{Name: name, Rows: [][]any{}}
+	if columns != nil {
+		for i := 0; i < columns.Len(); i++ {
+			table.Columns = append(table.Columns, columnName(columns.Index(i)))
+		}
+	}
+
+	iter := rows.Iterate()
+	defer iter.Done()
+	var row starlark.Value
+	for iter.Next(&row) {
+		if len(table.Rows) >= r.limits.MaxResultRows {
+			table.Truncated = true
+			break
+		}
+		values, err := rowValues(row, &table.Columns)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", b.Name(), err)
+		}
+		table.Rows = append(table.Rows, values)
+	}
+
+	r.result.Tables = append(r.result.Tables, table)
+	return starlark.None, nil
+}
+
+// rowValues 转换一行：dict 按列名取值（首行确定列），list/tuple 按位置取值
+func rowValues(row starlark.Value, columns *[]string) ([]any, error) {
+	switch v := row.(type) {
+	case *starlark.Dict:
+		if len(*columns) == 0 {
+			for _, k := range v.Keys() {
+				*columns = append(*columns, columnName(k))
+			}
+		}
+		values := make([]any, len(*columns))
+		for i, col := range *columns {
+			if x, found, _ := v.Get(starlark.String(col)); found {
+				values[i] = fromStarlark(x)
+			}
+		}
+		return values, nil
+	case starlark.Indexable:
+		values := make([]any, v.Len())
+		for i := range values {
+			values[i] = fromStarlark(v.Index(i))
+		}
+		if len(*columns) == 0 {
+			for i := range values {
+				*columns = append(*columns, "col_"+strconv.Itoa(i+1))
+			}
+		}
+		return values, nil
+	}
+	return nil, fmt.Errorf("rows must be dicts or lists, got %s", row.Type())
+}
+
+func columnName(v starlark.Value) string {
+	if s, ok := starlark.AsString(v); ok {
+		return s
+	}
+	return v.String()
+}
+
+func toStarlark(v any) starlark.Value {
+	switch x := v.(type) {
+	case nil:
+		return starlark.None
+	case int64:
+		return starlark.MakeInt64(x)
+	case float64:
+		return starlark.Float(x)
+	case bool:
+		return starlark.Bool(x)
+	case string:
+		return starlark.String(x)
+	}
+	return starlark.String(fmt.Sprint(v))
+}
+
+func fromStarlark(v starlark.Value) any {
+	switch x := v.(type) {
+	case starlark.NoneType:
+		return nil
+	case starlark.Bool:
+		return bool(x)
+	case starlark.Int:
+		if n, ok := x.Int64(); ok {
+			return n
+		}
+		return x.String()
+	case starlark.Float:
+		return float64(x)
+	case starlark.String:
+		return string(x)
+	}
+	return v.String()
+}
+
+func initRunStarlark() {
+	registerTool(ToolRunStarlark, newRunStarlarkTool)
+}
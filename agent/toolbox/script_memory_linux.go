@@ -0,0 +1,50 @@
+package toolbox
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// limitMemory 把当前进程的地址空间上限设为已用量加 n 字节，超出后分配失败、进程退出
+// 同时设置 Go 运行时的软上限，让垃圾回收在接近上限前更积极地回收
+func limitMemory(n uint64) error {
+	if n == 0 {
+		return nil
+	}
+	used, err := virtualMemorySize()
+	if err != nil {
+		return err
+	}
+	debug.SetMemoryLimit(int64(n))
+	limit := used + n
+	return syscall.Setrlimit(syscall.RLIMIT_AS, &syscall.Rlimit{Cur: limit, Max: limit})
+}
+
+// virtualMemorySize 读取 /proc/self/status 中的 VmSize
+func virtualMemorySize() (uint64, error) {
+	f, err := os.Open("/proc/self/status")
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if value, ok := strings.CutPrefix(scanner.Text(), "VmSize:"); ok {
+			kb, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimSpace(value), " kB"), 10, 64)
+			if err != nil {
+				return 0, fmt.Errorf("parse VmSize failed: %w", err)
+			}
+			return kb << 10, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+	return 0, fmt.Errorf("VmSize not found in /proc/self/status")
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package toolbox
+
+import "runtime/debug"
+
+// limitMemory 非 Linux 平台只能设置 Go 运行时的软上限，超出时垃圾回收更积极，但不会终止进程
+func limitMemory(n uint64) error {
+	if n > 0 {
+		debug.SetMemoryLimit(int64(n))
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package toolbox
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// run_starlark 在子进程中运行脚本，测试进程以 GO_AGENT_SCRIPT_WORKER=1 启动时充当该子进程
+func TestMain(m *testing.M) {
+	if ServeScriptWorker() {
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func newScriptStore(t *testing.T) *TableStore {
+	t.Helper()
+	store, err := NewTableStore(t.TempDir(), 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Save("sales.csv", writeCSV(t, "sales.csv", "region,amount\nnorth,10\nsouth,5\nnorth,7\n")); err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func TestRunScriptAnalysesTable(t *testing.T) {
+	store := newScriptStore(t)
+	script := `
+totals = {}
+for row in load_table("sales"):
+    totals[row["region"]] = totals.get(row["region"], 0) + row["amount"]
+print("tables:", tables())
+print("mean:", math.floor(sum_of(totals.values()) / len(totals)))
+result_table("totals", [{"region": k, "total": v} for k, v in sorted(totals.items())])
+result_table("numbers", [[i] for i in range(10)], columns=["n"])
+`
+	script = "def sum_of(xs):\n    s = 0\n    for x in xs:\n        s += x\n    return s\n" + script
+
+	limits := DefaultScriptLimits
+	limits.MaxResultRows = 3
+	res, err := RunScript(context.Background(), store, script, limits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Stdout != "tables: [\"sales\"]\nmean: 11\n" {
+		t.Fatalf("stdout = %q", res.Stdout)
+	}
+	if len(res.Tables) != 2 {
+		t.Fatalf("tables = %+v", res.Tables)
+	}
+	totals := res.Tables[0]
+	if strings.Join(totals.Columns, ",") != "region,total" || len(totals.Rows) != 2 || totals.Rows[0][0] != "north" || totals.Rows[0][1] != int64(17) {
+		t.Fatalf("totals = %+v", totals)
+	}
+	if numbers := res.Tables[1]; numbers.Columns[0] != "n" || len(numbers.Rows) != 3 || !numbers.Truncated {
+		t.Fatalf("numbers = %+v", numbers)
+	}
+}
+
+func TestRunScriptLimits(t *testing.T) {
+	store := newScriptStore(t)
+	ctx := context.Background()
+
+	limits := DefaultScriptLimits
+	limits.MaxSteps = 10000
+	if _, err := RunScript(ctx, store, "x = 0\nwhile True:\n    x += 1\n", limits); err == nil || !strings.Contains(err.Error(), "too many steps") {
+		t.Fatalf("step limit err = %v", err)
+	}
+
+	limits = DefaultScriptLimits
+	limits.Timeout = 50 * time.Millisecond
+	if _, err := RunScript(ctx, store, "while True:\n    pass\n", limits); err == nil || !strings.Contains(err.Error(), "time limit") {
+		t.Fatalf("timeout err = %v", err)
+	}
+
+	limits = DefaultScriptLimits
+	limits.MaxOutputBytes = 10
+	res, err := RunScript(ctx, store, `print("hello world, this is long")`, limits)
+	if err != nil || res.Stdout != "hello worl" || !res.StdoutTruncated {
+		t.Fatalf("stdout = %+v, err = %v", res, err)
+	}
+
+	for _, script := range []string{
+		`load("os.star", "x")`,
+		`open("/etc/passwd")`,
+		`load_table("missing")`,
+	} {
+		if _, err := RunScript(ctx, store, script, DefaultScriptLimits); err == nil {
+			t.Errorf("script %q succeeded, want error", script)
+		}
+	}
+}
+
+func TestRunScriptMemoryLimit(t *testing.T) {
+	store := newScriptStore(t)
+	ctx := context.Background()
+	limits := DefaultScriptLimits
+	limits.MaxMemoryBytes = 64 << 20
+
+	// 每次拼接只算一条指令，指令数限制拦不住，内存上限让子进程退出
+	script := "s = \"x\" * (1 << 20)\nfor i in range(16):\n    s = s + s\nprint(len(s))\n"
+	if _, err := RunScript(ctx, store, script, limits); err == nil || !strings.Contains(err.Error(), "memory limit of 64 MB") {
+		t.Fatalf("memory limit err = %v", err)
+	}
+	if _, err := RunScript(ctx, store, "x = [0] * (1 << 28)\n", limits); err == nil || !strings.Contains(err.Error(), "memory limit") {
+		t.Fatalf("list memory limit err = %v", err)
+	}
+
+	// 服务进程不受影响，后续脚本照常运行
+	res, err := RunScript(ctx, store, "print(len(load_table(\"sales\")))", limits)
+	if err != nil || res.Stdout != "3\n" {
+		t.Fatalf("after memory limit: %+v, %v", res, err)
+	}
+}
@@ -0,0 +1,147 @@
+package toolbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// scriptWorkerEnv 以该环境变量启动本程序时作为脚本子进程运行，见 ServeScriptWorker
+const scriptWorkerEnv = "GO_AGENT_SCRIPT_WORKER"
+
+// scriptKillGrace 子进程自身超时之外，父进程强制结束子进程前额外等待的时间（含进程启动）
+const scriptKillGrace = 2 * time.Second
+
+// scriptRequest 父进程通过标准输入发给脚本子进程的请求
+type scriptRequest struct {
+	Script   string       `json:"script"`
+	Limits   ScriptLimits `json:"limits"`
+	TableDir string       `json:"table_dir"`
+	MaxRows  int          `json:"max_rows"`
+}
+
+// scriptResponse 脚本子进程通过标准输出返回的结果
+type scriptResponse struct {
+	Result *ScriptResult `json:"result,omitempty"`
+	Error  string        `json:"error,omitempty"`
+}
+
+// RunScript 在子进程中运行脚本：子进程启动后先限制自身可新增的内存，超出时被操作系统终止，
+// 不影响服务进程；指令数与墙钟超时在子进程内限制，父进程另按超时兜底结束子进程
+func RunScript(ctx context.Context, store *TableStore, script string, limits ScriptLimits) (*ScriptResult, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("locate executable failed: %w", err)
+	}
+	req, err := json.Marshal(scriptRequest{Script: script, Limits: limits, TableDir: store.dir, MaxRows: store.maxRows})
+	if err != nil {
+		return nil, err
+	}
+
+	runCtx, cancel := context.WithTimeout(ctx, limits.Timeout+scriptKillGrace)
+	defer cancel()
+	cmd := exec.CommandContext(runCtx, exe)
+	// 子进程不继承环境变量，脚本拿不到密钥等配置
+	cmd.Env = []string{scriptWorkerEnv + "=1"}
+	cmd.Stdin = bytes.NewReader(req)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	runErr := cmd.Run()
+	var resp scriptResponse
+	if runErr == nil {
+		dec := json.NewDecoder(&stdout)
+		dec.UseNumber()
+		if err := dec.Decode(&resp); err != nil {
+			return nil, fmt.Errorf("read script result failed: %w", err)
+		}
+		if resp.Error != "" {
+			return nil, errors.New(resp.Error)
+		}
+		restoreNumbers(resp.Result)
+		return resp.Result, nil
+	}
+
+	switch {
+	case ctx.Err() != nil:
+		return nil, errors.New("script canceled")
+	case runCtx.Err() != nil:
+		return nil, errors.New(timeLimitMessage(limits))
+	case strings.Contains(stderr.String(), "out of memory"):
+		return nil, fmt.Errorf("script exceeded memory limit of %d MB", limits.MaxMemoryBytes>>20)
+	}
+	return nil, fmt.Errorf("script process failed: %w: %s", runErr, lastLine(stderr.String()))
+}
+
+// ServeScriptWorker 环境变量表明当前进程是脚本子进程时，从标准输入读取请求、运行脚本并把结果写到标准输出，返回 true
+// 程序入口（及测试的 TestMain）需最先调用，返回 true 时直接退出
+func ServeScriptWorker() bool {
+	if os.Getenv(scriptWorkerEnv) != "1" {
+		return false
+	}
+	if err := serveScript(os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	return true
+}
+
+func serveScript(r io.Reader, w io.Writer) error {
+	var req scriptRequest
+	if err := json.NewDecoder(r).Decode(&req); err != nil {
+		return fmt.Errorf("read script request failed: %w", err)
+	}
+	if err := limitMemory(req.Limits.MaxMemoryBytes); err != nil {
+		return fmt.Errorf("limit script memory failed: %w", err)
+	}
+
+	var resp scriptResponse
+	store, err := NewTableStore(req.TableDir, req.MaxRows)
+	if err == nil {
+		resp.Result, err = runScript(context.Background(), store, req.Script, req.Limits)
+	}
+	if err != nil {
+		resp.Error = err.Error()
+	}
+	return json.NewEncoder(w).Encode(resp)
+}
+
+// restoreNumbers 把表中经 JSON 传回的数值还原为与进程内运行一致的 int64/float64
+func restoreNumbers(result *ScriptResult) {
+	if result == nil {
+		return
+	}
+	for _, table := range result.Tables {
+		for _, row := range table.Rows {
+			for i, v := range row {
+				n, ok := v.(json.Number)
+				if !ok {
+					continue
+				}
+				if x, err := n.Int64(); err == nil {
+					row[i] = x
+				} else if f, err := n.Float64(); err == nil {
+					row[i] = f
+				} else {
+					row[i] = n.String()
+				}
+			}
+		}
+	}
+}
+
+func lastLine(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.LastIndexByte(s, '\n'); i >= 0 {
+		return s[i+1:]
+	}
+	return s
+}
@@ -0,0 +1,439 @@
+package toolbox
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"go-agent/config"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/components/tool/utils"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// ToolSQLQuery CSV 只读 SQL 查询工具名称
+const ToolSQLQuery = "sql_query"
+
+const (
+	sqlQueryTimeout   = 10 * time.Second
+	defaultSQLMaxRows = 200
+)
+
+// Tables 上传 CSV 的表存储，启用 sql_query 或 run_starlark 工具时初始化，未启用时为 nil
+var Tables *TableStore
+
+var (
+	tableNameInvalid = regexp.MustCompile(`[^a-z0-9_]+`)
+	readOnlyQuery    = regexp.MustCompile(`(?i)^\s*(select|with)\b`)
+)
+
+type sqlQueryInput struct {
+	Query string `json:"query" jsonschema:"description=a single read-only SQLite SELECT statement; list tables with: SELECT name FROM sqlite_master WHERE type='table'"`
+}
+
+// QueryResult 查询结果，超过行数上限时截断
+type QueryResult struct {
+	Columns   []string `json:"columns"`
+	Rows      [][]any  `json:"rows"`
+	Truncated bool     `json:"truncated,omitempty"`
+}
+
+// TableInfo 一张由 CSV 生成的表
+type TableInfo struct {
+	Name    string   `json:"name"`
+	Columns []string `json:"columns"`
+	Rows    int      `json:"rows"`
+}
+
+// TableStore 把目录中的 CSV 文件加载为内存 SQLite 表，目录变化后在下次查询时重新加载
+type TableStore struct {
+	dir     string
+	maxRows int
+
+	mu        sync.Mutex
+	db        *sql.DB
+	tables    []TableInfo
+	signature string
+}
+
+// NewTableStore 创建表存储
+func NewTableStore(dir string, maxRows int) (*TableStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("create table dir failed: %w", err)
+	}
+	if maxRows <= 0 {
+		maxRows = defaultSQLMaxRows
+	}
+	return &TableStore{dir: dir, maxRows: maxRows}, nil
+}
+
+// TableName 由上传文件名生成表名：小写，非字母数字替换为下划线
+func TableName(filename string) string {
+	name := strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename))
+	name = strings.Trim(tableNameInvalid.ReplaceAllString(strings.ToLower(name), "_"), "_")
+	if name == "" || name[0] >= '0' && name[0] <= '9' {
+		name = "t_" + name
+	}
+	return name
+}
+
+// Save 校验 CSV 并保存为表，同名表会被覆盖，返回表名
+func (s *TableStore) Save(filename, path string) (string, error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+
+	if _, err := csv.NewReader(src).Read(); err != nil {
+		return "", fmt.Errorf("invalid csv header: %w", err)
+	}
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+
+	table := TableName(filename)
+	target := filepath.Join(s.dir, table+".csv")
+	tmp := target + ".tmp"
+	dst, err := os.Create(tmp)
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		os.Remove(tmp)
+		return "", err
+	}
+	if err := dst.Close(); err != nil {
+		return "", err
+	}
+	return table, os.Rename(tmp, target)
+}
+
+// List 返回全部表及其列
+func (s *TableStore) List() ([]TableInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return append([]TableInfo(nil), s.tables...), nil
+}
+
+// Query 执行只读查询，只接受单条 SELECT/WITH 语句，连接本身也处于 query_only 模式
+func (s *TableStore) Query(ctx context.Context, query string) (*QueryResult, error) {
+	query = strings.TrimRight(strings.TrimSpace(query), ";")
+	if !readOnlyQuery.MatchString(query) {
+		return nil, errors.New("only a single SELECT statement is allowed")
+	}
+	if strings.Contains(query, ";") {
+		return nil, errors.New("multiple statements are not allowed")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, sqlQueryTimeout)
+	defer cancel()
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	result := &QueryResult{Columns: columns, Rows: [][]any{}}
+	for rows.Next() {
+		if len(result.Rows) >= s.maxRows {
+			result.Truncated = true
+			break
+		}
+		values, err := scanRow(rows, len(columns))
+		if err != nil {
+			return nil, err
+		}
+		result.Rows = append(result.Rows, values)
+	}
+	return result, rows.Err()
+}
+
+// scanRow 读取一行，文本列转换为 string
+func scanRow(rows *sql.Rows, n int) ([]any, error) {
+	values := make([]any, n)
+	ptrs := make([]any, n)
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	if err := rows.Scan(ptrs...); err != nil {
+		return nil, err
+	}
+	for i, v := range values {
+		if b, ok := v.([]byte); ok {
+			values[i] = string(b)
+		}
+	}
+	return values, nil
+}
+
+// ReadTable 读取整张表，最多 limit 行，超过时返回错误而不是静默截断
+func (s *TableStore) ReadTable(ctx context.Context, name string, limit int) (*QueryResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+
+	var info *TableInfo
+	for i := range s.tables {
+		if s.tables[i].Name == name {
+			info = &s.tables[i]
+		}
+	}
+	if info == nil {
+		return nil, fmt.Errorf("table %s does not exist", name)
+	}
+	if info.Rows > limit {
+		return nil, fmt.Errorf("table %s has %d rows, more than the limit of %d", name, info.Rows, limit)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, sqlQueryTimeout)
+	defer cancel()
+	rows, err := s.db.QueryContext(ctx, "SELECT * FROM "+quoteIdent(name))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := &QueryResult{Columns: info.Columns, Rows: make([][]any, 0, info.Rows)}
+	for rows.Next() {
+		values, err := scanRow(rows, len(info.Columns))
+		if err != nil {
+			return nil, err
+		}
+		result.Rows = append(result.Rows, values)
+	}
+	return result, rows.Err()
+}
+
+// load 目录内容变化时重建内存数据库，调用方需持有锁
+func (s *TableStore) load() error {
+	paths, err := filepath.Glob(filepath.Join(s.dir, "*.csv"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(paths)
+
+	h := sha256.New()
+	for _, path := range paths {
+		if info, err := os.Stat(path); err == nil {
+			fmt.Fprintf(h, "%s|%d|%d\n", path, info.Size(), info.ModTime().UnixNano())
+		}
+	}
+	signature := hex.EncodeToString(h.Sum(nil))
+	if s.db != nil && signature == s.signature {
+		return nil
+	}
+
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		return err
+	}
+	// 内存库按连接隔离，只保留一个连接
+	db.SetMaxOpenConns(1)
+
+	tables := make([]TableInfo, 0, len(paths))
+	for _, path := range paths {
+		info, err := loadCSV(db, path)
+		if err != nil {
+			db.Close()
+			return fmt.Errorf("load %s failed: %w", filepath.Base(path), err)
+		}
+		tables = append(tables, *info)
+	}
+	if _, err := db.Exec("PRAGMA query_only = ON"); err != nil {
+		db.Close()
+		return err
+	}
+
+	if s.db != nil {
+		s.db.Close()
+	}
+	s.db, s.tables, s.signature = db, tables, signature
+	return nil
+}
+
+// loadCSV 建表并导入数据，列类型按内容推断为 INTEGER、REAL 或 TEXT，空值为 NULL
+func loadCSV(db *sql.DB, path string) (*TableInfo, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, errors.New("empty csv")
+	}
+
+	columns := columnNames(records[0])
+	rows := records[1:]
+	types := make([]string, len(columns))
+	defs := make([]string, len(columns))
+	for i, col := range columns {
+		types[i] = inferColumnType(rows, i)
+		defs[i] = quoteIdent(col) + " " + types[i]
+	}
+
+	table := strings.TrimSuffix(filepath.Base(path), ".csv")
+	if _, err := db.Exec(fmt.Sprintf("CREATE TABLE %s (%s)", quoteIdent(table), strings.Join(defs, ", "))); err != nil {
+		return nil, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ")
+	stmt, err := tx.Prepare(fmt.Sprintf("INSERT INTO %s VALUES (%s)", quoteIdent(table), placeholders))
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	defer stmt.Close()
+
+	args := make([]any, len(columns))
+	for _, row := range rows {
+		for i := range columns {
+			args[i] = cellValue(row, i, types[i])
+		}
+		if _, err := stmt.Exec(args...); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &TableInfo{Name: table, Columns: columns, Rows: len(rows)}, nil
+}
+
+// columnNames 规整表头：空列名改为 col_N，重复列名追加序号
+func columnNames(header []string) []string {
+	seen := make(map[string]int, len(header))
+	columns := make([]string, len(header))
+	for i, name := range header {
+		name = strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))
+		if name == "" {
+			name = "col_" + strconv.Itoa(i+1)
+		}
+		key := strings.ToLower(name)
+		if n := seen[key]; n > 0 {
+			name = fmt.Sprintf("%s_%d", name, n+1)
+		}
+		seen[key]++
+		columns[i] = name
+	}
+	return columns
+}
+
+func inferColumnType(rows [][]string, col int) string {
+	typ := "INTEGER"
+	for _, row := range rows {
+		if col >= len(row) {
+			continue
+		}
+		v := strings.TrimSpace(row[col])
+		if v == "" {
+			continue
+		}
+		if typ == "INTEGER" {
+			if _, err := strconv.ParseInt(v, 10, 64); err == nil {
+				continue
+			}
+			typ = "REAL"
+		}
+		if _, err := strconv.ParseFloat(v, 64); err != nil {
+			return "TEXT"
+		}
+	}
+	return typ
+}
+
+func cellValue(row []string, col int, typ string) any {
+	if col >= len(row) || strings.TrimSpace(row[col]) == "" {
+		return nil
+	}
+	v := strings.TrimSpace(row[col])
+	switch typ {
+	case "INTEGER":
+		n, _ := strconv.ParseInt(v, 10, 64)
+		return n
+	case "REAL":
+		f, _ := strconv.ParseFloat(v, 64)
+		return f
+	}
+	return row[col]
+}
+
+func quoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// sharedTableStore 返回全局表存储，首次调用时按配置创建，sql_query 与 run_starlark 共用
+func sharedTableStore() (*TableStore, error) {
+	if Tables != nil {
+		return Tables, nil
+	}
+	maxRows, _ := strconv.Atoi(config.Cfg.AgentConf.SQLMaxRows)
+	store, err := NewTableStore(config.Cfg.AgentConf.TableDir, maxRows)
+	if err != nil {
+		return nil, err
+	}
+	Tables = store
+	return store, nil
+}
+
+func newSQLQueryTool(ctx context.Context) (tool.InvokableTool, error) {
+	store, err := sharedTableStore()
+	if err != nil {
+		return nil, err
+	}
+	return NewSQLQueryTool(store)
+}
+
+// NewSQLQueryTool 创建查询表存储的只读 SQL 工具
+func NewSQLQueryTool(store *TableStore) (tool.InvokableTool, error) {
+	return utils.InferTool(ToolSQLQuery,
+		fmt.Sprintf("Run a read-only SQLite query over tables created from uploaded CSV files (one table per file, named after the file). Returns at most %d rows.", store.maxRows),
+		func(ctx context.Context, in *sqlQueryInput) (*QueryResult, error) {
+			return store.Query(ctx, in.Query)
+		})
+}
+
+func initSQLQuery() {
+	registerTool(ToolSQLQuery, newSQLQueryTool)
+}
@@ -0,0 +1,70 @@
+package toolbox
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeCSV(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestTableStoreQuery(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewTableStore(t.TempDir(), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	table, err := store.Save("2024 Sales-Report.csv", writeCSV(t, "upload.csv", "region,amount,price\nnorth,10,1.5\nsouth,5,2\nnorth,7,\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if table != "t_2024_sales_report" {
+		t.Fatalf("table = %q", table)
+	}
+
+	res, err := store.Query(ctx, "SELECT region, SUM(amount) AS total FROM t_2024_sales_report GROUP BY region ORDER BY total DESC;")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Rows) != 2 || res.Rows[0][0] != "north" || res.Rows[0][1] != int64(17) {
+		t.Fatalf("rows = %v", res.Rows)
+	}
+
+	// 行数上限
+	res, err = store.Query(ctx, "SELECT * FROM t_2024_sales_report")
+	if err != nil || len(res.Rows) != 2 || !res.Truncated {
+		t.Fatalf("rows = %v, truncated = %v, err = %v", res.Rows, res.Truncated, err)
+	}
+
+	for _, q := range []string{
+		"DELETE FROM t_2024_sales_report",
+		"SELECT 1; DROP TABLE t_2024_sales_report",
+		"WITH x AS (SELECT 1) DELETE FROM t_2024_sales_report",
+	} {
+		if _, err := store.Query(ctx, q); err == nil {
+			t.Errorf("query %q succeeded, want error", q)
+		}
+	}
+
+	// 新上传的文件在下次查询时加载
+	if _, err := store.Save("users.csv", writeCSV(t, "users.csv", "name,age\nann,30\n")); err != nil {
+		t.Fatal(err)
+	}
+	tables, err := store.List()
+	if err != nil || len(tables) != 2 {
+		t.Fatalf("tables = %+v, err = %v", tables, err)
+	}
+	if _, err := store.Save("bad.csv", writeCSV(t, "bad.csv", "")); err == nil || !strings.Contains(err.Error(), "csv") {
+		t.Fatalf("empty csv err = %v", err)
+	}
+}
@@ -0,0 +1,255 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-agent/agent"
+	"go-agent/agent/plan"
+	"go-agent/agent/toolbox"
+	"go-agent/config"
+	"go-agent/model/chat_model"
+	"go-agent/model/prompt_template"
+	"go-agent/model/usage"
+	"net/http"
+	"strconv"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+	"github.com/gin-gonic/gin"
+)
+
+// AgentRunRequest 工具调用 Agent 请求
+type AgentRunRequest struct {
+	Question string            `json:"question" binding:"required"`
+	History  []ChatTestMessage `json:"history,omitempty"`
+	Model    string            `json:"model,omitempty"` // 指定聊天模型，需支持工具调用
+	// Tools 本次可用的工具名称（见 GET /api/agent/tools），为空时使用全部工具
+	Tools []string `json:"tools,omitempty"`
+	// MaxIterations 最多调用模型的轮数，为空时使用配置值，不能超过配置值
+	MaxIterations int `json:"max_iterations,omitempty"`
+	// PromptID 提示词模板，为空时使用 default_agent
+	PromptID     string `json:"prompt_id,omitempty"`
+	SystemPrompt string `json:"system_prompt,omitempty"`
+	// 生成参数：temperature、max_tokens、top_p、stop、seed
+	chat_model.GenerationParams
+}
+
+// AgentRunResponse 工具调用 Agent 响应
+type AgentRunResponse struct {
+	Success    bool          `json:"success"`
+	Message    string        `json:"message,omitempty"`
+	Question   string        `json:"question,omitempty"`
+	Answer     string        `json:"answer,omitempty"`
+	Provider   string        `json:"provider,omitempty"`
+	Prompt     string        `json:"prompt,omitempty"`
+	Iterations int           `json:"iterations,omitempty"`
+	Trace      []agent.Event `json:"trace,omitempty"`
+	Usage      *usage.Usage  `json:"usage,omitempty"`
+
+	// RunID 运行记录 ID，可通过 GET /api/agent/runs/:id 查看每一步
+	// 状态为 waiting_approval 时通过 /api/agent/runs/:id/approve|reject 继续
+	RunID   string        `json:"run_id,omitempty"`
+	Status  string        `json:"status,omitempty"`
+	Pending []agent.Event `json:"pending,omitempty"`
+}
+
+type AgentToolsResponse struct {
+	Success bool               `json:"success"`
+	Tools   []*schema.ToolInfo `json:"tools"`
+	// RequireApproval 执行前需要人工审批的工具
+	RequireApproval []string `json:"require_approval,omitempty"`
+}
+
+// AgentDecisionRequest 审批请求，请求体可以为空
+type AgentDecisionRequest struct {
+	// CallIDs 本次审批的工具调用，为空时对全部等待审批的调用生效
+	CallIDs []string `json:"call_ids,omitempty"`
+	Reason  string   `json:"reason,omitempty"` // 拒绝原因，会作为工具结果交给模型
+}
+
+// agentRun 校验完成、可以直接运行的 Agent 请求
+type agentRun struct {
+	conf     agent.Config
+	messages []*schema.Message
+	prompt   string
+	question string
+}
+
+// AgentRun 运行 Agent，完成后一次性返回回答与完整轨迹
+func AgentRun(c *gin.Context) {
+	var req AgentRunRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, AgentRunResponse{Success: false, Message: "请求格式错误: " + err.Error()})
+		return
+	}
+	ctx := c.Request.Context()
+
+	run, err := prepareAgentRun(ctx, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, AgentRunResponse{Success: false, Message: err.Error()})
+		return
+	}
+	rec, err := newAgentRunRecord(&req, run)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, AgentRunResponse{Success: false, Message: err.Error()})
+		return
+	}
+
+	err = executeAgentRun(ctx, rec, nil, startAgentRun(run))
+	c.JSON(agentRunResponse(ctx, req.Question, rec, err))
+}
+
+// AgentRunStream 运行 Agent，以 SSE 实时推送每一轮的工具调用与结果
+// 事件依次为 start（含 run_id）、iteration/thought/tool_call/tool_result（可重复）、answer、end，失败时推送 error
+// 工具调用需要审批时推送 approval_required 与 paused 后结束，审批通过 /api/agent/runs/:id/approve|reject 提交
+// 每一步都写入运行记录，连接中途断开后可以通过 /api/agent/runs/:id/resume 从最后完成的一步继续
+func AgentRunStream(c *gin.Context) {
+	var req AgentRunRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format: " + err.Error()})
+		return
+	}
+
+	run, err := prepareAgentRun(c.Request.Context(), &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	rec, err := newAgentRunRecord(&req, run)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	streamAgentRun(c, rec, startAgentRun(run))
+}
+
+// ListAgentTools 列出可供 Agent 调用的工具及其参数 JSON Schema
+func ListAgentTools(c *gin.Context) {
+	if toolbox.Tools == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "工具注册表未初始化"})
+		return
+	}
+	resp := AgentToolsResponse{Success: true, Tools: toolbox.Tools.List()}
+	for _, info := range resp.Tools {
+		if toolbox.Tools.RequiresApproval(info.Name) {
+			resp.RequireApproval = append(resp.RequireApproval, info.Name)
+		}
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// prepareAgentRun 选择模型、工具与提示词，构建首轮消息
+func prepareAgentRun(ctx context.Context, req *AgentRunRequest) (*agentRun, error) {
+	if toolbox.Tools == nil {
+		return nil, fmt.Errorf("工具注册表未初始化")
+	}
+
+	tcm, err := toolCallingModel(req.Model, req.GenerationParams)
+	if err != nil {
+		return nil, err
+	}
+	maxIterations, err := requestMaxIterations(req.MaxIterations)
+	if err != nil {
+		return nil, err
+	}
+
+	tools, err := toolbox.Tools.Get(req.Tools)
+	if err != nil {
+		return nil, err
+	}
+	// 选用了需要审批的工具时运行可能暂停，需要运行存储保存检查点
+	var needsApproval bool
+	for _, t := range tools {
+		info, err := t.Info(ctx)
+		if err != nil {
+			return nil, err
+		}
+		needsApproval = needsApproval || toolbox.Tools.RequiresApproval(info.Name)
+	}
+	if needsApproval && agent.Runs == nil {
+		return nil, fmt.Errorf("所选工具需要审批，但运行存储未初始化")
+	}
+
+	tmpl, err := resolvePrompt(req.PromptID, req.SystemPrompt, prompt_template.DefaultAgent)
+	if err != nil {
+		return nil, err
+	}
+	messages, err := tmpl.Format(ctx, chatHistory(req.History), req.Question, "", false)
+	if err != nil {
+		return nil, err
+	}
+
+	run := &agentRun{
+		conf: agent.Config{
+			RunID:         agent.NewRunID(),
+			Model:         tcm,
+			Tools:         tools,
+			MaxIterations: maxIterations,
+			ModelOptions:  req.GenerationParams.Options(),
+		},
+		messages: messages,
+		prompt:   tmpl.ID(),
+		question: req.Question,
+	}
+	if needsApproval {
+		run.conf.NeedsApproval, run.conf.CheckPoints = toolbox.Tools.RequiresApproval, agent.Runs
+	}
+	return run, nil
+}
+
+// toolCallingModel 选择支持工具调用的聊天模型并校验生成参数
+func toolCallingModel(name string, params chat_model.GenerationParams) (model.ToolCallingChatModel, error) {
+	cm, err := chat_model.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	tcm, ok := cm.(model.ToolCallingChatModel)
+	if !ok {
+		return nil, fmt.Errorf("聊天模型 %s 不支持工具调用", name)
+	}
+	if err := chat_model.ValidateParams(name, params); err != nil {
+		return nil, fmt.Errorf("生成参数不合法: %w", err)
+	}
+	return tcm, nil
+}
+
+// requestMaxIterations 请求的最大迭代轮数，为 0 时使用配置值，不能超过配置值
+func requestMaxIterations(n int) (int, error) {
+	maxIterations := agentMaxIterations()
+	if n < 0 || n > maxIterations {
+		return 0, fmt.Errorf("max_iterations 需在 1-%d 之间", maxIterations)
+	}
+	if n > 0 {
+		return n, nil
+	}
+	return maxIterations, nil
+}
+
+// agentMaxIterations 读取配置的最大迭代轮数
+func agentMaxIterations() int {
+	if config.Cfg != nil {
+		if n, err := strconv.Atoi(config.Cfg.AgentConf.MaxIterations); err == nil && n > 0 {
+			return n
+		}
+	}
+	return agent.DefaultMaxIterations
+}
+
+func agentRunErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, agent.ErrRunNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, agent.ErrRunStatus):
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
+func agentErrorStatus(err error) int {
+	if errors.Is(err, agent.ErrMaxIterations) || errors.Is(err, plan.ErrTooManyReplans) {
+		return http.StatusUnprocessableEntity
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,207 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"go-agent/agent/plan"
+	"go-agent/agent/toolbox"
+	"go-agent/config"
+	"go-agent/model/chat_model"
+	"go-agent/model/usage"
+	"net/http"
+	"strconv"
+
+	"github.com/cloudwego/eino/schema"
+	"github.com/gin-gonic/gin"
+)
+
+// PlanRunRequest 计划模式请求：先生成步骤计划，再逐步调用工具或检索执行
+type PlanRunRequest struct {
+	Question string            `json:"question" binding:"required"`
+	History  []ChatTestMessage `json:"history,omitempty"`
+	Model    string            `json:"model,omitempty"` // 指定聊天模型，需支持工具调用
+	// Tools 执行步骤时可用的工具名称，为空时使用全部工具
+	Tools []string `json:"tools,omitempty"`
+	// MaxSteps 计划最多的步骤数，为空时使用配置值，不能超过配置值
+	MaxSteps int `json:"max_steps,omitempty"`
+	// MaxIterations 每个步骤内最多调用模型的轮数，为空时使用配置值，不能超过配置值
+	MaxIterations int `json:"max_iterations,omitempty"`
+	// 生成参数：temperature、max_tokens、top_p、stop、seed
+	chat_model.GenerationParams
+}
+
+// PlanRunResponse 计划模式响应，Plan 为最终执行的计划及每一步的结果
+type PlanRunResponse struct {
+	Success  bool         `json:"success"`
+	Message  string       `json:"message,omitempty"`
+	Question string       `json:"question,omitempty"`
+	Answer   string       `json:"answer,omitempty"`
+	Provider string       `json:"provider,omitempty"`
+	Plan     []plan.Step  `json:"plan,omitempty"`
+	Replans  int          `json:"replans,omitempty"`
+	Trace    []plan.Event `json:"trace,omitempty"`
+	Usage    *usage.Usage `json:"usage,omitempty"`
+}
+
+// PlanRun 以计划模式运行 Agent，完成后一次性返回回答、计划与完整轨迹
+func PlanRun(c *gin.Context) {
+	var req PlanRunRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, PlanRunResponse{Success: false, Message: "请求格式错误: " + err.Error()})
+		return
+	}
+	ctx := c.Request.Context()
+
+	conf, err := preparePlanRun(ctx, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, PlanRunResponse{Success: false, Message: err.Error()})
+		return
+	}
+
+	result, err := plan.Run(ctx, conf, planMessages(&req), nil)
+	if err != nil {
+		c.JSON(agentErrorStatus(err), PlanRunResponse{
+			Success: false,
+			Message: "计划运行失败: " + err.Error(),
+			Plan:    result.Plan,
+			Replans: result.Replans,
+			Trace:   result.Trace,
+			Usage:   usage.FromContext(ctx).Summary(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, PlanRunResponse{
+		Success:  true,
+		Question: req.Question,
+		Answer:   result.Answer.Content,
+		Provider: chat_model.ProviderOf(result.Answer),
+		Plan:     result.Plan,
+		Replans:  result.Replans,
+		Trace:    result.Trace,
+		Usage:    usage.FromContext(ctx).Summary(),
+	})
+}
+
+// PlanRunStream 以计划模式运行 Agent，以 SSE 实时推送计划更新与每一步的结果
+// 事件依次为 start、plan、每一步的 step_start、iteration/thought/tool_call/tool_result（带 step 字段）、step_result，
+// 步骤失败后推送 replan 与新的计划，最后为 answer、end，失败时推送 error
+func PlanRunStream(c *gin.Context) {
+	var req PlanRunRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format: " + err.Error()})
+		return
+	}
+	ctx := c.Request.Context()
+
+	conf, err := preparePlanRun(ctx, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	flusher, ok := c.Writer.(http.Flusher)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Streaming not supported"})
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("Access-Control-Allow-Origin", "*")
+	c.Header("Access-Control-Allow-Headers", "Content-Type")
+	c.Writer.WriteHeader(http.StatusOK)
+
+	c.SSEvent("message", gin.H{"type": "start", "max_steps": conf.MaxSteps})
+	flusher.Flush()
+
+	result, err := plan.Run(ctx, conf, planMessages(&req), func(e plan.Event) {
+		c.SSEvent("message", e)
+		flusher.Flush()
+	})
+	if err != nil {
+		c.SSEvent("error", gin.H{"error": err.Error()})
+		flusher.Flush()
+		return
+	}
+
+	c.SSEvent("message", gin.H{
+		"type":     "end",
+		"provider": chat_model.ProviderOf(result.Answer),
+		"steps":    len(result.Plan),
+		"replans":  result.Replans,
+		"usage":    usage.FromContext(ctx).Summary(),
+	})
+	flusher.Flush()
+}
+
+// preparePlanRun 选择模型与工具，校验步骤数与轮数
+func preparePlanRun(ctx context.Context, req *PlanRunRequest) (plan.Config, error) {
+	var conf plan.Config
+	if toolbox.Tools == nil {
+		return conf, fmt.Errorf("工具注册表未初始化")
+	}
+
+	tcm, err := toolCallingModel(req.Model, req.GenerationParams)
+	if err != nil {
+		return conf, err
+	}
+	maxIterations, err := requestMaxIterations(req.MaxIterations)
+	if err != nil {
+		return conf, err
+	}
+	maxSteps, maxReplans := planLimits()
+	if req.MaxSteps < 0 || req.MaxSteps > maxSteps {
+		return conf, fmt.Errorf("max_steps 需在 1-%d 之间", maxSteps)
+	}
+	if req.MaxSteps > 0 {
+		maxSteps = req.MaxSteps
+	}
+
+	tools, err := toolbox.Tools.Get(req.Tools)
+	if err != nil {
+		return conf, err
+	}
+	// 计划的步骤没有暂停点，不能使用需要审批的工具
+	for _, t := range tools {
+		info, err := t.Info(ctx)
+		if err != nil {
+			return conf, err
+		}
+		if toolbox.Tools.RequiresApproval(info.Name) {
+			return conf, fmt.Errorf("工具 %s 需要审批，计划模式暂不支持", info.Name)
+		}
+	}
+
+	return plan.Config{
+		Model:         tcm,
+		Tools:         tools,
+		MaxSteps:      maxSteps,
+		MaxReplans:    maxReplans,
+		MaxIterations: maxIterations,
+		ModelOptions:  req.GenerationParams.Options(),
+	}, nil
+}
+
+// planLimits 读取配置的计划步骤数与重新规划次数，返回的重新规划次数小于 0 表示不重新规划
+func planLimits() (maxSteps, maxReplans int) {
+	maxSteps, maxReplans = plan.DefaultMaxSteps, plan.DefaultMaxReplans
+	if config.Cfg == nil {
+		return maxSteps, maxReplans
+	}
+	if n, err := strconv.Atoi(config.Cfg.AgentConf.PlanMaxSteps); err == nil && n > 0 {
+		maxSteps = n
+	}
+	if n, err := strconv.Atoi(config.Cfg.AgentConf.PlanMaxReplans); err == nil && n >= 0 {
+		maxReplans = n
+		if n == 0 {
+			maxReplans = -1
+		}
+	}
+	return maxSteps, maxReplans
+}
+
+func planMessages(req *PlanRunRequest) []*schema.Message {
+	return append(chatHistory(req.History), schema.UserMessage(req.Question))
+}
@@ -0,0 +1,338 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-agent/agent"
+	"go-agent/agent/toolbox"
+	"go-agent/model/chat_model"
+	"go-agent/model/usage"
+	"io"
+	"log"
+	"net/http"
+	"slices"
+	"sync"
+
+	"github.com/cloudwego/eino/schema"
+	"github.com/gin-gonic/gin"
+)
+
+var errNoRunStore = errors.New("运行存储未初始化")
+
+// AgentRunRecordResponse 运行记录，包含每一步的消息与轨迹
+type AgentRunRecordResponse struct {
+	Success bool             `json:"success"`
+	Run     *agent.RunRecord `json:"run"`
+}
+
+// agentExec 执行一段运行；onMessages 与 emit 由运行记录提供，用来保存每一步
+type agentExec func(ctx context.Context, onMessages func([]*schema.Message), emit func(agent.Event)) (*agent.Result, error)
+
+// GetAgentRun 查看运行记录
+func GetAgentRun(c *gin.Context) {
+	if agent.Runs == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": errNoRunStore.Error()})
+		return
+	}
+	rec, err := agent.Runs.Load(c.Param("id"))
+	if err != nil {
+		c.JSON(agentRunErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, AgentRunRecordResponse{Success: true, Run: rec})
+}
+
+// ApproveAgentRun 同意等待审批的工具调用并继续运行
+func ApproveAgentRun(c *gin.Context) {
+	decideAgentRun(c, true)
+}
+
+// RejectAgentRun 拒绝等待审批的工具调用，拒绝原因作为工具结果交给模型后继续运行
+func RejectAgentRun(c *gin.Context) {
+	decideAgentRun(c, false)
+}
+
+// decideAgentRun 从检查点恢复暂停的运行，直到给出回答、再次等待审批或失败
+func decideAgentRun(c *gin.Context, approved bool) {
+	var req AgentDecisionRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, AgentRunResponse{Success: false, Message: "请求格式错误: " + err.Error()})
+		return
+	}
+	if agent.Runs == nil {
+		c.JSON(http.StatusInternalServerError, AgentRunResponse{Success: false, Message: errNoRunStore.Error()})
+		return
+	}
+	ctx := c.Request.Context()
+
+	rec, err := agent.Runs.Load(c.Param("id"))
+	if err != nil {
+		c.JSON(agentRunErrorStatus(err), AgentRunResponse{Success: false, Message: err.Error()})
+		return
+	}
+	decisions := make(map[string]*agent.Decision)
+	for _, p := range rec.Pending {
+		if len(req.CallIDs) == 0 || slices.Contains(req.CallIDs, p.CallID) {
+			decisions[p.InterruptID] = &agent.Decision{Approved: approved, Reason: req.Reason}
+		}
+	}
+	if len(decisions) != len(req.CallIDs) && len(req.CallIDs) > 0 {
+		c.JSON(http.StatusBadRequest, AgentRunResponse{Success: false, Message: "call_ids 中包含未在等待审批的工具调用"})
+		return
+	}
+
+	rec, run, status, err := reopenAgentRun(ctx, rec.ID, agent.RunWaitingApproval)
+	if err != nil {
+		c.JSON(status, AgentRunResponse{Success: false, Message: err.Error()})
+		return
+	}
+	run.conf.NeedsApproval, run.conf.CheckPoints = toolbox.Tools.RequiresApproval, agent.Runs
+	run.conf.Iteration = rec.Iterations
+
+	messages := rec.Messages
+	err = executeAgentRun(ctx, rec, nil, func(ctx context.Context, onMessages func([]*schema.Message), emit func(agent.Event)) (*agent.Result, error) {
+		conf := run.conf
+		conf.OnMessages = onMessages
+		return agent.Resume(ctx, conf, messages, decisions, emit)
+	})
+	c.JSON(agentRunResponse(ctx, run.question, rec, err))
+}
+
+// ResumeAgentRun 从最后完成的一步继续中断或失败的运行，完成后一次性返回
+func ResumeAgentRun(c *gin.Context) {
+	ctx := c.Request.Context()
+	rec, run, status, err := reopenAgentRun(ctx, c.Param("id"), agent.RunInterrupted, agent.RunFailed)
+	if err != nil {
+		c.JSON(status, AgentRunResponse{Success: false, Message: err.Error()})
+		return
+	}
+	err = executeAgentRun(ctx, rec, nil, continueAgentRun(run, rec))
+	c.JSON(agentRunResponse(ctx, run.question, rec, err))
+}
+
+// ResumeAgentRunStream 从最后完成的一步继续中断或失败的运行，以 SSE 推送之后的每一步，事件同 /api/agent/run/stream
+func ResumeAgentRunStream(c *gin.Context) {
+	rec, run, status, err := reopenAgentRun(c.Request.Context(), c.Param("id"), agent.RunInterrupted, agent.RunFailed)
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	streamAgentRun(c, rec, continueAgentRun(run, rec))
+}
+
+// reopenAgentRun 按原始请求重建处于 from 状态之一的运行并把它标记为运行中，返回出错时应使用的状态码
+func reopenAgentRun(ctx context.Context, id string, from ...string) (*agent.RunRecord, *agentRun, int, error) {
+	if agent.Runs == nil {
+		return nil, nil, http.StatusInternalServerError, errNoRunStore
+	}
+	rec, err := agent.Runs.Load(id)
+	if err != nil {
+		return nil, nil, agentRunErrorStatus(err), err
+	}
+	if !slices.Contains(from, rec.Status) {
+		return nil, nil, http.StatusConflict, fmt.Errorf("%w: status %s", agent.ErrRunStatus, rec.Status)
+	}
+
+	var req AgentRunRequest
+	if err := json.Unmarshal(rec.Request, &req); err != nil {
+		return nil, nil, http.StatusInternalServerError, fmt.Errorf("运行记录损坏: %w", err)
+	}
+	run, err := prepareAgentRun(ctx, &req)
+	if err != nil {
+		return nil, nil, http.StatusBadRequest, err
+	}
+	run.conf.RunID = rec.ID
+
+	// 同一次暂停或中断只能被恢复一次
+	if rec, err = agent.Runs.Claim(id, from...); err != nil {
+		return nil, nil, agentRunErrorStatus(err), err
+	}
+	return rec, run, http.StatusOK, nil
+}
+
+func startAgentRun(run *agentRun) agentExec {
+	return func(ctx context.Context, onMessages func([]*schema.Message), emit func(agent.Event)) (*agent.Result, error) {
+		conf := run.conf
+		conf.OnMessages = onMessages
+		return agent.Run(ctx, conf, run.messages, emit)
+	}
+}
+
+// continueAgentRun 从运行记录保存的对话继续，轮数接着已完成的轮数计
+func continueAgentRun(run *agentRun, rec *agent.RunRecord) agentExec {
+	messages, iteration := rec.Messages, rec.CompletedIterations()
+	return func(ctx context.Context, onMessages func([]*schema.Message), emit func(agent.Event)) (*agent.Result, error) {
+		conf := run.conf
+		conf.OnMessages, conf.Iteration = onMessages, iteration
+		return agent.Continue(ctx, conf, messages, emit)
+	}
+}
+
+// newAgentRunRecord 为新运行创建运行记录，保存原始请求以便恢复时重建配置
+func newAgentRunRecord(req *AgentRunRequest, run *agentRun) (*agent.RunRecord, error) {
+	raw, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	rec := &agent.RunRecord{
+		ID:       run.conf.RunID,
+		Status:   agent.RunRunning,
+		Request:  raw,
+		Prompt:   run.prompt,
+		Messages: run.messages,
+		Input:    len(run.messages),
+	}
+	if err := saveAgentRun(rec); err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
+
+// executeAgentRun 执行一段运行，每一步都写入运行记录，结束后按结果更新状态
+func executeAgentRun(ctx context.Context, rec *agent.RunRecord, emit func(agent.Event), exec agentExec) error {
+	r := &runRecorder{rec: rec, emit: emit}
+	result, err := exec(ctx, r.messages, r.event)
+	if saveErr := r.finish(ctx, result, err); saveErr != nil && err == nil {
+		return saveErr
+	}
+	return err
+}
+
+// streamAgentRun 以 SSE 推送一段运行的每一步
+func streamAgentRun(c *gin.Context, rec *agent.RunRecord, exec agentExec) {
+	ctx := c.Request.Context()
+
+	flusher, ok := c.Writer.(http.Flusher)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Streaming not supported"})
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("Access-Control-Allow-Origin", "*")
+	c.Header("Access-Control-Allow-Headers", "Content-Type")
+	c.Writer.WriteHeader(http.StatusOK)
+
+	c.SSEvent("message", gin.H{"type": "start", "run_id": rec.ID, "prompt": rec.Prompt})
+	flusher.Flush()
+
+	// 轨迹回调在工具所在的 goroutine 中执行，tracer 已保证串行
+	err := executeAgentRun(ctx, rec, func(e agent.Event) {
+		c.SSEvent("message", e)
+		flusher.Flush()
+	}, exec)
+	if err != nil {
+		c.SSEvent("error", gin.H{"error": err.Error(), "run_id": rec.ID})
+		flusher.Flush()
+		return
+	}
+	if rec.Status == agent.RunWaitingApproval {
+		c.SSEvent("message", gin.H{
+			"type":    "paused",
+			"run_id":  rec.ID,
+			"pending": rec.Pending,
+			"usage":   usage.FromContext(ctx).Summary(),
+		})
+		flusher.Flush()
+		return
+	}
+
+	c.SSEvent("message", gin.H{
+		"type":       "end",
+		"run_id":     rec.ID,
+		"provider":   rec.Provider,
+		"iterations": rec.Iterations,
+		"usage":      usage.FromContext(ctx).Summary(),
+	})
+	flusher.Flush()
+}
+
+// agentRunResponse 按运行记录生成响应与状态码
+func agentRunResponse(ctx context.Context, question string, rec *agent.RunRecord, err error) (int, AgentRunResponse) {
+	resp := AgentRunResponse{
+		Success:    err == nil,
+		Prompt:     rec.Prompt,
+		Iterations: rec.Iterations,
+		Trace:      rec.Trace,
+		Usage:      usage.FromContext(ctx).Summary(),
+		RunID:      rec.ID,
+		Status:     rec.Status,
+	}
+	switch {
+	case err != nil:
+		resp.Message = "Agent 运行失败: " + err.Error()
+		return agentErrorStatus(err), resp
+	case rec.Status == agent.RunWaitingApproval:
+		resp.Message, resp.Pending = "工具调用等待审批", rec.Pending
+		return http.StatusAccepted, resp
+	}
+	resp.Question, resp.Answer, resp.Provider = question, rec.Answer, rec.Provider
+	return http.StatusOK, resp
+}
+
+// runRecorder 把运行的每一步写入运行记录：轨迹事件产生时只在内存中追加，
+// 对话每完成一步（模型输出或工具结果）时整体替换并连同轨迹落盘，避免每个事件都重写整个记录
+type runRecorder struct {
+	mu   sync.Mutex
+	rec  *agent.RunRecord
+	emit func(agent.Event)
+}
+
+func (r *runRecorder) event(e agent.Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rec.Trace = append(r.rec.Trace, e)
+	r.rec.Iterations = max(r.rec.Iterations, e.Iteration)
+	if r.emit != nil {
+		r.emit(e)
+	}
+}
+
+func (r *runRecorder) messages(msgs []*schema.Message) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rec.Messages = msgs
+	if err := saveAgentRun(r.rec); err != nil {
+		log.Printf("保存运行记录 %s 失败: %v", r.rec.ID, err)
+	}
+}
+
+// finish 记录本段运行的结果：请求被取消记为中断，仍有待审批调用时继续等待，否则记为完成或失败
+func (r *runRecorder) finish(ctx context.Context, result *agent.Result, runErr error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rec := r.rec
+	rec.Pending, rec.Error = nil, ""
+	if result != nil {
+		rec.Iterations = result.Iterations
+		rec.Pending = result.Pending
+	}
+	switch {
+	case runErr != nil && ctx.Err() != nil:
+		rec.Status, rec.Error = agent.RunInterrupted, runErr.Error()
+	case runErr != nil:
+		rec.Status, rec.Error = agent.RunFailed, runErr.Error()
+	case len(rec.Pending) > 0:
+		rec.Status = agent.RunWaitingApproval
+	default:
+		rec.Status = agent.RunCompleted
+		rec.Answer, rec.Provider = result.Answer.Content, chat_model.ProviderOf(result.Answer)
+	}
+	return saveAgentRun(rec)
+}
+
+// saveAgentRun 运行存储未初始化时运行只保存在内存中
+func saveAgentRun(rec *agent.RunRecord) error {
+	if agent.Runs == nil {
+		return nil
+	}
+	if err := agent.Runs.Save(rec); err != nil {
+		return fmt.Errorf("保存运行记录失败: %w", err)
+	}
+	return nil
+}
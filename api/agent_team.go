@@ -0,0 +1,171 @@
+package api
+
+import (
+	"errors"
+	"go-agent/agent"
+	"go-agent/agent/team"
+	"go-agent/model/chat_model"
+	"go-agent/model/usage"
+	"net/http"
+
+	"github.com/cloudwego/eino/schema"
+	"github.com/gin-gonic/gin"
+)
+
+var errTeamNotConfigured = errors.New("未配置多 Agent（AGENT_TEAM_FILE）")
+
+// TeamRunRequest 多 Agent 请求，各 Agent 的提示词、模型与工具由 AGENT_TEAM_FILE 配置
+type TeamRunRequest struct {
+	Question string            `json:"question" binding:"required"`
+	History  []ChatTestMessage `json:"history,omitempty"`
+}
+
+// TeamRunResponse 多 Agent 响应，轨迹中每条事件的 agent 字段标明来源
+type TeamRunResponse struct {
+	Success    bool          `json:"success"`
+	Message    string        `json:"message,omitempty"`
+	Question   string        `json:"question,omitempty"`
+	Answer     string        `json:"answer,omitempty"`
+	Provider   string        `json:"provider,omitempty"`
+	Iterations int           `json:"iterations,omitempty"` // 主管调用模型的轮数
+	Handoffs   int           `json:"handoffs,omitempty"`
+	Trace      []agent.Event `json:"trace,omitempty"`
+	Usage      *usage.Usage  `json:"usage,omitempty"`
+}
+
+type teamAgentInfo struct {
+	Name string `json:"name"`
+	team.Spec
+}
+
+type TeamResponse struct {
+	Success    bool            `json:"success"`
+	Supervisor team.Spec       `json:"supervisor"`
+	Agents     []teamAgentInfo `json:"agents"`
+}
+
+// GetTeam 查看多 Agent 配置
+func GetTeam(c *gin.Context) {
+	if team.Default == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": errTeamNotConfigured.Error()})
+		return
+	}
+	resp := TeamResponse{Success: true, Supervisor: team.Default.Supervisor}
+	for _, m := range team.Default.Members {
+		resp.Agents = append(resp.Agents, teamAgentInfo{Name: m.Name, Spec: m.Spec})
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// TeamRun 运行多 Agent，完成后一次性返回回答与完整交接轨迹
+func TeamRun(c *gin.Context) {
+	var req TeamRunRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, TeamRunResponse{Success: false, Message: "请求格式错误: " + err.Error()})
+		return
+	}
+	ctx := c.Request.Context()
+
+	t, status, err := buildTeam(c)
+	if err != nil {
+		c.JSON(status, TeamRunResponse{Success: false, Message: err.Error()})
+		return
+	}
+
+	result, err := t.Run(ctx, teamMessages(&req), nil)
+	if err != nil {
+		c.JSON(agentErrorStatus(err), TeamRunResponse{
+			Success:    false,
+			Message:    "多 Agent 运行失败: " + err.Error(),
+			Iterations: result.Iterations,
+			Handoffs:   result.Handoffs,
+			Trace:      result.Trace,
+			Usage:      usage.FromContext(ctx).Summary(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, TeamRunResponse{
+		Success:    true,
+		Question:   req.Question,
+		Answer:     result.Answer.Content,
+		Provider:   chat_model.ProviderOf(result.Answer),
+		Iterations: result.Iterations,
+		Handoffs:   result.Handoffs,
+		Trace:      result.Trace,
+		Usage:      usage.FromContext(ctx).Summary(),
+	})
+}
+
+// TeamRunStream 运行多 Agent，以 SSE 实时推送主管与成员的轨迹
+// 事件依次为 start、各 Agent 的 iteration/thought/tool_call/tool_result/answer 与主管的 handoff/handoff_result、end，失败时推送 error
+func TeamRunStream(c *gin.Context) {
+	var req TeamRunRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format: " + err.Error()})
+		return
+	}
+	ctx := c.Request.Context()
+
+	t, status, err := buildTeam(c)
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	flusher, ok := c.Writer.(http.Flusher)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Streaming not supported"})
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("Access-Control-Allow-Origin", "*")
+	c.Header("Access-Control-Allow-Headers", "Content-Type")
+	c.Writer.WriteHeader(http.StatusOK)
+
+	agents := make([]string, 0, len(t.Members))
+	for _, m := range t.Members {
+		agents = append(agents, m.Name)
+	}
+	c.SSEvent("message", gin.H{"type": "start", "agents": agents})
+	flusher.Flush()
+
+	// 成员可能被并行调用，团队的 tracer 已保证回调串行
+	result, err := t.Run(ctx, teamMessages(&req), func(e agent.Event) {
+		c.SSEvent("message", e)
+		flusher.Flush()
+	})
+	if err != nil {
+		c.SSEvent("error", gin.H{"error": err.Error()})
+		flusher.Flush()
+		return
+	}
+
+	c.SSEvent("message", gin.H{
+		"type":       "end",
+		"provider":   chat_model.ProviderOf(result.Answer),
+		"iterations": result.Iterations,
+		"handoffs":   result.Handoffs,
+		"usage":      usage.FromContext(ctx).Summary(),
+	})
+	flusher.Flush()
+}
+
+// buildTeam 按配置解析团队，返回出错时应使用的状态码
+func buildTeam(c *gin.Context) (*team.Team, int, error) {
+	if team.Default == nil {
+		return nil, http.StatusNotFound, errTeamNotConfigured
+	}
+	t, err := team.Build(c.Request.Context(), team.Default, agentMaxIterations())
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	return t, http.StatusOK, nil
+}
+
+func teamMessages(req *TeamRunRequest) []*schema.Message {
+	return append(chatHistory(req.History), schema.UserMessage(req.Question))
+}
@@ -0,0 +1,1229 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-agent/agent"
+	"go-agent/agent/plan"
+	"go-agent/agent/team"
+	"go-agent/agent/toolbox"
+	"go-agent/config"
+	"go-agent/model/chat_history"
+	"go-agent/model/chat_model"
+	"go-agent/model/embedding_model"
+	"go-agent/model/prompt_template"
+	"go-agent/model/token"
+	"go-agent/model/usage"
+	"go-agent/rag/memory"
+	"go-agent/rag/pack"
+	"go-agent/rag/tools"
+	"go-agent/rag/tools/db"
+	"go-agent/rag/tools/indexer"
+	"go-agent/rag/tools/retriever"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cloudwego/eino/schema"
+	"github.com/gin-gonic/gin"
+	mcpclient "github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/milvus-io/milvus-sdk-go/v2/client"
+	"github.com/milvus-io/milvus-sdk-go/v2/entity"
+)
+
+const testDocument = `go-agent 是一个基于 Eino 的示例项目。
+Milvus is the vector database used to store document chunks for retrieval.
+The splitter cuts documents into chunks of 1000 characters with 200 characters overlap.`
+
+// newOfflineServer 使用 fake 模型与进程内向量存储初始化全部组件，返回测试服务
+func newOfflineServer(t *testing.T, chat *chat_model.FakeChatModel, threshold string) *httptest.Server {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+
+	config.Cfg = &config.Config{
+		ChatModelType:      "fake",
+		EmbeddingModelType: "fake",
+		VectorDBType:       "memory",
+		FakeConf:           config.FakeConfig{EmbeddingDim: "128"},
+		MilvusConf: config.MilvusConfig{
+			SimilarityThreshold: threshold,
+			TopK:                "3",
+		},
+		AgentConf: config.AgentConfig{
+			Tools:    "search_knowledge_base,calculator,datetime,sql_query,run_starlark",
+			TableDir: t.TempDir(),
+		},
+	}
+
+	var err error
+	if chat_model.CM, err = chat_model.NewFallbackChatModel([]chat_model.Provider{{Name: "fake", Model: chat, ModelID: "echo"}}, chat_model.FallbackConfig{}); err != nil {
+		t.Fatalf("init chat model: %v", err)
+	}
+	if prompt_template.Store, err = prompt_template.NewStore(t.TempDir()); err != nil {
+		t.Fatalf("init prompt store: %v", err)
+	}
+	usage.Prices = usage.PriceTable{"fake/echo": {Prompt: 1, Completion: 2}}
+	if usage.Store, err = usage.NewStore(""); err != nil {
+		t.Fatalf("init usage store: %v", err)
+	}
+	if embedding_model.Embedding, err = embedding_model.NewEmbeddingModel(ctx); err != nil {
+		t.Fatalf("init embedding: %v", err)
+	}
+	db.Memory = db.NewMemoryStore()
+	if indexer.Indexer, err = indexer.NewIndexer(ctx); err != nil {
+		t.Fatalf("init indexer: %v", err)
+	}
+	if retriever.Retriever, err = retriever.NewRetriever(ctx); err != nil {
+		t.Fatalf("init retriever: %v", err)
+	}
+	if tools.Parser, err = tools.NewParser(ctx); err != nil {
+		t.Fatalf("init parser: %v", err)
+	}
+	if tools.Loader, err = tools.NewLoader(ctx); err != nil {
+		t.Fatalf("init loader: %v", err)
+	}
+	if tools.Splitter, err = tools.NewSplitter(ctx); err != nil {
+		t.Fatalf("init splitter: %v", err)
+	}
+	toolbox.Tables = nil
+	if toolbox.Tools, err = toolbox.NewRegistry(ctx); err != nil {
+		t.Fatalf("init agent tools: %v", err)
+	}
+	if agent.Runs, err = agent.NewRunStore(t.TempDir()); err != nil {
+		t.Fatalf("init agent runs: %v", err)
+	}
+
+	srv := httptest.NewServer(NewRouter())
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func uploadDocument(t *testing.T, srv *httptest.Server, name, content string) InsertDocumentResponse {
+	t.Helper()
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	part, err := w.CreateFormFile("file", name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write([]byte(content))
+	w.Close()
+
+	resp, err := http.Post(srv.URL+"/api/document/insert", w.FormDataContentType(), &body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var out InsertDocumentResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK || !out.Success {
+		t.Fatalf("insert document failed: status=%d resp=%+v", resp.StatusCode, out)
+	}
+	return out
+}
+
+func postJSON(t *testing.T, url string, req any) *http.Response {
+	t.Helper()
+	b, _ := json.Marshal(req)
+	resp, err := http.Post(url, "application/json", bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func TestInsertDocument(t *testing.T) {
+	srv := newOfflineServer(t, chat_model.NewFakeChatModel(), "0.1")
+
+	out := uploadDocument(t, srv, "guide.txt", testDocument)
+	if out.ChunkCount == 0 || out.ChunkCount != len(out.DocumentIDs) {
+		t.Fatalf("unexpected chunk count: %+v", out)
+	}
+	if got := db.Memory.Len(); got != out.ChunkCount {
+		t.Fatalf("memory store has %d chunks, want %d", got, out.ChunkCount)
+	}
+}
+
+func TestRAGAsk(t *testing.T) {
+	chat := chat_model.NewFakeChatModel(schema.AssistantMessage("Milvus 存储文档块。", nil))
+	srv := newOfflineServer(t, chat, "0.1")
+	uploadDocument(t, srv, "guide.txt", testDocument)
+
+	resp := postJSON(t, srv.URL+"/api/rag/ask", RAGAskRequest{Query: "which vector database stores document chunks"})
+	var out RAGAskResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+
+	if !out.Success || out.BelowThreshold || out.RetrievedDocs == 0 {
+		t.Fatalf("unexpected response: %+v", out)
+	}
+	if out.Answer != "Milvus 存储文档块。" {
+		t.Fatalf("answer = %q", out.Answer)
+	}
+
+	inputs := chat.Inputs()
+	if len(inputs) != 1 || !strings.Contains(inputs[0][0].Content, "Milvus is the vector database") {
+		t.Fatalf("retrieved document not in prompt: %+v", inputs)
+	}
+}
+
+func TestRAGAskPacksContext(t *testing.T) {
+	chat := chat_model.NewFakeChatModel()
+	srv := newOfflineServer(t, chat, "0.1")
+	var doc strings.Builder
+	for i := 1; i <= 60; i++ {
+		fmt.Fprintf(&doc, "Line %02d: the vector database stores chunks of documents.\n", i)
+	}
+	uploadDocument(t, srv, "long.txt", doc.String())
+	ask := func(budget string) RAGAskResponse {
+		t.Helper()
+		config.Cfg.RAGConf.ContextBudget = budget
+		resp := postJSON(t, srv.URL+"/api/rag/ask", RAGAskRequest{Query: "which vector database stores document chunks"})
+		var out RAGAskResponse
+		json.NewDecoder(resp.Body).Decode(&out)
+		if resp.StatusCode != http.StatusOK || !out.Success {
+			t.Fatalf("status = %d, response = %+v", resp.StatusCode, out)
+		}
+		return out
+	}
+
+	// 不限制预算：相邻的块合并为一段，切分重叠的内容只出现一次
+	out := ask("0")
+	if len(out.UsedChunks) != 3 || len(out.DroppedChunks) != 0 {
+		t.Fatalf("unexpected packing: used %v, dropped %v", out.UsedChunks, out.DroppedChunks)
+	}
+	inputs := chat.Inputs()
+	system := inputs[len(inputs)-1][0].Content
+	if strings.Count(system, "文档 ") != 1 {
+		t.Fatalf("adjacent chunks not merged:\n%s", system)
+	}
+	for i := 1; i <= 60; i++ {
+		if n := strings.Count(system, fmt.Sprintf("Line %02d:", i)); n > 1 {
+			t.Fatalf("line %d repeated %d times in merged context", i, n)
+		}
+	}
+
+	// 预算只够一个块：分数较低的块被丢弃并报告
+	out = ask("400")
+	if len(out.UsedChunks) != 1 || len(out.DroppedChunks) != 2 || out.DroppedChunks[0].Reason != pack.ReasonBudget || out.ContextTokens > 400 {
+		t.Fatalf("unexpected packing: used %v, dropped %+v, tokens %d", out.UsedChunks, out.DroppedChunks, out.ContextTokens)
+	}
+	if out.DroppedChunks[0].Score > out.MaxScore {
+		t.Fatalf("dropped a higher-score chunk: %+v", out.DroppedChunks)
+	}
+}
+
+func TestRAGAskRelevanceFiltering(t *testing.T) {
+	chat := chat_model.NewFakeChatModel()
+	srv := newOfflineServer(t, chat, "0.1")
+	var doc strings.Builder
+	for i := 1; i <= 20; i++ {
+		fmt.Fprintf(&doc, "Line %02d: the vector database stores chunks of documents.\n", i)
+	}
+	for i := 21; i <= 45; i++ {
+		fmt.Fprintf(&doc, "Line %02d: cooking pasta needs a large pot of boiling salted water.\n", i)
+	}
+	uploadDocument(t, srv, "mixed.txt", doc.String())
+	ask := func(req RAGAskRequest) RAGAskResponse {
+		t.Helper()
+		resp := postJSON(t, srv.URL+"/api/rag/ask", req)
+		var out RAGAskResponse
+		json.NewDecoder(resp.Body).Decode(&out)
+		if resp.StatusCode != http.StatusOK || !out.Success {
+			t.Fatalf("status = %d, response = %+v", resp.StatusCode, out)
+		}
+		return out
+	}
+	query := "which vector database stores document chunks"
+
+	// 低于最低相似度或与最高分差距过大的块不放入上下文
+	config.Cfg.RAGConf.MinScore, config.Cfg.RAGConf.RelativeCutoff = "0.02", "0.2"
+	out := ask(RAGAskRequest{Query: query})
+	if out.BelowThreshold || len(out.DroppedChunks) == 0 || out.DroppedChunks[0].Reason != pack.ReasonRelative || slices.Contains(out.UsedChunks, out.DroppedChunks[0].ID) {
+		t.Fatalf("low-score chunk not filtered: %+v", out)
+	}
+	if last := out.DroppedChunks[len(out.DroppedChunks)-1]; last.Reason != pack.ReasonLowScore || last.Score >= 0.02 {
+		t.Fatalf("chunk below min score not reported: %+v", out.DroppedChunks)
+	}
+
+	// 请求的 threshold 高于 RAG_MIN_SCORE 时同时作为逐块最低相似度
+	maxScore, noCutoff := out.MaxScore, 0.0
+	out = ask(RAGAskRequest{Query: query, Threshold: &maxScore, RelativeCutoff: &noCutoff})
+	if out.BelowThreshold || out.NoAnswerReason != "" || len(out.UsedChunks) == 0 {
+		t.Fatalf("threshold equal to the max score refused: %+v", out)
+	}
+	raised := false
+	for _, d := range out.DroppedChunks {
+		if d.Reason == pack.ReasonRelative || d.Reason == pack.ReasonLowScore && d.Score >= maxScore {
+			t.Fatalf("unexpected dropped chunk %+v", d)
+		}
+		raised = raised || d.Reason == pack.ReasonLowScore && d.Score >= 0.02
+	}
+	if !raised {
+		t.Fatalf("threshold not applied to chunks: %+v", out.DroppedChunks)
+	}
+
+	// 最高分达到本次阈值但所有块低于 RAG_MIN_SCORE：报告 filtered_out 而不是低于阈值
+	calls := len(chat.Inputs())
+	config.Cfg.RAGConf.MinScore = fmt.Sprint(maxScore + 0.01)
+	low := maxScore / 2
+	out = ask(RAGAskRequest{Query: query, Threshold: &low})
+	if out.BelowThreshold || out.NoAnswerReason != NoAnswerFilteredOut || out.Threshold != low || out.MaxScore != maxScore || out.NoAnswerPolicy != NoAnswerRefuse || len(chat.Inputs()) != calls {
+		t.Fatalf("unexpected filtered out response: %+v", out)
+	}
+	// 请求中的 min_score 覆盖配置
+	noMin := 0.0
+	out = ask(RAGAskRequest{Query: query, Threshold: &low, MinScore: &noMin})
+	if out.NoAnswerReason != "" || len(out.UsedChunks) == 0 {
+		t.Fatalf("min_score override ignored: %+v", out)
+	}
+	config.Cfg.RAGConf.MinScore = "0.02"
+
+	// 请求中的 threshold 覆盖配置，默认策略直接拒答
+	threshold := 0.99
+	calls = len(chat.Inputs())
+	out = ask(RAGAskRequest{Query: query, Threshold: &threshold})
+	if !out.BelowThreshold || out.NoAnswerReason != NoAnswerBelowThreshold || out.Threshold != 0.99 || out.NoAnswerPolicy != NoAnswerRefuse || !strings.HasPrefix(out.Answer, "抱歉") || len(chat.Inputs()) != calls {
+		t.Fatalf("unexpected refusal: %+v", out)
+	}
+
+	// clarify 策略：由模型提出澄清问题
+	config.Cfg.RAGConf.NoAnswerPolicy = NoAnswerClarify
+	out = ask(RAGAskRequest{Query: query, Threshold: &threshold})
+	inputs := chat.Inputs()
+	if out.NoAnswerPolicy != NoAnswerClarify || !strings.Contains(inputs[len(inputs)-1][0].Content, "clarifying question") {
+		t.Fatalf("unexpected clarify response: %+v", out)
+	}
+
+	// answer 策略：不带文档直接回答
+	config.Cfg.RAGConf.NoAnswerPolicy = NoAnswerGeneral
+	out = ask(RAGAskRequest{Query: query, Threshold: &threshold})
+	if out.NoAnswerPolicy != NoAnswerGeneral || out.Answer != "echo: "+query || out.Prompt != "default_chat@1" {
+		t.Fatalf("unexpected general answer: %+v", out)
+	}
+
+	// 无效的策略按 refuse 处理，不调用模型
+	config.Cfg.RAGConf.NoAnswerPolicy = "shrug"
+	calls = len(chat.Inputs())
+	out = ask(RAGAskRequest{Query: query, Threshold: &threshold})
+	if out.NoAnswerPolicy != NoAnswerRefuse || !strings.HasPrefix(out.Answer, "抱歉") || len(chat.Inputs()) != calls {
+		t.Fatalf("invalid policy did not fall back to refuse: %+v", out)
+	}
+}
+
+func TestRAGAskThresholdValidation(t *testing.T) {
+	chat := chat_model.NewFakeChatModel()
+	srv := newOfflineServer(t, chat, "0.1")
+	uploadDocument(t, srv, "guide.txt", testDocument)
+
+	cases := []struct {
+		name      string
+		threshold float64
+		want      int
+	}{
+		{"zero", 0, http.StatusOK},
+		{"one", 1, http.StatusOK},
+		{"negative", -0.1, http.StatusBadRequest},
+		{"above one", 1.5, http.StatusBadRequest},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			resp := postJSON(t, srv.URL+"/api/rag/ask", RAGAskRequest{Query: "what stores the chunks", Threshold: &tc.threshold})
+			var out RAGAskResponse
+			json.NewDecoder(resp.Body).Decode(&out)
+			if resp.StatusCode != tc.want || out.Success != (tc.want == http.StatusOK) {
+				t.Fatalf("status = %d, response = %+v", resp.StatusCode, out)
+			}
+		})
+	}
+	if len(chat.Inputs()) != 1 {
+		t.Fatalf("chat model called %d times, want only for threshold 0", len(chat.Inputs()))
+	}
+
+	badMin, badCutoff := 1.5, -0.1
+	for _, req := range []RAGAskRequest{
+		{Query: "what stores the chunks", MinScore: &badMin},
+		{Query: "what stores the chunks", RelativeCutoff: &badCutoff},
+	} {
+		if resp := postJSON(t, srv.URL+"/api/rag/ask", req); resp.StatusCode != http.StatusBadRequest {
+			t.Fatalf("min_score %v relative_cutoff %v: status = %d", req.MinScore, req.RelativeCutoff, resp.StatusCode)
+		}
+	}
+
+	metrics := []struct {
+		metric    entity.MetricType
+		threshold float64
+		valid     bool
+	}{
+		{entity.COSINE, 0.5, true},
+		{entity.COSINE, -0.5, false},
+		{entity.COSINE, 1.01, false},
+		{entity.L2, 0.8, true},
+		{entity.L2, -1, false},
+		{entity.IP, 42, true},
+		{entity.IP, -3, true},
+	}
+	for _, m := range metrics {
+		if err := validateThreshold(m.threshold, m.metric); (err == nil) != m.valid {
+			t.Fatalf("validateThreshold(%g, %s) = %v, want valid %v", m.threshold, m.metric, err, m.valid)
+		}
+	}
+}
+
+// indexMilvus 只实现 DescribeIndex 的 Milvus 客户端，集合索引使用给定的度量类型，为空时表示未建索引
+type indexMilvus struct {
+	client.Client
+	metric entity.MetricType
+}
+
+func (m indexMilvus) DescribeIndex(ctx context.Context, collName, fieldName string, opts ...client.IndexOption) ([]entity.Index, error) {
+	if m.metric == "" {
+		return nil, nil
+	}
+	return []entity.Index{entity.NewGenericIndex(fieldName, entity.HNSW, map[string]string{"metric_type": string(m.metric)})}, nil
+}
+
+func TestRAGThresholdUsesCollectionMetric(t *testing.T) {
+	prevCfg, prevMilvus := config.Cfg, db.Milvus
+	t.Cleanup(func() { config.Cfg, db.Milvus = prevCfg, prevMilvus })
+	ctx := context.Background()
+
+	cases := []struct {
+		name       string
+		configured string
+		collection entity.MetricType
+		threshold  float64
+		valid      bool
+	}{
+		// 集合实际的度量类型与配置不同时以集合为准
+		{"configured IP, collection COSINE", "IP", entity.COSINE, 1.5, false},
+		{"configured COSINE, collection IP", "COSINE", entity.IP, 5, true},
+		// 集合未建索引时取配置
+		{"no index uses config", "IP", "", 5, true},
+		{"no index uses config range", "COSINE", "", 5, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			config.Cfg = &config.Config{
+				VectorDBType: "milvus",
+				MilvusConf:   config.MilvusConfig{CollectionName: "docs", IndexType: "HNSW", MetricType: tc.configured},
+			}
+			db.Milvus = indexMilvus{metric: tc.collection}
+
+			_, err := ragRelevanceFor(ctx, &tc.threshold, nil, nil)
+			if (err == nil) != tc.valid || err != nil && !errors.Is(err, errInvalidRelevance) {
+				t.Fatalf("threshold %g: err = %v, want valid %v", tc.threshold, err, tc.valid)
+			}
+		})
+	}
+}
+
+func TestRAGAskBelowThreshold(t *testing.T) {
+	chat := chat_model.NewFakeChatModel()
+	srv := newOfflineServer(t, chat, "0.99")
+	uploadDocument(t, srv, "guide.txt", testDocument)
+
+	resp := postJSON(t, srv.URL+"/api/rag/ask", RAGAskRequest{Query: "weather forecast tomorrow"})
+	var out RAGAskResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+
+	if !out.Success || !out.BelowThreshold {
+		t.Fatalf("expected below threshold response: %+v", out)
+	}
+	if len(chat.Inputs()) != 0 {
+		t.Fatal("chat model should not be called below threshold")
+	}
+}
+
+func TestChatGenerate(t *testing.T) {
+	srv := newOfflineServer(t, chat_model.NewFakeChatModel(), "0.1")
+
+	resp := postJSON(t, srv.URL+"/api/chat/test", ChatTestRequest{Question: "hello"})
+	var out ChatTestResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Answer != "echo: hello" {
+		t.Fatalf("answer = %q", out.Answer)
+	}
+}
+
+func TestChatStream(t *testing.T) {
+	srv := newOfflineServer(t, chat_model.NewFakeChatModel(), "0.1")
+
+	resp := postJSON(t, srv.URL+"/api/chat/test/stream", ChatTestRequest{
+		Question: "stream this answer please",
+		History: []ChatTestMessage{
+			{Role: "user", Content: "hi"},
+			{Role: "assistant", Content: "hello"},
+		},
+	})
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/event-stream") {
+		t.Fatalf("content type = %q", ct)
+	}
+
+	var types []string
+	var answer strings.Builder
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+		var event struct {
+			Type    string `json:"type"`
+			Content string `json:"content"`
+		}
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			t.Fatalf("invalid event %q: %v", data, err)
+		}
+		types = append(types, event.Type)
+		answer.WriteString(event.Content)
+	}
+
+	if answer.String() != "echo: stream this answer please" {
+		t.Fatalf("answer = %q", answer.String())
+	}
+	if len(types) < 3 || types[0] != "start" || types[len(types)-1] != "end" {
+		t.Fatalf("unexpected event sequence: %v", types)
+	}
+}
+
+func TestListModelsAndUnknownModel(t *testing.T) {
+	srv := newOfflineServer(t, chat_model.NewFakeChatModel(), "0.1")
+
+	resp, err := http.Get(srv.URL + "/api/models")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var models ModelsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&models); err != nil {
+		t.Fatal(err)
+	}
+	if len(models.Embedding) != 1 || models.Embedding[0].Name != "fake" {
+		t.Fatalf("unexpected embedding models: %+v", models.Embedding)
+	}
+
+	resp = postJSON(t, srv.URL+"/api/chat/test", ChatTestRequest{Question: "hi", Model: "no-such-model"})
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400", resp.StatusCode)
+	}
+}
+
+func TestChatGenerationParams(t *testing.T) {
+	chat := chat_model.NewFakeChatModel()
+	srv := newOfflineServer(t, chat, "0.1")
+
+	temperature, maxTokens := float32(0.2), 64
+	req := ChatTestRequest{Question: "hi"}
+	req.Temperature, req.MaxTokens, req.Stop = &temperature, &maxTokens, []string{"END"}
+	resp := postJSON(t, srv.URL+"/api/chat/test", req)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d", resp.StatusCode)
+	}
+	opts := chat.LastOptions()
+	if opts.Temperature == nil || *opts.Temperature != 0.2 || *opts.MaxTokens != 64 || opts.Stop[0] != "END" {
+		t.Fatalf("generation params not passed through: %+v", opts)
+	}
+
+	resp = postJSON(t, srv.URL+"/api/chat/test", map[string]any{"question": "hi", "top_p": 1.5})
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400", resp.StatusCode)
+	}
+}
+
+func TestChatSummarizesLongHistory(t *testing.T) {
+	// 超出预算的历史分段总结，每段一次模型调用
+	var script []*schema.Message
+	for i := 1; i <= 6; i++ {
+		script = append(script, schema.AssistantMessage(fmt.Sprintf("摘要%d：用户叫小王", i), nil))
+	}
+	script = append(script, schema.AssistantMessage("回答一", nil))
+	for i := 7; i <= 8; i++ {
+		script = append(script, schema.AssistantMessage(fmt.Sprintf("摘要%d：用户叫小王，在学 Go", i), nil))
+	}
+	script = append(script, schema.AssistantMessage("回答二", nil), schema.AssistantMessage("回答三", nil))
+	chat := chat_model.NewFakeChatModel(script...)
+	srv := newOfflineServer(t, chat, "0.1")
+	var err error
+	chat_history.Default, err = chat_history.NewCompactor(config.ChatContextConfig{Budget: "300", SummaryTokens: "20"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { chat_history.Default = nil })
+
+	var history []ChatTestMessage
+	for i := 0; i < 8; i++ {
+		role := "user"
+		if i%2 == 1 {
+			role = "assistant"
+		}
+		history = append(history, ChatTestMessage{Role: role, Content: fmt.Sprintf("turn %d %s", i, strings.Repeat("x ", 200))})
+	}
+	ask := func(history []ChatTestMessage) ChatTestResponse {
+		t.Helper()
+		b, _ := json.Marshal(ChatTestRequest{Question: "我叫什么？", History: history})
+		req, _ := http.NewRequest(http.MethodPost, srv.URL+"/api/chat/test", bytes.NewReader(b))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Session-ID", "long")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var out ChatTestResponse
+		json.NewDecoder(resp.Body).Decode(&out)
+		if resp.StatusCode != http.StatusOK || out.Context == nil {
+			t.Fatalf("status = %d, response = %+v", resp.StatusCode, out)
+		}
+		return out
+	}
+
+	out := ask(history)
+	if out.Answer != "回答一" || out.Context.Summarized != 6 || out.Context.Cached || out.Context.Tokens > out.Context.Budget {
+		t.Fatalf("unexpected context: answer=%q %+v", out.Answer, out.Context)
+	}
+	inputs := chat.Inputs()
+	sent := inputs[len(inputs)-1]
+	if len(sent) != 4 || !strings.Contains(sent[0].Content, "摘要6") || !strings.HasPrefix(sent[1].Content, "turn 6") {
+		t.Fatalf("history not replaced by summary: %d messages, system %q", len(sent), sent[0].Content)
+	}
+	// 每段的输入不超出预算，并带着上一段的摘要
+	counter := token.ForProvider("fake")
+	for i, in := range inputs[:6] {
+		if n := counter.CountMessages(in); n > 300 {
+			t.Fatalf("summary chunk %d uses %d tokens", i, n)
+		}
+		if i > 0 && !strings.Contains(in[1].Content, fmt.Sprintf("摘要%d", i)) {
+			t.Fatalf("summary chunk %d does not fold the previous summary: %q", i, in[1].Content)
+		}
+	}
+
+	// 继续对话：只把新移出预算的轮次并入缓存的摘要
+	history = append(history, ChatTestMessage{Role: "user", Content: "turn 8 " + strings.Repeat("y ", 200)}, ChatTestMessage{Role: "assistant", Content: "回答一"})
+	out = ask(history)
+	inputs = chat.Inputs()
+	summaryInput := inputs[len(inputs)-3][1].Content
+	if out.Context.Summarized != 8 || !strings.Contains(summaryInput, "摘要6") || strings.Contains(summaryInput, "turn 0") {
+		t.Fatalf("summary not extended incrementally: %+v, input %q", out.Context, summaryInput)
+	}
+
+	// 同样的历史直接复用缓存，不再调用模型生成摘要
+	calls := len(chat.Inputs())
+	out = ask(history)
+	if !out.Context.Cached || len(chat.Inputs()) != calls+1 || out.Answer != "回答三" {
+		t.Fatalf("cached summary not reused: %+v, calls %d -> %d", out.Context, calls, len(chat.Inputs()))
+	}
+}
+
+func TestUsageAccounting(t *testing.T) {
+	srv := newOfflineServer(t, chat_model.NewFakeChatModel(), "0.1")
+
+	upload := uploadDocument(t, srv, "guide.txt", testDocument)
+	if upload.Usage == nil || upload.Usage.EmbeddingTokens == 0 {
+		t.Fatalf("insert should report embedding usage: %+v", upload.Usage)
+	}
+
+	b, _ := json.Marshal(ChatTestRequest{Question: "hello there"})
+	req, _ := http.NewRequest(http.MethodPost, srv.URL+"/api/chat/test", bytes.NewReader(b))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Session-ID", "s1")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var chat ChatTestResponse
+	json.NewDecoder(resp.Body).Decode(&chat)
+	if chat.Provider != "fake" || chat.Usage == nil || chat.Usage.CompletionTokens == 0 || chat.Usage.Cost <= 0 || !chat.Usage.Estimated {
+		t.Fatalf("unexpected chat usage: provider=%q usage=%+v", chat.Provider, chat.Usage)
+	}
+
+	resp, err = http.Get(srv.URL + "/api/usage?group_by=session,kind")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var out UsageResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+
+	var sessionChat *usage.Group
+	for _, g := range out.Report.Groups {
+		if g.Key["session"] == "s1" && g.Key["kind"] == usage.KindChat {
+			sessionChat = g
+		}
+	}
+	if sessionChat == nil || sessionChat.Calls != 1 || sessionChat.Cost != chat.Usage.Cost {
+		t.Fatalf("unexpected usage groups: %+v", out.Report.Groups)
+	}
+	if out.Report.Total.EmbeddingTokens != upload.Usage.EmbeddingTokens {
+		t.Fatalf("total embedding tokens = %d, want %d", out.Report.Total.EmbeddingTokens, upload.Usage.EmbeddingTokens)
+	}
+
+	bad, err := http.Get(srv.URL + "/api/usage?group_by=colour")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bad.Body.Close()
+	if bad.StatusCode != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400", bad.StatusCode)
+	}
+}
+
+func TestPromptTemplates(t *testing.T) {
+	chat := chat_model.NewFakeChatModel()
+	srv := newOfflineServer(t, chat, "0.1")
+
+	resp := postJSON(t, srv.URL+"/api/prompts", PromptRequest{Name: "pirate", System: "Speak like a pirate about {query}."})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("create status = %d", resp.StatusCode)
+	}
+	if resp = postJSON(t, srv.URL+"/api/prompts", PromptRequest{Name: "pirate", System: "again"}); resp.StatusCode != http.StatusConflict {
+		t.Fatalf("duplicate create status = %d, want 409", resp.StatusCode)
+	}
+
+	resp = postJSON(t, srv.URL+"/api/chat/test", ChatTestRequest{Question: "ships", PromptID: "pirate"})
+	var out ChatTestResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+	inputs := chat.Inputs()
+	if out.Prompt != "pirate@1" || inputs[len(inputs)-1][0].Content != "Speak like a pirate about ships." {
+		t.Fatalf("prompt = %q, system = %q", out.Prompt, inputs[len(inputs)-1][0].Content)
+	}
+
+	// system_prompt 按原文覆盖，花括号不会被当作变量
+	resp = postJSON(t, srv.URL+"/api/chat/test", ChatTestRequest{Question: "hi", SystemPrompt: "Reply in JSON like {\"a\":1}."})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("system_prompt status = %d", resp.StatusCode)
+	}
+	inputs = chat.Inputs()
+	if got := inputs[len(inputs)-1][0].Content; got != `Reply in JSON like {"a":1}.` {
+		t.Fatalf("system = %q", got)
+	}
+
+	if resp = postJSON(t, srv.URL+"/api/chat/test", ChatTestRequest{Question: "hi", PromptID: "pirate@9"}); resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("unknown version status = %d, want 400", resp.StatusCode)
+	}
+
+	// RAG 默认模板会把检索到的文档放进系统提示词
+	uploadDocument(t, srv, "guide.txt", testDocument)
+	resp = postJSON(t, srv.URL+"/api/rag/ask", RAGAskRequest{Query: "which vector database stores document chunks"})
+	var rag RAGAskResponse
+	if err := json.NewDecoder(resp.Body).Decode(&rag); err != nil {
+		t.Fatal(err)
+	}
+	inputs = chat.Inputs()
+	if rag.Prompt != "default_rag@1" || !strings.Contains(inputs[len(inputs)-1][0].Content, "Milvus is the vector database") {
+		t.Fatalf("prompt = %q, system = %q", rag.Prompt, inputs[len(inputs)-1][0].Content)
+	}
+}
+
+func TestAgentRunSearchesKnowledgeBase(t *testing.T) {
+	chat := chat_model.NewFakeChatModel(
+		schema.AssistantMessage("let me search", []schema.ToolCall{{
+			ID:       "call-1",
+			Function: schema.FunctionCall{Name: toolbox.ToolSearchKnowledgeBase, Arguments: `{"query":"vector database document chunks","top_k":2}`},
+		}}),
+		schema.AssistantMessage("Milvus stores the chunks.", nil),
+	)
+	srv := newOfflineServer(t, chat, "0.1")
+	uploadDocument(t, srv, "guide.txt", testDocument)
+
+	resp := postJSON(t, srv.URL+"/api/agent/run", AgentRunRequest{Question: "where are chunks stored?"})
+	var out AgentRunResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK || out.Answer != "Milvus stores the chunks." || out.Iterations != 2 {
+		t.Fatalf("status = %d, resp = %+v", resp.StatusCode, out)
+	}
+
+	var result *agent.Event
+	for i := range out.Trace {
+		if out.Trace[i].Type == agent.EventToolResult {
+			result = &out.Trace[i]
+		}
+	}
+	if result == nil || !strings.Contains(result.Result, "Milvus is the vector database") {
+		t.Fatalf("tool result = %+v", result)
+	}
+
+	resp = postJSON(t, srv.URL+"/api/agent/run", AgentRunRequest{Question: "hi", Tools: []string{"no_such_tool"}})
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("unknown tool status = %d, want 400", resp.StatusCode)
+	}
+}
+
+func TestAgentQueriesUploadedCSV(t *testing.T) {
+	chat := chat_model.NewFakeChatModel(
+		schema.AssistantMessage("", []schema.ToolCall{{
+			ID:       "call-1",
+			Function: schema.FunctionCall{Name: toolbox.ToolSQLQuery, Arguments: `{"query":"SELECT SUM(amount) FROM sales"}`},
+		}}),
+		schema.AssistantMessage("Total is 15.", nil),
+	)
+	srv := newOfflineServer(t, chat, "0.1")
+
+	out := uploadDocument(t, srv, "sales.csv", "region,amount\nnorth,10\nsouth,5\n")
+	if out.Table != "sales" {
+		t.Fatalf("table = %q", out.Table)
+	}
+
+	resp := postJSON(t, srv.URL+"/api/agent/run", AgentRunRequest{Question: "total sales?", Tools: []string{toolbox.ToolSQLQuery}})
+	var run AgentRunResponse
+	if err := json.NewDecoder(resp.Body).Decode(&run); err != nil {
+		t.Fatal(err)
+	}
+	if !run.Success || len(run.Trace) < 3 || !strings.Contains(run.Trace[2].Result, "15") {
+		t.Fatalf("resp = %+v", run)
+	}
+}
+
+func TestAgentToolApproval(t *testing.T) {
+	chat := chat_model.NewFakeChatModel(
+		schema.AssistantMessage("", []schema.ToolCall{{
+			ID:       "call-1",
+			Function: schema.FunctionCall{Name: toolbox.ToolCalculator, Arguments: `{"expression":"6*7"}`},
+		}}),
+		schema.AssistantMessage("It is 42.", nil),
+	)
+	srv := newOfflineServer(t, chat, "0.1")
+	if err := toolbox.Tools.SetApproval(toolbox.ToolCalculator, toolbox.ApprovalAlways); err != nil {
+		t.Fatal(err)
+	}
+
+	// 流式运行在工具执行前暂停，推送待审批的调用
+	resp := postJSON(t, srv.URL+"/api/agent/run/stream", AgentRunRequest{Question: "6*7?", Tools: []string{toolbox.ToolCalculator}})
+	var types []string
+	var runID string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+		var event struct {
+			Type  string `json:"type"`
+			RunID string `json:"run_id"`
+		}
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			t.Fatalf("invalid event %q: %v", data, err)
+		}
+		types = append(types, event.Type)
+		if event.RunID != "" {
+			runID = event.RunID
+		}
+	}
+	if got := strings.Join(types, ","); got != "start,iteration,tool_call,approval_required,paused" || runID == "" {
+		t.Fatalf("events = %s, run id = %q", got, runID)
+	}
+
+	resp = postJSON(t, srv.URL+"/api/agent/runs/"+runID+"/approve", AgentDecisionRequest{})
+	var out AgentRunResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK || out.Answer != "It is 42." || out.Status != agent.RunCompleted || out.Iterations != 2 {
+		t.Fatalf("status = %d, resp = %+v", resp.StatusCode, out)
+	}
+	if got := eventTypesOf(out.Trace); got != "iteration,tool_call,approval_required,tool_result,iteration,answer" {
+		t.Fatalf("trace = %s", got)
+	}
+
+	if resp = postJSON(t, srv.URL+"/api/agent/runs/"+runID+"/reject", AgentDecisionRequest{}); resp.StatusCode != http.StatusConflict {
+		t.Fatalf("decide finished run status = %d, want 409", resp.StatusCode)
+	}
+	if resp = postJSON(t, srv.URL+"/api/agent/runs/missing/approve", AgentDecisionRequest{}); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("unknown run status = %d, want 404", resp.StatusCode)
+	}
+}
+
+func TestAgentRunResume(t *testing.T) {
+	chat := chat_model.NewFakeChatModel(
+		schema.AssistantMessage("It is 42.", nil),
+		schema.AssistantMessage("Hello.", nil),
+	)
+	srv := newOfflineServer(t, chat, "0.1")
+
+	// 模拟进程重启前中断的运行：模型已要求调用计算器，工具结果还没有写入
+	raw, err := json.Marshal(AgentRunRequest{Question: "6*7?", Tools: []string{toolbox.ToolCalculator}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := &agent.RunRecord{
+		ID:      agent.NewRunID(),
+		Status:  agent.RunInterrupted,
+		Request: raw,
+		Messages: []*schema.Message{
+			schema.UserMessage("6*7?"),
+			schema.AssistantMessage("", []schema.ToolCall{{
+				ID:       "call-1",
+				Function: schema.FunctionCall{Name: toolbox.ToolCalculator, Arguments: `{"expression":"6*7"}`},
+			}}),
+		},
+		Input:      1,
+		Iterations: 1,
+	}
+	if err := agent.Runs.Save(rec); err != nil {
+		t.Fatal(err)
+	}
+
+	resp := postJSON(t, srv.URL+"/api/agent/runs/"+rec.ID+"/resume", nil)
+	var out AgentRunResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK || out.Answer != "It is 42." || out.Iterations != 2 || out.Status != agent.RunCompleted {
+		t.Fatalf("status = %d, resp = %+v", resp.StatusCode, out)
+	}
+	if got := eventTypesOf(out.Trace); got != "tool_result,iteration,answer" {
+		t.Fatalf("trace = %s", got)
+	}
+	if resp = postJSON(t, srv.URL+"/api/agent/runs/"+rec.ID+"/resume", nil); resp.StatusCode != http.StatusConflict {
+		t.Fatalf("resume finished run status = %d, want 409", resp.StatusCode)
+	}
+
+	// 每次运行都有运行记录，保存完整对话与轨迹
+	resp = postJSON(t, srv.URL+"/api/agent/run", AgentRunRequest{Question: "hi", Tools: []string{toolbox.ToolCalculator}})
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+	if out.RunID == "" || out.Status != agent.RunCompleted {
+		t.Fatalf("resp = %+v", out)
+	}
+	resp, err = http.Get(srv.URL + "/api/agent/runs/" + out.RunID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var got AgentRunRecordResponse
+	if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	run := got.Run
+	if run.Status != agent.RunCompleted || run.Answer != "Hello." || len(run.Messages) != run.Input+1 || eventTypesOf(run.Trace) != "iteration,answer" {
+		t.Fatalf("run = %+v", run)
+	}
+
+	if resp, err = http.Get(srv.URL + "/api/agent/runs/missing"); err != nil || resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("unknown run status = %v, err = %v", resp.StatusCode, err)
+	}
+}
+
+func TestPlanRunStream(t *testing.T) {
+	chat := chat_model.NewFakeChatModel(
+		schema.AssistantMessage(`{"steps":[{"task":"compute 6*7","tools":["calculator"]}]}`, nil),
+		schema.AssistantMessage("", []schema.ToolCall{{
+			ID:       "call-1",
+			Function: schema.FunctionCall{Name: toolbox.ToolCalculator, Arguments: `{"expression":"6*7"}`},
+		}}),
+		schema.AssistantMessage("42", nil),
+		schema.AssistantMessage("The answer is 42.", nil),
+	)
+	srv := newOfflineServer(t, chat, "0.1")
+
+	resp := postJSON(t, srv.URL+"/api/agent/plan/stream", PlanRunRequest{Question: "6*7?", Tools: []string{toolbox.ToolCalculator}})
+	var types []string
+	var answer string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+		var event plan.Event
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			t.Fatalf("invalid event %q: %v", data, err)
+		}
+		types = append(types, event.Type)
+		if event.Type == agent.EventAnswer {
+			answer = event.Content
+		}
+	}
+	want := "start,plan,step_start,iteration,tool_call,tool_result,iteration,step_result,answer,end"
+	if got := strings.Join(types, ","); got != want || answer != "The answer is 42." {
+		t.Fatalf("events = %s, answer = %q", got, answer)
+	}
+
+	if resp = postJSON(t, srv.URL+"/api/agent/plan", PlanRunRequest{Question: "6*7?", MaxSteps: 100}); resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("max_steps over limit status = %d, want 400", resp.StatusCode)
+	}
+}
+
+func TestMemories(t *testing.T) {
+	chat := chat_model.NewFakeChatModel(
+		schema.AssistantMessage(`{"memories":[`+
+			`{"content":"The user is building a Go service called billing-api","category":"project"},`+
+			`{"content":"The user prefers concise answers","category":"preference"}]}`, nil),
+		schema.AssistantMessage(`{"memories":[{"content":"The user prefers concise answers"}]}`, nil),
+	)
+	srv := newOfflineServer(t, chat, "0.1")
+
+	resp, err := http.Get(srv.URL + "/api/memories?user_id=u1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("disabled status = %d, want 404", resp.StatusCode)
+	}
+
+	config.Cfg.MemoryConf = config.MemoryConfig{TopK: "3", MinScore: "0.1", DedupScore: "0.9"}
+	if memory.Memories, err = memory.NewStore(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { memory.Memories = nil })
+
+	history := []ChatTestMessage{
+		{Role: "user", Content: "I'm building billing-api in Go, keep answers short."},
+		{Role: "assistant", Content: "Got it."},
+	}
+	var out MemoriesResponse
+	resp = postJSON(t, srv.URL+"/api/memories/extract", ExtractMemoriesRequest{UserID: "u1", History: history})
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK || len(out.Memories) != 2 || out.Memories[0].Category != "project" {
+		t.Fatalf("status = %d, resp = %+v", resp.StatusCode, out)
+	}
+	// 已有的记忆不会重复写入
+	resp = postJSON(t, srv.URL+"/api/memories/extract", ExtractMemoriesRequest{UserID: "u1", History: history})
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+	if len(out.Memories) != 0 {
+		t.Fatalf("duplicate memories added: %+v", out.Memories)
+	}
+
+	list := func(userID string) []memory.Memory {
+		resp, err := http.Get(srv.URL + "/api/memories?user_id=" + userID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var out MemoriesResponse
+		if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+			t.Fatal(err)
+		}
+		return out.Memories
+	}
+	memories := list("u1")
+	if len(memories) != 2 || len(list("u2")) != 0 {
+		t.Fatalf("memories = %+v", memories)
+	}
+
+	// 之后的对话把相关记忆加入系统提示词
+	postJSON(t, srv.URL+"/api/chat/test", ChatTestRequest{Question: "How should I structure the billing-api Go service?", UserID: "u1"})
+	inputs := chat.Inputs()
+	if sys := inputs[len(inputs)-1][0]; sys.Role != schema.System || !strings.Contains(sys.Content, "billing-api") {
+		t.Fatalf("system prompt = %+v", sys)
+	}
+
+	deleteMemory := func(id, userID string) int {
+		req, _ := http.NewRequest(http.MethodDelete, srv.URL+"/api/memories/"+id+"?user_id="+userID, nil)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	if status := deleteMemory(memories[0].ID, "u2"); status != http.StatusNotFound {
+		t.Fatalf("delete other user's memory status = %d, want 404", status)
+	}
+	if status := deleteMemory(memories[0].ID, "u1"); status != http.StatusOK {
+		t.Fatalf("delete status = %d", status)
+	}
+	if memories = list("u1"); len(memories) != 1 {
+		t.Fatalf("memories after delete = %+v", memories)
+	}
+}
+
+func TestMemoryExtractionInBackground(t *testing.T) {
+	chat := chat_model.NewFakeChatModel(
+		schema.AssistantMessage("Deploy it with a rolling update.", nil),
+		// 同一批中重复的事实只写入一条
+		schema.AssistantMessage(`{"memories":[`+
+			`{"content":"The user deploys billing-api on Kubernetes","category":"project"},`+
+			`{"content":"the user deploys  billing-api on Kubernetes."},`+
+			`{"content":"The user deploys billing-api on Kubernetes!"}]}`, nil),
+	)
+	srv := newOfflineServer(t, chat, "0.1")
+	config.Cfg.MemoryConf = config.MemoryConfig{TopK: "3", MinScore: "0.1", DedupScore: "0.9"}
+	var err error
+	if memory.Memories, err = memory.NewStore(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { memory.Memories = nil })
+
+	req, _ := json.Marshal(ChatTestRequest{Question: "How do I deploy billing-api to Kubernetes?", UserID: "u1", EndConversation: true})
+	httpReq, _ := http.NewRequest(http.MethodPost, srv.URL+"/api/chat/test", bytes.NewReader(req))
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("X-Session-ID", "s1")
+	resp, err := http.DefaultClient.Do(httpReq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	// 提取在后台进行，等待用量写入
+	var extraction *usage.Group
+	for deadline := time.Now().Add(5 * time.Second); extraction == nil && time.Now().Before(deadline); time.Sleep(20 * time.Millisecond) {
+		report, err := usage.Store.Query(usage.Query{GroupBy: []string{"session", "endpoint", "kind"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, g := range report.Groups {
+			if g.Key["endpoint"] == "memory:/api/chat/test" && g.Key["kind"] == usage.KindChat {
+				extraction = g
+			}
+		}
+	}
+	if extraction == nil || extraction.Key["session"] != "s1" || extraction.Calls != 1 {
+		t.Fatalf("extraction usage = %+v", extraction)
+	}
+
+	memories, err := memory.Memories.List(context.Background(), "u1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(memories) != 1 || memories[0].Content != "The user deploys billing-api on Kubernetes" {
+		t.Fatalf("memories = %+v", memories)
+	}
+}
+
+func TestTeamRun(t *testing.T) {
+	// 主管与成员共用同一个假模型，按调用顺序应答
+	chat := chat_model.NewFakeChatModel(
+		schema.AssistantMessage("", []schema.ToolCall{{
+			ID:       "h1",
+			Function: schema.FunctionCall{Name: "writer", Arguments: `{"task":"write a haiku about vectors"}`},
+		}}),
+		schema.AssistantMessage("vectors in the dark", nil),
+		schema.AssistantMessage("Here is your haiku: vectors in the dark", nil),
+	)
+	srv := newOfflineServer(t, chat, "0.1")
+
+	resp := postJSON(t, srv.URL+"/api/agent/team/run", TeamRunRequest{Question: "haiku?"})
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("unconfigured status = %d, want 404", resp.StatusCode)
+	}
+
+	conf, err := team.ParseConfig([]byte(`{"agents":{"writer":{"description":"writes poems","prompt":"You are a poet."}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	team.Default = conf
+	t.Cleanup(func() { team.Default = nil })
+
+	resp = postJSON(t, srv.URL+"/api/agent/team/run", TeamRunRequest{Question: "haiku?"})
+	var out TeamRunResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK || out.Answer != "Here is your haiku: vectors in the dark" || out.Handoffs != 1 {
+		t.Fatalf("status = %d, resp = %+v", resp.StatusCode, out)
+	}
+	var handoff, writerAnswer bool
+	for _, e := range out.Trace {
+		handoff = handoff || (e.Type == team.EventHandoff && e.Tool == "writer" && e.Content == "write a haiku about vectors")
+		writerAnswer = writerAnswer || (e.Agent == "writer" && e.Type == agent.EventAnswer)
+	}
+	if !handoff || !writerAnswer {
+		t.Fatalf("trace = %+v", out.Trace)
+	}
+	inputs := chat.Inputs()
+	if in := inputs[1]; in[0].Content != "You are a poet." || in[1].Content != "write a haiku about vectors" {
+		t.Fatalf("writer input = %+v", in)
+	}
+}
+
+func eventTypesOf(trace []agent.Event) string {
+	types := make([]string, len(trace))
+	for i, e := range trace {
+		types[i] = e.Type
+	}
+	return strings.Join(types, ",")
+}
+
+func TestMCPServerExposesKnowledgeBase(t *testing.T) {
+	chat := chat_model.NewFakeChatModel(schema.AssistantMessage("Milvus stores the chunks.", nil))
+	srv := newOfflineServer(t, chat, "0.1")
+	uploadDocument(t, srv, "guide.txt", testDocument)
+	ctx := context.Background()
+
+	c, err := mcpclient.NewStreamableHttpClient(srv.URL + MCPPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if err := c.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Initialize(ctx, mcp.InitializeRequest{}); err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := c.ListTools(ctx, mcp.ListToolsRequest{})
+	if err != nil || len(list.Tools) != 3 {
+		t.Fatalf("tools = %+v, err = %v", list, err)
+	}
+
+	call := func(name string, args map[string]any) string {
+		t.Helper()
+		req := mcp.CallToolRequest{}
+		req.Params.Name = name
+		req.Params.Arguments = args
+		res, err := c.CallTool(ctx, req)
+		if err != nil || res.IsError || len(res.Content) == 0 {
+			t.Fatalf("%s: res = %+v, err = %v", name, res, err)
+		}
+		return res.Content[0].(mcp.TextContent).Text
+	}
+
+	if out := call(MCPToolSearchKnowledgeBase, map[string]any{"query": "vector database", "top_k": 1}); !strings.Contains(out, "Milvus is the vector database") {
+		t.Fatalf("search = %s", out)
+	}
+
+	var ask mcpAskResult
+	if err := json.Unmarshal([]byte(call(MCPToolAskKnowledgeBase, map[string]any{"question": "where are chunks stored?"})), &ask); err != nil {
+		t.Fatal(err)
+	}
+	if ask.Answer != "Milvus stores the chunks." || ask.BelowThreshold || len(ask.Sources) == 0 || ask.Prompt != "default_rag@1" {
+		t.Fatalf("ask = %+v", ask)
+	}
+
+	// 逐块最低相似度高于最高分时不基于文档回答，也不报告低于阈值
+	ask = mcpAskResult{}
+	if err := json.Unmarshal([]byte(call(MCPToolAskKnowledgeBase, map[string]any{"question": "where are chunks stored?", "threshold": 0.1, "min_score": 1})), &ask); err != nil {
+		t.Fatal(err)
+	}
+	if ask.BelowThreshold || ask.NoAnswerReason != NoAnswerFilteredOut || !strings.HasPrefix(ask.Answer, "抱歉") {
+		t.Fatalf("ask = %+v", ask)
+	}
+
+	if out := call(MCPToolListCollections, nil); !strings.Contains(out, `"collections"`) {
+		t.Fatalf("collections = %s", out)
+	}
+}
@@ -1,10 +1,19 @@
 package api
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"go-agent/config"
+	"go-agent/model/chat_history"
 	"go-agent/model/chat_model"
+	"go-agent/model/prompt_template"
+	"go-agent/model/usage"
 	"io"
 	"net/http"
+	"strings"
 
+	"github.com/cloudwego/eino/components/model"
 	"github.com/cloudwego/eino/schema"
 	"github.com/gin-gonic/gin"
 )
@@ -13,6 +22,17 @@ import (
 type ChatTestRequest struct {
 	Question string            `json:"question" binding:"required"`
 	History  []ChatTestMessage `json:"history,omitempty"`
+	Model    string            `json:"model,omitempty"` // 指定聊天模型（见 GET /api/models），为空时使用默认故障转移链
+	// PromptID 提示词模板（"名称" 或 "名称@版本"），为空时使用 default_chat
+	PromptID string `json:"prompt_id,omitempty"`
+	// SystemPrompt 覆盖模板中的系统提示词
+	SystemPrompt string `json:"system_prompt,omitempty"`
+	// UserID 用户标识，启用长期记忆时检索该用户的相关记忆加入系统提示词
+	UserID string `json:"user_id,omitempty"`
+	// EndConversation 本轮是对话的最后一轮，回答后在后台从完整对话中提取该用户的记忆
+	EndConversation bool `json:"end_conversation,omitempty"`
+	// 生成参数：temperature、max_tokens、top_p、stop、seed
+	chat_model.GenerationParams
 }
 
 // ChatTestMessage 聊天消息结构（用于前端传递）
@@ -25,6 +45,11 @@ type ChatTestMessage struct {
 type ChatTestResponse struct {
 	Question string `json:"question"`
 	Answer   string `json:"answer"`
+	Provider string `json:"provider,omitempty"` // 实际应答的模型提供方
+	Prompt   string `json:"prompt,omitempty"`   // 使用的提示词模板（名称@版本）
+	// Context 上下文预算使用情况，未配置预算时为空
+	Context *chat_history.Info `json:"context,omitempty"`
+	Usage   *usage.Usage       `json:"usage,omitempty"`
 }
 
 // ChatGenerate 聊天模型的常规输出
@@ -35,44 +60,50 @@ func ChatGenerate(c *gin.Context) {
 		return
 	}
 
-	// 检查模型是否已初始化
-	if chat_model.CM == nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "ChatModel 未初始化"})
+	// 按请求选择模型
+	cm, err := chat_model.Get(req.Model)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := chat_model.ValidateParams(req.Model, req.GenerationParams); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid generation params: " + err.Error()})
 		return
 	}
 
 	// 使用请求的上下文
 	ctx := c.Request.Context()
 
-	// 构建消息列表
-	messages := make([]*schema.Message, 0)
-
-	// TODO 后期提示词模板写完替换
-	messages = append(messages, schema.SystemMessage("你是一个有用的AI助手。"))
-
-	// 添加历史对话
-	for _, msg := range req.History {
-		if msg.Role == "user" {
-			messages = append(messages, schema.UserMessage(msg.Content))
-		} else if msg.Role == "assistant" {
-			messages = append(messages, schema.AssistantMessage(msg.Content, []schema.ToolCall{}))
-		}
+	// 按提示词模板构建消息列表
+	tmpl, err := resolvePrompt(req.PromptID, req.SystemPrompt, prompt_template.DefaultChat)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	messages, contextInfo, err := chatMessages(ctx, &req, cm, tmpl, c.GetHeader("X-Session-ID"))
+	if err != nil {
+		c.JSON(chatMessagesStatus(err), gin.H{"error": err.Error()})
+		return
 	}
-
-	// 添加当前问题
-	messages = append(messages, schema.UserMessage(req.Question))
 
 	// 调用模型的 Generate 方法
-	response, err := chat_model.CM.Generate(ctx, messages)
+	response, err := cm.Generate(ctx, messages, req.GenerationParams.Options()...)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate answer: " + err.Error()})
 		return
 	}
+	if req.EndConversation {
+		rememberConversation(ctx, req.UserID, finishedConversation(&req, response.Content))
+	}
 
 	// 返回响应
 	c.JSON(http.StatusOK, ChatTestResponse{
 		Question: req.Question,
 		Answer:   response.Content,
+		Provider: chat_model.ProviderOf(response),
+		Prompt:   tmpl.ID(),
+		Context:  contextInfo,
+		Usage:    usage.FromContext(ctx).Summary(),
 	})
 }
 
@@ -84,9 +115,26 @@ func ChatStream(c *gin.Context) {
 		return
 	}
 
-	// 检查模型是否已初始化
-	if chat_model.CM == nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "ChatModel 未初始化"})
+	// 按请求选择模型
+	cm, err := chat_model.Get(req.Model)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := chat_model.ValidateParams(req.Model, req.GenerationParams); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid generation params: " + err.Error()})
+		return
+	}
+
+	// 按提示词模板构建消息列表
+	tmpl, err := resolvePrompt(req.PromptID, req.SystemPrompt, prompt_template.DefaultChat)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	messages, contextInfo, err := chatMessages(c.Request.Context(), &req, cm, tmpl, c.GetHeader("X-Session-ID"))
+	if err != nil {
+		c.JSON(chatMessagesStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -106,25 +154,7 @@ func ChatStream(c *gin.Context) {
 
 	c.Writer.WriteHeader(http.StatusOK)
 
-	// 构建消息列表
-	messages := make([]*schema.Message, 0)
-
-	// TODO 后期提示词模版写完替换该处
-	messages = append(messages, schema.SystemMessage("你是一个有用的AI助手。"))
-
-	// 添加历史对话
-	for _, msg := range req.History {
-		if msg.Role == "user" {
-			messages = append(messages, schema.UserMessage(msg.Content))
-		} else if msg.Role == "assistant" {
-			messages = append(messages, schema.AssistantMessage(msg.Content, []schema.ToolCall{}))
-		}
-	}
-
-	// 添加当前问题
-	messages = append(messages, schema.UserMessage(req.Question))
-
-	streamReader, err := chat_model.CM.Stream(c.Request.Context(), messages)
+	streamReader, err := cm.Stream(c.Request.Context(), messages, req.GenerationParams.Options()...)
 	if err != nil {
 		c.SSEvent("error", gin.H{"error": err.Error()})
 		flusher.Flush()
@@ -139,15 +169,24 @@ func ChatStream(c *gin.Context) {
 	flusher.Flush()
 
 	// 读取大模型流式返回的数据，并实时发送给客户端
+	provider := ""
+	var answer strings.Builder
 	for {
 		msg, err := streamReader.Recv()
 
 		if err != nil {
 			if err == io.EOF {
+				if req.EndConversation {
+					rememberConversation(c.Request.Context(), req.UserID, finishedConversation(&req, answer.String()))
+				}
 				// 流结束
 				c.SSEvent("message", gin.H{
-					"type":    "end",
-					"content": "",
+					"type":     "end",
+					"content":  "",
+					"provider": provider,
+					"prompt":   tmpl.ID(),
+					"context":  contextInfo,
+					"usage":    usage.FromContext(c.Request.Context()).Summary(),
 				})
 				flusher.Flush()
 				return
@@ -158,8 +197,13 @@ func ChatStream(c *gin.Context) {
 			return
 		}
 
+		if name := chat_model.ProviderOf(msg); name != "" {
+			provider = name
+		}
+
 		// 发送接收到的增量内容
 		if msg != nil && msg.Content != "" {
+			answer.WriteString(msg.Content)
 			c.SSEvent("message", gin.H{
 				"type":    "data",
 				"content": msg.Content,
@@ -168,3 +212,91 @@ func ChatStream(c *gin.Context) {
 		}
 	}
 }
+
+// chatHistory 转换前端传递的历史对话，忽略未知角色
+func chatHistory(history []ChatTestMessage) []*schema.Message {
+	messages := make([]*schema.Message, 0, len(history))
+	for _, msg := range history {
+		if msg.Role == "user" {
+			messages = append(messages, schema.UserMessage(msg.Content))
+		} else if msg.Role == "assistant" {
+			messages = append(messages, schema.AssistantMessage(msg.Content, []schema.ToolCall{}))
+		}
+	}
+	return messages
+}
+
+// errSummarizeHistory 历史对话超出上下文预算且生成摘要失败
+var errSummarizeHistory = errors.New("压缩历史对话失败")
+
+// chatMessages 按模板构建消息并加入用户记忆；启用上下文预算且历史超出预算时，较早的轮次替换为摘要（按会话缓存）
+func chatMessages(ctx context.Context, req *ChatTestRequest, cm model.BaseChatModel, tmpl *prompt_template.Template, session string) ([]*schema.Message, *chat_history.Info, error) {
+	memories := memoryPrompt(ctx, req.UserID, req.Question)
+	build := func(history []*schema.Message, summary string) ([]*schema.Message, error) {
+		messages, err := tmpl.Format(ctx, history, req.Question, "", false)
+		if err != nil {
+			return nil, err
+		}
+		return withSystem(messages, chat_history.Prompt(summary), memories), nil
+	}
+
+	history := chatHistory(req.History)
+	if chat_history.Default == nil {
+		messages, err := build(history, "")
+		return messages, nil, err
+	}
+
+	fixed, err := build(nil, "")
+	if err != nil {
+		return nil, nil, err
+	}
+	summaryModel := cm
+	if name := config.Cfg.ChatContextConf.SummaryModel; name != "" {
+		if summaryModel, err = chat_model.Get(name); err != nil {
+			return nil, nil, fmt.Errorf("%w: %v", errSummarizeHistory, err)
+		}
+	}
+	fit, err := chat_history.Default.Fit(ctx, chat_history.Request{
+		Session:   session,
+		Providers: chat_model.Providers(req.Model),
+		Model:     summaryModel,
+		Fixed:     fixed,
+		History:   history,
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", errSummarizeHistory, err)
+	}
+
+	messages, err := build(fit.History, fit.Summary)
+	return messages, &fit.Info, err
+}
+
+// chatMessagesStatus 构建消息失败的状态码：生成摘要失败为 500，模板错误为 400
+func chatMessagesStatus(err error) int {
+	if errors.Is(err, errSummarizeHistory) {
+		return http.StatusInternalServerError
+	}
+	return http.StatusBadRequest
+}
+
+// finishedConversation 历史对话加上本轮问答，用于提取记忆
+func finishedConversation(req *ChatTestRequest, answer string) []*schema.Message {
+	return append(chatHistory(req.History), schema.UserMessage(req.Question), schema.AssistantMessage(answer, nil))
+}
+
+// resolvePrompt 按 prompt_id 查找模板，system_prompt 不为空时覆盖系统提示词
+func resolvePrompt(promptID, systemPrompt, fallback string) (*prompt_template.Template, error) {
+	tmpl, err := prompt_template.Resolve(promptID, fallback)
+	if err != nil {
+		return nil, err
+	}
+	if systemPrompt != "" {
+		tmpl = tmpl.WithSystem(systemPrompt)
+	}
+	return tmpl, nil
+}
+
+// ChatProviders 查看各聊天模型提供方的健康与熔断状态
+func ChatProviders(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"providers": chat_model.Health()})
+}
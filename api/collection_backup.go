@@ -0,0 +1,125 @@
+package api
+
+import (
+	"fmt"
+	"go-agent/model/usage"
+	"go-agent/rag/backup"
+	"go-agent/rag/tools/db"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CollectionImportResponse struct {
+	Success        bool         `json:"success"`
+	Message        string       `json:"message"`
+	ImportedChunks int          `json:"imported_chunks,omitempty"`
+	Reembedded     bool         `json:"reembedded,omitempty"`
+	EmbeddingModel string       `json:"embedding_model,omitempty"` // 备份中记录的嵌入模型
+	Usage          *usage.Usage `json:"usage,omitempty"`
+}
+
+// ExportCollection 导出集合全部文档块（format=jsonl|tar，vectors=true 时包含向量）
+func ExportCollection(c *gin.Context) {
+	collectionName := c.Param("name")
+	if db.Milvus == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Milvus 客户端未初始化"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	exists, err := db.Milvus.HasCollection(ctx, collectionName)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "检查集合失败: " + err.Error()})
+		return
+	}
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": "集合不存在: " + collectionName})
+		return
+	}
+
+	opts := backup.ExportOptions{
+		Format: c.DefaultQuery("format", backup.FormatJSONL),
+	}
+	opts.IncludeVectors, _ = strconv.ParseBool(c.DefaultQuery("vectors", "false"))
+
+	var contentType, ext string
+	switch opts.Format {
+	case backup.FormatJSONL:
+		contentType, ext = "application/x-ndjson", "jsonl"
+	case backup.FormatTar:
+		contentType, ext = "application/gzip", "tar.gz"
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "不支持的导出格式: " + opts.Format})
+		return
+	}
+
+	fileName := fmt.Sprintf("%s_%s.%s", collectionName, time.Now().Format("20060102150405"), ext)
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fileName))
+	c.Status(http.StatusOK)
+
+	// 响应头已发出，导出中途失败只能记录日志并中断连接；此时不会写入结尾记录，导入会拒绝该文件
+	if err := backup.Export(ctx, collectionName, c.Writer, opts); err != nil {
+		log.Printf("导出集合 %s 失败: %v", collectionName, err)
+		c.Abort()
+	}
+}
+
+// ImportCollection 从上传的备份文件恢复集合，嵌入模型不一致时自动重新嵌入
+func ImportCollection(c *gin.Context) {
+	collectionName := c.Param("name")
+	if db.Milvus == nil {
+		c.JSON(http.StatusInternalServerError, CollectionImportResponse{
+			Success: false,
+			Message: "Milvus 客户端未初始化",
+		})
+		return
+	}
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, CollectionImportResponse{
+			Success: false,
+			Message: fmt.Sprintf("获取上传文件失败: %v", err),
+		})
+		return
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, CollectionImportResponse{
+			Success: false,
+			Message: fmt.Sprintf("打开上传文件失败: %v", err),
+		})
+		return
+	}
+	defer src.Close()
+
+	// 重新嵌入产生的用量计入目标集合
+	usage.FromContext(c.Request.Context()).SetCollection(collectionName)
+	result, err := backup.Import(c.Request.Context(), collectionName, src)
+	if err != nil {
+		resp := CollectionImportResponse{
+			Success: false,
+			Message: fmt.Sprintf("导入失败: %v", err),
+		}
+		if result != nil {
+			resp.ImportedChunks = result.Imported
+		}
+		c.JSON(http.StatusInternalServerError, resp)
+		return
+	}
+
+	c.JSON(http.StatusOK, CollectionImportResponse{
+		Success:        true,
+		Message:        fmt.Sprintf("集合 '%s' 导入成功", collectionName),
+		ImportedChunks: result.Imported,
+		Reembedded:     result.Reembedded,
+		EmbeddingModel: result.Manifest.EmbeddingModel,
+		Usage:          usage.FromContext(c.Request.Context()).Summary(),
+	})
+}
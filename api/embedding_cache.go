@@ -0,0 +1,32 @@
+package api
+
+import (
+	"go-agent/model/embedding_model"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type EmbeddingCacheStatsResponse struct {
+	Success bool                        `json:"success"`
+	Enabled bool                        `json:"enabled"`
+	Stats   *embedding_model.CacheStats `json:"stats,omitempty"`
+}
+
+// GetEmbeddingCacheStats 返回嵌入缓存的命中统计
+func GetEmbeddingCacheStats(c *gin.Context) {
+	if embedding_model.Cache == nil {
+		c.JSON(http.StatusOK, EmbeddingCacheStatsResponse{
+			Success: true,
+			Enabled: false,
+		})
+		return
+	}
+
+	stats := embedding_model.Cache.Stats()
+	c.JSON(http.StatusOK, EmbeddingCacheStatsResponse{
+		Success: true,
+		Enabled: true,
+		Stats:   &stats,
+	})
+}
@@ -1,13 +1,16 @@
 package api
 
 import (
-	"context"
 	"fmt"
+	"go-agent/agent/toolbox"
+	"go-agent/config"
+	"go-agent/model/usage"
 	"go-agent/rag/compose"
 	"io"
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/cloudwego/eino/components/document"
@@ -15,15 +18,18 @@ import (
 )
 
 type InsertDocumentResponse struct {
-	Success     bool     `json:"success"`
-	Message     string   `json:"message"`
-	DocumentIDs []string `json:"document_ids,omitempty"`
-	ChunkCount  int      `json:"chunk_count,omitempty"`
+	Success     bool         `json:"success"`
+	Message     string       `json:"message"`
+	DocumentIDs []string     `json:"document_ids,omitempty"`
+	ChunkCount  int          `json:"chunk_count,omitempty"`
+	Table       string       `json:"table,omitempty"` // CSV 文件同时保存为 sql_query 工具可查询的表
+	Usage       *usage.Usage `json:"usage,omitempty"`
 }
 
 // InsertDocument 处理文件上传并索引文档
 func InsertDocument(c *gin.Context) {
-	ctx := context.Background()
+	ctx := c.Request.Context()
+	usage.FromContext(ctx).SetCollection(config.Cfg.MilvusConf.CollectionName)
 
 	// 1. 获取上传的文件
 	file, err := c.FormFile("file")
@@ -127,11 +133,21 @@ func InsertDocument(c *gin.Context) {
 		return
 	}
 
-	// 10. 返回成功响应
+	// 10. CSV 文件同时保存为 SQL 工具可查询的表
+	var table string
+	if toolbox.Tables != nil && strings.EqualFold(filepath.Ext(file.Filename), ".csv") {
+		if table, err = toolbox.Tables.Save(file.Filename, tempFilePath); err != nil {
+			log.Printf("保存 CSV 表失败: %v, 文件: %s", err, file.Filename)
+		}
+	}
+
+	// 11. 返回成功响应
 	c.JSON(200, InsertDocumentResponse{
 		Success:     true,
 		Message:     fmt.Sprintf("文档 '%s' 索引成功", file.Filename),
 		DocumentIDs: documentIDs,
 		ChunkCount:  len(documentIDs),
+		Table:       table,
+		Usage:       usage.FromContext(ctx).Summary(),
 	})
 }
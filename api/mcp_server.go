@@ -0,0 +1,249 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-agent/config"
+	"go-agent/model/chat_model"
+	"go-agent/model/prompt_template"
+	"go-agent/model/usage"
+	"go-agent/rag/compose"
+	"go-agent/rag/pack"
+	"go-agent/rag/tools/db"
+	"log"
+	"net/http"
+
+	einoretriever "github.com/cloudwego/eino/components/retriever"
+	compose2 "github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// MCP 服务器暴露的工具
+const (
+	MCPToolSearchKnowledgeBase = "search_knowledge_base"
+	MCPToolAskKnowledgeBase    = "ask_knowledge_base"
+	MCPToolListCollections     = "list_collections"
+)
+
+// MCPPath Streamable HTTP MCP 端点
+const MCPPath = "/mcp"
+
+// maxMCPSearchTopK 单次检索最多返回的文档块数量
+const maxMCPSearchTopK = 20
+
+type mcpSearchResult struct {
+	Content string  `json:"content"`
+	Score   float64 `json:"score"`
+	Source  string  `json:"source,omitempty"`
+}
+
+type mcpAskResult struct {
+	Answer         string            `json:"answer"`
+	Provider       string            `json:"provider,omitempty"`
+	Prompt         string            `json:"prompt,omitempty"`
+	BelowThreshold bool              `json:"below_threshold,omitempty"`
+	NoAnswerReason string            `json:"no_answer_reason,omitempty"` // below_threshold 或 filtered_out
+	MaxScore       float64           `json:"max_score"`
+	Sources        []mcpSearchResult `json:"sources,omitempty"`
+}
+
+type mcpCollectionsResult struct {
+	Collections []string `json:"collections"`
+	Default     string   `json:"default"` // 检索与问答使用的集合
+}
+
+// NewMCPServer 创建把知识库暴露为 MCP 工具的服务器：检索、问答与集合列表
+func NewMCPServer() *server.MCPServer {
+	s := server.NewMCPServer("go-agent", "1.0.0",
+		server.WithToolCapabilities(false),
+		server.WithRecovery(),
+		server.WithToolHandlerMiddleware(mcpUsageMiddleware),
+	)
+
+	s.AddTool(mcp.NewTool(MCPToolSearchKnowledgeBase,
+		mcp.WithDescription("Search the go-agent document knowledge base and return the most relevant chunks with similarity scores and sources."),
+		mcp.WithString("query", mcp.Required(), mcp.Description("the search query")),
+		mcp.WithNumber("top_k", mcp.Description("number of chunks to return (default: configured top k)"), mcp.Min(1), mcp.Max(maxMCPSearchTopK)),
+		mcp.WithReadOnlyHintAnnotation(true),
+	), mcpSearchKnowledgeBase)
+
+	s.AddTool(mcp.NewTool(MCPToolAskKnowledgeBase,
+		mcp.WithDescription("Answer a question from the go-agent knowledge base (retrieval-augmented generation). Returns the answer and the chunks it was based on."),
+		mcp.WithString("question", mcp.Required(), mcp.Description("the question to answer")),
+		mcp.WithString("model", mcp.Description("chat model to use (default: configured fallback chain)")),
+		mcp.WithString("prompt_id", mcp.Description("prompt template as name or name@version (default: default_rag)")),
+		mcp.WithNumber("threshold", mcp.Description("minimum top similarity required to answer from the documents; also the per-chunk minimum when higher than the configured one (default: configured threshold)")),
+		mcp.WithNumber("min_score", mcp.Description("per-chunk minimum similarity (default: configured RAG_MIN_SCORE)")),
+		mcp.WithNumber("relative_cutoff", mcp.Description("drop chunks scoring more than this below the best chunk, 0 disables (default: configured RAG_RELATIVE_CUTOFF)"), mcp.Min(0)),
+		mcp.WithReadOnlyHintAnnotation(true),
+	), mcpAskKnowledgeBase)
+
+	s.AddTool(mcp.NewTool(MCPToolListCollections,
+		mcp.WithDescription("List the knowledge base collections and the one used for search and answers."),
+		mcp.WithReadOnlyHintAnnotation(true),
+	), mcpListCollections)
+
+	return s
+}
+
+// NewMCPHandler 返回 Streamable HTTP 处理器，挂载在 MCPPath
+func NewMCPHandler() http.Handler {
+	return server.NewStreamableHTTPServer(NewMCPServer(), server.WithEndpointPath(MCPPath))
+}
+
+// ServeMCPStdio 以 stdio 模式运行 MCP 服务器，直到标准输入关闭或收到退出信号
+func ServeMCPStdio() error {
+	return server.ServeStdio(NewMCPServer())
+}
+
+// mcpUsageMiddleware stdio 模式下没有 HTTP 用量中间件，为每次工具调用单独收集并写入用量
+func mcpUsageMiddleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if usage.FromContext(ctx) != nil {
+			return next(ctx, req)
+		}
+
+		ctx, recorder := usage.NewContext(ctx, usage.Scope{Endpoint: "mcp:" + req.Params.Name})
+		res, err := next(ctx, req)
+		if usage.Store != nil {
+			if err := usage.Store.Append(recorder.Records()); err != nil {
+				log.Printf("写入用量记录失败: %v", err)
+			}
+		}
+		return res, err
+	}
+}
+
+func mcpSearchKnowledgeBase(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	query, err := req.RequireString("query")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	usage.FromContext(ctx).SetCollection(config.Cfg.MilvusConf.CollectionName)
+
+	var opts []compose2.Option
+	if topK := req.GetInt("top_k", 0); topK > 0 {
+		opts = append(opts, compose2.WithRetrieverOption(einoretriever.WithTopK(min(topK, maxMCPSearchTopK))))
+	}
+	docs, err := mcpRetrieve(ctx, query, opts...)
+	if err != nil {
+		return mcp.NewToolResultErrorFromErr("search failed", err), nil
+	}
+	return mcpJSONResult(map[string]any{"results": mcpSources(docs)})
+}
+
+func mcpAskKnowledgeBase(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	question, err := req.RequireString("question")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	usage.FromContext(ctx).SetCollection(config.Cfg.MilvusConf.CollectionName)
+
+	modelName := req.GetString("model", "")
+	cm, err := chat_model.Get(modelName)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	tmpl, err := resolvePrompt(req.GetString("prompt_id", ""), "", prompt_template.DefaultRAG)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	relevance, err := ragRelevanceFor(ctx, mcpOptionalFloat(req, "threshold"), mcpOptionalFloat(req, "min_score"), mcpOptionalFloat(req, "relative_cutoff"))
+	if errors.Is(err, errInvalidRelevance) {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if err != nil {
+		return mcp.NewToolResultErrorFromErr("describe collection metric failed", err), nil
+	}
+
+	docs, err := mcpRetrieve(ctx, question)
+	if err != nil {
+		return mcp.NewToolResultErrorFromErr("search failed", err), nil
+	}
+
+	result := mcpAskResult{Sources: mcpSources(docs)}
+	for _, doc := range docs {
+		result.MaxScore = max(result.MaxScore, docScore(doc))
+	}
+	relevant, _ := pack.Filter(docs, relevance.Filter)
+	if reason := relevance.noAnswerReason(len(docs), result.MaxScore, len(relevant)); reason != "" {
+		answer, _, err := answerWithoutDocuments(ctx, cm, ragNoAnswerPolicy(), question, ragRefusal(len(docs)))
+		if err != nil {
+			return mcp.NewToolResultErrorFromErr("answer failed", err), nil
+		}
+		result.Answer = answer.Content
+		result.Provider = chat_model.ProviderOf(answer)
+		result.BelowThreshold = reason == NoAnswerBelowThreshold
+		result.NoAnswerReason = reason
+		return mcpJSONResult(result)
+	}
+
+	packed, err := packRAGDocuments(ctx, modelName, tmpl, question, relevant)
+	if err != nil {
+		return mcp.NewToolResultErrorFromErr("answer failed", err), nil
+	}
+	answer, err := generateRAGAnswer(ctx, cm, tmpl, question, formatRAGDocuments(packed.Passages))
+	if err != nil {
+		return mcp.NewToolResultErrorFromErr("answer failed", err), nil
+	}
+	result.Answer = answer.Content
+	result.Provider = chat_model.ProviderOf(answer)
+	result.Prompt = tmpl.ID()
+	return mcpJSONResult(result)
+}
+
+func mcpListCollections(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	result := mcpCollectionsResult{Default: config.Cfg.MilvusConf.CollectionName}
+	if db.Milvus == nil {
+		// memory 模式只有进程内的一个集合
+		result.Collections = []string{result.Default}
+		return mcpJSONResult(result)
+	}
+
+	names, err := listCollectionNames(ctx)
+	if err != nil {
+		return mcp.NewToolResultErrorFromErr("list collections failed", err), nil
+	}
+	result.Collections = names
+	return mcpJSONResult(result)
+}
+
+// mcpRetrieve 通过检索图召回文档
+func mcpRetrieve(ctx context.Context, query string, opts ...compose2.Option) ([]*schema.Document, error) {
+	runner, err := compose.BuildRetrieverGraph(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("构建检索图失败: %w", err)
+	}
+	return runner.Invoke(ctx, query, opts...)
+}
+
+// mcpOptionalFloat 读取可选的数值参数，未传时返回 nil 以使用配置值
+func mcpOptionalFloat(req mcp.CallToolRequest, name string) *float64 {
+	if _, ok := req.GetArguments()[name]; !ok {
+		return nil
+	}
+	v := req.GetFloat(name, 0)
+	return &v
+}
+
+func mcpSources(docs []*schema.Document) []mcpSearchResult {
+	results := make([]mcpSearchResult, 0, len(docs))
+	for _, doc := range docs {
+		source, _ := doc.MetaData[db.MetaKeySource].(string)
+		results = append(results, mcpSearchResult{Content: doc.Content, Score: docScore(doc), Source: source})
+	}
+	return results
+}
+
+// mcpJSONResult 以 JSON 文本返回结果，同时作为结构化内容
+func mcpJSONResult(v any) (*mcp.CallToolResult, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return mcp.NewToolResultStructured(v, string(b)), nil
+}
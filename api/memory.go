@@ -0,0 +1,169 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"go-agent/config"
+	"go-agent/model/chat_model"
+	"go-agent/model/usage"
+	"go-agent/rag/memory"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/cloudwego/eino/schema"
+	"github.com/gin-gonic/gin"
+)
+
+var errMemoryDisabled = errors.New("未启用长期记忆（MEMORY_ENABLED）")
+
+// ExtractMemoriesRequest 从一段结束的对话中提取用户记忆
+type ExtractMemoriesRequest struct {
+	UserID  string            `json:"user_id" binding:"required"`
+	History []ChatTestMessage `json:"history" binding:"required"`
+	Model   string            `json:"model,omitempty"` // 提取使用的聊天模型，为空时使用 MEMORY_MODEL
+}
+
+// MemoriesResponse 记忆列表
+type MemoriesResponse struct {
+	Success  bool            `json:"success"`
+	Message  string          `json:"message,omitempty"`
+	Memories []memory.Memory `json:"memories"`
+}
+
+// ListMemories 列出用户的全部记忆，user_id 由查询参数指定
+func ListMemories(c *gin.Context) {
+	if memory.Memories == nil {
+		c.JSON(http.StatusNotFound, MemoriesResponse{Success: false, Message: errMemoryDisabled.Error()})
+		return
+	}
+	userID := c.Query("user_id")
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, MemoriesResponse{Success: false, Message: "缺少 user_id"})
+		return
+	}
+	memories, err := memory.Memories.List(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, MemoriesResponse{Success: false, Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, MemoriesResponse{Success: true, Memories: memories})
+}
+
+// DeleteMemory 删除用户的一条记忆，只能删除 user_id 自己的记忆
+func DeleteMemory(c *gin.Context) {
+	if memory.Memories == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": errMemoryDisabled.Error()})
+		return
+	}
+	userID := c.Query("user_id")
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "缺少 user_id"})
+		return
+	}
+	if err := memory.Memories.Delete(c.Request.Context(), userID, c.Param("id")); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, memory.ErrNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+// ExtractMemories 从对话中提取用户记忆并写入，返回新写入的记忆（与已有记忆重复的不会写入）
+func ExtractMemories(c *gin.Context) {
+	var req ExtractMemoriesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, MemoriesResponse{Success: false, Message: "请求格式错误: " + err.Error()})
+		return
+	}
+	if memory.Memories == nil {
+		c.JSON(http.StatusNotFound, MemoriesResponse{Success: false, Message: errMemoryDisabled.Error()})
+		return
+	}
+	if req.Model == "" {
+		req.Model = config.Cfg.MemoryConf.Model
+	}
+	cm, err := chat_model.Get(req.Model)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, MemoriesResponse{Success: false, Message: err.Error()})
+		return
+	}
+
+	added, err := memory.Memories.Remember(c.Request.Context(), cm, req.UserID, chatHistory(req.History))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, MemoriesResponse{Success: false, Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, MemoriesResponse{Success: true, Memories: added})
+}
+
+// memoryPrompt 检索与问题相关的用户记忆整理为系统提示词段落；未启用、没有相关记忆或检索失败时返回空串，不影响对话
+func memoryPrompt(ctx context.Context, userID, question string) string {
+	if memory.Memories == nil || userID == "" {
+		return ""
+	}
+	memories, err := memory.Memories.Search(ctx, userID, question)
+	if err != nil {
+		log.Printf("检索用户 %s 的记忆失败: %v", userID, err)
+		return ""
+	}
+	return memory.Prompt(memories)
+}
+
+// withSystem 把非空的段落追加到系统提示词，没有系统消息时插入一条
+func withSystem(messages []*schema.Message, sections ...string) []*schema.Message {
+	var parts []string
+	for _, section := range sections {
+		if section != "" {
+			parts = append(parts, section)
+		}
+	}
+	if len(parts) == 0 {
+		return messages
+	}
+	text := strings.Join(parts, "\n\n")
+
+	if len(messages) > 0 && messages[0].Role == schema.System {
+		system := *messages[0]
+		system.Content += "\n\n" + text
+		return append([]*schema.Message{&system}, messages[1:]...)
+	}
+	return append([]*schema.Message{schema.SystemMessage(text)}, messages...)
+}
+
+// rememberConversation 对话结束时在后台提取用户记忆，不阻塞响应
+// 请求的用量在响应后即已写入，后台提取使用独立的收集器，沿用请求的归属，端点记为 memory:<请求端点>，完成后单独写入
+func rememberConversation(ctx context.Context, userID string, conversation []*schema.Message) {
+	if memory.Memories == nil || userID == "" {
+		return
+	}
+	cm, err := chat_model.Get(config.Cfg.MemoryConf.Model)
+	if err != nil {
+		log.Printf("提取用户 %s 的记忆失败: %v", userID, err)
+		return
+	}
+	scope := usage.FromContext(ctx).Scope()
+	scope.Endpoint = "memory:" + scope.Endpoint
+	ctx, recorder := usage.NewContext(context.WithoutCancel(ctx), scope)
+	go func() {
+		defer func() {
+			if usage.Store == nil {
+				return
+			}
+			if err := usage.Store.Append(recorder.Records()); err != nil {
+				log.Printf("写入用量记录失败: %v", err)
+			}
+		}()
+		added, err := memory.Memories.Remember(ctx, cm, userID, conversation)
+		if err != nil {
+			log.Printf("提取用户 %s 的记忆失败: %v", userID, err)
+			return
+		}
+		if len(added) > 0 {
+			log.Printf("为用户 %s 写入 %d 条记忆", userID, len(added))
+		}
+	}()
+}
@@ -1,8 +1,10 @@
 package api
 
 import (
+	"context"
 	"go-agent/rag/tools/db"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 )
@@ -18,6 +20,24 @@ type MilvusDropCollectionResponse struct {
 	Message string `json:"message,omitempty"`
 }
 
+type CollectionStatsResponse struct {
+	Success bool                `json:"success"`
+	Message string              `json:"message,omitempty"`
+	Stats   *db.CollectionStats `json:"stats,omitempty"`
+}
+
+type CollectionChunksResponse struct {
+	Success bool        `json:"success"`
+	Message string      `json:"message,omitempty"`
+	Offset  int64       `json:"offset"`
+	Limit   int64       `json:"limit"`
+	Source  string      `json:"source,omitempty"`
+	Chunks  []*db.Chunk `json:"chunks"`
+}
+
+// maxChunksPageSize 单页最多返回的文档块数量
+const maxChunksPageSize = 1000
+
 // ListMilvusCollections 返回所有 Milvus 集合名称
 func ListMilvusCollections(c *gin.Context) {
 	if db.Milvus == nil {
@@ -28,7 +48,7 @@ func ListMilvusCollections(c *gin.Context) {
 		return
 	}
 
-	collections, err := db.Milvus.ListCollections(c.Request.Context())
+	names, err := listCollectionNames(c.Request.Context())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, MilvusCollectionsResponse{
 			Success: false,
@@ -37,17 +57,26 @@ func ListMilvusCollections(c *gin.Context) {
 		return
 	}
 
-	names := make([]string, 0, len(collections))
-	for _, collection := range collections {
-		names = append(names, collection.Name)
-	}
-
 	c.JSON(http.StatusOK, MilvusCollectionsResponse{
 		Success:     true,
 		Collections: names,
 	})
 }
 
+// listCollectionNames 列出 Milvus 全部集合名称
+func listCollectionNames(ctx context.Context) ([]string, error) {
+	collections, err := db.Milvus.ListCollections(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(collections))
+	for _, collection := range collections {
+		names = append(names, collection.Name)
+	}
+	return names, nil
+}
+
 // DeleteMilvusCollection 删除指定 Milvus 集合
 func DeleteMilvusCollection(c *gin.Context) {
 	collectionName := c.Param("name")
@@ -81,3 +110,107 @@ func DeleteMilvusCollection(c *gin.Context) {
 		Message: "删除成功",
 	})
 }
+
+// GetCollectionStats 返回集合的行数、维度、索引与嵌入模型，sources=true 时遍历集合统计来源文档与估算存储大小
+func GetCollectionStats(c *gin.Context) {
+	collectionName := c.Param("name")
+	withSources, _ := strconv.ParseBool(c.DefaultQuery("sources", "false"))
+	if db.Milvus == nil {
+		c.JSON(http.StatusInternalServerError, CollectionStatsResponse{
+			Success: false,
+			Message: "Milvus 客户端未初始化",
+		})
+		return
+	}
+
+	exists, err := db.Milvus.HasCollection(c.Request.Context(), collectionName)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, CollectionStatsResponse{
+			Success: false,
+			Message: "检查集合失败: " + err.Error(),
+		})
+		return
+	}
+	if !exists {
+		c.JSON(http.StatusNotFound, CollectionStatsResponse{
+			Success: false,
+			Message: "集合不存在: " + collectionName,
+		})
+		return
+	}
+
+	stats, err := db.GetCollectionStats(c.Request.Context(), collectionName, withSources)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, CollectionStatsResponse{
+			Success: false,
+			Message: "获取集合统计失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, CollectionStatsResponse{
+		Success: true,
+		Stats:   stats,
+	})
+}
+
+// ListCollectionChunks 分页查看集合中存储的文档块，可按来源文档过滤
+func ListCollectionChunks(c *gin.Context) {
+	collectionName := c.Param("name")
+	if db.Milvus == nil {
+		c.JSON(http.StatusInternalServerError, CollectionChunksResponse{
+			Success: false,
+			Message: "Milvus 客户端未初始化",
+		})
+		return
+	}
+
+	offset, err := strconv.ParseInt(c.DefaultQuery("offset", "0"), 10, 64)
+	if err != nil || offset < 0 {
+		c.JSON(http.StatusBadRequest, CollectionChunksResponse{
+			Success: false,
+			Message: "offset 参数无效",
+		})
+		return
+	}
+	limit, err := strconv.ParseInt(c.DefaultQuery("limit", "20"), 10, 64)
+	if err != nil || limit <= 0 || limit > maxChunksPageSize {
+		c.JSON(http.StatusBadRequest, CollectionChunksResponse{
+			Success: false,
+			Message: "limit 参数无效，取值范围 1-" + strconv.Itoa(maxChunksPageSize),
+		})
+		return
+	}
+
+	source := c.Query("source")
+	expr := ""
+	if source != "" {
+		expr = db.SourceExpr(source)
+	}
+
+	ctx := c.Request.Context()
+	if err := db.LoadCollection(ctx, collectionName); err != nil {
+		c.JSON(http.StatusInternalServerError, CollectionChunksResponse{
+			Success: false,
+			Message: "加载集合失败: " + err.Error(),
+		})
+		return
+	}
+
+	chunks, err := db.QueryChunks(ctx, collectionName, expr, offset, limit, false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, CollectionChunksResponse{
+			Success: false,
+			Message: "查询文档块失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, CollectionChunksResponse{
+		Success: true,
+		Offset:  offset,
+		Limit:   limit,
+		Source:  source,
+		Chunks:  chunks,
+	})
+}
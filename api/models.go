@@ -0,0 +1,24 @@
+package api
+
+import (
+	"go-agent/model/chat_model"
+	"go-agent/model/embedding_model"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ModelsResponse struct {
+	Success   bool                   `json:"success"`
+	Chat      []chat_model.ModelInfo `json:"chat"`
+	Embedding []embedding_model.Info `json:"embedding"`
+}
+
+// ListModels 列出已加载的聊天与嵌入模型及其能力
+func ListModels(c *gin.Context) {
+	c.JSON(http.StatusOK, ModelsResponse{
+		Success:   true,
+		Chat:      chat_model.List(),
+		Embedding: embedding_model.List(),
+	})
+}
@@ -0,0 +1,176 @@
+package api
+
+import (
+	"errors"
+	"go-agent/model/prompt_template"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PromptRequest 创建或更新提示词模板，更新时 name 取路径参数
+type PromptRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	System      string `json:"system" binding:"required"`
+	User        string `json:"user,omitempty"`
+}
+
+type PromptResponse struct {
+	Success   bool                        `json:"success"`
+	Message   string                      `json:"message,omitempty"`
+	Template  *prompt_template.Template   `json:"template,omitempty"`
+	Templates []*prompt_template.Template `json:"templates,omitempty"`
+}
+
+// ListPrompts 列出全部模板的最新版本
+func ListPrompts(c *gin.Context) {
+	if !promptStoreReady(c) {
+		return
+	}
+	c.JSON(http.StatusOK, PromptResponse{
+		Success:   true,
+		Templates: prompt_template.Store.List(),
+	})
+}
+
+// GetPrompt 查看模板，?version= 指定版本，默认最新版本
+func GetPrompt(c *gin.Context) {
+	if !promptStoreReady(c) {
+		return
+	}
+	version, ok := promptVersion(c)
+	if !ok {
+		return
+	}
+
+	t, err := prompt_template.Store.Get(c.Param("name"), version)
+	if err != nil {
+		promptError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, PromptResponse{Success: true, Template: t})
+}
+
+// ListPromptVersions 列出模板的全部版本
+func ListPromptVersions(c *gin.Context) {
+	if !promptStoreReady(c) {
+		return
+	}
+
+	versions, err := prompt_template.Store.Versions(c.Param("name"))
+	if err != nil {
+		promptError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, PromptResponse{Success: true, Templates: versions})
+}
+
+// CreatePrompt 创建新模板（第 1 版）
+func CreatePrompt(c *gin.Context) {
+	if !promptStoreReady(c) {
+		return
+	}
+	var req PromptRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, PromptResponse{
+			Success: false,
+			Message: "请求格式错误: " + err.Error(),
+		})
+		return
+	}
+
+	t, err := prompt_template.Store.Create(req.template(req.Name))
+	if err != nil {
+		promptError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, PromptResponse{Success: true, Message: "模板已创建", Template: t})
+}
+
+// UpdatePrompt 修改模板，写入新版本，旧版本保留可按 name@version 使用
+func UpdatePrompt(c *gin.Context) {
+	if !promptStoreReady(c) {
+		return
+	}
+	var req PromptRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, PromptResponse{
+			Success: false,
+			Message: "请求格式错误: " + err.Error(),
+		})
+		return
+	}
+
+	t, err := prompt_template.Store.Update(req.template(c.Param("name")))
+	if err != nil {
+		promptError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, PromptResponse{Success: true, Message: "模板已更新", Template: t})
+}
+
+// DeletePrompt 删除模板，?version= 只删除指定版本
+func DeletePrompt(c *gin.Context) {
+	if !promptStoreReady(c) {
+		return
+	}
+	version, ok := promptVersion(c)
+	if !ok {
+		return
+	}
+
+	if err := prompt_template.Store.Delete(c.Param("name"), version); err != nil {
+		promptError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, PromptResponse{Success: true, Message: "模板已删除"})
+}
+
+func (r PromptRequest) template(name string) prompt_template.Template {
+	return prompt_template.Template{
+		Name:        name,
+		Description: r.Description,
+		System:      r.System,
+		User:        r.User,
+	}
+}
+
+func promptStoreReady(c *gin.Context) bool {
+	if prompt_template.Store == nil {
+		c.JSON(http.StatusInternalServerError, PromptResponse{
+			Success: false,
+			Message: "提示词模板存储未初始化",
+		})
+		return false
+	}
+	return true
+}
+
+func promptVersion(c *gin.Context) (int, bool) {
+	raw := c.Query("version")
+	if raw == "" {
+		return 0, true
+	}
+	version, err := strconv.Atoi(raw)
+	if err != nil || version <= 0 {
+		c.JSON(http.StatusBadRequest, PromptResponse{
+			Success: false,
+			Message: "version 参数无效",
+		})
+		return 0, false
+	}
+	return version, true
+}
+
+func promptError(c *gin.Context, err error) {
+	status := http.StatusBadRequest
+	switch {
+	case errors.Is(err, prompt_template.ErrNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, prompt_template.ErrExists):
+		status = http.StatusConflict
+	}
+	c.JSON(status, PromptResponse{Success: false, Message: err.Error()})
+}
@@ -2,37 +2,76 @@ package api
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"go-agent/config"
 	"go-agent/model/chat_model"
+	"go-agent/model/prompt_template"
+	"go-agent/model/token"
+	"go-agent/model/usage"
 	"go-agent/rag/compose"
+	"go-agent/rag/pack"
+	"go-agent/rag/tools/db"
+	"go-agent/rag/tools/retriever"
 	"log"
 	"net/http"
 	"strconv"
 
-	"github.com/cloudwego/eino/components/prompt"
+	"github.com/cloudwego/eino/components/model"
+	compose2 "github.com/cloudwego/eino/compose"
 	"github.com/cloudwego/eino/schema"
 	"github.com/gin-gonic/gin"
+	"github.com/milvus-io/milvus-sdk-go/v2/entity"
 )
 
 type RAGAskRequest struct {
 	Query string `json:"query" binding:"required"`
+	// Model 指定聊天模型（见 GET /api/models），为空时使用默认故障转移链
+	Model string `json:"model,omitempty"`
+	// PromptID 提示词模板（"名称" 或 "名称@版本"），为空时使用 default_rag
+	PromptID string `json:"prompt_id,omitempty"`
+	// SystemPrompt 覆盖模板中的系统提示词，可用 {documents} 指定文档插入位置，未引用时追加在末尾
+	SystemPrompt string `json:"system_prompt,omitempty"`
+	// 生成参数：temperature、max_tokens、top_p、stop、seed
+	chat_model.GenerationParams
+	// SearchParams 覆盖本次检索参数，如 {"ef":128}、{"nprobe":32}
+	SearchParams map[string]any `json:"search_params,omitempty"`
+	// Threshold 覆盖 MILVUS_SIMILARITY_THRESHOLD，最高相似度低于该值时按无答案策略回复；
+	// 高于 RAG_MIN_SCORE 时同时作为逐块最低相似度
+	Threshold *float64 `json:"threshold,omitempty"`
+	// MinScore、RelativeCutoff 覆盖 RAG_MIN_SCORE、RAG_RELATIVE_CUTOFF 的逐块过滤条件
+	MinScore       *float64 `json:"min_score,omitempty"`
+	RelativeCutoff *float64 `json:"relative_cutoff,omitempty"`
 }
 
 type RAGAskResponse struct {
-	Success        bool    `json:"success"`
-	Message        string  `json:"message"`
-	Query          string  `json:"query,omitempty"`
-	Answer         string  `json:"answer,omitempty"`
-	RetrievedDocs  int     `json:"retrieved_docs,omitempty"`  // 检索到的文档数量
-	MaxScore       float64 `json:"max_score,omitempty"`       // 最高相似度分数
-	BelowThreshold bool    `json:"below_threshold,omitempty"` // 是否低于阈值
-	Error          string  `json:"error,omitempty"`
+	Success        bool         `json:"success"`
+	Message        string       `json:"message"`
+	Query          string       `json:"query,omitempty"`
+	Answer         string       `json:"answer,omitempty"`
+	Provider       string       `json:"provider,omitempty"` // 实际应答的模型提供方
+	Prompt         string       `json:"prompt,omitempty"`   // 使用的提示词模板（名称@版本）
+	Usage          *usage.Usage `json:"usage,omitempty"`
+	RetrievedDocs  int          `json:"retrieved_docs,omitempty"`  // 检索到的文档数量
+	MaxScore       float64      `json:"max_score,omitempty"`       // 最高相似度分数
+	Threshold      float64      `json:"threshold,omitempty"`       // 本次使用的相似度阈值
+	BelowThreshold bool         `json:"below_threshold,omitempty"` // 是否低于阈值
+	// NoAnswerReason 按无答案策略回复的原因：below_threshold 或 filtered_out
+	NoAnswerReason string `json:"no_answer_reason,omitempty"`
+	// NoAnswerPolicy 没有足够相关的文档时采用的无答案策略：refuse、answer 或 clarify
+	NoAnswerPolicy string `json:"no_answer_policy,omitempty"`
+	// ContextTokens 放入提示词的文档估算 token 数，UsedChunks 为放入的块，
+	// DroppedChunks 为相似度过低、重复或超出 RAG_CONTEXT_BUDGET 而丢弃的块
+	ContextTokens int            `json:"context_tokens,omitempty"`
+	UsedChunks    []string       `json:"used_chunks,omitempty"`
+	DroppedChunks []pack.Dropped `json:"dropped_chunks,omitempty"`
+	Error         string         `json:"error,omitempty"`
 }
 
 // RAGAsk 处理 RAG 提问（从知识库检索并回答）
 func RAGAsk(c *gin.Context) {
-	ctx := context.Background()
+	ctx := c.Request.Context()
+	usage.FromContext(ctx).SetCollection(config.Cfg.MilvusConf.CollectionName)
 
 	// 获取用户问题
 	var req RAGAskRequest
@@ -53,6 +92,48 @@ func RAGAsk(c *gin.Context) {
 		return
 	}
 
+	relevance, err := ragRelevanceFor(ctx, req.Threshold, req.MinScore, req.RelativeCutoff)
+	if err != nil {
+		status, message := http.StatusInternalServerError, "读取集合度量类型失败"
+		if errors.Is(err, errInvalidRelevance) {
+			status, message = http.StatusBadRequest, "相似度阈值无效"
+		}
+		c.JSON(status, RAGAskResponse{
+			Success: false,
+			Message: message,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	cm, err := chat_model.Get(req.Model)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, RAGAskResponse{
+			Success: false,
+			Message: "模型选择失败",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if err := chat_model.ValidateParams(req.Model, req.GenerationParams); err != nil {
+		c.JSON(http.StatusBadRequest, RAGAskResponse{
+			Success: false,
+			Message: "生成参数不合法",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	tmpl, err := resolvePrompt(req.PromptID, req.SystemPrompt, prompt_template.DefaultRAG)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, RAGAskResponse{
+			Success: false,
+			Message: "提示词模板无效",
+			Error:   err.Error(),
+		})
+		return
+	}
+
 	log.Printf("开始执行 RAG 检索，问题: %s", req.Query)
 
 	// 构建检索图
@@ -66,7 +147,11 @@ func RAGAsk(c *gin.Context) {
 	}
 
 	// 执行检索（输入 query string，输出 []*schema.Document）
-	docs, err := retrieverRunner.Invoke(ctx, req.Query)
+	var invokeOpts []compose2.Option
+	if len(req.SearchParams) > 0 {
+		invokeOpts = append(invokeOpts, compose2.WithRetrieverOption(retriever.WithSearchParams(req.SearchParams)))
+	}
+	docs, err := retrieverRunner.Invoke(ctx, req.Query, invokeOpts...)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, RAGAskResponse{
 			Success: false,
@@ -78,37 +163,10 @@ func RAGAsk(c *gin.Context) {
 
 	log.Printf("检索成功，共找到 %d 个相关文档", len(docs))
 
-	// 检查是否检索到文档
-	if len(docs) == 0 {
-		log.Printf("未检索到任何相关文档")
-		c.JSON(http.StatusOK, RAGAskResponse{
-			Success:        true,
-			Message:        "知识库中未找到相关信息",
-			Query:          req.Query,
-			Answer:         "抱歉，知识库中不存在与您的问题相关的信息。",
-			RetrievedDocs:  0,
-			BelowThreshold: true,
-		})
-		return
-	}
-
 	// 打印检索到的文档（用于排查相似度问题）
 	maxScore := 0.0
 	for i, doc := range docs {
-		score := doc.Score()
-		// 兼容部分召回器把分数放在 metadata 的情况
-		if score == 0 {
-			if raw, ok := doc.MetaData["score"]; ok {
-				if v, ok := raw.(float64); ok {
-					score = v
-				}
-			}
-			if raw, ok := doc.MetaData["distance"]; ok {
-				if v, ok := raw.(float64); ok {
-					score = 1 - v
-				}
-			}
-		}
+		score := docScore(doc)
 		if score > maxScore {
 			maxScore = score
 		}
@@ -120,55 +178,63 @@ func RAGAsk(c *gin.Context) {
 		log.Printf("召回文档[%d] ID=%s score=%.6f content=%s metadata=%v", i, doc.ID, score, contentPreview, doc.MetaData)
 	}
 
-	// 检查相似度阈值（使用最高相似度分数）
-	similarityThreshold := 0.7 // 默认值
-	if config.Cfg != nil && config.Cfg.MilvusConf.SimilarityThreshold != "" {
-		if threshold, err := strconv.ParseFloat(config.Cfg.MilvusConf.SimilarityThreshold, 64); err == nil {
-			similarityThreshold = threshold
-		} else {
-			log.Printf("警告: 相似度阈值配置无效，使用默认值 0.7: %v", err)
-		}
-	}
-
+	// 检查相似度阈值（使用最高相似度分数），请求中的 threshold 优先
+	similarityThreshold := relevance.Threshold
 	log.Printf("最高相似度分数: %.4f, 阈值: %.4f", maxScore, similarityThreshold)
 
-	// 7. 如果相似度低于阈值，返回提示信息
-	if maxScore < similarityThreshold {
-		log.Printf("相似度低于阈值，返回提示信息")
+	// 逐块过滤：低于最低相似度或与最高分相差超过相对阈值的块不放入上下文
+	relevant, filtered := pack.Filter(docs, relevance.Filter)
+
+	// 没有足够相关的文档时按无答案策略回复
+	if reason := relevance.noAnswerReason(len(docs), maxScore, len(relevant)); reason != "" {
+		policy := ragNoAnswerPolicy()
+		log.Printf("没有足够相关的文档（%s），按无答案策略 %s 回复", reason, policy)
+		answer, prompt, err := answerWithoutDocuments(ctx, cm, policy, req.Query, ragRefusal(len(docs)), req.GenerationParams.Options()...)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, RAGAskResponse{
+				Success: false,
+				Message: fmt.Sprintf("生成回答失败: %v", err),
+				Error:   err.Error(),
+			})
+			return
+		}
+		message := "检索到的文档相似度较低"
+		switch {
+		case len(docs) == 0:
+			message = "知识库中未找到相关信息"
+		case reason == NoAnswerFilteredOut:
+			message = "检索到的文档均未通过逐块过滤"
+		}
 		c.JSON(http.StatusOK, RAGAskResponse{
 			Success:        true,
-			Message:        "检索到的文档相似度较低",
+			Message:        message,
 			Query:          req.Query,
-			Answer:         "抱歉，知识库中不存在与您的问题高度相关的信息。",
+			Answer:         answer.Content,
+			Provider:       chat_model.ProviderOf(answer),
+			Prompt:         prompt,
+			Usage:          usage.FromContext(ctx).Summary(),
 			RetrievedDocs:  len(docs),
 			MaxScore:       maxScore,
-			BelowThreshold: true,
+			Threshold:      similarityThreshold,
+			BelowThreshold: reason == NoAnswerBelowThreshold,
+			NoAnswerReason: reason,
+			NoAnswerPolicy: policy,
+			DroppedChunks:  filtered,
 		})
 		return
 	}
 
-	// 相似度达到阈值，格式化文档并生成回答
-	var documentsText string
-	for i, doc := range docs {
-		score := doc.Score()
-		if score == 0 {
-			if raw, ok := doc.MetaData["score"]; ok {
-				if v, ok := raw.(float64); ok {
-					score = v
-				}
-			}
-			if raw, ok := doc.MetaData["distance"]; ok {
-				if v, ok := raw.(float64); ok {
-					score = 1 - v
-				}
-			}
-		}
-		documentsText += fmt.Sprintf("文档 %d (相似度: %.4f):\n%s\n\n",
-			i+1, score, doc.Content)
+	// 相似度达到阈值，按上下文预算打包文档并生成回答
+	packed, err := packRAGDocuments(ctx, req.Model, tmpl, req.Query, relevant)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, RAGAskResponse{
+			Success: false,
+			Message: fmt.Sprintf("构建上下文失败: %v", err),
+			Error:   err.Error(),
+		})
+		return
 	}
-
-	// 构建提示词并调用 ChatModel
-	answer, err := generateRAGAnswer(ctx, req.Query, documentsText)
+	answer, err := generateRAGAnswer(ctx, cm, tmpl, req.Query, formatRAGDocuments(packed.Passages), req.GenerationParams.Options()...)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, RAGAskResponse{
 			Success: false,
@@ -183,43 +249,280 @@ func RAGAsk(c *gin.Context) {
 		Message:        "检索成功并生成回答",
 		Query:          req.Query,
 		Answer:         answer.Content,
+		Provider:       chat_model.ProviderOf(answer),
+		Prompt:         tmpl.ID(),
+		Usage:          usage.FromContext(ctx).Summary(),
 		RetrievedDocs:  len(docs),
 		MaxScore:       maxScore,
 		BelowThreshold: false,
+		Threshold:      similarityThreshold,
+		ContextTokens:  packed.Tokens,
+		UsedChunks:     packed.ChunkIDs(),
+		DroppedChunks:  append(filtered, packed.Dropped...),
 	})
 }
 
-// generateRAGAnswer 基于检索到的文档生成回答
-func generateRAGAnswer(ctx context.Context, query, documentsText string) (*schema.Message, error) {
-	// 检查模型是否已初始化
-	if chat_model.CM == nil {
-		return nil, fmt.Errorf("ChatModel 未初始化")
+// docScore 读取文档相似度，兼容部分召回器把分数放在 metadata 的情况
+func docScore(doc *schema.Document) float64 {
+	score := doc.Score()
+	if score == 0 {
+		if v, ok := doc.MetaData["score"].(float64); ok {
+			score = v
+		}
 	}
+	return score
+}
 
-	// 创建 ChatTemplate
-	chatTemplate := prompt.FromMessages(
-		schema.FString,
-		schema.SystemMessage(`你是一个有用的助手。请基于以下检索到的文档内容回答用户的问题。
-如果文档中没有相关信息，请说明你不知道。
+// ragSimilarityThreshold 读取配置的相似度阈值，最高分低于阈值时不生成回答
+func ragSimilarityThreshold() float64 {
+	similarityThreshold := 0.7 // 默认值
+	if config.Cfg != nil && config.Cfg.MilvusConf.SimilarityThreshold != "" {
+		if threshold, err := strconv.ParseFloat(config.Cfg.MilvusConf.SimilarityThreshold, 64); err == nil {
+			similarityThreshold = threshold
+		} else {
+			log.Printf("警告: 相似度阈值配置无效，使用默认值 0.7: %v", err)
+		}
+	}
+	return similarityThreshold
+}
 
-检索到的文档：
-{documents}`),
-		schema.UserMessage("{query}"),
-	)
+// ragMetric 返回知识库检索分数对应的度量类型：进程内向量存储按余弦相似度检索，
+// milvus 以集合现有索引的度量类型为准，集合未建索引时才取索引配置
+func ragMetric(ctx context.Context) (entity.MetricType, error) {
+	if config.Cfg.VectorDBType == "memory" {
+		return entity.COSINE, nil
+	}
+	collection := config.Cfg.MilvusConf.CollectionName
+	if db.Milvus != nil {
+		metric, err := db.CollectionMetric(ctx, collection)
+		if err != nil {
+			return "", err
+		}
+		if metric != "" {
+			return metric, nil
+		}
+	}
+	spec, err := db.IndexSpecFor(collection)
+	if err != nil {
+		return "", err
+	}
+	return entity.MetricType(spec.MetricType), nil
+}
 
-	// 格式化模板
-	data := map[string]any{
-		"query":     query,
-		"documents": documentsText,
+// validateThreshold 检查相似度阈值是否在度量的取值范围内：COSINE 与按 1/(1+d) 换算的 L2 为 [0,1]，IP 的分数没有固定范围
+func validateThreshold(threshold float64, metric entity.MetricType) error {
+	if metric == entity.IP {
+		return nil
 	}
+	if threshold < 0 || threshold > 1 {
+		return fmt.Errorf("threshold must be between 0 and 1 for metric %s, got %g", metric, threshold)
+	}
+	return nil
+}
+
+// 无答案的原因
+const (
+	NoAnswerBelowThreshold = "below_threshold" // 没有检索到文档或最高相似度低于阈值
+	NoAnswerFilteredOut    = "filtered_out"    // 最高相似度达到阈值，但所有块都被逐块过滤丢弃
+)
+
+// errInvalidRelevance 请求中的阈值或逐块过滤参数无效
+var errInvalidRelevance = errors.New("invalid relevance parameters")
+
+// ragRelevance 一次问答使用的相关性条件：最高相似度阈值与逐块过滤
+type ragRelevance struct {
+	Threshold float64
+	Filter    pack.FilterOptions
+}
+
+// ragRelevanceFor 合并配置与请求中的覆盖项。请求的 threshold 高于 RAG_MIN_SCORE 时同时作为逐块最低相似度，
+// 保证放入上下文的块都达到本次阈值；显式给出的 minScore、relativeCutoff 优先
+func ragRelevanceFor(ctx context.Context, threshold, minScore, relativeCutoff *float64) (ragRelevance, error) {
+	r := ragRelevance{Threshold: ragSimilarityThreshold(), Filter: ragFilterOptions()}
+	if threshold != nil || minScore != nil {
+		metric, err := ragMetric(ctx)
+		if err != nil {
+			return r, err
+		}
+		if threshold != nil {
+			if err := validateThreshold(*threshold, metric); err != nil {
+				return r, fmt.Errorf("%w: %v", errInvalidRelevance, err)
+			}
+			r.Threshold = *threshold
+			r.Filter.MinScore = max(r.Filter.MinScore, *threshold)
+		}
+		if minScore != nil {
+			if err := validateThreshold(*minScore, metric); err != nil {
+				return r, fmt.Errorf("%w: min_score: %v", errInvalidRelevance, err)
+			}
+			r.Filter.MinScore = *minScore
+		}
+	}
+	if relativeCutoff != nil {
+		if *relativeCutoff < 0 {
+			return r, fmt.Errorf("%w: relative_cutoff must not be negative, got %g", errInvalidRelevance, *relativeCutoff)
+		}
+		r.Filter.RelativeCutoff = *relativeCutoff
+	}
+	return r, nil
+}
+
+// noAnswerReason 判断检索结果是否足以回答，返回空表示可以基于保留的块生成回答
+func (r ragRelevance) noAnswerReason(retrieved int, maxScore float64, relevant int) string {
+	switch {
+	case retrieved == 0 || maxScore < r.Threshold:
+		return NoAnswerBelowThreshold
+	case relevant == 0:
+		return NoAnswerFilteredOut
+	}
+	return ""
+}
+
+// ragFilterOptions 读取逐块过滤配置，配置无效时不按该项过滤
+func ragFilterOptions() pack.FilterOptions {
+	opts := pack.FilterOptions{Score: docScore}
+	if config.Cfg == nil {
+		return opts
+	}
+	conf := config.Cfg.RAGConf
+	if conf.MinScore != "" {
+		if v, err := strconv.ParseFloat(conf.MinScore, 64); err == nil {
+			opts.MinScore = v
+		} else {
+			log.Printf("警告: RAG_MIN_SCORE 配置无效，不按最低相似度过滤: %v", err)
+		}
+	}
+	if conf.RelativeCutoff != "" {
+		if v, err := strconv.ParseFloat(conf.RelativeCutoff, 64); err == nil && v >= 0 {
+			opts.RelativeCutoff = v
+		} else {
+			log.Printf("警告: RAG_RELATIVE_CUTOFF 配置无效，不按相对分数过滤: %q", conf.RelativeCutoff)
+		}
+	}
+	return opts
+}
+
+// 无答案策略：没有足够相关的文档时的回复方式
+const (
+	NoAnswerRefuse  = "refuse"  // 固定回复知识库中没有相关信息
+	NoAnswerGeneral = "answer"  // 不带文档，由模型直接回答
+	NoAnswerClarify = "clarify" // 由模型向用户提出一个澄清问题
+)
+
+const clarifyPrompt = `The knowledge base has no content relevant enough to the user's question, so do not answer it.
+Ask the user one short clarifying question, in the language of the question, that would help find the right information: for example which product, version, term or scenario they mean.
+Reply with the question only.`
+
+// ragNoAnswerPolicy 读取配置的无答案策略，默认 refuse
+func ragNoAnswerPolicy() string {
+	if config.Cfg == nil {
+		return NoAnswerRefuse
+	}
+	switch policy := config.Cfg.RAGConf.NoAnswerPolicy; policy {
+	case NoAnswerRefuse, NoAnswerGeneral, NoAnswerClarify:
+		return policy
+	case "":
+		return NoAnswerRefuse
+	default:
+		log.Printf("警告: RAG_NO_ANSWER_POLICY 配置无效，使用 refuse: %q", policy)
+		return NoAnswerRefuse
+	}
+}
+
+// ragRefusal refuse 策略的固定回复
+func ragRefusal(retrieved int) string {
+	if retrieved == 0 {
+		return "抱歉，知识库中不存在与您的问题相关的信息。"
+	}
+	return "抱歉，知识库中不存在与您的问题高度相关的信息。"
+}
+
+// answerWithoutDocuments 按无答案策略回复，返回回答与使用的提示词模板（名称@版本，未使用模板时为空）
+func answerWithoutDocuments(ctx context.Context, cm model.BaseChatModel, policy, query, refusal string, opts ...model.Option) (*schema.Message, string, error) {
+	switch policy {
+	case NoAnswerGeneral:
+		tmpl, err := prompt_template.Resolve("", prompt_template.DefaultChat)
+		if err != nil {
+			return nil, "", err
+		}
+		messages, err := tmpl.Format(ctx, nil, query, "", false)
+		if err != nil {
+			return nil, "", fmt.Errorf("格式化模板失败: %w", err)
+		}
+		answer, err := cm.Generate(ctx, messages, opts...)
+		if err != nil {
+			return nil, "", fmt.Errorf("生成回答失败: %w", err)
+		}
+		return answer, tmpl.ID(), nil
+	case NoAnswerClarify:
+		answer, err := cm.Generate(ctx, []*schema.Message{schema.SystemMessage(clarifyPrompt), schema.UserMessage(query)}, opts...)
+		if err != nil {
+			return nil, "", fmt.Errorf("生成澄清问题失败: %w", err)
+		}
+		return answer, "", nil
+	default:
+		return schema.AssistantMessage(refusal, nil), "", nil
+	}
+}
+
+// ragContextBudget 读取配置的 RAG 输入 token 上限，0 表示不限制
+func ragContextBudget() int {
+	if config.Cfg == nil {
+		return 0
+	}
+	budget, err := strconv.Atoi(config.Cfg.RAGConf.ContextBudget)
+	if err != nil || budget < 0 {
+		if config.Cfg.RAGConf.ContextBudget != "" {
+			log.Printf("警告: RAG_CONTEXT_BUDGET 配置无效，不限制上下文: %q", config.Cfg.RAGConf.ContextBudget)
+		}
+		return 0
+	}
+	return budget
+}
+
+// packRAGDocuments 按上下文预算打包检索到的文档：预算扣除提示词与问题后剩余的部分留给文档，
+// 按应答提供方估算 token，重复与超出预算的块记录在结果中
+func packRAGDocuments(ctx context.Context, modelName string, tmpl *prompt_template.Template, query string, docs []*schema.Document) (*pack.Result, error) {
+	counter := token.ForProvider(chat_model.PrimaryProvider(modelName))
+	budget := ragContextBudget()
+	if budget > 0 {
+		messages, err := tmpl.Format(ctx, nil, query, "", true)
+		if err != nil {
+			return nil, fmt.Errorf("格式化模板失败: %w", err)
+		}
+		reserved := counter.CountMessages(messages)
+		if reserved >= budget {
+			return nil, fmt.Errorf("提示词与问题约 %d token，已超出 RAG_CONTEXT_BUDGET (%d)", reserved, budget)
+		}
+		budget -= reserved
+	}
+
+	packed := pack.Pack(docs, pack.Options{Budget: budget, Counter: counter, Score: docScore})
+	for _, d := range packed.Dropped {
+		log.Printf("文档块 %s (score=%.4f) 未放入上下文: %s", d.ID, d.Score, d.Reason)
+	}
+	return packed, nil
+}
+
+// formatRAGDocuments 把打包后的文档格式化为提示词中的 {documents}
+func formatRAGDocuments(passages []pack.Passage) string {
+	var documentsText string
+	for i, p := range passages {
+		documentsText += fmt.Sprintf("文档 %d (相似度: %.4f):\n%s\n\n",
+			i+1, p.Score, p.Content)
+	}
+	return documentsText
+}
 
-	messages, err := chatTemplate.Format(ctx, data)
+// generateRAGAnswer 基于检索到的文档按提示词模板生成回答
+func generateRAGAnswer(ctx context.Context, cm model.BaseChatModel, tmpl *prompt_template.Template, query, documentsText string, opts ...model.Option) (*schema.Message, error) {
+	messages, err := tmpl.Format(ctx, nil, query, documentsText, true)
 	if err != nil {
 		return nil, fmt.Errorf("格式化模板失败: %w", err)
 	}
 
 	// 调用 ChatModel 生成回答
-	answer, err := chat_model.CM.Generate(ctx, messages)
+	answer, err := cm.Generate(ctx, messages, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("生成回答失败: %w", err)
 	}
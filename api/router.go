@@ -1,6 +1,7 @@
 package api
 
 import (
+	"go-agent/config"
 	"log"
 	"os"
 	"path/filepath"
@@ -8,15 +9,25 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// Run 启动 HTTP 服务
 func Run() {
+	r := NewRouter()
+	err := r.Run(":8080")
+	if err != nil {
+		log.Fatalf("run fail: %v", err)
+	}
+}
+
+// NewRouter 创建注册了全部路由的 gin 引擎
+func NewRouter() *gin.Engine {
 	r := gin.Default()
 	r.MaxMultipartMemory = 50 << 20
 
 	// 添加 CORS 中间件
 	r.Use(func(c *gin.Context) {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, DELETE, OPTIONS")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-API-Key, X-Session-ID")
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
@@ -26,6 +37,9 @@ func Run() {
 		c.Next()
 	})
 
+	// 用量统计中间件
+	r.Use(UsageMiddleware())
+
 	// 静态文件服务 - 提供测试页面
 	// 获取项目根目录（相对于当前工作目录）
 	workDir, err := os.Getwd()
@@ -134,15 +148,65 @@ func Run() {
 	// 添加聊天测试路由
 	r.POST("/api/chat/test", ChatGenerate)
 	r.POST("/api/chat/test/stream", ChatStream)
+	r.GET("/api/chat/providers", ChatProviders)
+
+	// 工具调用 Agent
+	r.POST("/api/agent/run", AgentRun)
+	r.POST("/api/agent/run/stream", AgentRunStream)
+	r.GET("/api/agent/tools", ListAgentTools)
+	r.GET("/api/agent/runs/:id", GetAgentRun)
+	r.POST("/api/agent/runs/:id/approve", ApproveAgentRun)
+	r.POST("/api/agent/runs/:id/reject", RejectAgentRun)
+	r.POST("/api/agent/runs/:id/resume", ResumeAgentRun)
+	r.POST("/api/agent/runs/:id/resume/stream", ResumeAgentRunStream)
+
+	// 计划模式：先生成步骤计划，再逐步执行，失败时重新规划
+	r.POST("/api/agent/plan", PlanRun)
+	r.POST("/api/agent/plan/stream", PlanRunStream)
+
+	// 多 Agent：主管把子任务交给配置文件中的成员
+	r.GET("/api/agent/team", GetTeam)
+	r.POST("/api/agent/team/run", TeamRun)
+	r.POST("/api/agent/team/run/stream", TeamRunStream)
+
+	// 长期记忆：从结束的对话中提取的用户记忆
+	r.GET("/api/memories", ListMemories)
+	r.POST("/api/memories/extract", ExtractMemories)
+	r.DELETE("/api/memories/:id", DeleteMemory)
+
+	// 已加载的模型列表
+	r.GET("/api/models", ListModels)
+
+	// 用量与费用统计
+	r.GET("/api/usage", GetUsage)
+
+	// 提示词模板管理
+	r.GET("/api/prompts", ListPrompts)
+	r.POST("/api/prompts", CreatePrompt)
+	r.GET("/api/prompts/:name", GetPrompt)
+	r.PUT("/api/prompts/:name", UpdatePrompt)
+	r.DELETE("/api/prompts/:name", DeletePrompt)
+	r.GET("/api/prompts/:name/versions", ListPromptVersions)
+
+	// 嵌入缓存统计
+	r.GET("/api/embedding/cache", GetEmbeddingCacheStats)
 
 	// RAG 召回问答
 	r.POST("/api/rag/ask", RAGAsk)
 	// Milvus 集合管理
 	r.GET("/api/milvus/collections", ListMilvusCollections)
 	r.DELETE("/api/milvus/collections/:name", DeleteMilvusCollection)
-
-	err = r.Run(":8080")
-	if err != nil {
-		log.Fatalf("run fail: %v", err)
+	// 集合统计与内容查看
+	r.GET("/api/collections/:name", GetCollectionStats)
+	r.GET("/api/collections/:name/chunks", ListCollectionChunks)
+	// 集合备份与恢复
+	r.GET("/api/collections/:name/export", ExportCollection)
+	r.POST("/api/collections/:name/import", ImportCollection)
+
+	// 以 MCP（Streamable HTTP）暴露知识库
+	if config.Cfg == nil || config.Cfg.MCPConf.ServerHTTP != "false" {
+		r.Any(MCPPath, gin.WrapH(NewMCPHandler()))
 	}
+
+	return r
 }
@@ -0,0 +1,113 @@
+package api
+
+import (
+	"go-agent/model/usage"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type UsageResponse struct {
+	Success bool          `json:"success"`
+	Message string        `json:"message,omitempty"`
+	Report  *usage.Report `json:"report,omitempty"`
+}
+
+// UsageMiddleware 为每个请求挂载用量收集器，请求结束后写入用量存储
+// 会话取自 X-Session-ID，API Key 取自 X-API-Key 或 Authorization: Bearer，仅保存指纹
+func UsageMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		apiKey := c.GetHeader("X-API-Key")
+		if apiKey == "" {
+			apiKey = strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		}
+
+		ctx, recorder := usage.NewContext(c.Request.Context(), usage.Scope{
+			Session:  c.GetHeader("X-Session-ID"),
+			APIKey:   usage.KeyID(apiKey),
+			Endpoint: c.FullPath(),
+		})
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+
+		if usage.Store == nil {
+			return
+		}
+		if err := usage.Store.Append(recorder.Records()); err != nil {
+			log.Printf("写入用量记录失败: %v", err)
+		}
+	}
+}
+
+// GetUsage 查询用量与费用，from/to 支持 RFC3339 或 2006-01-02（to 为日期时包含当天）
+// group_by 可选 session,api_key,collection,endpoint,kind,provider,model,day,month，多个用逗号分隔
+func GetUsage(c *gin.Context) {
+	if usage.Store == nil {
+		c.JSON(http.StatusInternalServerError, UsageResponse{
+			Success: false,
+			Message: "用量存储未初始化",
+		})
+		return
+	}
+
+	from, err := parseUsageTime(c.Query("from"), false)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, UsageResponse{
+			Success: false,
+			Message: "from 参数无效: " + err.Error(),
+		})
+		return
+	}
+	to, err := parseUsageTime(c.Query("to"), true)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, UsageResponse{
+			Success: false,
+			Message: "to 参数无效: " + err.Error(),
+		})
+		return
+	}
+
+	var groupBy []string
+	for _, field := range strings.Split(c.Query("group_by"), ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			groupBy = append(groupBy, field)
+		}
+	}
+
+	report, err := usage.Store.Query(usage.Query{From: from, To: to, GroupBy: groupBy})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, UsageResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, UsageResponse{
+		Success: true,
+		Report:  report,
+	})
+}
+
+// parseUsageTime 解析查询时间，endOfDay 为 true 时日期格式取次日零点作为开区间上界
+func parseUsageTime(s string, endOfDay bool) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+
+	t, err := time.ParseInLocation("2006-01-02", s, time.Local)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
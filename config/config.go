@@ -7,16 +7,42 @@ import (
 )
 
 type Config struct {
-	// 模型类型配置
+	// 模型类型配置，ChatModelType 支持按优先级配置多个，如 "ark,qwen,openai"
 	ChatModelType      string
 	EmbeddingModelType string
 	VectorDBType       string
 
+	// ChatGenerationDefaults 各提供方的默认生成参数，JSON: {"ark":{"temperature":0.3,"max_tokens":2048}}
+	ChatGenerationDefaults string
+
 	ArkConf    ArkConfig
 	OpenAIConf OpenAIConfig
 	QwenConf   QwenConfig
 
+	OllamaConf           OllamaConfig
+	OpenAICompatibleConf OpenAICompatibleConfig
+	FakeConf             FakeConfig
+
+	ChatFallbackConf ChatFallbackConfig
+
 	MilvusConf MilvusConfig
+
+	EmbeddingCacheConf EmbeddingCacheConfig
+	EmbeddingBatchConf EmbeddingBatchConfig
+
+	UsageConf UsageConfig
+
+	PromptConf PromptConfig
+
+	AgentConf AgentConfig
+
+	MCPConf MCPConfig
+
+	MemoryConf MemoryConfig
+
+	ChatContextConf ChatContextConfig
+
+	RAGConf RAGConfig
 }
 
 type ArkConfig struct {
@@ -26,6 +52,7 @@ type ArkConfig struct {
 }
 
 type OpenAIConfig struct {
+	BaseUrl         string
 	OpenAIKey       string
 	OpenAIChatModel string
 	OpenAIEmbedding string
@@ -38,6 +65,122 @@ type QwenConfig struct {
 	QwenEmbedding string
 }
 
+// ChatFallbackConfig 多聊天模型故障转移配置
+type ChatFallbackConfig struct {
+	MaxRetries       string // 单个提供方遇到 429/5xx 时的重试次数
+	BreakerThreshold string // 连续失败多少次后熔断该提供方
+	BreakerCooldown  string // 熔断持续时间，如 30s，到期后放行一次试探请求
+}
+
+// UsageConfig 用量与费用统计配置
+type UsageConfig struct {
+	StorePath string // 用量记录文件（JSONL），为空时只保存在内存中
+	Prices    string // 价格表 JSON，每百万 token 单价：{"ark/模型名":{"prompt":0.8,"completion":2}}
+}
+
+// PromptConfig 提示词模板配置
+type PromptConfig struct {
+	Dir            string // 模板目录，每个模板一个子目录，每个版本一个 v<版本>.json
+	ReloadInterval string // 轮询目录变化的间隔，如 5s，0 表示不自动重新加载
+}
+
+// AgentConfig 工具调用 Agent 配置
+type AgentConfig struct {
+	MaxIterations string // 单次运行最多调用模型的轮数，也是请求可设置的上限
+	// Tools 启用的内置工具：search_knowledge_base,calculator,datetime,http_get,sql_query,run_starlark
+	Tools         string
+	HTTPAllowlist string // http_get 允许访问的主机，逗号分隔，支持 *.example.com
+	TableDir      string // sql_query 与 run_starlark 使用的 CSV 表目录，上传的 CSV 文件会保存到这里
+	SQLMaxRows    string // sql_query 单次最多返回的行数，也是 run_starlark 每张产出表的行数上限
+
+	ScriptMaxSteps    string // run_starlark 最多执行的指令数
+	ScriptTimeout     string // run_starlark 运行超时，如 10s
+	ScriptMaxOutput   string // run_starlark print 输出的字节上限
+	ScriptMaxMemoryMB string // run_starlark 脚本子进程可新增的内存上限（MB），超出时子进程被终止
+
+	// ToolApproval 工具审批策略，逗号分隔的 工具名=always|never，工具名可用 * 结尾匹配前缀，如 github_*=always
+	ToolApproval string
+	RunDir       string // 运行记录（每一步的消息与轨迹）与检查点目录
+
+	TeamFile string // 多 Agent 配置文件：{"supervisor":{...},"agents":{"名称":{...}}}，为空时不启用
+
+	PlanMaxSteps   string // 计划模式单个计划最多的步骤数，也是请求可设置的上限
+	PlanMaxReplans string // 计划模式步骤失败后最多重新规划的次数，0 表示不重新规划
+}
+
+// MCPConfig MCP 客户端与服务器配置，客户端连接的服务器列表写在 JSON 文件中
+type MCPConfig struct {
+	ServersFile       string // 服务器配置文件，格式同 {"mcpServers":{"名称":{...}}}，为空时不启用
+	Timeout           string // 连接与单次工具调用的超时，如 30s
+	ReconnectInterval string // 检查连接、重连断开服务器的间隔，如 30s
+
+	ServerHTTP string // 是否在 /mcp 以 Streamable HTTP 暴露知识库工具，true/false
+}
+
+// MemoryConfig 长期记忆配置：从结束的对话中提取关于用户的记忆，之后的对话检索相关记忆加入系统提示词
+type MemoryConfig struct {
+	Enabled    string // 是否启用，true/false
+	Collection string // milvus 模式下保存记忆的集合，不能与知识库集合相同
+	Model      string // 提取记忆使用的聊天模型，为空时使用默认故障转移链
+	TopK       string // 每次对话最多加入系统提示词的记忆条数
+	MinScore   string // 记忆与问题的最低相似度，低于该值的记忆不加入系统提示词
+	DedupScore string // 新记忆与已有记忆的相似度不低于该值时视为重复，不再写入
+}
+
+// ChatContextConfig 对话上下文预算：历史对话超出预算时把较早的轮次总结为摘要，按会话缓存
+type ChatContextConfig struct {
+	Budget          string // 单次请求输入（提示词、历史与问题）的 token 上限，0 表示不限制
+	ProviderBudgets string // 按提供方覆盖预算，JSON: {"ollama":4000,"qwen":30000}
+	SummaryModel    string // 生成摘要使用的聊天模型，为空时使用本次对话的模型
+	SummaryTokens   string // 为摘要预留的 token 数，也是要求模型控制的摘要长度
+	CacheSize       string // 缓存摘要的会话数，会话由请求头 X-Session-ID 区分
+}
+
+// RAGConfig RAG 问答配置
+type RAGConfig struct {
+	// ContextBudget 单次问答输入（提示词、问题与文档）的 token 上限，超出时按相似度从低到高丢弃文档块，0 表示不限制
+	ContextBudget string
+
+	MinScore       string // 逐块最低相似度，低于该值的块不放入上下文
+	RelativeCutoff string // 只保留与最高分相差不超过该值的块，0 表示不按相对分数过滤
+	// NoAnswerPolicy 没有足够相关的文档时：refuse 固定回复、answer 不带文档直接回答、clarify 向用户提出澄清问题
+	NoAnswerPolicy string
+}
+
+type EmbeddingCacheConfig struct {
+	Size string // 内存 LRU 条目数，0 表示关闭缓存
+	Dir  string // 落盘目录，为空时只使用内存缓存
+}
+
+type EmbeddingBatchConfig struct {
+	BatchSize         string // 单次请求最大文本条数，为空时按提供方默认值
+	Concurrency       string
+	RequestsPerMinute string // 0 表示不限
+	TokensPerMinute   string // 0 表示不限
+	MaxRetries        string
+}
+
+type OllamaConfig struct {
+	BaseUrl        string
+	ChatModel      string
+	EmbeddingModel string
+}
+
+// OpenAICompatibleConfig 任意兼容 OpenAI 接口的服务，如 vLLM、内部网关
+type OpenAICompatibleConfig struct {
+	BaseUrl        string
+	APIKey         string
+	Headers        string // "Key1:Value1,Key2:Value2"
+	ChatModel      string
+	EmbeddingModel string
+}
+
+// FakeConfig 离线测试用的假模型配置
+type FakeConfig struct {
+	ChatReply    string // 固定回复，为空时回显用户消息
+	EmbeddingDim string
+}
+
 type MilvusConfig struct {
 	MilvusAddr          string
 	MilvusUserName      string
@@ -45,6 +188,14 @@ type MilvusConfig struct {
 	SimilarityThreshold string
 	CollectionName      string
 	TopK                string
+
+	// 向量索引配置，参数均为 JSON，例如 {"M":16,"efConstruction":200}、{"ef":64}
+	IndexType    string
+	MetricType   string
+	IndexParams  string
+	SearchParams string
+	// CollectionIndexes 按集合覆盖索引配置，JSON: {"集合名":{"index_type":"HNSW","metric_type":"IP",...}}
+	CollectionIndexes string
 }
 
 var Cfg *Config
@@ -61,12 +212,15 @@ func LoadConfig() (*Config, error) {
 		EmbeddingModelType: getEnv("EMBEDDING_MODEL_TYPE", "ark"),
 		VectorDBType:       getEnv("VECTOR_DB_TYPE", "milvus"),
 
+		ChatGenerationDefaults: getEnv("CHAT_GENERATION_DEFAULTS", ""),
+
 		ArkConf: ArkConfig{
 			ArkKey:            getEnv("ARK_KEY", ""),
 			ArkEmbeddingModel: getEnv("ARK_EMBEDDING_MODEL", "doubao-embedding-text-240715"),
 			ArkChatModel:      getEnv("ARK_CHAT_MODEL", "doubao-seed-1-8-251228"),
 		},
 		OpenAIConf: OpenAIConfig{
+			BaseUrl:         getEnv("OPENAI_BASE_URL", ""),
 			OpenAIKey:       getEnv("OPENAI_KEY", ""),
 			OpenAIChatModel: getEnv("OPENAI_CHAT_MODEL", "gpt-4"),
 			OpenAIEmbedding: getEnv("OPENAI_EMBEDDING_MODEL", ""),
@@ -77,6 +231,27 @@ func LoadConfig() (*Config, error) {
 			QwenEmbedding: getEnv("QWEN_EMBEDDING_MODEL", ""),
 			QwenChatModel: getEnv("QWEN_CHAT_MODEL", ""),
 		},
+		OllamaConf: OllamaConfig{
+			BaseUrl:        getEnv("OLLAMA_BASE_URL", "http://localhost:11434"),
+			ChatModel:      getEnv("OLLAMA_CHAT_MODEL", ""),
+			EmbeddingModel: getEnv("OLLAMA_EMBEDDING_MODEL", ""),
+		},
+		OpenAICompatibleConf: OpenAICompatibleConfig{
+			BaseUrl:        getEnv("OPENAI_COMPATIBLE_BASE_URL", ""),
+			APIKey:         getEnv("OPENAI_COMPATIBLE_KEY", ""),
+			Headers:        getEnv("OPENAI_COMPATIBLE_HEADERS", ""),
+			ChatModel:      getEnv("OPENAI_COMPATIBLE_CHAT_MODEL", ""),
+			EmbeddingModel: getEnv("OPENAI_COMPATIBLE_EMBEDDING_MODEL", ""),
+		},
+		FakeConf: FakeConfig{
+			ChatReply:    getEnv("FAKE_CHAT_REPLY", ""),
+			EmbeddingDim: getEnv("FAKE_EMBEDDING_DIM", "64"),
+		},
+		ChatFallbackConf: ChatFallbackConfig{
+			MaxRetries:       getEnv("CHAT_MAX_RETRIES", "2"),
+			BreakerThreshold: getEnv("CHAT_BREAKER_THRESHOLD", "3"),
+			BreakerCooldown:  getEnv("CHAT_BREAKER_COOLDOWN", "30s"),
+		},
 		MilvusConf: MilvusConfig{
 			MilvusAddr:          getEnv("MILVUS_ADDR", "localhost:27017"),
 			MilvusUserName:      getEnv("MILVUS_USERNAME", ""),
@@ -84,6 +259,76 @@ func LoadConfig() (*Config, error) {
 			SimilarityThreshold: getEnv("MILVUS_SIMILARITY_THRESHOLD", "0.7"),
 			CollectionName:      getEnv("MILVUS_COLLECTION_NAME", "GoAgent"),
 			TopK:                getEnv("MILVUS_TOPK", "10"),
+			IndexType:           getEnv("MILVUS_INDEX_TYPE", "AUTOINDEX"),
+			MetricType:          getEnv("MILVUS_METRIC_TYPE", "COSINE"),
+			IndexParams:         getEnv("MILVUS_INDEX_PARAMS", ""),
+			SearchParams:        getEnv("MILVUS_SEARCH_PARAMS", ""),
+			CollectionIndexes:   getEnv("MILVUS_COLLECTION_INDEXES", ""),
+		},
+		EmbeddingBatchConf: EmbeddingBatchConfig{
+			BatchSize:         getEnv("EMBEDDING_BATCH_SIZE", ""),
+			Concurrency:       getEnv("EMBEDDING_CONCURRENCY", "4"),
+			RequestsPerMinute: getEnv("EMBEDDING_RPM", "0"),
+			TokensPerMinute:   getEnv("EMBEDDING_TPM", "0"),
+			MaxRetries:        getEnv("EMBEDDING_MAX_RETRIES", "3"),
+		},
+		EmbeddingCacheConf: EmbeddingCacheConfig{
+			Size: getEnv("EMBEDDING_CACHE_SIZE", "10000"),
+			Dir:  getEnv("EMBEDDING_CACHE_DIR", ""),
+		},
+		UsageConf: UsageConfig{
+			StorePath: getEnv("USAGE_STORE_PATH", "./data/usage.jsonl"),
+			Prices:    getEnv("USAGE_PRICES", ""),
+		},
+		PromptConf: PromptConfig{
+			Dir:            getEnv("PROMPT_DIR", "./prompts"),
+			ReloadInterval: getEnv("PROMPT_RELOAD_INTERVAL", "5s"),
+		},
+		AgentConf: AgentConfig{
+			MaxIterations: getEnv("AGENT_MAX_ITERATIONS", "8"),
+			Tools:         getEnv("AGENT_TOOLS", "search_knowledge_base,calculator,datetime"),
+			HTTPAllowlist: getEnv("AGENT_HTTP_ALLOWLIST", ""),
+			TableDir:      getEnv("AGENT_TABLE_DIR", "./data/tables"),
+			SQLMaxRows:    getEnv("AGENT_SQL_MAX_ROWS", "200"),
+
+			ScriptMaxSteps:    getEnv("AGENT_SCRIPT_MAX_STEPS", "10000000"),
+			ScriptTimeout:     getEnv("AGENT_SCRIPT_TIMEOUT", "10s"),
+			ScriptMaxOutput:   getEnv("AGENT_SCRIPT_MAX_OUTPUT", "16384"),
+			ScriptMaxMemoryMB: getEnv("AGENT_SCRIPT_MAX_MEMORY_MB", "256"),
+
+			ToolApproval: getEnv("AGENT_TOOL_APPROVAL", ""),
+			RunDir:       getEnv("AGENT_RUN_DIR", "./data/runs"),
+			TeamFile:     getEnv("AGENT_TEAM_FILE", ""),
+
+			PlanMaxSteps:   getEnv("AGENT_PLAN_MAX_STEPS", "8"),
+			PlanMaxReplans: getEnv("AGENT_PLAN_MAX_REPLANS", "2"),
+		},
+		MCPConf: MCPConfig{
+			ServersFile:       getEnv("MCP_SERVERS_FILE", ""),
+			Timeout:           getEnv("MCP_TIMEOUT", "30s"),
+			ReconnectInterval: getEnv("MCP_RECONNECT_INTERVAL", "30s"),
+			ServerHTTP:        getEnv("MCP_SERVER_HTTP", "true"),
+		},
+		MemoryConf: MemoryConfig{
+			Enabled:    getEnv("MEMORY_ENABLED", "false"),
+			Collection: getEnv("MEMORY_COLLECTION", "GoAgentMemory"),
+			Model:      getEnv("MEMORY_MODEL", ""),
+			TopK:       getEnv("MEMORY_TOPK", "5"),
+			MinScore:   getEnv("MEMORY_MIN_SCORE", "0.3"),
+			DedupScore: getEnv("MEMORY_DEDUP_SCORE", "0.9"),
+		},
+		ChatContextConf: ChatContextConfig{
+			Budget:          getEnv("CHAT_CONTEXT_BUDGET", "8000"),
+			ProviderBudgets: getEnv("CHAT_CONTEXT_PROVIDER_BUDGETS", ""),
+			SummaryModel:    getEnv("CHAT_SUMMARY_MODEL", ""),
+			SummaryTokens:   getEnv("CHAT_SUMMARY_TOKENS", "512"),
+			CacheSize:       getEnv("CHAT_SUMMARY_CACHE_SIZE", "1000"),
+		},
+		RAGConf: RAGConfig{
+			ContextBudget:  getEnv("RAG_CONTEXT_BUDGET", "4000"),
+			MinScore:       getEnv("RAG_MIN_SCORE", "0.5"),
+			RelativeCutoff: getEnv("RAG_RELATIVE_CUTOFF", "0.15"),
+			NoAnswerPolicy: getEnv("RAG_NO_ANSWER_POLICY", "refuse"),
 		},
 	}
 
@@ -2,18 +2,36 @@ package main
 
 import (
 	"context"
+	"flag"
+	"go-agent/agent"
+	"go-agent/agent/mcpclient"
+	"go-agent/agent/team"
+	"go-agent/agent/toolbox"
 	"go-agent/api"
 	"go-agent/config"
+	"go-agent/model/chat_history"
 	"go-agent/model/chat_model"
 	"go-agent/model/embedding_model"
+	"go-agent/model/prompt_template"
+	"go-agent/model/usage"
+	"go-agent/rag/memory"
 	"go-agent/rag/tools"
 	"go-agent/rag/tools/db"
 	"go-agent/rag/tools/indexer"
 	"go-agent/rag/tools/retriever"
 	"log"
+	"time"
 )
 
 func main() {
+	// run_starlark 的脚本在本程序的子进程中运行，子进程不启动服务
+	if toolbox.ServeScriptWorker() {
+		return
+	}
+
+	mcpStdio := flag.Bool("mcp-stdio", false, "以 stdio MCP 服务器模式运行，通过标准输入输出暴露知识库工具，不启动 HTTP 服务")
+	flag.Parse()
+
 	var err error
 	ctx := context.Background()
 
@@ -23,12 +41,35 @@ func main() {
 		log.Fatal("警告: 未找到 .env 文件")
 	}
 
-	// 初始化数据库
-	db.Milvus, err = db.NewMilvus(ctx)
+	// 初始化数据库（memory 模式使用进程内向量存储，无需 Milvus）
+	if config.Cfg.VectorDBType == "milvus" {
+		db.Milvus, err = db.NewMilvus(ctx)
+		if err != nil {
+			log.Fatalf("Milvus init fail: %v", err)
+		}
+		defer db.Milvus.Close()
+	}
+
+	// 初始化用量统计
+	usage.Prices, err = usage.ParsePrices(config.Cfg.UsageConf.Prices)
+	if err != nil {
+		log.Fatalf("usage prices init fail: %v", err)
+	}
+	usage.Store, err = usage.NewStore(config.Cfg.UsageConf.StorePath)
+	if err != nil {
+		log.Fatalf("usage store init fail: %v", err)
+	}
+
+	// 初始化提示词模板，目录被外部修改时自动重新加载
+	prompt_template.Store, err = prompt_template.NewStore(config.Cfg.PromptConf.Dir)
 	if err != nil {
-		log.Fatalf("Milvus init fail: %v", err)
+		log.Fatalf("prompt template init fail: %v", err)
+	}
+	if interval, err := time.ParseDuration(config.Cfg.PromptConf.ReloadInterval); err == nil {
+		go prompt_template.Store.Watch(ctx, interval)
+	} else {
+		log.Printf("警告: PROMPT_RELOAD_INTERVAL 配置无效，不自动重新加载提示词模板: %v", err)
 	}
-	defer db.Milvus.Close()
 
 	// 初始化模型
 	chat_model.CM, err = chat_model.NewChatModel(ctx)
@@ -54,6 +95,20 @@ func main() {
 		log.Fatalf("retriever init fail: %v", err)
 	}
 
+	// 初始化长期记忆，记忆写入专用集合
+	if config.Cfg.MemoryConf.Enabled == "true" {
+		memory.Memories, err = memory.NewStore(ctx)
+		if err != nil {
+			log.Fatalf("memory store init fail: %v", err)
+		}
+	}
+
+	// 初始化对话上下文预算，历史超出预算时较早的轮次总结为摘要
+	chat_history.Default, err = chat_history.NewCompactor(config.Cfg.ChatContextConf)
+	if err != nil {
+		log.Fatalf("chat context init fail: %v", err)
+	}
+
 	// 初始化解析器
 	tools.Parser, err = tools.NewParser(ctx)
 	if err != nil {
@@ -72,5 +127,51 @@ func main() {
 		log.Fatalf("splitter init fail: %v", err)
 	}
 
+	// 初始化 Agent 工具（依赖召回器）
+	toolbox.Tools, err = toolbox.NewRegistry(ctx)
+	if err != nil {
+		log.Fatalf("agent tools init fail: %v", err)
+	}
+
+	// 初始化运行存储，保存每次运行的每一步及等待审批的检查点
+	agent.Runs, err = agent.NewRunStore(config.Cfg.AgentConf.RunDir)
+	if err != nil {
+		log.Fatalf("agent run store init fail: %v", err)
+	}
+
+	// 加载多 Agent 配置，模型与工具在每次运行时按配置解析
+	if config.Cfg.AgentConf.TeamFile != "" {
+		team.Default, err = team.LoadConfig(config.Cfg.AgentConf.TeamFile)
+		if err != nil {
+			log.Fatalf("agent team init fail: %v", err)
+		}
+	}
+
+	// 连接 MCP 服务器并注册其工具，断开的服务器在后台重连
+	mcpclient.Clients, err = mcpclient.NewManagerFromConfig(ctx, toolbox.Tools)
+	if err != nil {
+		log.Fatalf("mcp client init fail: %v", err)
+	}
+	if mcpclient.Clients != nil {
+		defer mcpclient.Clients.Close()
+		go mcpclient.Clients.Run(ctx)
+	}
+
+	if *mcpStdio {
+		// 标准输出用于 MCP 协议，日志只能写到标准错误（log 默认即是）
+		if err := api.ServeMCPStdio(); err != nil {
+			log.Fatalf("mcp stdio server fail: %v", err)
+		}
+		return
+	}
+
+	// 上次进程退出时仍在运行的记录标记为中断，可以通过 /api/agent/runs/:id/resume 继续
+	// 只在 HTTP 服务模式下执行：stdio MCP 进程可能与 HTTP 服务共用运行目录，不能把对方正在进行的运行标记为中断
+	if ids, err := agent.Runs.MarkInterrupted(); err != nil {
+		log.Printf("mark interrupted agent runs fail: %v", err)
+	} else if len(ids) > 0 {
+		log.Printf("agent runs interrupted by restart: %v", ids)
+	}
+
 	api.Run()
 }
@@ -0,0 +1,320 @@
+package chat_history
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"go-agent/config"
+	"go-agent/model/token"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+// 默认配置
+const (
+	DefaultSummaryTokens = 512
+	DefaultCacheSize     = 1000
+)
+
+// Default 全局压缩器，未配置上下文预算时为 nil，历史原样发送
+var Default *Compactor
+
+const summaryPrompt = `You compress the earlier part of a conversation between a user and an assistant so that it can continue without the full transcript.
+Write a concise summary in the language of the conversation. Keep every fact that may matter later: names, numbers, dates, decisions, the user's goals, preferences and constraints, and questions that are still open. Drop greetings and repetition.
+Keep it under about %d tokens. Reply with the summary only.`
+
+// Info 本次请求的上下文预算使用情况
+type Info struct {
+	Budget     int  `json:"budget"`
+	Tokens     int  `json:"tokens"`               // 发送给模型的输入估算 token 数
+	Summarized int  `json:"summarized,omitempty"` // 被摘要替代的历史消息条数
+	Cached     bool `json:"cached,omitempty"`     // 摘要直接取自缓存，未调用模型
+}
+
+// Request 一次压缩请求
+type Request struct {
+	Session   string              // 会话标识，为空时不缓存摘要
+	Providers []string            // 可能应答的提供方（故障转移链），各自决定 token 计数方式与预算
+	Model     model.BaseChatModel // 生成摘要的聊天模型
+	Fixed     []*schema.Message   // 系统提示词、问题等固定部分
+	History   []*schema.Message
+}
+
+// Result 压缩结果，Summary 为空表示历史未超出预算、原样保留
+type Result struct {
+	History []*schema.Message // 原样保留的最近轮次
+	Summary string            // 较早轮次的摘要
+	Info    Info
+}
+
+// Compactor 让对话历史不超过上下文预算：超出时保留尽量多的最近轮次，较早的轮次总结为摘要
+// 摘要按会话缓存，同一会话继续对话时只需把新移出的轮次增量并入摘要
+type Compactor struct {
+	budget        int
+	budgets       map[string]int
+	summaryTokens int
+	capacity      int
+
+	mu    sync.Mutex
+	ll    *list.List
+	items map[string]*list.Element
+}
+
+type summaryEntry struct {
+	session string
+	covered int    // 摘要覆盖的历史消息条数
+	digest  string // 被覆盖消息的哈希，用于确认客户端传来的历史前缀未被修改
+	summary string
+}
+
+// NewCompactor 按配置创建压缩器，预算均为 0 时返回 nil
+func NewCompactor(conf config.ChatContextConfig) (*Compactor, error) {
+	c := &Compactor{
+		summaryTokens: DefaultSummaryTokens,
+		capacity:      DefaultCacheSize,
+		ll:            list.New(),
+		items:         make(map[string]*list.Element),
+	}
+	if conf.Budget != "" {
+		n, err := strconv.Atoi(conf.Budget)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("CHAT_CONTEXT_BUDGET 需为非负整数: %q", conf.Budget)
+		}
+		c.budget = n
+	}
+	if conf.ProviderBudgets != "" {
+		if err := json.Unmarshal([]byte(conf.ProviderBudgets), &c.budgets); err != nil {
+			return nil, fmt.Errorf("CHAT_CONTEXT_PROVIDER_BUDGETS 格式错误: %w", err)
+		}
+	}
+	if n, err := strconv.Atoi(conf.SummaryTokens); err == nil && n > 0 {
+		c.summaryTokens = n
+	}
+	if n, err := strconv.Atoi(conf.CacheSize); err == nil && n >= 0 {
+		c.capacity = n
+	}
+
+	if c.budget == 0 && len(c.budgets) == 0 {
+		return nil, nil
+	}
+	return c, nil
+}
+
+// Budget 返回提供方的上下文预算，0 表示不限制
+func (c *Compactor) Budget(provider string) int {
+	if b, ok := c.budgets[provider]; ok {
+		return b
+	}
+	return c.budget
+}
+
+// Fit 估算整个输入的 token 数，超出预算时把较早的轮次替换为摘要
+// 故障转移链中的每个提供方按各自的计数方式与预算估算，取保留轮次最少的结果，转移到任一提供方时输入都不超出其预算
+func (c *Compactor) Fit(ctx context.Context, req Request) (*Result, error) {
+	providers := req.Providers
+	if len(providers) == 0 {
+		providers = []string{""}
+	}
+
+	res := &Result{History: req.History}
+	var limit *fitLimit
+	for i, provider := range providers {
+		l := c.limit(provider, req)
+		switch {
+		case l.budget <= 0:
+			if i == 0 {
+				res.Info = Info{Tokens: l.total}
+			}
+			continue
+		case l.total <= l.budget:
+			// 未超出预算时报告余量最少的提供方
+			if res.Info.Budget == 0 || l.budget-l.total < res.Info.Budget-res.Info.Tokens {
+				res.Info = Info{Budget: l.budget, Tokens: l.total}
+			}
+			continue
+		}
+		l.split = splitPoint(l.counter, req.History, l.budget-l.reserved-c.summaryTokens)
+		if limit == nil || l.split > limit.split || (l.split == limit.split && l.budget < limit.budget) {
+			limit = &l
+		}
+	}
+	if limit == nil {
+		return res, nil
+	}
+
+	older, recent := req.History[:limit.split], req.History[limit.split:]
+	summary, cached, err := c.summarize(ctx, req.Session, req.Model, limit.counter, limit.budget, older)
+	if err != nil {
+		return nil, err
+	}
+
+	res.History, res.Summary = recent, summary
+	res.Info = Info{
+		Budget:     limit.budget,
+		Tokens:     limit.reserved + limit.counter.CountMessages(recent) + limit.counter.Count(Prompt(summary)),
+		Summarized: limit.split,
+		Cached:     cached,
+	}
+	return res, nil
+}
+
+// fitLimit 一个提供方的预算与按其计数方式估算的输入
+type fitLimit struct {
+	counter  token.Counter
+	budget   int
+	reserved int // 固定部分的 token 数
+	total    int // 完整输入的 token 数
+	split    int // 超出预算时原样保留的最近轮次的起点
+}
+
+func (c *Compactor) limit(provider string, req Request) fitLimit {
+	counter := token.ForProvider(provider)
+	reserved := counter.CountMessages(req.Fixed)
+	return fitLimit{
+		counter:  counter,
+		budget:   c.Budget(provider),
+		reserved: reserved,
+		total:    reserved + counter.CountMessages(req.History),
+	}
+}
+
+// Prompt 把摘要整理为加入系统提示词的段落，摘要为空时返回空串
+func Prompt(summary string) string {
+	if summary == "" {
+		return ""
+	}
+	return "本次对话较早部分的摘要（原文已省略）：\n" + summary
+}
+
+// Summarize 让聊天模型总结对话，previous 为之前的摘要，新的摘要会合并两者
+// 整个输入超出 budget（按 counter 估算，0 表示不限制）时分段总结，每段并入上一段得到的摘要；单条消息超长时截断
+func Summarize(ctx context.Context, cm model.BaseChatModel, counter token.Counter, budget int, previous string, messages []*schema.Message, maxTokens int) (string, error) {
+	system := fmt.Sprintf(summaryPrompt, maxTokens)
+	for len(messages) > 0 {
+		var input strings.Builder
+		if previous != "" {
+			fmt.Fprintf(&input, "Summary of the conversation so far:\n%s\n\nMessages that follow:\n", previous)
+		}
+		// 固定部分：系统提示词、之前的摘要、两条消息的格式开销与输出的摘要
+		available := budget - counter.Count(system) - counter.Count(input.String()) - 2*counter.PerMessage - maxTokens
+		if budget > 0 && available <= 0 {
+			return "", fmt.Errorf("context budget %d is too small to summarize the conversation", budget)
+		}
+
+		n := 0
+		for _, msg := range messages {
+			line := fmt.Sprintf("%s: %s\n", msg.Role, msg.Content)
+			cost := counter.Count(line)
+			if budget > 0 && cost > available {
+				if n == 0 {
+					input.WriteString(truncate(counter, line, available))
+					n++
+				}
+				break
+			}
+			input.WriteString(line)
+			available -= cost
+			n++
+		}
+		messages = messages[n:]
+
+		out, err := cm.Generate(ctx, []*schema.Message{
+			schema.SystemMessage(system),
+			schema.UserMessage(input.String()),
+		})
+		if err != nil {
+			return "", fmt.Errorf("summarize conversation failed: %w", err)
+		}
+		previous = strings.TrimSpace(out.Content)
+	}
+	return previous, nil
+}
+
+// truncate 截取 text 的前缀，使其不超过 limit 个 token
+func truncate(counter token.Counter, text string, limit int) string {
+	runes := []rune(text)
+	n := sort.Search(len(runes)+1, func(i int) bool { return counter.Count(string(runes[:i])) > limit }) - 1
+	return string(runes[:max(n, 0)])
+}
+
+// summarize 返回较早轮次的摘要：缓存的摘要覆盖的正是这些消息时直接复用，覆盖其前缀时只增量总结新增部分
+func (c *Compactor) summarize(ctx context.Context, session string, cm model.BaseChatModel, counter token.Counter, budget int, older []*schema.Message) (string, bool, error) {
+	previous, start := "", 0
+	if entry := c.get(session); entry != nil && entry.covered <= len(older) && entry.digest == digest(older[:entry.covered]) {
+		if entry.covered == len(older) {
+			return entry.summary, true, nil
+		}
+		previous, start = entry.summary, entry.covered
+	}
+
+	summary, err := Summarize(ctx, cm, counter, budget, previous, older[start:], c.summaryTokens)
+	if err != nil {
+		return "", false, err
+	}
+	c.put(&summaryEntry{session: session, covered: len(older), digest: digest(older), summary: summary})
+	return summary, false, nil
+}
+
+func (c *Compactor) get(session string) *summaryEntry {
+	if session == "" {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[session]
+	if !ok {
+		return nil
+	}
+	c.ll.MoveToFront(el)
+	return el.Value.(*summaryEntry)
+}
+
+func (c *Compactor) put(entry *summaryEntry) {
+	if entry.session == "" || c.capacity == 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[entry.session]; ok {
+		el.Value = entry
+		c.ll.MoveToFront(el)
+		return
+	}
+	c.items[entry.session] = c.ll.PushFront(entry)
+	for c.ll.Len() > c.capacity {
+		oldest := c.ll.Back()
+		c.ll.Remove(oldest)
+		delete(c.items, oldest.Value.(*summaryEntry).session)
+	}
+}
+
+// splitPoint 返回原样保留的最近轮次的起点：从最后一条消息向前，在 available 内尽量多保留以用户消息开头的完整轮次
+func splitPoint(counter token.Counter, history []*schema.Message, available int) int {
+	split, used := len(history), 0
+	for i := len(history) - 1; i >= 0; i-- {
+		used += counter.PerMessage + counter.Count(history[i].Content)
+		if used > available {
+			break
+		}
+		if history[i].Role == schema.User {
+			split = i
+		}
+	}
+	return split
+}
+
+func digest(messages []*schema.Message) string {
+	h := sha256.New()
+	for _, msg := range messages {
+		fmt.Fprintf(h, "%s\x00%s\x00", msg.Role, msg.Content)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
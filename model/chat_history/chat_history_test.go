@@ -0,0 +1,302 @@
+package chat_history
+
+import (
+	"context"
+	"fmt"
+	"go-agent/config"
+	"go-agent/model/chat_model"
+	"go-agent/model/token"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+// turns 生成交替的用户/助手消息，每条内容为 chars 个 ASCII 字符
+func turns(n, chars int) []*schema.Message {
+	history := make([]*schema.Message, n)
+	for i := range history {
+		content := fmt.Sprintf("%d", i) + strings.Repeat("a", chars-1)
+		if i%2 == 0 {
+			history[i] = schema.UserMessage(content)
+		} else {
+			history[i] = schema.AssistantMessage(content, nil)
+		}
+	}
+	return history
+}
+
+func TestSplitPoint(t *testing.T) {
+	// 每条消息 10 token 加 4 token 格式开销
+	cases := []struct {
+		name      string
+		history   []*schema.Message
+		available int
+		want      int
+	}{
+		{"all fit", turns(4, 40), 100, 0},
+		{"last turn fits", turns(4, 40), 28, 2},
+		{"only half a turn fits", turns(4, 40), 27, 4},
+		{"nothing fits", turns(4, 40), 0, 4},
+		{"negative available", turns(4, 40), -5, 4},
+		{"ends with user", turns(3, 40), 14, 2},
+		{"leading assistant summarized", turns(4, 40)[1:], 100, 1},
+		{"empty", nil, 100, 0},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := splitPoint(token.Default, tc.history, tc.available); got != tc.want {
+				t.Fatalf("splitPoint = %d, want %d", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestFit(t *testing.T) {
+	// 4 条 400 字符的消息：openai 与默认计数每条 104 token 共 416，ollama 每条 119 token 共 476
+	cases := []struct {
+		name      string
+		conf      config.ChatContextConfig
+		providers []string
+		want      Info
+		summary   bool
+	}{
+		{
+			name:      "under budget",
+			conf:      config.ChatContextConfig{Budget: "500"},
+			providers: []string{"openai"},
+			want:      Info{Budget: 500, Tokens: 416},
+		},
+		{
+			name:      "unlimited provider",
+			conf:      config.ChatContextConfig{ProviderBudgets: `{"openai":0}`},
+			providers: []string{"openai"},
+			want:      Info{Tokens: 416},
+		},
+		{
+			name:      "over budget",
+			conf:      config.ChatContextConfig{Budget: "400"},
+			providers: []string{"openai"},
+			want:      Info{Budget: 400, Summarized: 2},
+			summary:   true,
+		},
+		{
+			name:    "no providers uses default budget",
+			conf:    config.ChatContextConfig{Budget: "400"},
+			want:    Info{Budget: 400, Summarized: 2},
+			summary: true,
+		},
+		{
+			name:      "smallest budget in chain",
+			conf:      config.ChatContextConfig{ProviderBudgets: `{"openai":600,"ollama":460}`},
+			providers: []string{"openai", "ollama"},
+			want:      Info{Budget: 460, Summarized: 2},
+			summary:   true,
+		},
+		{
+			name:      "chain order does not matter",
+			conf:      config.ChatContextConfig{ProviderBudgets: `{"openai":600,"ollama":460}`},
+			providers: []string{"ollama", "openai"},
+			want:      Info{Budget: 460, Summarized: 2},
+			summary:   true,
+		},
+		{
+			name:      "unlimited provider in chain",
+			conf:      config.ChatContextConfig{ProviderBudgets: `{"openai":0,"ollama":460}`},
+			providers: []string{"openai", "ollama"},
+			want:      Info{Budget: 460, Summarized: 2},
+			summary:   true,
+		},
+		{
+			name:      "least headroom reported",
+			conf:      config.ChatContextConfig{ProviderBudgets: `{"openai":420,"ollama":600}`},
+			providers: []string{"ollama", "openai"},
+			want:      Info{Budget: 420, Tokens: 416},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.conf.SummaryTokens = "10"
+			c, err := NewCompactor(tc.conf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			history := turns(4, 400)
+			res, err := c.Fit(context.Background(), Request{
+				Providers: tc.providers,
+				Model:     chat_model.NewFakeChatModel(schema.AssistantMessage("摘要", nil)),
+				History:   history,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !tc.summary {
+				if res.Info != tc.want || res.Summary != "" || len(res.History) != len(history) {
+					t.Fatalf("info = %+v, summary = %q, history = %d", res.Info, res.Summary, len(res.History))
+				}
+				return
+			}
+			if res.Summary != "摘要" || res.Info.Budget != tc.want.Budget || res.Info.Summarized != tc.want.Summarized {
+				t.Fatalf("info = %+v, summary = %q", res.Info, res.Summary)
+			}
+			if len(res.History) != len(history)-tc.want.Summarized || res.Info.Tokens > res.Info.Budget {
+				t.Fatalf("kept %d messages using %d tokens", len(res.History), res.Info.Tokens)
+			}
+		})
+	}
+}
+
+func TestNewCompactorWithoutBudget(t *testing.T) {
+	c, err := NewCompactor(config.ChatContextConfig{Budget: "0"})
+	if err != nil || c != nil {
+		t.Fatalf("compactor = %v, err = %v", c, err)
+	}
+	if _, err := NewCompactor(config.ChatContextConfig{Budget: "-1"}); err == nil {
+		t.Fatal("negative budget accepted")
+	}
+	if _, err := NewCompactor(config.ChatContextConfig{ProviderBudgets: "{"}); err == nil {
+		t.Fatal("invalid provider budgets accepted")
+	}
+}
+
+func TestSummarizeChunks(t *testing.T) {
+	counter := token.Default
+	const maxTokens = 10
+	// 固定部分之外，第一段还能放下 70 token 的消息（每条 22 或 23 token）
+	budget := counter.Count(fmt.Sprintf(summaryPrompt, maxTokens)) + 2*counter.PerMessage + maxTokens + 70
+
+	cases := []struct {
+		name     string
+		budget   int
+		messages []*schema.Message
+		calls    int
+	}{
+		{"fits in one call", budget, turns(2, 80), 1},
+		{"unlimited", 0, turns(12, 80), 1},
+		{"split into chunks", budget, turns(6, 80), 3},
+		{"oversized message truncated", budget, turns(1, 1000), 1},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var script []*schema.Message
+			for i := 1; i <= tc.calls; i++ {
+				script = append(script, schema.AssistantMessage(fmt.Sprintf("summary %d", i), nil))
+			}
+			cm := chat_model.NewFakeChatModel(script...)
+
+			summary, err := Summarize(context.Background(), cm, counter, tc.budget, "", tc.messages, maxTokens)
+			if err != nil {
+				t.Fatal(err)
+			}
+			inputs := cm.Inputs()
+			if len(inputs) != tc.calls || summary != fmt.Sprintf("summary %d", tc.calls) {
+				t.Fatalf("calls = %d, summary = %q, want %d calls", len(inputs), summary, tc.calls)
+			}
+			for i, in := range inputs {
+				if tc.budget > 0 && counter.CountMessages(in)+maxTokens > tc.budget {
+					t.Fatalf("chunk %d uses %d tokens, budget %d", i, counter.CountMessages(in), tc.budget)
+				}
+				// 后续各段带着上一段的摘要
+				if i > 0 && !strings.Contains(in[1].Content, fmt.Sprintf("summary %d", i)) {
+					t.Fatalf("chunk %d does not fold the previous summary: %q", i, in[1].Content)
+				}
+			}
+		})
+	}
+}
+
+func TestSummarizeBudgetTooSmall(t *testing.T) {
+	cm := chat_model.NewFakeChatModel()
+	if _, err := Summarize(context.Background(), cm, token.Default, 50, "", turns(2, 40), 10); err == nil {
+		t.Fatal("want error when the summary prompt alone exceeds the budget")
+	}
+	if len(cm.Inputs()) != 0 {
+		t.Fatal("model called despite the budget being too small")
+	}
+}
+
+func TestSummaryCache(t *testing.T) {
+	c, err := NewCompactor(config.ChatContextConfig{Budget: "100", CacheSize: "2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	put := func(session, summary string) {
+		c.put(&summaryEntry{session: session, summary: summary})
+	}
+	summaryOf := func(session string) string {
+		if e := c.get(session); e != nil {
+			return e.summary
+		}
+		return ""
+	}
+
+	put("a", "A")
+	put("b", "B")
+	if summaryOf("a") != "A" { // a 变为最近使用
+		t.Fatal("a missing")
+	}
+	put("c", "C") // 淘汰 b
+	if summaryOf("b") != "" || summaryOf("a") != "A" || summaryOf("c") != "C" {
+		t.Fatalf("least recently used entry not evicted: a=%q b=%q c=%q", summaryOf("a"), summaryOf("b"), summaryOf("c"))
+	}
+
+	put("a", "A2")
+	if summaryOf("a") != "A2" || c.ll.Len() != 2 {
+		t.Fatalf("update: a=%q len=%d", summaryOf("a"), c.ll.Len())
+	}
+
+	// 没有会话标识的摘要不缓存
+	put("", "anonymous")
+	if c.get("") != nil || c.ll.Len() != 2 {
+		t.Fatal("entry without session cached")
+	}
+
+	disabled, err := NewCompactor(config.ChatContextConfig{Budget: "100", CacheSize: "0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	disabled.put(&summaryEntry{session: "a", summary: "A"})
+	if disabled.get("a") != nil {
+		t.Fatal("cache size 0 still caches")
+	}
+}
+
+func TestSummarizeReusesCache(t *testing.T) {
+	c, err := NewCompactor(config.ChatContextConfig{Budget: "100"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cm := chat_model.NewFakeChatModel(schema.AssistantMessage("first", nil), schema.AssistantMessage("second", nil))
+	history := turns(6, 10)
+	ctx := context.Background()
+
+	summary, cached, err := c.summarize(ctx, "s", cm, token.Default, 0, history[:2])
+	if err != nil || summary != "first" || cached {
+		t.Fatalf("summary = %q, cached = %v, err = %v", summary, cached, err)
+	}
+	// 覆盖相同的消息时直接复用
+	if summary, cached, _ = c.summarize(ctx, "s", cm, token.Default, 0, history[:2]); summary != "first" || !cached {
+		t.Fatalf("summary = %q, cached = %v", summary, cached)
+	}
+	// 新移出的消息增量并入
+	if summary, _, _ = c.summarize(ctx, "s", cm, token.Default, 0, history[:4]); summary != "second" {
+		t.Fatalf("summary = %q", summary)
+	}
+	inputs := cm.Inputs()
+	if len(inputs) != 2 || !strings.Contains(inputs[1][1].Content, "first") || strings.Contains(inputs[1][1].Content, history[0].Content) {
+		t.Fatalf("incremental input = %q", inputs[len(inputs)-1][1].Content)
+	}
+
+	// 历史前缀被修改时重新总结
+	edited := append([]*schema.Message{schema.UserMessage("changed")}, history[1:4]...)
+	if _, cached, _ = c.summarize(ctx, "s", cm, token.Default, 0, edited); cached {
+		t.Fatal("edited history reused the cached summary")
+	}
+	if inputs = cm.Inputs(); !strings.Contains(inputs[2][1].Content, "changed") || strings.Contains(inputs[2][1].Content, "second") {
+		t.Fatalf("edited history input = %q", inputs[2][1].Content)
+	}
+}
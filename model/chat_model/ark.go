@@ -10,9 +10,17 @@ import (
 
 func initArk() {
 	registerChatModel("ark", func(ctx context.Context) (model2.BaseChatModel, error) {
+		params, err := generationDefaults("ark")
+		if err != nil {
+			return nil, err
+		}
 		return ark.NewChatModel(ctx, &ark.ChatModelConfig{
-			APIKey: config.Cfg.ArkConf.ArkKey,
-			Model:  config.Cfg.ArkConf.ArkChatModel,
+			APIKey:      config.Cfg.ArkConf.ArkKey,
+			Model:       config.Cfg.ArkConf.ArkChatModel,
+			Temperature: params.Temperature,
+			MaxTokens:   params.MaxTokens,
+			TopP:        params.TopP,
+			Stop:        params.Stop,
 		})
 	})
 }
@@ -0,0 +1,131 @@
+package chat_model
+
+import (
+	"sync"
+	"time"
+)
+
+// 熔断器状态
+const (
+	BreakerClosed   = "closed"    // 正常放行
+	BreakerOpen     = "open"      // 熔断中，直接跳过
+	BreakerHalfOpen = "half_open" // 冷却结束，放行一次试探请求
+)
+
+// ProviderHealth 单个提供方的健康状况
+type ProviderHealth struct {
+	Name                string     `json:"name"`
+	State               string     `json:"state"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	TotalRequests       int64      `json:"total_requests"`
+	TotalFailures       int64      `json:"total_failures"`
+	LastError           string     `json:"last_error,omitempty"`
+	LastFailureAt       *time.Time `json:"last_failure_at,omitempty"`
+	OpenUntil           *time.Time `json:"open_until,omitempty"`
+}
+
+// breaker 按连续失败次数熔断的断路器
+type breaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	probing   bool // 半开状态下是否已有试探请求在途
+
+	requests   int64
+	total      int64
+	lastErr    string
+	lastFailAt time.Time
+}
+
+func newBreaker(threshold int, cooldown time.Duration) *breaker {
+	if threshold <= 0 {
+		threshold = 1
+	}
+	return &breaker{threshold: threshold, cooldown: cooldown}
+}
+
+// allow 判断是否放行请求；半开状态下只放行一个试探请求
+func (b *breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state(time.Now()) {
+	case BreakerOpen:
+		return false
+	case BreakerHalfOpen:
+		if b.probing {
+			return false
+		}
+		b.probing = true
+	}
+	b.requests++
+	return true
+}
+
+func (b *breaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures = 0
+	b.probing = false
+	b.openUntil = time.Time{}
+}
+
+func (b *breaker) failure(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	b.failures++
+	b.total++
+	b.lastErr = err.Error()
+	b.lastFailAt = now
+	if b.failures >= b.threshold {
+		b.openUntil = now.Add(b.cooldown)
+	}
+	b.probing = false
+}
+
+// release 请求被调用方取消时归还试探名额，不计入成败
+func (b *breaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+// state 调用方需持有锁
+func (b *breaker) state(now time.Time) string {
+	if b.failures < b.threshold {
+		return BreakerClosed
+	}
+	if now.Before(b.openUntil) {
+		return BreakerOpen
+	}
+	return BreakerHalfOpen
+}
+
+func (b *breaker) health(name string) ProviderHealth {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	h := ProviderHealth{
+		Name:                name,
+		State:               b.state(now),
+		ConsecutiveFailures: b.failures,
+		TotalRequests:       b.requests,
+		TotalFailures:       b.total,
+		LastError:           b.lastErr,
+	}
+	if !b.lastFailAt.IsZero() {
+		t := b.lastFailAt
+		h.LastFailureAt = &t
+	}
+	if h.State == BreakerOpen {
+		t := b.openUntil
+		h.OpenUntil = &t
+	}
+	return h
+}
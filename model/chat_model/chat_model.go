@@ -4,6 +4,12 @@ import (
 	"context"
 	"fmt"
 	"go-agent/config"
+	"go-agent/model/retry"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/cloudwego/eino/components/model"
 )
@@ -13,17 +19,218 @@ type ChatModelFactory func(ctx context.Context) (model.BaseChatModel, error)
 var chatModelRegistry = make(map[string]ChatModelFactory)
 var CM model.BaseChatModel
 
+// providerOrder 聊天模型提供方的固定展示顺序
+var providerOrder = []string{"ark", "openai", "qwen", "ollama", "openai_compatible", "fake"}
+
+// loadedModels 启动时加载的全部聊天模型，按名称供单次请求选用
+var loadedModels = make(map[string]*FallbackChatModel)
+
+// defaultChain 默认故障转移链中的提供方名称
+var defaultChain []string
+
+// Capabilities 聊天模型能力
+type Capabilities struct {
+	Streaming   bool `json:"streaming"`
+	ToolCalling bool `json:"tool_calling"`
+}
+
+// ModelInfo 已加载的聊天模型信息
+type ModelInfo struct {
+	Name         string       `json:"name"`    // 请求中 model 字段使用的名称
+	Model        string       `json:"model"`   // 提供方的模型名
+	Default      bool         `json:"default"` // 是否在默认故障转移链中
+	State        string       `json:"state"`   // 熔断状态
+	Capabilities Capabilities `json:"capabilities"`
+}
+
 // NewChatModel 根据配置创建 ChatModel 实例
+// CHAT_MODEL_TYPE 可按优先级配置多个提供方（如 "ark,qwen,openai"），失败时依次故障转移；
+// 其余已配置密钥/模型的提供方同时加载，供请求通过 model 字段单独选用
 func NewChatModel(ctx context.Context) (model.BaseChatModel, error) {
 	initArk()
 	initOpenAI()
 	initQwen()
-	create, ok := chatModelRegistry[config.Cfg.ChatModelType]
+	initOllama()
+	initOpenAICompatible()
+	initFake()
+
+	conf := fallbackConfig()
+	loadedModels = make(map[string]*FallbackChatModel)
+	defaultChain = nil
+
+	for _, name := range strings.Split(config.Cfg.ChatModelType, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" || slices.Contains(defaultChain, name) {
+			continue
+		}
+		if _, ok := chatModelRegistry[name]; !ok {
+			return nil, fmt.Errorf("不支持的 ChatModel 类型: %s", name)
+		}
+		defaultChain = append(defaultChain, name)
+	}
+
+	var chain []*fallbackProvider
+	for _, name := range providerOrder {
+		inChain := slices.Contains(defaultChain, name)
+		if !inChain && providerModelName(name) == "" {
+			continue
+		}
+
+		cm, err := chatModelRegistry[name](ctx)
+		if err != nil {
+			if inChain {
+				return nil, fmt.Errorf("初始化 ChatModel %s 失败: %w", name, err)
+			}
+			log.Printf("警告: 聊天模型 %s 初始化失败，已跳过: %v", name, err)
+			continue
+		}
+
+		p := &fallbackProvider{
+			name:    name,
+			modelID: providerModelName(name),
+			model:   cm,
+			breaker: newBreaker(conf.BreakerThreshold, conf.BreakerCooldown),
+		}
+		loadedModels[name] = &FallbackChatModel{providers: []*fallbackProvider{p}, retry: conf.Retry}
+	}
+
+	for _, name := range defaultChain {
+		chain = append(chain, loadedModels[name].providers[0])
+	}
+	if len(chain) == 0 {
+		return nil, fmt.Errorf("at least one chat provider is required")
+	}
+
+	return &FallbackChatModel{providers: chain, retry: conf.Retry}, nil
+}
+
+// Get 按名称返回已加载的聊天模型，名称为空时返回默认模型 CM
+func Get(name string) (model.BaseChatModel, error) {
+	if name == "" {
+		if CM == nil {
+			return nil, fmt.Errorf("ChatModel 未初始化")
+		}
+		return CM, nil
+	}
+
+	m, ok := loadedModels[name]
+	if !ok {
+		return nil, fmt.Errorf("未加载的聊天模型: %s", name)
+	}
+	return m, nil
+}
+
+// PrimaryProvider 返回请求优先使用的提供方名称：指定 name 时即为 name，否则为默认模型的第一个提供方
+func PrimaryProvider(name string) string {
+	if name != "" {
+		return name
+	}
+	if f, ok := CM.(*FallbackChatModel); ok && len(f.providers) > 0 {
+		return f.providers[0].name
+	}
+	return ""
+}
+
+// Providers 返回请求可能使用的提供方名称：指定 name 时只有 name，否则为默认模型故障转移链中的全部提供方
+func Providers(name string) []string {
+	if name != "" {
+		return []string{name}
+	}
+	f, ok := CM.(*FallbackChatModel)
 	if !ok {
-		return nil, fmt.Errorf("不支持的 ChatModel 类型: %s", config.Cfg.ChatModelType)
+		return nil
+	}
+	names := make([]string, len(f.providers))
+	for i, p := range f.providers {
+		names[i] = p.name
+	}
+	return names
+}
+
+// List 返回已加载的聊天模型信息
+func List() []ModelInfo {
+	infos := make([]ModelInfo, 0, len(loadedModels))
+	for _, name := range providerOrder {
+		m, ok := loadedModels[name]
+		if !ok {
+			continue
+		}
+
+		p := m.providers[0]
+		_, toolCalling := p.model.(model.ToolCallingChatModel)
+		infos = append(infos, ModelInfo{
+			Name:    name,
+			Model:   p.modelID,
+			Default: slices.Contains(defaultChain, name),
+			State:   p.breaker.health(name).State,
+			Capabilities: Capabilities{
+				Streaming:   true,
+				ToolCalling: toolCalling,
+			},
+		})
+	}
+	return infos
+}
+
+// Health 返回全部已加载提供方的健康状况
+func Health() []ProviderHealth {
+	health := make([]ProviderHealth, 0, len(loadedModels))
+	for _, name := range providerOrder {
+		if m, ok := loadedModels[name]; ok {
+			health = append(health, m.Health()...)
+		}
+	}
+	return health
+}
+
+// providerModelName 返回提供方配置的模型名，未配置凭据或模型时返回空
+// fake 只在 CHAT_MODEL_TYPE 中显式列出时加载
+func providerModelName(name string) string {
+	switch name {
+	case "ark":
+		if config.Cfg.ArkConf.ArkKey != "" {
+			return config.Cfg.ArkConf.ArkChatModel
+		}
+	case "openai":
+		if config.Cfg.OpenAIConf.OpenAIKey != "" {
+			return config.Cfg.OpenAIConf.OpenAIChatModel
+		}
+	case "qwen":
+		if config.Cfg.QwenConf.QwenKey != "" {
+			return config.Cfg.QwenConf.QwenChatModel
+		}
+	case "ollama":
+		return config.Cfg.OllamaConf.ChatModel
+	case "openai_compatible":
+		if config.Cfg.OpenAICompatibleConf.BaseUrl != "" {
+			return config.Cfg.OpenAICompatibleConf.ChatModel
+		}
+	case "fake":
+		if slices.Contains(defaultChain, "fake") {
+			return "fake"
+		}
+	}
+	return ""
+}
+
+// fallbackConfig 读取故障转移配置
+func fallbackConfig() FallbackConfig {
+	conf := config.Cfg.ChatFallbackConf
+	policy := retry.DefaultPolicy
+	if n, err := strconv.Atoi(conf.MaxRetries); err == nil {
+		policy.MaxRetries = n
+	}
+
+	threshold, err := strconv.Atoi(conf.BreakerThreshold)
+	if err != nil {
+		threshold = 3
+	}
+	cooldown, err := time.ParseDuration(conf.BreakerCooldown)
+	if err != nil {
+		cooldown = 30 * time.Second
 	}
 
-	return create(ctx)
+	return FallbackConfig{Retry: policy, BreakerThreshold: threshold, BreakerCooldown: cooldown}
 }
 
 // registerChatModel 注册聊天模型进入工厂
@@ -0,0 +1,111 @@
+package chat_model
+
+import (
+	"context"
+	"go-agent/config"
+	"sync"
+
+	model2 "github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+const (
+	// fakeStreamChunkRunes 流式输出时每个分片的字符数
+	fakeStreamChunkRunes = 8
+	// fakeMaxInputs 最多保留的调用记录数，避免长时间运行时内存增长
+	fakeMaxInputs = 64
+)
+
+// FakeChatModel 离线测试用的确定性聊天模型
+// 有脚本时按顺序返回脚本中的消息（可包含工具调用），脚本用完或为空时回显最后一条用户消息
+type FakeChatModel struct {
+	state *fakeState
+	tools []*schema.ToolInfo
+}
+
+type fakeState struct {
+	mu     sync.Mutex
+	script []*schema.Message
+	next   int
+	fixed  string // 非空时脚本用完后固定返回该内容，而不是回显
+	inputs [][]*schema.Message
+	opts   *model2.Options // 最近一次调用的通用选项
+}
+
+// NewFakeChatModel 创建脚本化的假聊天模型
+func NewFakeChatModel(script ...*schema.Message) *FakeChatModel {
+	return &FakeChatModel{state: &fakeState{script: script}}
+}
+
+func (f *FakeChatModel) Generate(ctx context.Context, input []*schema.Message, opts ...model2.Option) (*schema.Message, error) {
+	return f.reply(input, opts), nil
+}
+
+func (f *FakeChatModel) Stream(ctx context.Context, input []*schema.Message, opts ...model2.Option) (*schema.StreamReader[*schema.Message], error) {
+	msg := f.reply(input, opts)
+	if len(msg.ToolCalls) > 0 {
+		return schema.StreamReaderFromArray([]*schema.Message{msg}), nil
+	}
+
+	runes := []rune(msg.Content)
+	chunks := make([]*schema.Message, 0, len(runes)/fakeStreamChunkRunes+1)
+	for start := 0; start < len(runes); start += fakeStreamChunkRunes {
+		end := min(start+fakeStreamChunkRunes, len(runes))
+		chunks = append(chunks, schema.AssistantMessage(string(runes[start:end]), nil))
+	}
+	return schema.StreamReaderFromArray(chunks), nil
+}
+
+// WithTools 返回绑定工具后的副本，副本与原实例共享脚本进度
+func (f *FakeChatModel) WithTools(tools []*schema.ToolInfo) (model2.ToolCallingChatModel, error) {
+	return &FakeChatModel{state: f.state, tools: tools}, nil
+}
+
+// Inputs 返回每次调用收到的消息列表，便于测试断言提示词
+func (f *FakeChatModel) Inputs() [][]*schema.Message {
+	f.state.mu.Lock()
+	defer f.state.mu.Unlock()
+	return append([][]*schema.Message(nil), f.state.inputs...)
+}
+
+// LastOptions 返回最近一次调用收到的通用选项（温度、最大 token 等）
+func (f *FakeChatModel) LastOptions() *model2.Options {
+	f.state.mu.Lock()
+	defer f.state.mu.Unlock()
+	return f.state.opts
+}
+
+func (f *FakeChatModel) reply(input []*schema.Message, opts []model2.Option) *schema.Message {
+	s := f.state
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.opts = model2.GetCommonOptions(nil, opts...)
+
+	s.inputs = append(s.inputs, input)
+	if len(s.inputs) > fakeMaxInputs {
+		s.inputs = s.inputs[len(s.inputs)-fakeMaxInputs:]
+	}
+
+	if s.next < len(s.script) {
+		msg := *s.script[s.next]
+		s.next++
+		return &msg
+	}
+	if s.fixed != "" {
+		return schema.AssistantMessage(s.fixed, nil)
+	}
+
+	for i := len(input) - 1; i >= 0; i-- {
+		if input[i].Role == schema.User {
+			return schema.AssistantMessage("echo: "+input[i].Content, nil)
+		}
+	}
+	return schema.AssistantMessage("echo:", nil)
+}
+
+func initFake() {
+	registerChatModel("fake", func(ctx context.Context) (model2.BaseChatModel, error) {
+		return &FakeChatModel{state: &fakeState{fixed: config.Cfg.FakeConf.ChatReply}}, nil
+	})
+}
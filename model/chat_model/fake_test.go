@@ -0,0 +1,66 @@
+package chat_model
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+func TestFakeChatModelScriptThenEcho(t *testing.T) {
+	ctx := context.Background()
+	m := NewFakeChatModel(schema.AssistantMessage("scripted", nil))
+	input := []*schema.Message{schema.SystemMessage("sys"), schema.UserMessage("hi")}
+
+	first, _ := m.Generate(ctx, input)
+	second, _ := m.Generate(ctx, input)
+	if first.Content != "scripted" || second.Content != "echo: hi" {
+		t.Fatalf("got %q then %q", first.Content, second.Content)
+	}
+	if len(m.Inputs()) != 2 {
+		t.Fatalf("recorded %d inputs, want 2", len(m.Inputs()))
+	}
+}
+
+func TestFakeChatModelStream(t *testing.T) {
+	m := NewFakeChatModel()
+	sr, err := m.Stream(context.Background(), []*schema.Message{schema.UserMessage("一段需要被切成多个分片的流式回复")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sr.Close()
+
+	var b strings.Builder
+	chunks := 0
+	for {
+		msg, err := sr.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		chunks++
+		b.WriteString(msg.Content)
+	}
+
+	if b.String() != "echo: 一段需要被切成多个分片的流式回复" || chunks < 2 {
+		t.Fatalf("got %q in %d chunks", b.String(), chunks)
+	}
+}
+
+func TestFakeChatModelWithToolsSharesScript(t *testing.T) {
+	ctx := context.Background()
+	call := schema.AssistantMessage("", []schema.ToolCall{{ID: "1", Function: schema.FunctionCall{Name: "search"}}})
+	m := NewFakeChatModel(call, schema.AssistantMessage("done", nil))
+
+	bound, _ := m.WithTools([]*schema.ToolInfo{{Name: "search"}})
+	first, _ := bound.Generate(ctx, []*schema.Message{schema.UserMessage("q")})
+	second, _ := m.Generate(ctx, []*schema.Message{schema.UserMessage("q")})
+	if len(first.ToolCalls) != 1 || second.Content != "done" {
+		t.Fatalf("got %+v then %+v", first, second)
+	}
+}
@@ -0,0 +1,279 @@
+package chat_model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-agent/model/retry"
+	"go-agent/model/token"
+	"go-agent/model/usage"
+	"io"
+	"log"
+	"maps"
+	"strings"
+	"time"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+// ExtraKeyProvider 回答消息 Extra 中记录实际应答提供方的键
+const ExtraKeyProvider = "provider"
+
+// ErrAllProvidersUnavailable 所有提供方均处于熔断状态
+var ErrAllProvidersUnavailable = errors.New("all chat providers are unavailable (circuit open)")
+
+// FallbackConfig 故障转移配置
+type FallbackConfig struct {
+	Retry            retry.Policy // 单个提供方对 429/5xx 的重试策略
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+}
+
+// Provider 参与故障转移的一个聊天模型
+type Provider struct {
+	Name    string
+	Model   model.BaseChatModel
+	ModelID string // 提供方的模型名，用于用量与计费
+}
+
+// FallbackChatModel 按优先级依次调用多个聊天模型：
+// 同一提供方的临时错误先退避重试，仍失败则切换到下一个；连续失败的提供方会被熔断一段时间
+type FallbackChatModel struct {
+	providers []*fallbackProvider
+	retry     retry.Policy
+}
+
+type fallbackProvider struct {
+	name    string
+	modelID string
+	model   model.BaseChatModel
+	breaker *breaker
+}
+
+// NewFallbackChatModel 创建故障转移聊天模型，providers 按优先级排列
+func NewFallbackChatModel(providers []Provider, conf FallbackConfig) (*FallbackChatModel, error) {
+	if len(providers) == 0 {
+		return nil, fmt.Errorf("at least one chat provider is required")
+	}
+
+	f := &FallbackChatModel{retry: conf.Retry}
+	for _, p := range providers {
+		f.providers = append(f.providers, &fallbackProvider{
+			name:    p.Name,
+			modelID: p.ModelID,
+			model:   p.Model,
+			breaker: newBreaker(conf.BreakerThreshold, conf.BreakerCooldown),
+		})
+	}
+	return f, nil
+}
+
+func (f *FallbackChatModel) Generate(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	var msg *schema.Message
+	p, err := f.try(ctx, func(ctx context.Context, p *fallbackProvider) error {
+		var err error
+		msg, err = p.model.Generate(ctx, input, opts...)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	setProvider(msg, p.name)
+	recordUsage(ctx, p, input, msg.ResponseMeta, msg.Content)
+	return msg, nil
+}
+
+// Stream 首个分片到达前的错误会触发重试与故障转移；开始输出后的错误直接透传
+func (f *FallbackChatModel) Stream(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	var sr *schema.StreamReader[*schema.Message]
+	var first *schema.Message
+	var empty bool
+
+	provider, err := f.try(ctx, func(ctx context.Context, p *fallbackProvider) error {
+		var err error
+		sr, err = p.model.Stream(ctx, input, opts...)
+		if err != nil {
+			return err
+		}
+
+		first, err = sr.Recv()
+		if err == nil && first == nil {
+			first = schema.AssistantMessage("", nil)
+		}
+		if errors.Is(err, io.EOF) {
+			sr.Close()
+			empty = true
+			return nil
+		}
+		if err != nil {
+			sr.Close()
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if empty {
+		return schema.StreamReaderFromArray([]*schema.Message{}), nil
+	}
+
+	setProvider(first, provider.name)
+	out, sender := schema.Pipe[*schema.Message](1)
+	go func() {
+		defer sr.Close()
+		defer sender.Close()
+
+		// 流式用量通常在最后一个分片返回，结束时再记录
+		var meta *schema.ResponseMeta
+		var content strings.Builder
+		track := func(msg *schema.Message) {
+			if msg == nil {
+				return
+			}
+			content.WriteString(msg.Content)
+			if msg.ResponseMeta != nil && msg.ResponseMeta.Usage != nil {
+				meta = msg.ResponseMeta
+			}
+		}
+
+		track(first)
+		if sender.Send(first, nil) {
+			return
+		}
+		for {
+			msg, err := sr.Recv()
+			if errors.Is(err, io.EOF) {
+				recordUsage(ctx, provider, input, meta, content.String())
+				return
+			}
+			track(msg)
+			if sender.Send(msg, err) || err != nil {
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+// WithTools 为每个提供方绑定工具，返回的副本与原实例共享熔断状态
+func (f *FallbackChatModel) WithTools(tools []*schema.ToolInfo) (model.ToolCallingChatModel, error) {
+	bound := &FallbackChatModel{retry: f.retry}
+	for _, p := range f.providers {
+		tcm, ok := p.model.(model.ToolCallingChatModel)
+		if !ok {
+			return nil, fmt.Errorf("chat provider %s does not support tool calling", p.name)
+		}
+		m, err := tcm.WithTools(tools)
+		if err != nil {
+			return nil, fmt.Errorf("bind tools to %s failed: %w", p.name, err)
+		}
+		bound.providers = append(bound.providers, &fallbackProvider{name: p.name, modelID: p.modelID, model: m, breaker: p.breaker})
+	}
+	return bound, nil
+}
+
+// Health 返回各提供方的熔断与失败统计
+func (f *FallbackChatModel) Health() []ProviderHealth {
+	health := make([]ProviderHealth, 0, len(f.providers))
+	for _, p := range f.providers {
+		health = append(health, p.breaker.health(p.name))
+	}
+	return health
+}
+
+// try 按优先级调用 fn，返回成功的提供方
+func (f *FallbackChatModel) try(ctx context.Context, fn func(ctx context.Context, p *fallbackProvider) error) (*fallbackProvider, error) {
+	var errs []string
+	attempted := 0
+	for _, p := range f.providers {
+		if !p.breaker.allow() {
+			errs = append(errs, fmt.Sprintf("%s: circuit open", p.name))
+			continue
+		}
+		attempted++
+
+		err := retry.Do(ctx, f.retry, func(ctx context.Context) error {
+			err := fn(ctx, p)
+			if err != nil && retry.IsTransient(err) {
+				log.Printf("聊天模型 %s 调用失败，准备重试: %v", p.name, err)
+			}
+			return err
+		})
+		if err == nil {
+			p.breaker.success()
+			return p, nil
+		}
+
+		// 调用方取消时不再切换，也不计入提供方失败
+		if ctx.Err() != nil {
+			p.breaker.release()
+			return nil, err
+		}
+
+		// 400/413/422 等请求本身的错误换提供方也无法成功，直接返回且不计入熔断
+		if retry.IsRequestError(err) {
+			p.breaker.release()
+			return nil, err
+		}
+
+		// 其余错误都切换下一个提供方并计入熔断：除可重试错误外，还包括域名解析失败、TLS 握手失败、
+		// 连接被代理关闭，以及 401/403/404 等提供方配置错误或已下线
+		p.breaker.failure(err)
+		log.Printf("聊天模型 %s 不可用，切换下一个提供方: %v", p.name, err)
+		errs = append(errs, fmt.Sprintf("%s: %v", p.name, err))
+	}
+
+	if attempted == 0 {
+		return nil, ErrAllProvidersUnavailable
+	}
+	return nil, fmt.Errorf("all chat providers failed: %s", strings.Join(errs, "; "))
+}
+
+// setProvider 记录应答提供方，复制 Extra 以免改动底层模型持有的数据
+func setProvider(msg *schema.Message, name string) {
+	extra := maps.Clone(msg.Extra)
+	if extra == nil {
+		extra = make(map[string]any)
+	}
+	extra[ExtraKeyProvider] = name
+	msg.Extra = extra
+}
+
+// recordUsage 记录一次成功调用的 token 用量，提供方未返回用量时按文本长度估算
+func recordUsage(ctx context.Context, p *fallbackProvider, input []*schema.Message, meta *schema.ResponseMeta, output string) {
+	if usage.FromContext(ctx) == nil {
+		return
+	}
+
+	rec := usage.Record{
+		Kind:     usage.KindChat,
+		Provider: p.name,
+		Model:    p.modelID,
+	}
+	if meta != nil && meta.Usage != nil {
+		rec.PromptTokens = meta.Usage.PromptTokens
+		rec.CompletionTokens = meta.Usage.CompletionTokens
+		rec.TotalTokens = meta.Usage.TotalTokens
+	} else {
+		counter := token.ForProvider(p.name)
+		for _, msg := range input {
+			rec.PromptTokens += counter.Count(msg.Content)
+		}
+		rec.CompletionTokens = counter.Count(output)
+		rec.Estimated = true
+	}
+	usage.Add(ctx, rec)
+}
+
+// ProviderOf 返回消息的实际应答提供方，非故障转移模型生成的消息返回空
+func ProviderOf(msg *schema.Message) string {
+	if msg == nil {
+		return ""
+	}
+	name, _ := msg.Extra[ExtraKeyProvider].(string)
+	return name
+}
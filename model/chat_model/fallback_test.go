@@ -0,0 +1,232 @@
+package chat_model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-agent/config"
+	"go-agent/model/retry"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+// failingChatModel 始终返回指定错误，并记录调用次数
+type failingChatModel struct {
+	err   error
+	calls int
+}
+
+func (m *failingChatModel) Generate(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	m.calls++
+	return nil, m.err
+}
+
+func (m *failingChatModel) Stream(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	m.calls++
+	return nil, m.err
+}
+
+var testFallbackConf = FallbackConfig{
+	Retry:            retry.Policy{MaxRetries: 2},
+	BreakerThreshold: 2,
+	BreakerCooldown:  time.Hour,
+}
+
+func TestFallbackFailsOverAndReportsProvider(t *testing.T) {
+	down := &failingChatModel{err: &retry.StatusError{StatusCode: 503, Err: errors.New("service unavailable")}}
+	fm, _ := NewFallbackChatModel([]Provider{
+		{Name: "ark", Model: down},
+		{Name: "qwen", Model: NewFakeChatModel()},
+	}, testFallbackConf)
+
+	msg, err := fm.Generate(context.Background(), []*schema.Message{schema.UserMessage("hi")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ProviderOf(msg) != "qwen" || msg.Content != "echo: hi" {
+		t.Fatalf("got provider %q content %q", ProviderOf(msg), msg.Content)
+	}
+	// 503 属于临时错误：首次调用 + 2 次重试
+	if down.calls != 3 {
+		t.Fatalf("transient error called %d times, want 3", down.calls)
+	}
+}
+
+func TestFallbackRequestErrorsDoNotFailOver(t *testing.T) {
+	for _, code := range []int{400, 413, 422} {
+		bad := &failingChatModel{err: &retry.StatusError{StatusCode: code, Err: errors.New("invalid request")}}
+		backup := NewFakeChatModel()
+		fm, _ := NewFallbackChatModel([]Provider{
+			{Name: "ark", Model: bad},
+			{Name: "openai", Model: backup},
+		}, testFallbackConf)
+
+		for range testFallbackConf.BreakerThreshold + 1 {
+			_, err := fm.Generate(context.Background(), []*schema.Message{schema.UserMessage("hi")})
+			var statusErr *retry.StatusError
+			if !errors.As(err, &statusErr) || statusErr.StatusCode != code {
+				t.Fatalf("%d: err = %v, want the request error returned as is", code, err)
+			}
+		}
+		// 不重试、不切换提供方，也不计入熔断
+		if bad.calls != testFallbackConf.BreakerThreshold+1 || len(backup.Inputs()) != 0 {
+			t.Fatalf("%d: calls = %d, backup calls = %d", code, bad.calls, len(backup.Inputs()))
+		}
+		if h := fm.Health()[0]; h.State != BreakerClosed || h.ConsecutiveFailures != 0 || h.TotalFailures != 0 {
+			t.Fatalf("%d: breaker touched: %+v", code, h)
+		}
+	}
+}
+
+func TestFallbackFailsOverOnNonTransientProviderErrors(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+	}{
+		{"dns not found", &net.OpError{Op: "dial", Net: "tcp", Err: &net.DNSError{Err: "no such host", Name: "ark.example.com", IsNotFound: true}}},
+		{"eof", fmt.Errorf("post chat completions: %w", io.EOF)},
+		{"unauthorized", &retry.StatusError{StatusCode: 401, Err: errors.New("invalid api key")}},
+		{"forbidden", &retry.StatusError{StatusCode: 403, Err: errors.New("forbidden")}},
+		{"not found", &retry.StatusError{StatusCode: 404, Err: errors.New("endpoint not found")}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			down := &failingChatModel{err: tc.err}
+			fm, _ := NewFallbackChatModel([]Provider{
+				{Name: "ark", Model: down},
+				{Name: "qwen", Model: NewFakeChatModel()},
+			}, testFallbackConf)
+
+			msg, err := fm.Generate(context.Background(), []*schema.Message{schema.UserMessage("hi")})
+			if err != nil || ProviderOf(msg) != "qwen" {
+				t.Fatalf("err = %v, want failover to qwen", err)
+			}
+			// 不可重试：只调用一次，但计入熔断
+			if down.calls != 1 {
+				t.Fatalf("primary called %d times, want 1", down.calls)
+			}
+			if h := fm.Health()[0]; h.ConsecutiveFailures != 1 || h.TotalFailures != 1 {
+				t.Fatalf("failure not counted: %+v", h)
+			}
+		})
+	}
+}
+
+func TestFallbackCircuitBreaker(t *testing.T) {
+	down := &failingChatModel{err: &retry.StatusError{StatusCode: 502, Err: errors.New("bad gateway")}}
+	fm, _ := NewFallbackChatModel([]Provider{{Name: "ark", Model: down}}, testFallbackConf)
+	input := []*schema.Message{schema.UserMessage("hi")}
+
+	for range 2 {
+		if _, err := fm.Generate(context.Background(), input); err == nil || errors.Is(err, ErrAllProvidersUnavailable) {
+			t.Fatalf("expected provider failure, got %v", err)
+		}
+	}
+	if _, err := fm.Generate(context.Background(), input); !errors.Is(err, ErrAllProvidersUnavailable) {
+		t.Fatalf("expected open circuit, got %v", err)
+	}
+	// 两次请求各含 2 次重试，熔断后不再调用
+	if down.calls != 6 {
+		t.Fatalf("open circuit still called provider: %d calls", down.calls)
+	}
+
+	health := fm.Health()
+	if health[0].State != BreakerOpen || health[0].ConsecutiveFailures != 2 || health[0].OpenUntil == nil {
+		t.Fatalf("unexpected health: %+v", health[0])
+	}
+}
+
+func TestBreakerHalfOpenAllowsSingleProbe(t *testing.T) {
+	b := newBreaker(1, 0)
+	b.failure(errors.New("boom"))
+
+	if !b.allow() {
+		t.Fatal("expected probe after cooldown")
+	}
+	if b.allow() {
+		t.Fatal("only one probe should be in flight")
+	}
+	b.success()
+	if h := b.health("x"); h.State != BreakerClosed {
+		t.Fatalf("state = %s, want closed", h.State)
+	}
+}
+
+func TestFallbackStream(t *testing.T) {
+	down := &failingChatModel{err: &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}}
+	fm, _ := NewFallbackChatModel([]Provider{
+		{Name: "ark", Model: down},
+		{Name: "fake", Model: NewFakeChatModel()},
+	}, FallbackConfig{BreakerThreshold: 3, BreakerCooldown: time.Minute})
+
+	sr, err := fm.Stream(context.Background(), []*schema.Message{schema.UserMessage("a longer streamed reply")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sr.Close()
+
+	var msgs []*schema.Message
+	for {
+		msg, err := sr.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		msgs = append(msgs, msg)
+	}
+
+	full, err := schema.ConcatMessages(msgs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if full.Content != "echo: a longer streamed reply" || ProviderOf(full) != "fake" {
+		t.Fatalf("got %q from %q", full.Content, ProviderOf(full))
+	}
+	if !strings.Contains(fm.Health()[0].LastError, "connection refused") {
+		t.Fatalf("failure not recorded: %+v", fm.Health()[0])
+	}
+}
+
+func TestNewChatModelLoadsConfiguredProviders(t *testing.T) {
+	config.Cfg = &config.Config{
+		ChatModelType: "fake",
+		OllamaConf:    config.OllamaConfig{BaseUrl: "http://localhost:11434", ChatModel: "qwen2.5:7b"},
+	}
+
+	cm, err := NewChatModel(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	CM = cm
+
+	infos := List()
+	if len(infos) != 2 || infos[0].Name != "ollama" || infos[0].Default || infos[1].Name != "fake" || !infos[1].Default {
+		t.Fatalf("unexpected models: %+v", infos)
+	}
+	if !infos[1].Capabilities.ToolCalling {
+		t.Fatal("fake model should support tool calling")
+	}
+
+	m, err := Get("fake")
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, _ := m.Generate(context.Background(), []*schema.Message{schema.UserMessage("hi")})
+	if ProviderOf(msg) != "fake" {
+		t.Fatalf("provider = %q", ProviderOf(msg))
+	}
+	if _, err := Get("ark"); err == nil {
+		t.Fatal("unconfigured provider should not be loaded")
+	}
+}
@@ -0,0 +1,33 @@
+package chat_model
+
+import (
+	"context"
+	"go-agent/config"
+	"strings"
+
+	"github.com/cloudwego/eino-ext/components/model/openai"
+	model2 "github.com/cloudwego/eino/components/model"
+)
+
+// ollamaAPIKey Ollama 不校验密钥，但 OpenAI 客户端要求非空
+const ollamaAPIKey = "ollama"
+
+// initOllama 通过 Ollama 的 OpenAI 兼容接口 (/v1) 接入本地模型
+func initOllama() {
+	registerChatModel("ollama", func(ctx context.Context) (model2.BaseChatModel, error) {
+		params, err := generationDefaults("ollama")
+		if err != nil {
+			return nil, err
+		}
+		return openai.NewChatModel(ctx, &openai.ChatModelConfig{
+			BaseURL:     strings.TrimRight(config.Cfg.OllamaConf.BaseUrl, "/") + "/v1",
+			APIKey:      ollamaAPIKey,
+			Model:       config.Cfg.OllamaConf.ChatModel,
+			Temperature: params.Temperature,
+			MaxTokens:   params.MaxTokens,
+			TopP:        params.TopP,
+			Stop:        params.Stop,
+			Seed:        params.Seed,
+		})
+	})
+}
@@ -10,9 +10,19 @@ import (
 
 func initOpenAI() {
 	registerChatModel("openai", func(ctx context.Context) (model2.BaseChatModel, error) {
+		params, err := generationDefaults("openai")
+		if err != nil {
+			return nil, err
+		}
 		return openai.NewChatModel(ctx, &openai.ChatModelConfig{
-			APIKey: config.Cfg.OpenAIConf.OpenAIKey,
-			Model:  config.Cfg.OpenAIConf.OpenAIChatModel,
+			BaseURL:     config.Cfg.OpenAIConf.BaseUrl,
+			APIKey:      config.Cfg.OpenAIConf.OpenAIKey,
+			Model:       config.Cfg.OpenAIConf.OpenAIChatModel,
+			Temperature: params.Temperature,
+			MaxTokens:   params.MaxTokens,
+			TopP:        params.TopP,
+			Stop:        params.Stop,
+			Seed:        params.Seed,
 		})
 	})
 }
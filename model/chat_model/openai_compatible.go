@@ -0,0 +1,32 @@
+package chat_model
+
+import (
+	"context"
+	"go-agent/config"
+	"go-agent/model/transport"
+
+	"github.com/cloudwego/eino-ext/components/model/openai"
+	model2 "github.com/cloudwego/eino/components/model"
+)
+
+// initOpenAICompatible 接入任意兼容 OpenAI 接口的服务（vLLM、内部网关等）
+func initOpenAICompatible() {
+	registerChatModel("openai_compatible", func(ctx context.Context) (model2.BaseChatModel, error) {
+		conf := config.Cfg.OpenAICompatibleConf
+		params, err := generationDefaults("openai_compatible")
+		if err != nil {
+			return nil, err
+		}
+		return openai.NewChatModel(ctx, &openai.ChatModelConfig{
+			BaseURL:     conf.BaseUrl,
+			APIKey:      conf.APIKey,
+			Model:       conf.ChatModel,
+			HTTPClient:  transport.NewHTTPClient(transport.ParseHeaders(conf.Headers)),
+			Temperature: params.Temperature,
+			MaxTokens:   params.MaxTokens,
+			TopP:        params.TopP,
+			Stop:        params.Stop,
+			Seed:        params.Seed,
+		})
+	})
+}
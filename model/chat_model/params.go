@@ -0,0 +1,138 @@
+package chat_model
+
+import (
+	"encoding/json"
+	"fmt"
+	"go-agent/config"
+
+	"github.com/cloudwego/eino-ext/components/model/openai"
+	"github.com/cloudwego/eino/components/model"
+)
+
+// GenerationParams 生成参数，未设置的字段使用提供方配置的默认值或服务端默认值
+type GenerationParams struct {
+	Temperature *float32 `json:"temperature,omitempty"`
+	MaxTokens   *int     `json:"max_tokens,omitempty"`
+	TopP        *float32 `json:"top_p,omitempty"`
+	Stop        []string `json:"stop,omitempty"`
+	Seed        *int     `json:"seed,omitempty"`
+}
+
+// paramLimits 提供方对生成参数的取值限制
+type paramLimits struct {
+	maxTemperature float32
+	topPExclusive0 bool // top_p 是否不允许取 0
+	maxStop        int  // stop 序列数量上限，0 表示不限
+	seed           bool // 是否支持 seed
+}
+
+// providerLimits 各提供方的参数限制，未列出的提供方只做通用校验
+var providerLimits = map[string]paramLimits{
+	"ark":               {maxTemperature: 1, maxStop: 4},
+	"openai":            {maxTemperature: 2, maxStop: 4, seed: true},
+	"qwen":              {maxTemperature: 2, topPExclusive0: true, seed: true},
+	"ollama":            {maxTemperature: 2, seed: true},
+	"openai_compatible": {maxTemperature: 2, seed: true},
+}
+
+// IsZero 是否未设置任何参数
+func (p GenerationParams) IsZero() bool {
+	return p.Temperature == nil && p.MaxTokens == nil && p.TopP == nil && len(p.Stop) == 0 && p.Seed == nil
+}
+
+// Options 转换为 eino 模型调用选项
+// seed 不在 eino 通用选项中，通过 OpenAI 协议的额外请求字段传递（openai/qwen/ollama/openai_compatible 共用该实现）
+func (p GenerationParams) Options() []model.Option {
+	var opts []model.Option
+	if p.Temperature != nil {
+		opts = append(opts, model.WithTemperature(*p.Temperature))
+	}
+	if p.MaxTokens != nil {
+		opts = append(opts, model.WithMaxTokens(*p.MaxTokens))
+	}
+	if p.TopP != nil {
+		opts = append(opts, model.WithTopP(*p.TopP))
+	}
+	if len(p.Stop) > 0 {
+		opts = append(opts, model.WithStop(p.Stop))
+	}
+	if p.Seed != nil {
+		opts = append(opts, openai.WithExtraFields(map[string]any{"seed": *p.Seed}))
+	}
+	return opts
+}
+
+// Validate 按提供方限制校验参数
+func (p GenerationParams) Validate(provider string) error {
+	if p.Temperature != nil && *p.Temperature < 0 {
+		return fmt.Errorf("temperature must be >= 0")
+	}
+	if p.MaxTokens != nil && *p.MaxTokens <= 0 {
+		return fmt.Errorf("max_tokens must be > 0")
+	}
+	if p.TopP != nil && (*p.TopP < 0 || *p.TopP > 1) {
+		return fmt.Errorf("top_p must be in [0, 1]")
+	}
+	if p.Seed != nil && *p.Seed < 0 {
+		return fmt.Errorf("seed must be >= 0")
+	}
+
+	limits, ok := providerLimits[provider]
+	if !ok {
+		return nil
+	}
+	if p.Temperature != nil && *p.Temperature > limits.maxTemperature {
+		return fmt.Errorf("%s: temperature must be in [0, %g]", provider, limits.maxTemperature)
+	}
+	if p.TopP != nil && limits.topPExclusive0 && *p.TopP == 0 {
+		return fmt.Errorf("%s: top_p must be in (0, 1]", provider)
+	}
+	if limits.maxStop > 0 && len(p.Stop) > limits.maxStop {
+		return fmt.Errorf("%s: at most %d stop sequences are allowed", provider, limits.maxStop)
+	}
+	if p.Seed != nil && !limits.seed {
+		return fmt.Errorf("%s: seed is not supported", provider)
+	}
+	return nil
+}
+
+// ValidateParams 按 name 选中的模型校验参数；默认故障转移链需满足链上每个提供方的限制
+func ValidateParams(name string, p GenerationParams) error {
+	if p.IsZero() {
+		return nil
+	}
+
+	m, err := Get(name)
+	if err != nil {
+		return err
+	}
+	fm, ok := m.(*FallbackChatModel)
+	if !ok {
+		return p.Validate("")
+	}
+	for _, provider := range fm.providers {
+		if err := p.Validate(provider.name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// generationDefaults 读取 CHAT_GENERATION_DEFAULTS 中提供方的默认生成参数
+func generationDefaults(provider string) (GenerationParams, error) {
+	var params GenerationParams
+	raw := config.Cfg.ChatGenerationDefaults
+	if raw == "" {
+		return params, nil
+	}
+
+	var all map[string]GenerationParams
+	if err := json.Unmarshal([]byte(raw), &all); err != nil {
+		return params, fmt.Errorf("invalid CHAT_GENERATION_DEFAULTS: %w", err)
+	}
+	params = all[provider]
+	if err := params.Validate(provider); err != nil {
+		return params, fmt.Errorf("invalid CHAT_GENERATION_DEFAULTS: %w", err)
+	}
+	return params, nil
+}
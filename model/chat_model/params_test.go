@@ -0,0 +1,58 @@
+package chat_model
+
+import (
+	"go-agent/config"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/eino/components/model"
+)
+
+func ptr[T any](v T) *T { return &v }
+
+func TestGenerationParamsValidate(t *testing.T) {
+	cases := []struct {
+		provider string
+		params   GenerationParams
+		wantErr  string
+	}{
+		{"openai", GenerationParams{Temperature: ptr[float32](1.5), Seed: ptr(7)}, ""},
+		{"ark", GenerationParams{Temperature: ptr[float32](1.5)}, "temperature must be in [0, 1]"},
+		{"ark", GenerationParams{Seed: ptr(1)}, "seed is not supported"},
+		{"openai", GenerationParams{Stop: []string{"a", "b", "c", "d", "e"}}, "at most 4 stop sequences"},
+		{"qwen", GenerationParams{TopP: ptr[float32](0)}, "top_p must be in (0, 1]"},
+		{"fake", GenerationParams{MaxTokens: ptr(0)}, "max_tokens must be > 0"},
+		{"fake", GenerationParams{Temperature: ptr[float32](5)}, ""},
+	}
+
+	for _, tc := range cases {
+		err := tc.params.Validate(tc.provider)
+		if tc.wantErr == "" && err != nil {
+			t.Errorf("%s %+v: unexpected error %v", tc.provider, tc.params, err)
+		}
+		if tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)) {
+			t.Errorf("%s %+v: got %v, want %q", tc.provider, tc.params, err, tc.wantErr)
+		}
+	}
+}
+
+func TestGenerationParamsOptions(t *testing.T) {
+	p := GenerationParams{Temperature: ptr[float32](0.2), MaxTokens: ptr(128), TopP: ptr[float32](0.9), Stop: []string{"END"}}
+	o := model.GetCommonOptions(nil, p.Options()...)
+	if *o.Temperature != 0.2 || *o.MaxTokens != 128 || *o.TopP != 0.9 || o.Stop[0] != "END" {
+		t.Fatalf("unexpected options: %+v", o)
+	}
+}
+
+func TestGenerationDefaults(t *testing.T) {
+	config.Cfg = &config.Config{ChatGenerationDefaults: `{"ark":{"temperature":0.3,"max_tokens":2048}}`}
+	p, err := generationDefaults("ark")
+	if err != nil || *p.Temperature != 0.3 || *p.MaxTokens != 2048 {
+		t.Fatalf("got %+v, %v", p, err)
+	}
+
+	config.Cfg.ChatGenerationDefaults = `{"ark":{"temperature":1.8}}`
+	if _, err := generationDefaults("ark"); err == nil {
+		t.Fatal("out-of-range default should be rejected")
+	}
+}
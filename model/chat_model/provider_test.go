@@ -0,0 +1,120 @@
+package chat_model
+
+import (
+	"context"
+	"encoding/json"
+	"go-agent/config"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+// capturedRequest 兼容 OpenAI 接口的测试服务收到的请求
+type capturedRequest struct {
+	Path   string
+	Header http.Header
+	Model  string
+}
+
+// newOpenAIServer 启动模拟 OpenAI 聊天接口的测试服务，记录收到的请求
+func newOpenAIServer(t *testing.T) (*httptest.Server, func() []capturedRequest) {
+	t.Helper()
+	var mu sync.Mutex
+	var requests []capturedRequest
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Model string `json:"model"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		mu.Lock()
+		requests = append(requests, capturedRequest{Path: r.URL.Path, Header: r.Header.Clone(), Model: body.Model})
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"chatcmpl-1","object":"chat.completion","created":1,"model":"` + body.Model + `",` +
+			`"choices":[{"index":0,"message":{"role":"assistant","content":"hello from server"},"finish_reason":"stop"}],` +
+			`"usage":{"prompt_tokens":5,"completion_tokens":3,"total_tokens":8}}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, func() []capturedRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]capturedRequest(nil), requests...)
+	}
+}
+
+func TestOpenAICompatibleChatModel(t *testing.T) {
+	srv, requests := newOpenAIServer(t)
+	config.Cfg = &config.Config{
+		OpenAICompatibleConf: config.OpenAICompatibleConfig{
+			BaseUrl:   srv.URL + "/gateway/v1",
+			APIKey:    "secret",
+			Headers:   "X-Tenant: team-a, X-Trace:abc",
+			ChatModel: "qwen2.5-72b-instruct",
+		},
+	}
+	initOpenAICompatible()
+
+	cm, err := chatModelRegistry["openai_compatible"](context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, err := cm.Generate(context.Background(), []*schema.Message{schema.UserMessage("hi")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Content != "hello from server" {
+		t.Fatalf("content = %q", msg.Content)
+	}
+
+	got := requests()
+	if len(got) != 1 {
+		t.Fatalf("server got %d requests", len(got))
+	}
+	req := got[0]
+	if req.Path != "/gateway/v1/chat/completions" {
+		t.Fatalf("path = %q, base url not used", req.Path)
+	}
+	if req.Model != "qwen2.5-72b-instruct" {
+		t.Fatalf("model = %q", req.Model)
+	}
+	if req.Header.Get("Authorization") != "Bearer secret" {
+		t.Fatalf("authorization = %q", req.Header.Get("Authorization"))
+	}
+	if req.Header.Get("X-Tenant") != "team-a" || req.Header.Get("X-Trace") != "abc" {
+		t.Fatalf("custom headers missing: %v", req.Header)
+	}
+}
+
+func TestOllamaChatModel(t *testing.T) {
+	srv, requests := newOpenAIServer(t)
+	config.Cfg = &config.Config{
+		OllamaConf: config.OllamaConfig{BaseUrl: srv.URL + "/", ChatModel: "llama3.1:8b"},
+	}
+	initOllama()
+
+	cm, err := chatModelRegistry["ollama"](context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cm.Generate(context.Background(), []*schema.Message{schema.UserMessage("hi")}); err != nil {
+		t.Fatal(err)
+	}
+
+	got := requests()
+	if len(got) != 1 {
+		t.Fatalf("server got %d requests", len(got))
+	}
+	// 结尾的 / 被去掉后拼接 Ollama 的 OpenAI 兼容路径
+	if got[0].Path != "/v1/chat/completions" || got[0].Model != "llama3.1:8b" {
+		t.Fatalf("request = %+v", got[0])
+	}
+	if got[0].Header.Get("Authorization") != "Bearer "+ollamaAPIKey {
+		t.Fatalf("authorization = %q", got[0].Header.Get("Authorization"))
+	}
+}
@@ -10,10 +10,19 @@ import (
 
 func initQwen() {
 	registerChatModel("qwen", func(ctx context.Context) (model2.BaseChatModel, error) {
+		params, err := generationDefaults("qwen")
+		if err != nil {
+			return nil, err
+		}
 		return qwen.NewChatModel(ctx, &qwen.ChatModelConfig{
-			BaseURL: "https://dashscope.aliyuncs.com/compatible-mode/v1",
-			APIKey:  config.Cfg.QwenConf.QwenKey,
-			Model:   config.Cfg.QwenConf.QwenChatModel,
+			BaseURL:     "https://dashscope.aliyuncs.com/compatible-mode/v1",
+			APIKey:      config.Cfg.QwenConf.QwenKey,
+			Model:       config.Cfg.QwenConf.QwenChatModel,
+			Temperature: params.Temperature,
+			MaxTokens:   params.MaxTokens,
+			TopP:        params.TopP,
+			Stop:        params.Stop,
+			Seed:        params.Seed,
 		})
 	})
 }
@@ -0,0 +1,191 @@
+package embedding_model
+
+import (
+	"context"
+	"fmt"
+	"go-agent/model/retry"
+	"go-agent/model/token"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/cloudwego/eino/components/embedding"
+)
+
+// defaultBatchSize 未知提供方的默认批大小
+const defaultBatchSize = 64
+
+// defaultBatchSizes 各提供方单次请求允许的最大文本条数
+var defaultBatchSizes = map[string]int{
+	"ark":    256,
+	"openai": 512,
+	"qwen":   10,
+}
+
+// BatchConfig 批量嵌入配置
+type BatchConfig struct {
+	BatchSize         int // 单次请求的最大文本条数
+	Concurrency       int // 并发请求数
+	RequestsPerMinute int // 每分钟请求数上限，0 表示不限
+	TokensPerMinute   int // 每分钟 token 上限（估算值），0 表示不限
+	Retry             retry.Policy
+}
+
+// BatchEmbedder 批量嵌入装饰器：按提供方批大小切分、限流并发调用，并对 429/5xx 退避重试
+type BatchEmbedder struct {
+	embedder embedding.Embedder
+	conf     BatchConfig
+	requests *minuteLimiter
+	tokens   *minuteLimiter
+}
+
+// NewBatchEmbedder 创建批量嵌入装饰器
+func NewBatchEmbedder(embedder embedding.Embedder, conf BatchConfig) *BatchEmbedder {
+	if conf.BatchSize <= 0 {
+		conf.BatchSize = defaultBatchSize
+	}
+	if conf.Concurrency <= 0 {
+		conf.Concurrency = 1
+	}
+
+	return &BatchEmbedder{
+		embedder: embedder,
+		conf:     conf,
+		requests: newMinuteLimiter(conf.RequestsPerMinute),
+		tokens:   newMinuteLimiter(conf.TokensPerMinute),
+	}
+}
+
+func (b *BatchEmbedder) EmbedStrings(ctx context.Context, texts []string, opts ...embedding.Option) ([][]float64, error) {
+	if len(texts) <= b.conf.BatchSize {
+		return b.embedBatch(ctx, texts, opts...)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	vectors := make([][]float64, len(texts))
+	sem := make(chan struct{}, b.conf.Concurrency)
+	var wg sync.WaitGroup
+	var once sync.Once
+	var firstErr error
+
+	for start := 0; start < len(texts); start += b.conf.BatchSize {
+		end := min(start+b.conf.BatchSize, len(texts))
+
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func(start, end int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			batch, err := b.embedBatch(ctx, texts[start:end], opts...)
+			if err != nil {
+				once.Do(func() {
+					firstErr = fmt.Errorf("embed batch [%d,%d) failed: %w", start, end, err)
+					cancel()
+				})
+				return
+			}
+			copy(vectors[start:end], batch)
+		}(start, end)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return vectors, nil
+}
+
+func (b *BatchEmbedder) embedBatch(ctx context.Context, texts []string, opts ...embedding.Option) ([][]float64, error) {
+	tokens := 0
+	for _, text := range texts {
+		tokens += token.Estimate(text)
+	}
+
+	var vectors [][]float64
+	err := retry.Do(ctx, b.conf.Retry, func(ctx context.Context) error {
+		if err := b.requests.wait(ctx, 1); err != nil {
+			return err
+		}
+		if err := b.tokens.wait(ctx, tokens); err != nil {
+			return err
+		}
+
+		var err error
+		vectors, err = b.embedder.EmbedStrings(ctx, texts, opts...)
+		if err != nil && retry.IsTransient(err) {
+			log.Printf("嵌入请求失败，准备重试: %v", err)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(vectors) != len(texts) {
+		return nil, fmt.Errorf("embedding result length not match need: %d, got: %d", len(texts), len(vectors))
+	}
+	return vectors, nil
+}
+
+// minuteLimiter 按分钟配额匀速补充的令牌桶
+type minuteLimiter struct {
+	mu       sync.Mutex
+	capacity float64
+	perSec   float64
+	tokens   float64
+	last     time.Time
+}
+
+func newMinuteLimiter(perMinute int) *minuteLimiter {
+	if perMinute <= 0 {
+		return nil
+	}
+	return &minuteLimiter{
+		capacity: float64(perMinute),
+		perSec:   float64(perMinute) / 60,
+		tokens:   float64(perMinute),
+		last:     time.Now(),
+	}
+}
+
+// wait 阻塞直到可以取出 n 个令牌；n 超过桶容量时按容量计，避免永远等待
+func (l *minuteLimiter) wait(ctx context.Context, n int) error {
+	if l == nil {
+		return nil
+	}
+	need := min(float64(n), l.capacity)
+
+	for {
+		l.mu.Lock()
+		now := time.Now()
+		l.tokens = min(l.capacity, l.tokens+now.Sub(l.last).Seconds()*l.perSec)
+		l.last = now
+		if l.tokens >= need {
+			l.tokens -= need
+			l.mu.Unlock()
+			return nil
+		}
+		delay := time.Duration((need - l.tokens) / l.perSec * float64(time.Second))
+		l.mu.Unlock()
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
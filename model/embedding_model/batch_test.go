@@ -0,0 +1,181 @@
+package embedding_model
+
+import (
+	"context"
+	"errors"
+	"go-agent/model/retry"
+	"math/rand/v2"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cloudwego/eino/components/embedding"
+)
+
+// indexEmbedder 把文本 "i" 嵌入为 [i]，记录每次请求的批大小，可按批注入错误
+type indexEmbedder struct {
+	mu      sync.Mutex
+	batches []int
+	fail    func(call int, texts []string) error
+}
+
+func (e *indexEmbedder) EmbedStrings(ctx context.Context, texts []string, opts ...embedding.Option) ([][]float64, error) {
+	e.mu.Lock()
+	e.batches = append(e.batches, len(texts))
+	call := len(e.batches)
+	e.mu.Unlock()
+
+	if e.fail != nil {
+		if err := e.fail(call, texts); err != nil {
+			return nil, err
+		}
+	}
+	// 打乱各批完成顺序
+	time.Sleep(time.Duration(rand.N(5)) * time.Millisecond)
+
+	vectors := make([][]float64, len(texts))
+	for i, text := range texts {
+		n, _ := strconv.Atoi(text)
+		vectors[i] = []float64{float64(n)}
+	}
+	return vectors, nil
+}
+
+func numberTexts(n int) []string {
+	texts := make([]string, n)
+	for i := range texts {
+		texts[i] = strconv.Itoa(i)
+	}
+	return texts
+}
+
+func TestBatchEmbedderSplitsAndKeepsOrder(t *testing.T) {
+	cases := []struct {
+		name        string
+		texts       int
+		batchSize   int
+		concurrency int
+		wantBatches int
+	}{
+		{"single batch", 5, 10, 4, 1},
+		{"exact multiple", 12, 4, 3, 3},
+		{"remainder", 10, 3, 4, 4},
+		{"sequential", 7, 2, 1, 4},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			inner := &indexEmbedder{}
+			b := NewBatchEmbedder(inner, BatchConfig{BatchSize: tc.batchSize, Concurrency: tc.concurrency})
+
+			vectors, err := b.EmbedStrings(context.Background(), numberTexts(tc.texts))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i, vec := range vectors {
+				if len(vec) != 1 || vec[0] != float64(i) {
+					t.Fatalf("vector %d = %v, order not preserved", i, vec)
+				}
+			}
+			if len(inner.batches) != tc.wantBatches {
+				t.Fatalf("batches = %v, want %d", inner.batches, tc.wantBatches)
+			}
+			total := 0
+			for _, n := range inner.batches {
+				if n > tc.batchSize {
+					t.Fatalf("batch of %d exceeds size %d", n, tc.batchSize)
+				}
+				total += n
+			}
+			if total != tc.texts {
+				t.Fatalf("embedded %d texts, want %d", total, tc.texts)
+			}
+		})
+	}
+}
+
+func TestBatchEmbedderRetriesTransientErrors(t *testing.T) {
+	inner := &indexEmbedder{fail: func(call int, texts []string) error {
+		if call == 1 {
+			return &retry.StatusError{StatusCode: 429, Err: errors.New("rate limited")}
+		}
+		return nil
+	}}
+	b := NewBatchEmbedder(inner, BatchConfig{BatchSize: 10, Retry: retry.Policy{MaxRetries: 2, BaseBackoff: time.Millisecond, MaxBackoff: time.Millisecond}})
+
+	vectors, err := b.EmbedStrings(context.Background(), numberTexts(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vectors) != 3 || len(inner.batches) != 2 {
+		t.Fatalf("vectors = %v, calls = %d", vectors, len(inner.batches))
+	}
+}
+
+func TestBatchEmbedderReportsFailedBatch(t *testing.T) {
+	bad := &retry.StatusError{StatusCode: 400, Err: errors.New("input too long")}
+	inner := &indexEmbedder{fail: func(call int, texts []string) error {
+		if texts[0] == "4" {
+			return bad
+		}
+		return nil
+	}}
+	b := NewBatchEmbedder(inner, BatchConfig{BatchSize: 2, Concurrency: 1, Retry: retry.Policy{MaxRetries: 3}})
+
+	_, err := b.EmbedStrings(context.Background(), numberTexts(8))
+	if !errors.Is(err, bad) || err.Error() != "embed batch [4,6) failed: "+bad.Error() {
+		t.Fatalf("err = %v", err)
+	}
+	// 客户端错误不重试，失败后不再发起后续批次
+	if len(inner.batches) != 3 {
+		t.Fatalf("batches = %v, want 3 calls", inner.batches)
+	}
+}
+
+func TestMinuteLimiterWaits(t *testing.T) {
+	l := newMinuteLimiter(6000) // 100 个/秒
+	ctx := context.Background()
+
+	start := time.Now()
+	if err := l.wait(ctx, 6000); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 20*time.Millisecond {
+		t.Fatalf("full bucket should not wait, took %v", elapsed)
+	}
+
+	start = time.Now()
+	if err := l.wait(ctx, 10); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 80*time.Millisecond || elapsed > time.Second {
+		t.Fatalf("empty bucket waited %v, want about 100ms", elapsed)
+	}
+
+	// 超过容量的请求按容量计
+	if err := newMinuteLimiter(60).wait(ctx, 1000); err != nil {
+		t.Fatal(err)
+	}
+	// 未配置限额时不限流
+	if err := newMinuteLimiter(0).wait(ctx, 1<<30); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMinuteLimiterCancel(t *testing.T) {
+	l := newMinuteLimiter(60)
+	if err := l.wait(context.Background(), 60); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := l.wait(ctx, 30); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want deadline exceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("wait not interrupted, took %v", elapsed)
+	}
+}
@@ -0,0 +1,252 @@
+package embedding_model
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"sync/atomic"
+
+	"github.com/cloudwego/eino/components/embedding"
+)
+
+// CacheStats 嵌入缓存命中统计
+type CacheStats struct {
+	Model     string  `json:"model"`
+	Size      int     `json:"size"`     // 内存中的条目数
+	Capacity  int     `json:"capacity"` // 内存 LRU 容量
+	DiskDir   string  `json:"disk_dir,omitempty"`
+	Hits      int64   `json:"hits"`
+	DiskHits  int64   `json:"disk_hits"` // hits 中来自磁盘的部分
+	Misses    int64   `json:"misses"`
+	HitRate   float64 `json:"hit_rate"`
+	Evictions int64   `json:"evictions"`
+}
+
+// CachedEmbedder 嵌入结果缓存装饰器，按 提供方/模型/文本哈希 缓存向量
+// 内存使用 LRU，可选落盘以便重启后复用
+type CachedEmbedder struct {
+	embedder embedding.Embedder
+	model    string
+	capacity int
+	dir      string
+
+	mu    sync.Mutex
+	ll    *list.List
+	items map[string]*list.Element
+
+	hits      atomic.Int64
+	diskHits  atomic.Int64
+	misses    atomic.Int64
+	evictions atomic.Int64
+}
+
+type cacheEntry struct {
+	key    string
+	vector []float64
+}
+
+// NewCachedEmbedder 创建缓存装饰器，dir 为空时只使用内存缓存
+func NewCachedEmbedder(embedder embedding.Embedder, model string, capacity int, dir string) (*CachedEmbedder, error) {
+	if capacity <= 0 {
+		return nil, fmt.Errorf("embedding cache capacity must be positive")
+	}
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("create embedding cache dir failed: %w", err)
+		}
+	}
+
+	return &CachedEmbedder{
+		embedder: embedder,
+		model:    model,
+		capacity: capacity,
+		dir:      dir,
+		ll:       list.New(),
+		items:    make(map[string]*list.Element),
+	}, nil
+}
+
+func (c *CachedEmbedder) EmbedStrings(ctx context.Context, texts []string, opts ...embedding.Option) ([][]float64, error) {
+	model := c.model
+	if o := embedding.GetCommonOptions(nil, opts...); o.Model != nil && *o.Model != "" {
+		model = c.model + "@" + *o.Model
+	}
+
+	vectors := make([][]float64, len(texts))
+	keys := make([]string, len(texts))
+	var missTexts []string
+	var missIdx []int
+
+	for i, text := range texts {
+		keys[i] = cacheKey(model, text)
+		if vec, ok := c.get(keys[i]); ok {
+			vectors[i] = vec
+			c.hits.Add(1)
+			continue
+		}
+		missTexts = append(missTexts, text)
+		missIdx = append(missIdx, i)
+	}
+
+	if len(missTexts) == 0 {
+		return vectors, nil
+	}
+	c.misses.Add(int64(len(missTexts)))
+
+	embedded, err := c.embedder.EmbedStrings(ctx, missTexts, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if len(embedded) != len(missTexts) {
+		return nil, fmt.Errorf("embedding result length not match need: %d, got: %d", len(missTexts), len(embedded))
+	}
+
+	for j, i := range missIdx {
+		vectors[i] = embedded[j]
+		c.put(keys[i], embedded[j])
+	}
+
+	return vectors, nil
+}
+
+// Stats 返回缓存统计
+func (c *CachedEmbedder) Stats() CacheStats {
+	c.mu.Lock()
+	size := c.ll.Len()
+	c.mu.Unlock()
+
+	stats := CacheStats{
+		Model:     c.model,
+		Size:      size,
+		Capacity:  c.capacity,
+		DiskDir:   c.dir,
+		Hits:      c.hits.Load(),
+		DiskHits:  c.diskHits.Load(),
+		Misses:    c.misses.Load(),
+		Evictions: c.evictions.Load(),
+	}
+	if total := stats.Hits + stats.Misses; total > 0 {
+		stats.HitRate = float64(stats.Hits) / float64(total)
+	}
+	return stats
+}
+
+// get 返回缓存向量的副本，调用方修改返回值不会影响缓存
+func (c *CachedEmbedder) get(key string) ([]float64, bool) {
+	c.mu.Lock()
+	if el, ok := c.items[key]; ok {
+		c.ll.MoveToFront(el)
+		vec := slices.Clone(el.Value.(*cacheEntry).vector)
+		c.mu.Unlock()
+		return vec, true
+	}
+	c.mu.Unlock()
+
+	if c.dir == "" {
+		return nil, false
+	}
+	vec, err := c.readDisk(key)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("读取嵌入缓存失败: %v", err)
+		}
+		return nil, false
+	}
+	c.diskHits.Add(1)
+	c.putMemory(key, slices.Clone(vec))
+	return vec, true
+}
+
+// put 缓存向量的副本，vec 仍归调用方所有
+func (c *CachedEmbedder) put(key string, vec []float64) {
+	c.putMemory(key, slices.Clone(vec))
+	if c.dir != "" {
+		if err := c.writeDisk(key, vec); err != nil {
+			log.Printf("写入嵌入缓存失败: %v", err)
+		}
+	}
+}
+
+func (c *CachedEmbedder) putMemory(key string, vec []float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		c.ll.MoveToFront(el)
+		el.Value.(*cacheEntry).vector = vec
+		return
+	}
+	c.items[key] = c.ll.PushFront(&cacheEntry{key: key, vector: vec})
+
+	for c.ll.Len() > c.capacity {
+		oldest := c.ll.Back()
+		c.ll.Remove(oldest)
+		delete(c.items, oldest.Value.(*cacheEntry).key)
+		c.evictions.Add(1)
+	}
+}
+
+// diskPath 按哈希前两位分目录，避免单目录文件过多
+func (c *CachedEmbedder) diskPath(key string) string {
+	return filepath.Join(c.dir, key[:2], key+".vec")
+}
+
+func (c *CachedEmbedder) readDisk(key string) ([]float64, error) {
+	b, err := os.ReadFile(c.diskPath(key))
+	if err != nil {
+		return nil, err
+	}
+	if len(b)%8 != 0 {
+		return nil, fmt.Errorf("corrupted cache file: %s", c.diskPath(key))
+	}
+	vec := make([]float64, len(b)/8)
+	for i := range vec {
+		vec[i] = math.Float64frombits(binary.LittleEndian.Uint64(b[i*8:]))
+	}
+	return vec, nil
+}
+
+func (c *CachedEmbedder) writeDisk(key string, vec []float64) error {
+	path := c.diskPath(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	b := make([]byte, len(vec)*8)
+	for i, v := range vec {
+		binary.LittleEndian.PutUint64(b[i*8:], math.Float64bits(v))
+	}
+
+	// 先写临时文件再重命名，避免并发读到半截数据
+	tmp, err := os.CreateTemp(filepath.Dir(path), key+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func cacheKey(model, text string) string {
+	h := sha256.New()
+	h.Write([]byte(model))
+	h.Write([]byte{0})
+	h.Write([]byte(text))
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,165 @@
+package embedding_model
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/cloudwego/eino/components/embedding"
+)
+
+// countingEmbedder 记录实际被嵌入的文本
+type countingEmbedder struct {
+	inner embedding.Embedder
+	texts []string
+}
+
+func (c *countingEmbedder) EmbedStrings(ctx context.Context, texts []string, opts ...embedding.Option) ([][]float64, error) {
+	c.texts = append(c.texts, texts...)
+	return c.inner.EmbedStrings(ctx, texts, opts...)
+}
+
+func newCounting(t *testing.T) *countingEmbedder {
+	t.Helper()
+	fake, err := NewFakeEmbedder(16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &countingEmbedder{inner: fake}
+}
+
+func embed(t *testing.T, c *CachedEmbedder, texts ...string) [][]float64 {
+	t.Helper()
+	vectors, err := c.EmbedStrings(context.Background(), texts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vectors) != len(texts) {
+		t.Fatalf("got %d vectors for %d texts", len(vectors), len(texts))
+	}
+	return vectors
+}
+
+func TestCachedEmbedderHitMiss(t *testing.T) {
+	inner := newCounting(t)
+	c, err := NewCachedEmbedder(inner, "fake", 10, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first := embed(t, c, "a", "b")
+	second := embed(t, c, "b", "c", "a")
+	if !slices.Equal(inner.texts, []string{"a", "b", "c"}) {
+		t.Fatalf("embedded texts = %v, want only misses", inner.texts)
+	}
+	if !slices.Equal(second[0], first[1]) || !slices.Equal(second[2], first[0]) {
+		t.Fatal("cached vectors not returned in input order")
+	}
+
+	stats := c.Stats()
+	if stats.Hits != 2 || stats.Misses != 3 || stats.Size != 3 || stats.HitRate != 0.4 {
+		t.Fatalf("stats = %+v", stats)
+	}
+}
+
+func TestCachedEmbedderReturnsCopies(t *testing.T) {
+	c, err := NewCachedEmbedder(newCounting(t), "fake", 10, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	miss := embed(t, c, "a")[0]
+	want := slices.Clone(miss)
+	miss[0] = 42
+
+	hit := embed(t, c, "a")[0]
+	if !slices.Equal(hit, want) {
+		t.Fatal("mutating a miss result changed the cache")
+	}
+	hit[0] = 42
+	if again := embed(t, c, "a")[0]; !slices.Equal(again, want) {
+		t.Fatal("mutating a hit result changed the cache")
+	}
+}
+
+func TestCachedEmbedderEviction(t *testing.T) {
+	inner := newCounting(t)
+	c, err := NewCachedEmbedder(inner, "fake", 2, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	embed(t, c, "a", "b")
+	embed(t, c, "a") // a 变为最近使用
+	embed(t, c, "c") // 淘汰 b
+	inner.texts = nil
+
+	embed(t, c, "a", "c")
+	if len(inner.texts) != 0 {
+		t.Fatalf("recently used entries evicted: %v", inner.texts)
+	}
+	embed(t, c, "b")
+	if !slices.Equal(inner.texts, []string{"b"}) {
+		t.Fatalf("embedded texts = %v, want b re-embedded", inner.texts)
+	}
+	if stats := c.Stats(); stats.Size != 2 || stats.Evictions != 2 {
+		t.Fatalf("stats = %+v", stats)
+	}
+}
+
+func TestCachedEmbedderModelIsolation(t *testing.T) {
+	dir := t.TempDir()
+	inner := newCounting(t)
+	a, _ := NewCachedEmbedder(inner, "model-a", 10, dir)
+	b, _ := NewCachedEmbedder(inner, "model-b", 10, dir)
+
+	embed(t, a, "hello")
+	embed(t, b, "hello")
+	if len(inner.texts) != 2 {
+		t.Fatalf("different models shared a cache entry: %v", inner.texts)
+	}
+
+	// 单次调用指定的模型同样隔离
+	inner.texts = nil
+	if _, err := a.EmbedStrings(context.Background(), []string{"hello"}, embedding.WithModel("other")); err != nil {
+		t.Fatal(err)
+	}
+	embed(t, a, "hello")
+	if !slices.Equal(inner.texts, []string{"hello"}) {
+		t.Fatalf("embedded texts = %v, want only the per-call model to miss", inner.texts)
+	}
+}
+
+func TestCachedEmbedderDiskRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	inner := newCounting(t)
+	c, err := NewCachedEmbedder(inner, "fake", 10, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := embed(t, c, "persisted", "另一段文本")
+
+	// 模拟重启：新的内存缓存从磁盘读取
+	inner.texts = nil
+	restarted, err := NewCachedEmbedder(inner, "fake", 10, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := embed(t, restarted, "persisted", "另一段文本")
+	if len(inner.texts) != 0 {
+		t.Fatalf("disk cache missed: %v", inner.texts)
+	}
+	for i := range want {
+		if !slices.Equal(got[i], want[i]) {
+			t.Fatalf("vector %d changed after disk round trip", i)
+		}
+	}
+	if stats := restarted.Stats(); stats.DiskHits != 2 || stats.Hits != 2 || stats.Size != 2 {
+		t.Fatalf("stats = %+v", stats)
+	}
+
+	got[0][0] = 42
+	if again := embed(t, restarted, "persisted")[0]; !slices.Equal(again, want[0]) {
+		t.Fatal("mutating a disk hit changed the cache")
+	}
+}
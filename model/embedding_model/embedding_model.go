@@ -4,6 +4,9 @@ import (
 	"context"
 	"fmt"
 	"go-agent/config"
+	"go-agent/model/retry"
+	"strconv"
+	"strings"
 
 	"github.com/cloudwego/eino/components/embedding"
 )
@@ -13,16 +16,129 @@ type EmbeddingModelFactory func(ctx context.Context) (embedding.Embedder, error)
 var embeddingModelRegistry = make(map[string]EmbeddingModelFactory)
 var Embedding embedding.Embedder
 
+// Cache 嵌入结果缓存，未启用时为 nil
+var Cache *CachedEmbedder
+
 func NewEmbeddingModel(ctx context.Context) (embedding.Embedder, error) {
 	initArk()
 	initOpenAI()
 	initQwen()
+	initOllama()
+	initOpenAICompatible()
+	initFake()
 	create, ok := embeddingModelRegistry[config.Cfg.EmbeddingModelType]
 	if !ok {
 		return nil, fmt.Errorf("不支持的 EmbeddingModel 类型: %s", config.Cfg.EmbeddingModelType)
 	}
 
-	return create(ctx)
+	emb, err := create(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return withCache(withBatching(withUsage(emb)))
+}
+
+// withUsage 为嵌入模型套上用量记录
+func withUsage(emb embedding.Embedder) embedding.Embedder {
+	model := strings.TrimPrefix(ModelName(), config.Cfg.EmbeddingModelType+"/")
+	return NewUsageEmbedder(emb, config.Cfg.EmbeddingModelType, model)
+}
+
+// withBatching 按配置为嵌入模型套上批量、限流与重试
+func withBatching(emb embedding.Embedder) embedding.Embedder {
+	conf := config.Cfg.EmbeddingBatchConf
+	batchConf := BatchConfig{
+		BatchSize:         atoiOr(conf.BatchSize, defaultBatchSizes[config.Cfg.EmbeddingModelType]),
+		Concurrency:       atoiOr(conf.Concurrency, 4),
+		RequestsPerMinute: atoiOr(conf.RequestsPerMinute, 0),
+		TokensPerMinute:   atoiOr(conf.TokensPerMinute, 0),
+		Retry:             retry.DefaultPolicy,
+	}
+	batchConf.Retry.MaxRetries = atoiOr(conf.MaxRetries, retry.DefaultPolicy.MaxRetries)
+
+	return NewBatchEmbedder(emb, batchConf)
+}
+
+// atoiOr 解析整数配置，为空或非法时返回默认值
+func atoiOr(s string, def int) int {
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return def
+	}
+	return v
+}
+
+// withCache 按配置为嵌入模型套上缓存
+func withCache(emb embedding.Embedder) (embedding.Embedder, error) {
+	size, err := strconv.Atoi(config.Cfg.EmbeddingCacheConf.Size)
+	if err != nil || size <= 0 {
+		return emb, nil
+	}
+
+	Cache, err = NewCachedEmbedder(emb, ModelName(), size, config.Cfg.EmbeddingCacheConf.Dir)
+	if err != nil {
+		return nil, err
+	}
+	return Cache, nil
+}
+
+// ModelName 返回当前配置的嵌入模型标识，格式为 "类型/模型名"
+func ModelName() string {
+	var name string
+	switch config.Cfg.EmbeddingModelType {
+	case "ark":
+		name = config.Cfg.ArkConf.ArkEmbeddingModel
+	case "openai":
+		name = config.Cfg.OpenAIConf.OpenAIEmbedding
+	case "qwen":
+		name = config.Cfg.QwenConf.QwenEmbedding
+	case "ollama":
+		name = config.Cfg.OllamaConf.EmbeddingModel
+	case "openai_compatible":
+		name = config.Cfg.OpenAICompatibleConf.EmbeddingModel
+	case "fake":
+		name = "hash-" + config.Cfg.FakeConf.EmbeddingDim
+	}
+
+	return config.Cfg.EmbeddingModelType + "/" + name
+}
+
+// Info 嵌入模型信息
+type Info struct {
+	Name         string       `json:"name"`
+	Model        string       `json:"model"`
+	Default      bool         `json:"default"`
+	Capabilities Capabilities `json:"capabilities"`
+}
+
+// Capabilities 嵌入模型能力
+type Capabilities struct {
+	MaxBatchSize int  `json:"max_batch_size"` // 单次请求最大文本条数
+	Cached       bool `json:"cached"`         // 是否启用嵌入缓存
+}
+
+// List 返回已加载的嵌入模型；入库与检索必须使用同一嵌入模型，因此只加载一个
+func List() []Info {
+	if Embedding == nil {
+		return []Info{}
+	}
+
+	model := strings.TrimPrefix(ModelName(), config.Cfg.EmbeddingModelType+"/")
+	batchSize := atoiOr(config.Cfg.EmbeddingBatchConf.BatchSize, defaultBatchSizes[config.Cfg.EmbeddingModelType])
+	if batchSize <= 0 {
+		batchSize = defaultBatchSize
+	}
+
+	return []Info{{
+		Name:    config.Cfg.EmbeddingModelType,
+		Model:   model,
+		Default: true,
+		Capabilities: Capabilities{
+			MaxBatchSize: batchSize,
+			Cached:       Cache != nil,
+		},
+	}}
 }
 
 // registerEmbeddingModel 注册嵌入模型进入工厂
@@ -0,0 +1,104 @@
+package embedding_model
+
+import (
+	"context"
+	"fmt"
+	"go-agent/config"
+	"hash/fnv"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/cloudwego/eino/components/embedding"
+)
+
+// defaultFakeDim 假嵌入模型的默认维度
+const defaultFakeDim = 64
+
+// FakeEmbedder 离线测试用的确定性嵌入模型
+// 将文本切成词（中日韩字符按单字）后哈希到固定维度并归一化，词重叠越多余弦相似度越高
+type FakeEmbedder struct {
+	dim int
+}
+
+// NewFakeEmbedder 创建指定维度的假嵌入模型
+func NewFakeEmbedder(dim int) (*FakeEmbedder, error) {
+	if dim <= 0 {
+		return nil, fmt.Errorf("fake embedding dim must be positive, got %d", dim)
+	}
+	return &FakeEmbedder{dim: dim}, nil
+}
+
+func (f *FakeEmbedder) EmbedStrings(ctx context.Context, texts []string, opts ...embedding.Option) ([][]float64, error) {
+	vectors := make([][]float64, len(texts))
+	for i, text := range texts {
+		vectors[i] = f.embed(text)
+	}
+	return vectors, nil
+}
+
+func (f *FakeEmbedder) embed(text string) []float64 {
+	vec := make([]float64, f.dim)
+	for _, term := range fakeTerms(text) {
+		h := fnv.New64a()
+		h.Write([]byte(term))
+		sum := h.Sum64()
+		sign := 1.0
+		if sum&1 == 1 {
+			sign = -1
+		}
+		vec[(sum>>1)%uint64(f.dim)] += sign
+	}
+
+	var norm float64
+	for _, v := range vec {
+		norm += v * v
+	}
+	if norm == 0 {
+		// 空文本给一个固定的单位向量，保证结果可归一化
+		vec[0] = 1
+		return vec
+	}
+	norm = math.Sqrt(norm)
+	for i := range vec {
+		vec[i] /= norm
+	}
+	return vec
+}
+
+// fakeTerms 按非字母数字切词，中日韩字符按单字切分，统一小写
+func fakeTerms(text string) []string {
+	var terms []string
+	var b strings.Builder
+	flush := func() {
+		if b.Len() > 0 {
+			terms = append(terms, b.String())
+			b.Reset()
+		}
+	}
+
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul):
+			flush()
+			terms = append(terms, string(r))
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			b.WriteRune(r)
+		default:
+			flush()
+		}
+	}
+	flush()
+	return terms
+}
+
+func initFake() {
+	registerEmbeddingModel("fake", func(ctx context.Context) (embedding.Embedder, error) {
+		dim, err := strconv.Atoi(config.Cfg.FakeConf.EmbeddingDim)
+		if err != nil {
+			dim = defaultFakeDim
+		}
+		return NewFakeEmbedder(dim)
+	})
+}
@@ -0,0 +1,59 @@
+package embedding_model
+
+import (
+	"context"
+	"go-agent/rag/tools/db"
+	"math"
+	"testing"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+func TestFakeEmbedderDeterministic(t *testing.T) {
+	emb, err := NewFakeEmbedder(32)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a, _ := emb.EmbedStrings(context.Background(), []string{"hello world", ""})
+	b, _ := emb.EmbedStrings(context.Background(), []string{"hello world"})
+	if len(a[0]) != 32 || len(a[1]) != 32 {
+		t.Fatalf("dims = %d, %d", len(a[0]), len(a[1]))
+	}
+	for i := range a[0] {
+		if a[0][i] != b[0][i] {
+			t.Fatal("embedding is not deterministic")
+		}
+	}
+
+	var norm float64
+	for _, v := range a[0] {
+		norm += v * v
+	}
+	if math.Abs(norm-1) > 1e-9 {
+		t.Fatalf("norm = %f, want 1", norm)
+	}
+}
+
+func TestFakeEmbedderSimilarity(t *testing.T) {
+	emb, _ := NewFakeEmbedder(256)
+	store := db.NewMemoryStore()
+
+	texts := []string{"milvus stores vectors", "今天天气很好", "bananas are yellow"}
+	vectors, _ := emb.EmbedStrings(context.Background(), texts)
+	docs := make([]*schema.Document, len(texts))
+	for i, text := range texts {
+		docs[i] = &schema.Document{ID: text, Content: text}
+	}
+	store.Upsert(docs, vectors)
+
+	for _, tc := range []struct{ query, want string }{
+		{"where are vectors stored milvus", "milvus stores vectors"},
+		{"天气怎么样", "今天天气很好"},
+	} {
+		q, _ := emb.EmbedStrings(context.Background(), []string{tc.query})
+		if got := store.Search(q[0], 1)[0].ID; got != tc.want {
+			t.Errorf("query %q matched %q, want %q", tc.query, got, tc.want)
+		}
+	}
+}
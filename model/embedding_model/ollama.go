@@ -0,0 +1,29 @@
+package embedding_model
+
+import (
+	"context"
+	"go-agent/config"
+	"strings"
+
+	"github.com/cloudwego/eino-ext/components/embedding/openai"
+	"github.com/cloudwego/eino/components/embedding"
+)
+
+// ollamaAPIKey Ollama 不校验密钥，但 OpenAI 客户端要求非空
+const ollamaAPIKey = "ollama"
+
+// initOllama 通过 Ollama 的 OpenAI 兼容接口 (/v1/embeddings) 接入本地嵌入模型
+func initOllama() {
+	registerEmbeddingModel("ollama", func(ctx context.Context) (embedding.Embedder, error) {
+		emb, err := openai.NewEmbedder(ctx, &openai.EmbeddingConfig{
+			BaseURL: strings.TrimRight(config.Cfg.OllamaConf.BaseUrl, "/") + "/v1",
+			APIKey:  ollamaAPIKey,
+			Model:   config.Cfg.OllamaConf.EmbeddingModel,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		return emb, nil
+	})
+}
@@ -11,8 +11,9 @@ import (
 func initOpenAI() {
 	registerEmbeddingModel("openai", func(ctx context.Context) (embedding.Embedder, error) {
 		emb, err := openai.NewEmbedder(ctx, &openai.EmbeddingConfig{
-			APIKey: config.Cfg.OpenAIConf.OpenAIKey,
-			Model:  config.Cfg.OpenAIConf.OpenAIEmbedding,
+			BaseURL: config.Cfg.OpenAIConf.BaseUrl,
+			APIKey:  config.Cfg.OpenAIConf.OpenAIKey,
+			Model:   config.Cfg.OpenAIConf.OpenAIEmbedding,
 		})
 		if err != nil {
 			return nil, err
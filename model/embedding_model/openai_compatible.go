@@ -0,0 +1,28 @@
+package embedding_model
+
+import (
+	"context"
+	"go-agent/config"
+	"go-agent/model/transport"
+
+	"github.com/cloudwego/eino-ext/components/embedding/openai"
+	"github.com/cloudwego/eino/components/embedding"
+)
+
+// initOpenAICompatible 接入任意兼容 OpenAI 接口的嵌入服务（vLLM、内部网关等）
+func initOpenAICompatible() {
+	registerEmbeddingModel("openai_compatible", func(ctx context.Context) (embedding.Embedder, error) {
+		conf := config.Cfg.OpenAICompatibleConf
+		emb, err := openai.NewEmbedder(ctx, &openai.EmbeddingConfig{
+			BaseURL:    conf.BaseUrl,
+			APIKey:     conf.APIKey,
+			Model:      conf.EmbeddingModel,
+			HTTPClient: transport.NewHTTPClient(transport.ParseHeaders(conf.Headers)),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		return emb, nil
+	})
+}
@@ -0,0 +1,125 @@
+package embedding_model
+
+import (
+	"context"
+	"encoding/json"
+	"go-agent/config"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// capturedRequest 兼容 OpenAI 接口的测试服务收到的请求
+type capturedRequest struct {
+	Path   string
+	Header http.Header
+	Model  string
+	Input  []string
+}
+
+// newOpenAIServer 启动模拟 OpenAI 嵌入接口的测试服务，第 i 条输入返回向量 [i, 1]
+func newOpenAIServer(t *testing.T) (*httptest.Server, func() []capturedRequest) {
+	t.Helper()
+	var mu sync.Mutex
+	var requests []capturedRequest
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Model string   `json:"model"`
+			Input []string `json:"input"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		mu.Lock()
+		requests = append(requests, capturedRequest{Path: r.URL.Path, Header: r.Header.Clone(), Model: body.Model, Input: body.Input})
+		mu.Unlock()
+
+		type item struct {
+			Object    string    `json:"object"`
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		}
+		data := make([]item, len(body.Input))
+		for i := range body.Input {
+			data[i] = item{Object: "embedding", Index: i, Embedding: []float32{float32(i), 1}}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"object": "list",
+			"model":  body.Model,
+			"data":   data,
+			"usage":  map[string]int{"prompt_tokens": 4, "total_tokens": 4},
+		})
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, func() []capturedRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]capturedRequest(nil), requests...)
+	}
+}
+
+func TestOpenAICompatibleEmbedder(t *testing.T) {
+	srv, requests := newOpenAIServer(t)
+	config.Cfg = &config.Config{
+		OpenAICompatibleConf: config.OpenAICompatibleConfig{
+			BaseUrl:        srv.URL + "/gateway/v1",
+			APIKey:         "secret",
+			Headers:        "X-Tenant: team-a",
+			EmbeddingModel: "bge-m3",
+		},
+	}
+	initOpenAICompatible()
+
+	emb, err := embeddingModelRegistry["openai_compatible"](context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	vectors, err := emb.EmbedStrings(context.Background(), []string{"a", "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vectors) != 2 || vectors[1][0] != 1 || vectors[1][1] != 1 {
+		t.Fatalf("vectors = %v", vectors)
+	}
+
+	got := requests()
+	if len(got) != 1 {
+		t.Fatalf("server got %d requests", len(got))
+	}
+	req := got[0]
+	if req.Path != "/gateway/v1/embeddings" || req.Model != "bge-m3" || len(req.Input) != 2 {
+		t.Fatalf("request = %+v", req)
+	}
+	if req.Header.Get("Authorization") != "Bearer secret" || req.Header.Get("X-Tenant") != "team-a" {
+		t.Fatalf("headers = %v", req.Header)
+	}
+}
+
+func TestOllamaEmbedder(t *testing.T) {
+	srv, requests := newOpenAIServer(t)
+	config.Cfg = &config.Config{
+		OllamaConf: config.OllamaConfig{BaseUrl: srv.URL + "/", EmbeddingModel: "nomic-embed-text"},
+	}
+	initOllama()
+
+	emb, err := embeddingModelRegistry["ollama"](context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := emb.EmbedStrings(context.Background(), []string{"hello"}); err != nil {
+		t.Fatal(err)
+	}
+
+	got := requests()
+	if len(got) != 1 {
+		t.Fatalf("server got %d requests", len(got))
+	}
+	if got[0].Path != "/v1/embeddings" || got[0].Model != "nomic-embed-text" {
+		t.Fatalf("request = %+v", got[0])
+	}
+	if got[0].Header.Get("Authorization") != "Bearer "+ollamaAPIKey {
+		t.Fatalf("authorization = %q", got[0].Header.Get("Authorization"))
+	}
+}
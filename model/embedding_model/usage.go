@@ -0,0 +1,71 @@
+package embedding_model
+
+import (
+	"context"
+	"go-agent/model/token"
+	"go-agent/model/usage"
+	"sync"
+
+	"github.com/cloudwego/eino/callbacks"
+	"github.com/cloudwego/eino/components"
+	"github.com/cloudwego/eino/components/embedding"
+)
+
+// UsageEmbedder 用量记录装饰器：通过嵌入组件回调读取提供方返回的 token 用量，
+// 提供方未上报时按文本长度估算，记录到请求上下文中的用量收集器
+type UsageEmbedder struct {
+	embedder embedding.Embedder
+	provider string
+	model    string
+}
+
+// NewUsageEmbedder 创建用量记录装饰器
+func NewUsageEmbedder(embedder embedding.Embedder, provider, model string) *UsageEmbedder {
+	return &UsageEmbedder{embedder: embedder, provider: provider, model: model}
+}
+
+func (u *UsageEmbedder) EmbedStrings(ctx context.Context, texts []string, opts ...embedding.Option) ([][]float64, error) {
+	if usage.FromContext(ctx) == nil {
+		return u.embedder.EmbedStrings(ctx, texts, opts...)
+	}
+
+	var mu sync.Mutex
+	var reported *embedding.TokenUsage
+	handler := callbacks.NewHandlerBuilder().
+		OnEndFn(func(ctx context.Context, info *callbacks.RunInfo, output callbacks.CallbackOutput) context.Context {
+			if out := embedding.ConvCallbackOutput(output); out != nil && out.TokenUsage != nil {
+				mu.Lock()
+				reported = out.TokenUsage
+				mu.Unlock()
+			}
+			return ctx
+		}).
+		Build()
+	cbCtx := callbacks.InitCallbacks(ctx, &callbacks.RunInfo{
+		Name:      u.model,
+		Type:      u.provider,
+		Component: components.ComponentOfEmbedding,
+	}, handler)
+
+	vectors, err := u.embedder.EmbedStrings(cbCtx, texts, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	rec := usage.Record{Kind: usage.KindEmbedding, Provider: u.provider, Model: u.model}
+	mu.Lock()
+	if reported != nil {
+		rec.PromptTokens = reported.PromptTokens
+		rec.TotalTokens = reported.TotalTokens
+	}
+	mu.Unlock()
+	if rec.TotalTokens == 0 {
+		for _, text := range texts {
+			rec.PromptTokens += token.Estimate(text)
+		}
+		rec.Estimated = true
+	}
+	usage.Add(ctx, rec)
+
+	return vectors, nil
+}
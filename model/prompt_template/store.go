@@ -0,0 +1,378 @@
+package prompt_template
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Store 全局模板存储，未初始化时只能使用内置模板
+var Store *FileStore
+
+var (
+	ErrNotFound = errors.New("prompt template not found")
+	ErrExists   = errors.New("prompt template already exists")
+)
+
+// highWaterFile 记录各模板分配过的最大版本号，删除版本后新版本号也不会复用
+const highWaterFile = ".versions.json"
+
+// FileStore 磁盘模板存储：<dir>/<名称>/v<版本>.json，每次修改写入新版本
+// 目录中的文件被外部修改时由 Watch 定期重新加载
+type FileStore struct {
+	dir string
+
+	mu        sync.RWMutex
+	templates map[string][]*Template // 按版本升序
+	highWater map[string]int         // 各模板分配过的最大版本号，含已删除的版本
+	signature string                 // 目录内容签名，用于检测外部修改
+}
+
+// NewStore 创建模板存储并加载目录，缺失的内置模板会写入为第 1 版
+func NewStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("create prompt dir failed: %w", err)
+	}
+
+	s := &FileStore{dir: dir, templates: make(map[string][]*Template), highWater: make(map[string]int)}
+	if b, err := os.ReadFile(filepath.Join(dir, highWaterFile)); err == nil {
+		if err := json.Unmarshal(b, &s.highWater); err != nil {
+			return nil, fmt.Errorf("read %s failed: %w", highWaterFile, err)
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	for name, t := range builtins {
+		if _, err := os.Stat(filepath.Join(dir, name)); os.IsNotExist(err) {
+			seed := *t
+			seed.Version = s.highWater[name] + 1
+			seed.CreatedAt = time.Now()
+			if err := s.claim(name, seed.Version); err != nil {
+				return nil, err
+			}
+			if err := s.write(&seed); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if _, err := s.Reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Get 返回指定版本，version 为 0 时返回最新版本
+func (s *FileStore) Get(name string, version int) (*Template, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	versions := s.templates[name]
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	if version == 0 {
+		return versions[len(versions)-1], nil
+	}
+	for _, t := range versions {
+		if t.Version == version {
+			return t, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s@%d", ErrNotFound, name, version)
+}
+
+// Versions 返回模板的全部版本（升序）
+func (s *FileStore) Versions(name string) ([]*Template, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	versions := s.templates[name]
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	return append([]*Template(nil), versions...), nil
+}
+
+// List 返回每个模板的最新版本，按名称排序
+func (s *FileStore) List() []*Template {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	list := make([]*Template, 0, len(s.templates))
+	for _, versions := range s.templates {
+		list = append(list, versions[len(versions)-1])
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// Create 创建新模板，从第 1 版开始；同名模板曾被删除时接着已分配过的版本号编号
+func (s *FileStore) Create(t Template) (*Template, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.templates[t.Name]) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrExists, t.Name)
+	}
+	return s.save(t, s.highWater[t.Name]+1)
+}
+
+// Update 为已有模板写入新版本
+func (s *FileStore) Update(t Template) (*Template, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	versions := s.templates[t.Name]
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, t.Name)
+	}
+	return s.save(t, max(s.highWater[t.Name], versions[len(versions)-1].Version)+1)
+}
+
+// Delete 删除模板的指定版本，version 为 0 时删除全部版本
+func (s *FileStore) Delete(name string, version int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	versions := s.templates[name]
+	if len(versions) == 0 {
+		return fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+
+	if version == 0 {
+		if err := os.RemoveAll(filepath.Join(s.dir, name)); err != nil {
+			return err
+		}
+		delete(s.templates, name)
+		s.refreshSignature()
+		return nil
+	}
+
+	for i, t := range versions {
+		if t.Version != version {
+			continue
+		}
+		if err := os.Remove(s.path(name, version)); err != nil {
+			return err
+		}
+		s.templates[name] = append(versions[:i:i], versions[i+1:]...)
+		if len(s.templates[name]) == 0 {
+			delete(s.templates, name)
+			os.Remove(filepath.Join(s.dir, name))
+		}
+		s.refreshSignature()
+		return nil
+	}
+	return fmt.Errorf("%w: %s@%d", ErrNotFound, name, version)
+}
+
+// Reload 重新扫描模板目录，目录内容未变化时跳过，返回是否发生了重新加载
+func (s *FileStore) Reload() (bool, error) {
+	paths, signature, err := s.scan()
+	if err != nil {
+		return false, err
+	}
+
+	s.mu.RLock()
+	unchanged := signature == s.signature
+	s.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	templates := make(map[string][]*Template)
+	for _, path := range paths {
+		t, err := readTemplate(path)
+		if err != nil {
+			log.Printf("警告: 跳过无效的提示词模板 %s: %v", path, err)
+			continue
+		}
+		templates[t.Name] = append(templates[t.Name], t)
+	}
+	for _, versions := range templates {
+		sort.Slice(versions, func(i, j int) bool { return versions[i].Version < versions[j].Version })
+	}
+
+	s.mu.Lock()
+	s.templates = templates
+	s.signature = signature
+	// 外部写入的版本同样计入已分配的版本号
+	for name, versions := range templates {
+		if latest := versions[len(versions)-1].Version; latest > s.highWater[name] {
+			s.highWater[name] = latest
+		}
+	}
+	s.mu.Unlock()
+	return true, nil
+}
+
+// Watch 按间隔轮询模板目录，外部修改后自动重新加载，ctx 结束时停止
+func (s *FileStore) Watch(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			reloaded, err := s.Reload()
+			if err != nil {
+				log.Printf("重新加载提示词模板失败: %v", err)
+			} else if reloaded {
+				log.Printf("提示词模板已重新加载")
+			}
+		}
+	}
+}
+
+// save 校验并写入一个版本，调用方需持有写锁
+func (s *FileStore) save(t Template, version int) (*Template, error) {
+	t.Version = version
+	t.CreatedAt = time.Now()
+	if err := t.Validate(); err != nil {
+		return nil, err
+	}
+	if err := s.claim(t.Name, version); err != nil {
+		return nil, err
+	}
+	if err := s.write(&t); err != nil {
+		return nil, err
+	}
+
+	s.templates[t.Name] = append(s.templates[t.Name], &t)
+	s.refreshSignature()
+	return &t, nil
+}
+
+// refreshSignature 自身写入后更新目录签名，避免下一轮轮询重复加载，调用方需持有写锁
+func (s *FileStore) refreshSignature() {
+	if _, signature, err := s.scan(); err == nil {
+		s.signature = signature
+	}
+}
+
+// claim 在写入模板前记录已分配的版本号并落盘，写入失败时该版本号同样不再使用
+func (s *FileStore) claim(name string, version int) error {
+	if version <= s.highWater[name] {
+		return nil
+	}
+	s.highWater[name] = version
+	b, err := json.MarshalIndent(s.highWater, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(s.dir, highWaterFile)
+	if err := os.WriteFile(path+".tmp", b, 0644); err != nil {
+		return fmt.Errorf("write %s failed: %w", highWaterFile, err)
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+func (s *FileStore) write(t *Template) error {
+	path := s.path(t.Name, t.Version)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(t, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func (s *FileStore) path(name string, version int) string {
+	return filepath.Join(s.dir, name, "v"+strconv.Itoa(version)+".json")
+}
+
+// scan 列出全部模板文件，并按路径、大小与修改时间计算目录签名
+func (s *FileStore) scan() ([]string, string, error) {
+	paths, err := filepath.Glob(filepath.Join(s.dir, "*", "v*.json"))
+	if err != nil {
+		return nil, "", err
+	}
+	sort.Strings(paths)
+
+	h := sha256.New()
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		fmt.Fprintf(h, "%s|%d|%d\n", path, info.Size(), info.ModTime().UnixNano())
+	}
+	return paths, hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// readTemplate 读取模板文件，名称与版本以路径为准
+func readTemplate(path string) (*Template, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var t Template
+	if err := json.Unmarshal(b, &t); err != nil {
+		return nil, err
+	}
+
+	t.Name = filepath.Base(filepath.Dir(path))
+	version, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(filepath.Base(path), "v"), ".json"))
+	if err != nil || version <= 0 {
+		return nil, fmt.Errorf("invalid version in file name")
+	}
+	t.Version = version
+
+	if err := t.Validate(); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// Resolve 按 prompt_id（"名称" 或 "名称@版本"）查找模板；id 为空时使用 fallback 指定的内置模板
+// 存储未初始化时只能使用内置模板
+func Resolve(id, fallback string) (*Template, error) {
+	if id == "" {
+		id = fallback
+	}
+	name, version, err := ParseID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if Store != nil {
+		t, err := Store.Get(name, version)
+		if err == nil || !errors.Is(err, ErrNotFound) || builtins[name] == nil || version > 1 {
+			return t, err
+		}
+	}
+
+	// 内置模板被删除或存储未初始化时回退到代码中的版本
+	t, ok := builtins[name]
+	if !ok || version > 1 {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	c := *t
+	c.Version = 1
+	return &c, nil
+}
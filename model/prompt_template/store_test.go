@@ -0,0 +1,173 @@
+package prompt_template
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStoreVersions(t *testing.T) {
+	s, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Get(DefaultChat, 1); err != nil {
+		t.Fatalf("builtin not seeded: %v", err)
+	}
+
+	if _, err := s.Create(Template{Name: "qa", System: "v1 {query}"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Create(Template{Name: "qa", System: "dup"}); !errors.Is(err, ErrExists) {
+		t.Fatalf("duplicate create err = %v", err)
+	}
+	if _, err := s.Create(Template{Name: "bad name", System: "x"}); err == nil {
+		t.Fatal("expected invalid name error")
+	}
+	if _, err := s.Create(Template{Name: "broken", System: "unclosed {query"}); err == nil {
+		t.Fatal("expected template syntax error")
+	}
+
+	v2, err := s.Update(Template{Name: "qa", System: "v2"})
+	if err != nil || v2.Version != 2 {
+		t.Fatalf("update = %+v, %v", v2, err)
+	}
+	if latest, _ := s.Get("qa", 0); latest.System != "v2" {
+		t.Fatalf("latest = %+v", latest)
+	}
+	if old, _ := s.Get("qa", 1); old.System != "v1 {query}" {
+		t.Fatalf("v1 = %+v", old)
+	}
+
+	if err := s.Delete("qa", 2); err != nil {
+		t.Fatal(err)
+	}
+	if latest, _ := s.Get("qa", 0); latest.Version != 1 {
+		t.Fatalf("latest after delete = %+v", latest)
+	}
+	if err := s.Delete("qa", 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Get("qa", 0); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("get deleted err = %v", err)
+	}
+}
+
+func TestStoreNeverReusesVersions(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.Create(Template{Name: "qa", System: "v1"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Update(Template{Name: "qa", System: "v2"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Delete("qa", 2); err != nil {
+		t.Fatal(err)
+	}
+	// 删除最新版本后，新版本不能复用 v2，否则固定引用 qa@2 的请求会悄悄换成新内容
+	v3, err := s.Update(Template{Name: "qa", System: "v3"})
+	if err != nil || v3.Version != 3 {
+		t.Fatalf("update after delete = %+v, %v", v3, err)
+	}
+	if _, err := s.Get("qa", 2); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("deleted version resolved: %v", err)
+	}
+
+	// 删除全部版本后重新创建，接着编号
+	if err := s.Delete("qa", 0); err != nil {
+		t.Fatal(err)
+	}
+	recreated, err := s.Create(Template{Name: "qa", System: "again"})
+	if err != nil || recreated.Version != 4 {
+		t.Fatalf("recreate = %+v, %v", recreated, err)
+	}
+
+	// 重启后仍然有效
+	if err := s.Delete("qa", 4); err != nil {
+		t.Fatal(err)
+	}
+	restarted, err := NewStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v5, err := restarted.Create(Template{Name: "qa", System: "after restart"}); err != nil || v5.Version != 5 {
+		t.Fatalf("create after restart = %+v, %v", v5, err)
+	}
+
+	// 外部写入的版本也计入
+	if err := os.WriteFile(filepath.Join(dir, "qa", "v9.json"), []byte(`{"system":"external"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := restarted.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if err := restarted.Delete("qa", 9); err != nil {
+		t.Fatal(err)
+	}
+	if v10, err := restarted.Update(Template{Name: "qa", System: "next"}); err != nil || v10.Version != 10 {
+		t.Fatalf("update after external version = %+v, %v", v10, err)
+	}
+}
+
+func TestStoreReloadExternalChange(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reloaded, _ := s.Reload(); reloaded {
+		t.Fatal("reload without changes")
+	}
+
+	if err := os.MkdirAll(filepath.Join(dir, "ops"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "ops", "v3.json"), []byte(`{"system":"ops {query}"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// 无效文件被跳过，不影响其他模板
+	if err := os.WriteFile(filepath.Join(dir, "ops", "v4.json"), []byte(`{`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if reloaded, err := s.Reload(); err != nil || !reloaded {
+		t.Fatalf("reload = %v, %v", reloaded, err)
+	}
+	got, err := s.Get("ops", 0)
+	if err != nil || got.Version != 3 {
+		t.Fatalf("ops = %+v, %v", got, err)
+	}
+}
+
+func TestResolveAndFormat(t *testing.T) {
+	Store = nil
+	tmpl, err := Resolve("", DefaultRAG)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tmpl.ID() != "default_rag@1" {
+		t.Fatalf("id = %s", tmpl.ID())
+	}
+	if _, err := Resolve("default_rag@2", DefaultRAG); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("missing version err = %v", err)
+	}
+
+	// 覆盖的系统提示词未引用 {documents} 时追加文档段落
+	msgs, err := tmpl.WithSystem("Use {x} literally.").Format(context.Background(), nil, "q", "DOCS", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "Use {x} literally." + "\n\n检索到的文档：\nDOCS"; msgs[0].Content != want {
+		t.Fatalf("system = %q", msgs[0].Content)
+	}
+	if len(msgs) != 2 || msgs[1].Content != "q" {
+		t.Fatalf("messages = %+v", msgs)
+	}
+}
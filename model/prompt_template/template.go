@@ -0,0 +1,146 @@
+package prompt_template
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cloudwego/eino/components/prompt"
+	"github.com/cloudwego/eino/schema"
+)
+
+// 内置模板名称，未指定 prompt_id 时使用
+const (
+	DefaultChat  = "default_chat"
+	DefaultRAG   = "default_rag"
+	DefaultAgent = "default_agent"
+)
+
+// 模板变量：{query} 为用户问题，{documents} 为检索到的文档（仅 RAG），历史对话自动插入在系统消息之后
+const (
+	VarQuery     = "query"
+	VarDocuments = "documents"
+	varHistory   = "history"
+)
+
+// documentsSection 模板未引用 {documents} 时用于 RAG 的追加段落
+const documentsSection = "\n\n检索到的文档：\n{documents}"
+
+// builtins 内置模板，首次启动时写入模板目录，之后可通过接口修改
+var builtins = map[string]*Template{
+	DefaultChat: {
+		Name:        DefaultChat,
+		Description: "默认对话提示词",
+		System:      "你是一个有用的AI助手。",
+		User:        "{query}",
+	},
+	DefaultRAG: {
+		Name:        DefaultRAG,
+		Description: "默认 RAG 问答提示词",
+		System: `你是一个有用的助手。请基于以下检索到的文档内容回答用户的问题。
+如果文档中没有相关信息，请说明你不知道。
+
+检索到的文档：
+{documents}`,
+		User: "{query}",
+	},
+	DefaultAgent: {
+		Name:        DefaultAgent,
+		Description: "默认工具调用 Agent 提示词",
+		System: `你是一个可以调用工具的智能助手。
+需要查询资料、计算或获取外部信息时，先调用合适的工具，再根据工具返回的结果回答用户的问题。
+工具返回错误或无法提供所需信息时，请如实说明，不要编造。`,
+		User: "{query}",
+	},
+}
+
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// Template 一个版本的提示词模板，使用 FString 语法（{变量}，字面量花括号写作 {{ }}）
+type Template struct {
+	Name        string    `json:"name"`
+	Version     int       `json:"version"`
+	Description string    `json:"description,omitempty"`
+	System      string    `json:"system"`
+	User        string    `json:"user,omitempty"` // 为空时等同于 "{query}"
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// ID 返回 "名称@版本"
+func (t *Template) ID() string {
+	return fmt.Sprintf("%s@%d", t.Name, t.Version)
+}
+
+// Validate 校验名称并试渲染，确保模板语法正确
+func (t *Template) Validate() error {
+	if !namePattern.MatchString(t.Name) {
+		return fmt.Errorf("invalid template name %q: only letters, digits, '_' and '-' (1-64) are allowed", t.Name)
+	}
+	if strings.TrimSpace(t.System) == "" {
+		return fmt.Errorf("system prompt is required")
+	}
+	if _, err := t.Format(context.Background(), nil, "", "", true); err != nil {
+		return err
+	}
+	return nil
+}
+
+// WithSystem 返回替换系统提示词后的副本，text 按原文处理，只保留 {query} 与 {documents} 两个变量
+func (t *Template) WithSystem(text string) *Template {
+	c := *t
+	c.System = escapeLiteral(text)
+	return &c
+}
+
+// Format 渲染消息列表：系统消息、历史对话、用户消息
+// withDocuments 为 true 时用于 RAG，模板未引用 {documents} 时自动追加文档段落
+func (t *Template) Format(ctx context.Context, history []*schema.Message, query, documents string, withDocuments bool) ([]*schema.Message, error) {
+	system := t.System
+	if withDocuments && !strings.Contains(system, "{"+VarDocuments+"}") {
+		system += documentsSection
+	}
+	user := t.User
+	if user == "" {
+		user = "{" + VarQuery + "}"
+	}
+
+	tpl := prompt.FromMessages(schema.FString,
+		schema.SystemMessage(system),
+		schema.MessagesPlaceholder(varHistory, true),
+		schema.UserMessage(user),
+	)
+	messages, err := tpl.Format(ctx, map[string]any{
+		VarQuery:     query,
+		VarDocuments: documents,
+		varHistory:   history,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("format template %s failed: %w", t.Name, err)
+	}
+	return messages, nil
+}
+
+// ParseID 解析 "名称" 或 "名称@版本"，版本为 0 表示最新版本
+func ParseID(id string) (string, int, error) {
+	name, v, ok := strings.Cut(id, "@")
+	if !ok {
+		return name, 0, nil
+	}
+	version, err := strconv.Atoi(v)
+	if err != nil || version <= 0 {
+		return "", 0, fmt.Errorf("invalid prompt version in %q", id)
+	}
+	return name, version, nil
+}
+
+// escapeLiteral 转义原文中的花括号，仅保留 {query} 与 {documents} 变量
+func escapeLiteral(text string) string {
+	text = strings.NewReplacer("{", "{{", "}", "}}").Replace(text)
+	for _, v := range []string{VarQuery, VarDocuments} {
+		text = strings.ReplaceAll(text, "{{"+v+"}}", "{"+v+"}")
+	}
+	return text
+}
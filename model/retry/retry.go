@@ -0,0 +1,121 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+
+	"github.com/meguminnnnnnnnn/go-openai"
+	arkmodel "github.com/volcengine/volcengine-go-sdk/service/arkruntime/model"
+)
+
+// Policy 重试策略：指数退避 + 全抖动
+type Policy struct {
+	MaxRetries  int           // 首次调用之外的最大重试次数
+	BaseBackoff time.Duration // 第一次重试的退避上限
+	MaxBackoff  time.Duration // 单次退避上限
+}
+
+// DefaultPolicy 默认重试策略
+var DefaultPolicy = Policy{
+	MaxRetries:  3,
+	BaseBackoff: 500 * time.Millisecond,
+	MaxBackoff:  10 * time.Second,
+}
+
+// StatusError 带 HTTP 状态码的错误，自行发起 HTTP 请求的客户端返回此类型以便识别可重试错误
+type StatusError struct {
+	StatusCode int
+	Err        error
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("status code %d: %v", e.StatusCode, e.Err)
+}
+
+func (e *StatusError) Unwrap() error {
+	return e.Err
+}
+
+// StatusCode 从错误链中取出 HTTP 状态码，支持 StatusError 与 OpenAI 兼容（含通义、Ollama）及方舟 SDK 的错误类型
+func StatusCode(err error) (int, bool) {
+	var statusErr *StatusError
+	var openaiAPI *openai.APIError
+	var openaiReq *openai.RequestError
+	var arkAPI *arkmodel.APIError
+	var arkReq *arkmodel.RequestError
+	switch {
+	case errors.As(err, &statusErr):
+		return statusErr.StatusCode, true
+	case errors.As(err, &openaiAPI) && openaiAPI.HTTPStatusCode > 0:
+		return openaiAPI.HTTPStatusCode, true
+	case errors.As(err, &openaiReq) && openaiReq.HTTPStatusCode > 0:
+		return openaiReq.HTTPStatusCode, true
+	case errors.As(err, &arkAPI) && arkAPI.HTTPStatusCode > 0:
+		return arkAPI.HTTPStatusCode, true
+	case errors.As(err, &arkReq) && arkReq.HTTPStatusCode > 0:
+		return arkReq.HTTPStatusCode, true
+	}
+	return 0, false
+}
+
+// IsTransient 判断错误是否为可重试的限流、服务端或网络错误：
+// 状态码 408/429/5xx、网络超时、连接被拒绝或重置、响应中途断开、调用超时
+// 其余错误（包括 400/401/404 等客户端错误）重试也不会成功，返回 false
+func IsTransient(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	if code, ok := StatusCode(err); ok {
+		return code == http.StatusRequestTimeout || code == http.StatusTooManyRequests || code >= 500
+	}
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// IsRequestError 判断错误是否由请求本身造成（400/413/422）：参数无效、内容过长等，换提供方同样会失败
+func IsRequestError(err error) bool {
+	code, ok := StatusCode(err)
+	return ok && (code == http.StatusBadRequest || code == http.StatusRequestEntityTooLarge || code == http.StatusUnprocessableEntity)
+}
+
+// Backoff 返回第 attempt 次重试（从 0 开始）前的等待时间
+func (p Policy) Backoff(attempt int) time.Duration {
+	backoff := p.BaseBackoff << attempt
+	if backoff <= 0 || backoff > p.MaxBackoff {
+		backoff = p.MaxBackoff
+	}
+	if backoff <= 0 {
+		return 0
+	}
+	return rand.N(backoff) + 1
+}
+
+// Do 执行 fn，遇到可重试错误时按策略退避重试
+func Do(ctx context.Context, p Policy, fn func(ctx context.Context) error) error {
+	var err error
+	for attempt := 0; ; attempt++ {
+		err = fn(ctx)
+		if err == nil || !IsTransient(err) || attempt >= p.MaxRetries {
+			return err
+		}
+
+		timer := time.NewTimer(p.Backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return errors.Join(err, ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/meguminnnnnnnnn/go-openai"
+	arkmodel "github.com/volcengine/volcengine-go-sdk/service/arkruntime/model"
+)
+
+func TestIsTransient(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"status 429", &StatusError{StatusCode: 429, Err: errors.New("slow down")}, true},
+		{"status 503 wrapped", fmt.Errorf("embed: %w", &StatusError{StatusCode: 503, Err: errors.New("unavailable")}), true},
+		{"status 408", &StatusError{StatusCode: 408, Err: errors.New("timeout")}, true},
+		{"status 400", &StatusError{StatusCode: 400, Err: errors.New("bad request")}, false},
+		{"status 401", &StatusError{StatusCode: 401, Err: errors.New("unauthorized")}, false},
+		{"openai api 500", fmt.Errorf("create chat completion: %w", &openai.APIError{HTTPStatusCode: 500}), true},
+		{"openai api 404", &openai.APIError{HTTPStatusCode: 404, Message: "model 500-turbo not found"}, false},
+		{"openai request 502", &openai.RequestError{HTTPStatusCode: 502, Err: errors.New("bad gateway")}, true},
+		{"ark api 429", &arkmodel.APIError{HTTPStatusCode: 429}, true},
+		{"ark request 400", &arkmodel.RequestError{HTTPStatusCode: 400, Err: errors.New("invalid")}, false},
+		// 状态码只看类型化字段，不再匹配错误信息中的数字
+		{"number in message", errors.New("input length 5000 exceeds limit, see doc 429"), false},
+		{"deadline exceeded", context.DeadlineExceeded, true},
+		{"canceled", fmt.Errorf("call: %w", context.Canceled), false},
+		{"unexpected eof", fmt.Errorf("read body: %w", io.ErrUnexpectedEOF), true},
+		{"connection refused", &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}, true},
+		{"connection reset", fmt.Errorf("post: %w", &net.OpError{Op: "read", Net: "tcp", Err: os.NewSyscallError("read", syscall.ECONNRESET)}), true},
+		{"net timeout", &net.DNSError{Err: "i/o timeout", IsTimeout: true}, true},
+		{"dns not found", &net.DNSError{Err: "no such host", IsNotFound: true}, false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := IsTransient(tc.err); got != tc.want {
+				t.Fatalf("IsTransient(%v) = %v, want %v", tc.err, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestIsRequestError(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"status 400", &StatusError{StatusCode: 400, Err: errors.New("bad request")}, true},
+		{"status 413 wrapped", fmt.Errorf("chat: %w", &StatusError{StatusCode: 413, Err: errors.New("too large")}), true},
+		{"openai api 422", &openai.APIError{HTTPStatusCode: 422}, true},
+		{"ark request 400", &arkmodel.RequestError{HTTPStatusCode: 400, Err: errors.New("invalid")}, true},
+		{"status 401", &StatusError{StatusCode: 401, Err: errors.New("unauthorized")}, false},
+		{"status 404", &StatusError{StatusCode: 404, Err: errors.New("not found")}, false},
+		{"status 503", &StatusError{StatusCode: 503, Err: errors.New("unavailable")}, false},
+		{"eof", io.EOF, false},
+		{"dns not found", &net.DNSError{Err: "no such host", IsNotFound: true}, false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := IsRequestError(tc.err); got != tc.want {
+				t.Fatalf("IsRequestError(%v) = %v, want %v", tc.err, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	p := Policy{BaseBackoff: 10 * time.Millisecond, MaxBackoff: 50 * time.Millisecond}
+	for attempt, limit := range []time.Duration{10, 20, 40, 50, 50} {
+		limit *= time.Millisecond
+		for range 100 {
+			if d := p.Backoff(attempt); d <= 0 || d > limit {
+				t.Fatalf("Backoff(%d) = %v, want (0, %v]", attempt, d, limit)
+			}
+		}
+	}
+	// 移位溢出时取上限
+	if d := p.Backoff(80); d <= 0 || d > p.MaxBackoff {
+		t.Fatalf("Backoff(80) = %v", d)
+	}
+	if d := (Policy{}).Backoff(3); d != 0 {
+		t.Fatalf("zero policy backoff = %v", d)
+	}
+}
+
+func TestDo(t *testing.T) {
+	transient := &StatusError{StatusCode: 503, Err: errors.New("unavailable")}
+	permanent := &StatusError{StatusCode: 400, Err: errors.New("bad request")}
+	p := Policy{MaxRetries: 3, BaseBackoff: time.Millisecond, MaxBackoff: time.Millisecond}
+
+	cases := []struct {
+		name      string
+		errs      []error // 依次返回，用完后返回 nil
+		wantCalls int
+		wantErr   error
+	}{
+		{"success", nil, 1, nil},
+		{"recovers", []error{transient, transient}, 3, nil},
+		{"gives up", []error{transient, transient, transient, transient, transient}, 4, transient},
+		{"permanent", []error{permanent, transient}, 1, permanent},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			calls := 0
+			err := Do(context.Background(), p, func(ctx context.Context) error {
+				calls++
+				if calls <= len(tc.errs) {
+					return tc.errs[calls-1]
+				}
+				return nil
+			})
+			if calls != tc.wantCalls || !errors.Is(err, tc.wantErr) || (tc.wantErr == nil) != (err == nil) {
+				t.Fatalf("calls = %d err = %v, want %d %v", calls, err, tc.wantCalls, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestDoStopsOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	p := Policy{MaxRetries: 5, BaseBackoff: time.Hour, MaxBackoff: time.Hour}
+	transient := &StatusError{StatusCode: 429, Err: errors.New("rate limited")}
+
+	time.AfterFunc(20*time.Millisecond, cancel)
+	start := time.Now()
+	calls := 0
+	err := Do(ctx, p, func(ctx context.Context) error {
+		calls++
+		return transient
+	})
+	if calls != 1 || !errors.Is(err, transient) || !errors.Is(err, context.Canceled) {
+		t.Fatalf("calls = %d err = %v", calls, err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("backoff not interrupted, took %v", elapsed)
+	}
+}
@@ -0,0 +1,67 @@
+package token
+
+import (
+	"math"
+	"unicode"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+// Counter 按分词器特点估算 token 数
+// 各厂商分词器不同且大多不公开，这里按经验系数估算，用于预算控制而非计费
+type Counter struct {
+	CJKPerToken   float64 // 每个 token 对应的中日韩字符数
+	CharsPerToken float64 // 每个 token 对应的其余字符数
+	PerMessage    int     // 每条消息的格式开销（角色、分隔符等）
+}
+
+// Default 未知提供方使用的估算系数：中日韩字符约 1 token/字，其余字符约 4 字符/token
+var Default = Counter{CJKPerToken: 1, CharsPerToken: 4, PerMessage: 4}
+
+// counters 各提供方的估算系数，中文词表较大的分词器每个 token 可覆盖更多汉字
+var counters = map[string]Counter{
+	"ark":    {CJKPerToken: 1.5, CharsPerToken: 4, PerMessage: 4},
+	"openai": {CJKPerToken: 1.2, CharsPerToken: 4, PerMessage: 4},
+	"qwen":   {CJKPerToken: 1.4, CharsPerToken: 4, PerMessage: 4},
+	"ollama": {CJKPerToken: 0.8, CharsPerToken: 3.5, PerMessage: 4},
+}
+
+// ForProvider 返回提供方的计数器，未知提供方返回 Default
+func ForProvider(provider string) Counter {
+	if c, ok := counters[provider]; ok {
+		return c
+	}
+	return Default
+}
+
+// Count 估算文本的 token 数
+func (c Counter) Count(text string) int {
+	cjk, other := 0, 0
+	for _, r := range text {
+		if isCJK(r) {
+			cjk++
+		} else {
+			other++
+		}
+	}
+	return int(math.Ceil(float64(cjk)/c.CJKPerToken) + math.Ceil(float64(other)/c.CharsPerToken))
+}
+
+// CountMessages 估算消息列表的 token 数，包括每条消息的格式开销
+func (c Counter) CountMessages(messages []*schema.Message) int {
+	total := 0
+	for _, msg := range messages {
+		total += c.PerMessage + c.Count(msg.Content)
+	}
+	return total
+}
+
+// Estimate 粗略估算文本的 token 数
+// 各厂商分词器不同，这里按经验值估算：中日韩字符约 1 token/字，其余字符约 4 字符/token
+func Estimate(text string) int {
+	return Default.Count(text)
+}
+
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
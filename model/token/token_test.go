@@ -0,0 +1,67 @@
+package token
+
+import (
+	"testing"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+func TestCounterCount(t *testing.T) {
+	cases := []struct {
+		name     string
+		provider string
+		text     string
+		want     int
+	}{
+		{"empty", "", "", 0},
+		{"ascii exact", "", "abcd", 1},
+		{"ascii rounds up", "", "abcde", 2},
+		{"cjk", "", "你好", 2},
+		{"mixed", "", "你好 ab", 3},
+		{"kana and hangul", "", "カナ한글", 4},
+		{"ark cjk", "ark", "你好世", 2},
+		{"qwen cjk", "qwen", "你好世界", 3},
+		{"ollama ascii", "ollama", "abcdefg", 2},
+		{"ollama cjk", "ollama", "你好世界", 5},
+		{"unknown provider", "nope", "abcde", 2},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := ForProvider(tc.provider).Count(tc.text); got != tc.want {
+				t.Fatalf("Count(%q) = %d, want %d", tc.text, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCounterCountMessages(t *testing.T) {
+	messages := []*schema.Message{
+		schema.SystemMessage("abcdefgh"),
+		schema.UserMessage("你好"),
+		schema.AssistantMessage("", nil),
+	}
+	cases := []struct {
+		name    string
+		counter Counter
+		want    int
+	}{
+		{"default", Default, 2 + 2 + 3*4},
+		{"openai", ForProvider("openai"), 2 + 2 + 3*4},
+		{"no overhead", Counter{CJKPerToken: 1, CharsPerToken: 4}, 4},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.counter.CountMessages(messages); got != tc.want {
+				t.Fatalf("CountMessages = %d, want %d", got, tc.want)
+			}
+		})
+	}
+	if got := Default.CountMessages(nil); got != 0 {
+		t.Fatalf("CountMessages(nil) = %d", got)
+	}
+	if Estimate("你好 ab") != Default.Count("你好 ab") {
+		t.Fatal("Estimate differs from Default")
+	}
+}
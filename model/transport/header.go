@@ -0,0 +1,43 @@
+package transport
+
+import (
+	"net/http"
+	"strings"
+)
+
+// headerTransport 为每个请求附加固定请求头，用于网关鉴权、租户标识等
+type headerTransport struct {
+	base    http.RoundTripper
+	headers map[string]string
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	for k, v := range t.headers {
+		req.Header.Set(k, v)
+	}
+	return t.base.RoundTrip(req)
+}
+
+// NewHTTPClient 返回附加固定请求头的 http.Client，headers 为空时返回 nil 以使用 SDK 默认客户端
+func NewHTTPClient(headers map[string]string) *http.Client {
+	if len(headers) == 0 {
+		return nil
+	}
+	return &http.Client{
+		Transport: &headerTransport{base: http.DefaultTransport, headers: headers},
+	}
+}
+
+// ParseHeaders 解析 "Key1:Value1,Key2:Value2" 形式的请求头配置
+func ParseHeaders(s string) map[string]string {
+	headers := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		k, v, ok := strings.Cut(pair, ":")
+		if !ok || strings.TrimSpace(k) == "" {
+			continue
+		}
+		headers[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	}
+	return headers
+}
@@ -0,0 +1,44 @@
+package usage
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Price 每百万 token 的单价，货币单位由价格表自行约定
+type Price struct {
+	Prompt     float64 `json:"prompt"`
+	Completion float64 `json:"completion"`
+}
+
+// PriceTable 价格表，键为 "提供方/模型" 或仅 "提供方"（该提供方所有模型的默认价格）
+type PriceTable map[string]Price
+
+// ParsePrices 解析 JSON 价格表，如 {"ark/doubao-seed-1-8-251228":{"prompt":0.8,"completion":2},"openai":{"prompt":2.5,"completion":10}}
+func ParsePrices(raw string) (PriceTable, error) {
+	if raw == "" {
+		return PriceTable{}, nil
+	}
+
+	var table PriceTable
+	if err := json.Unmarshal([]byte(raw), &table); err != nil {
+		return nil, fmt.Errorf("invalid USAGE_PRICES: %w", err)
+	}
+	for key, price := range table {
+		if price.Prompt < 0 || price.Completion < 0 {
+			return nil, fmt.Errorf("invalid USAGE_PRICES: negative price for %s", key)
+		}
+	}
+	return table, nil
+}
+
+// Cost 按价格表计算费用，先匹配 "提供方/模型"，再匹配 "提供方"
+func (t PriceTable) Cost(provider, model string, promptTokens, completionTokens int) float64 {
+	price, ok := t[provider+"/"+model]
+	if !ok {
+		if price, ok = t[provider]; !ok {
+			return 0
+		}
+	}
+	return (float64(promptTokens)*price.Prompt + float64(completionTokens)*price.Completion) / 1e6
+}
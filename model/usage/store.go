@@ -0,0 +1,200 @@
+package usage
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Store 全局用量存储，未初始化时不落盘
+var Store *JSONLStore
+
+// groupFields 支持的分组维度
+var groupFields = map[string]func(Record) string{
+	"session":    func(r Record) string { return r.Session },
+	"api_key":    func(r Record) string { return r.APIKey },
+	"collection": func(r Record) string { return r.Collection },
+	"endpoint":   func(r Record) string { return r.Endpoint },
+	"kind":       func(r Record) string { return r.Kind },
+	"provider":   func(r Record) string { return r.Provider },
+	"model":      func(r Record) string { return r.Provider + "/" + r.Model },
+	"day":        func(r Record) string { return r.Time.Local().Format("2006-01-02") },
+	"month":      func(r Record) string { return r.Time.Local().Format("2006-01") },
+}
+
+// Query 用量查询条件，From/To 为零值时不限，区间为 [From, To)
+type Query struct {
+	From    time.Time
+	To      time.Time
+	GroupBy []string
+}
+
+// Group 一个分组的用量合计
+type Group struct {
+	Key              map[string]string `json:"key,omitempty"`
+	Calls            int               `json:"calls"`
+	PromptTokens     int               `json:"prompt_tokens"`
+	CompletionTokens int               `json:"completion_tokens"`
+	EmbeddingTokens  int               `json:"embedding_tokens"`
+	TotalTokens      int               `json:"total_tokens"`
+	Cost             float64           `json:"cost"`
+	EstimatedCalls   int               `json:"estimated_calls"` // 其中按文本长度估算用量的调用数
+}
+
+// Report 用量报表
+type Report struct {
+	GroupBy []string `json:"group_by,omitempty"`
+	Groups  []*Group `json:"groups"`
+	Total   Group    `json:"total"`
+}
+
+// JSONLStore 以 JSONL 追加写入的本地用量存储，path 为空时只保存在内存中
+type JSONLStore struct {
+	mu      sync.Mutex
+	path    string
+	records []Record // 仅内存模式使用
+}
+
+// NewStore 创建用量存储
+func NewStore(path string) (*JSONLStore, error) {
+	if path != "" {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return nil, fmt.Errorf("create usage store dir failed: %w", err)
+		}
+	}
+	return &JSONLStore{path: path}, nil
+}
+
+// Append 追加用量记录
+func (s *JSONLStore) Append(records []Record) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.path == "" {
+		s.records = append(s.records, records...)
+		return nil
+	}
+
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("open usage store failed: %w", err)
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, rec := range records {
+		if err := enc.Encode(rec); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
+
+// Query 按时间范围过滤并分组汇总
+func (s *JSONLStore) Query(q Query) (*Report, error) {
+	for _, field := range q.GroupBy {
+		if _, ok := groupFields[field]; !ok {
+			return nil, fmt.Errorf("unsupported group_by: %s", field)
+		}
+	}
+
+	report := &Report{GroupBy: q.GroupBy, Groups: []*Group{}}
+	groups := make(map[string]*Group)
+	err := s.scan(func(rec Record) {
+		if !q.From.IsZero() && rec.Time.Before(q.From) {
+			return
+		}
+		if !q.To.IsZero() && !rec.Time.Before(q.To) {
+			return
+		}
+
+		report.Total.add(rec)
+		if len(q.GroupBy) == 0 {
+			return
+		}
+
+		values := make([]string, len(q.GroupBy))
+		for i, field := range q.GroupBy {
+			values[i] = groupFields[field](rec)
+		}
+		id := strings.Join(values, "\x00")
+		g, ok := groups[id]
+		if !ok {
+			g = &Group{Key: make(map[string]string, len(q.GroupBy))}
+			for i, field := range q.GroupBy {
+				g.Key[field] = values[i]
+			}
+			groups[id] = g
+			report.Groups = append(report.Groups, g)
+		}
+		g.add(rec)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(report.Groups, func(i, j int) bool {
+		return report.Groups[i].Cost > report.Groups[j].Cost ||
+			report.Groups[i].Cost == report.Groups[j].Cost && report.Groups[i].TotalTokens > report.Groups[j].TotalTokens
+	})
+	return report, nil
+}
+
+func (s *JSONLStore) scan(fn func(Record)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.path == "" {
+		for _, rec := range s.records {
+			fn(rec)
+		}
+		return nil
+	}
+
+	f, err := os.Open(s.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("open usage store failed: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var rec Record
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			// 跳过写入中断产生的残行
+			continue
+		}
+		fn(rec)
+	}
+	return scanner.Err()
+}
+
+func (g *Group) add(rec Record) {
+	g.Calls++
+	if rec.Kind == KindEmbedding {
+		g.EmbeddingTokens += rec.TotalTokens
+	} else {
+		g.PromptTokens += rec.PromptTokens
+		g.CompletionTokens += rec.CompletionTokens
+	}
+	g.TotalTokens += rec.TotalTokens
+	g.Cost += rec.Cost
+	if rec.Estimated {
+		g.EstimatedCalls++
+	}
+}
@@ -0,0 +1,163 @@
+package usage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// 用量类型
+const (
+	KindChat      = "chat"
+	KindEmbedding = "embedding"
+)
+
+// Prices 全局价格表，未配置时费用记为 0
+var Prices PriceTable
+
+// Scope 用量归属：会话、API Key、集合与接口
+type Scope struct {
+	Session    string
+	APIKey     string // API Key 指纹，见 KeyID
+	Collection string
+	Endpoint   string
+}
+
+// Record 一次模型调用的用量
+type Record struct {
+	Time             time.Time `json:"time"`
+	Kind             string    `json:"kind"`
+	Provider         string    `json:"provider"`
+	Model            string    `json:"model,omitempty"`
+	PromptTokens     int       `json:"prompt_tokens"`
+	CompletionTokens int       `json:"completion_tokens"`
+	TotalTokens      int       `json:"total_tokens"`
+	Estimated        bool      `json:"estimated,omitempty"` // 提供方未返回用量，按文本长度估算
+	Cost             float64   `json:"cost"`
+	Session          string    `json:"session,omitempty"`
+	APIKey           string    `json:"api_key,omitempty"`
+	Collection       string    `json:"collection,omitempty"`
+	Endpoint         string    `json:"endpoint,omitempty"`
+}
+
+// Usage 单次请求的用量汇总，随接口响应返回
+type Usage struct {
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	EmbeddingTokens  int     `json:"embedding_tokens"`
+	TotalTokens      int     `json:"total_tokens"`
+	Cost             float64 `json:"cost"`
+	Estimated        bool    `json:"estimated,omitempty"` // 部分用量为估算值
+}
+
+// Recorder 收集单次请求内的全部模型调用用量，并发安全
+type Recorder struct {
+	mu      sync.Mutex
+	scope   Scope
+	records []Record
+}
+
+type recorderKey struct{}
+
+// NewContext 返回携带用量收集器的上下文
+func NewContext(ctx context.Context, scope Scope) (context.Context, *Recorder) {
+	r := &Recorder{scope: scope}
+	return context.WithValue(ctx, recorderKey{}, r), r
+}
+
+// FromContext 返回上下文中的用量收集器，不存在时返回 nil
+func FromContext(ctx context.Context) *Recorder {
+	r, _ := ctx.Value(recorderKey{}).(*Recorder)
+	return r
+}
+
+// Add 向上下文中的收集器追加一条用量，无收集器时忽略
+func Add(ctx context.Context, rec Record) {
+	FromContext(ctx).Add(rec)
+}
+
+// Add 追加一条用量，补全归属、时间与费用
+func (r *Recorder) Add(rec Record) {
+	if r == nil {
+		return
+	}
+	if rec.TotalTokens == 0 {
+		rec.TotalTokens = rec.PromptTokens + rec.CompletionTokens
+	}
+	if rec.Time.IsZero() {
+		rec.Time = time.Now()
+	}
+	rec.Cost = Prices.Cost(rec.Provider, rec.Model, rec.PromptTokens, rec.CompletionTokens)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	rec.Session = r.scope.Session
+	rec.APIKey = r.scope.APIKey
+	rec.Collection = r.scope.Collection
+	rec.Endpoint = r.scope.Endpoint
+	r.records = append(r.records, rec)
+}
+
+// SetCollection 设置本次请求操作的集合
+func (r *Recorder) SetCollection(collection string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.scope.Collection = collection
+}
+
+// Scope 返回收集器的用量归属，收集器为 nil 时返回零值
+func (r *Recorder) Scope() Scope {
+	if r == nil {
+		return Scope{}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.scope
+}
+
+// Records 返回已收集的用量
+func (r *Recorder) Records() []Record {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Record(nil), r.records...)
+}
+
+// Summary 汇总已收集的用量，没有任何模型调用时返回 nil
+func (r *Recorder) Summary() *Usage {
+	records := r.Records()
+	if len(records) == 0 {
+		return nil
+	}
+
+	u := &Usage{}
+	for _, rec := range records {
+		switch rec.Kind {
+		case KindEmbedding:
+			u.EmbeddingTokens += rec.TotalTokens
+		default:
+			u.PromptTokens += rec.PromptTokens
+			u.CompletionTokens += rec.CompletionTokens
+		}
+		u.TotalTokens += rec.TotalTokens
+		u.Cost += rec.Cost
+		u.Estimated = u.Estimated || rec.Estimated
+	}
+	return u
+}
+
+// KeyID 返回 API Key 的指纹（SHA-256 前 12 位），避免明文落盘
+func KeyID(key string) string {
+	if key == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])[:12]
+}
@@ -0,0 +1,69 @@
+package usage
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRecorderSummaryAndCost(t *testing.T) {
+	Prices = PriceTable{
+		"openai/gpt-4o": {Prompt: 2.5, Completion: 10},
+		"ark":           {Prompt: 1, Completion: 1},
+	}
+	t.Cleanup(func() { Prices = nil })
+
+	ctx, r := NewContext(context.Background(), Scope{Session: "s1"})
+	r.SetCollection("docs")
+	Add(ctx, Record{Kind: KindChat, Provider: "openai", Model: "gpt-4o", PromptTokens: 1000, CompletionTokens: 500})
+	Add(ctx, Record{Kind: KindEmbedding, Provider: "ark", Model: "emb", PromptTokens: 2000, Estimated: true})
+	Add(context.Background(), Record{Kind: KindChat, PromptTokens: 1}) // 无收集器时忽略
+
+	u := r.Summary()
+	if u.PromptTokens != 1000 || u.CompletionTokens != 500 || u.EmbeddingTokens != 2000 || u.TotalTokens != 3500 || !u.Estimated {
+		t.Fatalf("unexpected summary: %+v", u)
+	}
+	if want := 0.0075 + 0.002; u.Cost < want-1e-12 || u.Cost > want+1e-12 {
+		t.Fatalf("cost = %v, want %v", u.Cost, want)
+	}
+	if rec := r.Records()[0]; rec.Session != "s1" || rec.Collection != "docs" {
+		t.Fatalf("scope not applied: %+v", rec)
+	}
+}
+
+func TestStoreQuery(t *testing.T) {
+	store, err := NewStore(filepath.Join(t.TempDir(), "usage.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	day1 := time.Date(2026, 3, 1, 10, 0, 0, 0, time.Local)
+	day2 := day1.AddDate(0, 0, 1)
+	store.Append([]Record{
+		{Time: day1, Kind: KindChat, APIKey: "a", TotalTokens: 10, Cost: 1},
+		{Time: day1, Kind: KindChat, APIKey: "b", TotalTokens: 20, Cost: 3},
+	})
+	store.Append([]Record{{Time: day2, Kind: KindChat, APIKey: "a", TotalTokens: 30, Cost: 2}})
+
+	report, err := store.Query(Query{GroupBy: []string{"api_key"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 费用相同时按 token 数降序
+	if len(report.Groups) != 2 || report.Groups[0].Key["api_key"] != "a" || report.Groups[0].Calls != 2 || report.Groups[0].Cost != 3 {
+		t.Fatalf("unexpected groups: %+v %+v", report.Groups[0], report.Groups[1])
+	}
+	if report.Total.Calls != 3 || report.Total.TotalTokens != 60 {
+		t.Fatalf("unexpected total: %+v", report.Total)
+	}
+
+	report, _ = store.Query(Query{From: day2, GroupBy: []string{"day"}})
+	if len(report.Groups) != 1 || report.Groups[0].Key["day"] != "2026-03-02" || report.Total.Cost != 2 {
+		t.Fatalf("time filter failed: %+v", report)
+	}
+
+	if _, err := store.Query(Query{GroupBy: []string{"colour"}}); err == nil {
+		t.Fatal("unknown group_by should fail")
+	}
+}
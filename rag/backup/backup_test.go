@@ -0,0 +1,189 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"go-agent/model/embedding_model"
+	"go-agent/rag/tools/db"
+	"go-agent/rag/tools/indexer"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testDim = 32
+
+func testChunks(t *testing.T) []*db.Chunk {
+	t.Helper()
+	embedder, err := embedding_model.NewFakeEmbedder(testDim)
+	if err != nil {
+		t.Fatal(err)
+	}
+	contents := []string{
+		"Go 语言的 goroutine 是轻量级线程",
+		"Milvus 是开源向量数据库",
+		"备份文件的结尾记录用于校验完整性",
+	}
+	vectors, err := embedder.EmbedStrings(context.Background(), contents)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	chunks := make([]*db.Chunk, len(contents))
+	for i, content := range contents {
+		vec := make([]float32, testDim)
+		for j, v := range vectors[i] {
+			vec[j] = float32(v)
+		}
+		chunks[i] = &db.Chunk{
+			ID:       "doc_chunk_" + string(rune('0'+i)),
+			Content:  content,
+			MetaData: map[string]any{db.MetaKeySource: "doc.md"},
+			Vector:   vec,
+		}
+	}
+	return chunks
+}
+
+func scanOf(chunks []*db.Chunk, err error) scanFunc {
+	return func(fn func([]*db.Chunk) error) error {
+		if fnErr := fn(chunks); fnErr != nil {
+			return fnErr
+		}
+		return err
+	}
+}
+
+func testManifest() *Manifest {
+	return &Manifest{
+		Version:        manifestVersion,
+		Collection:     "docs",
+		EmbeddingModel: "fake",
+		Dimension:      testDim,
+		IncludeVectors: true,
+		ExportedAt:     time.Now(),
+	}
+}
+
+func TestExportImportRoundTrip(t *testing.T) {
+	embedder, err := embedding_model.NewFakeEmbedder(testDim)
+	if err != nil {
+		t.Fatal(err)
+	}
+	embedding_model.Embedding = embedder
+	chunks := testChunks(t)
+
+	for _, format := range []string{FormatJSONL, FormatTar} {
+		for _, reembed := range []bool{false, true} {
+			var buf bytes.Buffer
+			var err error
+			if format == FormatTar {
+				err = exportTar(testManifest(), scanOf(chunks, nil), &buf)
+			} else {
+				err = exportJSONL(testManifest(), scanOf(chunks, nil), &buf)
+			}
+			if err != nil {
+				t.Fatalf("%s: export: %v", format, err)
+			}
+
+			manifest, r, cleanup, err := openBackup(&buf)
+			if err != nil {
+				t.Fatalf("%s: open: %v", format, err)
+			}
+			if manifest.Collection != "docs" || manifest.Dimension != testDim {
+				t.Fatalf("%s: manifest = %+v", format, manifest)
+			}
+
+			store := db.NewMemoryStore()
+			result := &ImportResult{Manifest: manifest}
+			err = load(context.Background(), indexer.NewMemoryIndexer(store), r, reembed, testDim, result)
+			cleanup()
+			if err != nil {
+				t.Fatalf("%s reembed=%v: load: %v", format, reembed, err)
+			}
+			if result.Imported != len(chunks) || store.Len() != len(chunks) {
+				t.Fatalf("%s reembed=%v: imported %d, stored %d, want %d", format, reembed, result.Imported, store.Len(), len(chunks))
+			}
+
+			// 回放的向量与重新嵌入的向量都应让每个块检索到自己
+			for _, chunk := range chunks {
+				query, _ := embedder.EmbedStrings(context.Background(), []string{chunk.Content})
+				hits := store.Search(query[0], 1)
+				if len(hits) != 1 || hits[0].ID != chunk.ID || hits[0].Content != chunk.Content {
+					t.Fatalf("%s reembed=%v: search %q got %+v", format, reembed, chunk.ID, hits)
+				}
+				if hits[0].MetaData[db.MetaKeySource] != "doc.md" {
+					t.Fatalf("%s reembed=%v: metadata lost: %+v", format, reembed, hits[0].MetaData)
+				}
+			}
+		}
+	}
+}
+
+func TestImportRejectsTruncatedBackup(t *testing.T) {
+	chunks := testChunks(t)
+
+	// 导出中途失败：已写出的块之后没有结尾记录
+	var failed bytes.Buffer
+	err := exportJSONL(testManifest(), scanOf(chunks[:2], errors.New("milvus unavailable")), &failed)
+	if err == nil {
+		t.Fatal("export should report scan error")
+	}
+	if _, _, _, err := openBackup(&failed); err == nil || !strings.Contains(err.Error(), "缺少结尾记录") {
+		t.Fatalf("partial export should be rejected, got %v", err)
+	}
+
+	var full bytes.Buffer
+	if err := exportJSONL(testManifest(), scanOf(chunks, nil), &full); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.SplitAfter(full.String(), "\n")
+
+	// 文件在结尾记录之前被截断
+	truncated := strings.Join(lines[:len(lines)-2], "")
+	if _, _, _, err := openBackup(strings.NewReader(truncated)); err == nil {
+		t.Fatal("truncated backup should be rejected")
+	}
+
+	// 丢失中间一行：数量与校验和都不一致
+	missing := lines[0] + lines[1] + strings.Join(lines[3:], "")
+	if _, _, _, err := openBackup(strings.NewReader(missing)); err == nil || !strings.Contains(err.Error(), "已损坏") {
+		t.Fatalf("backup missing a chunk should be rejected, got %v", err)
+	}
+
+	// 内容被修改：数量一致但校验和不一致
+	corrupted := strings.Replace(full.String(), "Milvus", "Mivlus", 1)
+	if _, _, _, err := openBackup(strings.NewReader(corrupted)); err == nil || !strings.Contains(err.Error(), "已损坏") {
+		t.Fatalf("corrupted backup should be rejected, got %v", err)
+	}
+}
+
+func TestImportAcceptsLegacyBackup(t *testing.T) {
+	chunks := testChunks(t)
+	manifest := testManifest()
+	manifest.Version = 1
+
+	var buf bytes.Buffer
+	if err := exportJSONL(manifest, scanOf(chunks, nil), &buf); err != nil {
+		t.Fatal(err)
+	}
+	// 版本 1 的备份没有结尾记录
+	lines := strings.SplitAfter(buf.String(), "\n")
+	legacy := strings.Join(lines[:len(lines)-2], "")
+
+	_, r, cleanup, err := openBackup(strings.NewReader(legacy))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+
+	store := db.NewMemoryStore()
+	result := &ImportResult{}
+	if err := load(context.Background(), indexer.NewMemoryIndexer(store), r, false, testDim, result); err != nil {
+		t.Fatal(err)
+	}
+	if result.Imported != len(chunks) {
+		t.Fatalf("imported %d, want %d", result.Imported, len(chunks))
+	}
+}
@@ -0,0 +1,214 @@
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"go-agent/rag/tools/db"
+	"hash"
+	"io"
+	"os"
+	"time"
+)
+
+const (
+	// FormatJSONL 首行为 Manifest，其后每行一个文档块，最后一行为 Trailer
+	FormatJSONL = "jsonl"
+	// FormatTar tar.gz 包，内含 manifest.json 与 chunks.jsonl（文档块与结尾的 Trailer）
+	FormatTar = "tar"
+
+	manifestFile = "manifest.json"
+	chunksFile   = "chunks.jsonl"
+
+	// manifestVersion 2 起文档块之后写入 Trailer，导入时据此拒绝被截断的备份
+	manifestVersion = 2
+)
+
+// Manifest 备份清单，记录恢复时判断是否需要重新嵌入的信息
+type Manifest struct {
+	Version        int       `json:"version"`
+	Collection     string    `json:"collection"`
+	EmbeddingModel string    `json:"embedding_model"` // 建库时的嵌入模型，未记录时为空
+	Dimension      int       `json:"dimension"`
+	MetricType     string    `json:"metric_type,omitempty"`
+	IncludeVectors bool      `json:"include_vectors"`
+	ChunkCount     int       `json:"chunk_count,omitempty"` // 仅 tar 格式可预先得知
+	ExportedAt     time.Time `json:"exported_at"`
+}
+
+// Trailer 备份的结尾记录：文档块数量与全部文档块行（含换行符）的 SHA-256
+// 导出中途失败时不会写入，导入时缺失或不一致说明备份被截断或损坏
+type Trailer struct {
+	ChunkCount int    `json:"chunk_count"`
+	SHA256     string `json:"sha256"`
+}
+
+// trailerLine 结尾记录所在的行，文档块的行以 {"id": 开头，不会与之混淆
+type trailerLine struct {
+	End *Trailer `json:"end"`
+}
+
+// ExportOptions 导出选项
+type ExportOptions struct {
+	Format         string
+	IncludeVectors bool
+}
+
+// BuildManifest 读取集合元信息生成备份清单
+func BuildManifest(ctx context.Context, collection string, includeVectors bool) (*Manifest, error) {
+	coll, err := db.Milvus.DescribeCollection(ctx, collection)
+	if err != nil {
+		return nil, fmt.Errorf("describe collection failed: %w", err)
+	}
+
+	manifest := &Manifest{
+		Version:        manifestVersion,
+		Collection:     collection,
+		EmbeddingModel: db.CollectionEmbeddingModel(coll),
+		Dimension:      db.VectorDim(coll),
+		IncludeVectors: includeVectors,
+		ExportedAt:     time.Now(),
+	}
+
+	indexes, err := db.Milvus.DescribeIndex(ctx, collection, "vector")
+	if err == nil && len(indexes) > 0 {
+		manifest.MetricType = indexes[0].Params()["metric_type"]
+	}
+
+	return manifest, nil
+}
+
+// scanFunc 分批遍历待导出的文档块
+type scanFunc func(fn func([]*db.Chunk) error) error
+
+// Export 将集合全部文档块写入 w
+func Export(ctx context.Context, collection string, w io.Writer, opts ExportOptions) error {
+	if err := db.LoadCollection(ctx, collection); err != nil {
+		return err
+	}
+
+	manifest, err := BuildManifest(ctx, collection, opts.IncludeVectors)
+	if err != nil {
+		return err
+	}
+	scan := func(fn func([]*db.Chunk) error) error {
+		return db.ScanChunks(ctx, collection, "", opts.IncludeVectors, fn)
+	}
+
+	switch opts.Format {
+	case FormatJSONL, "":
+		return exportJSONL(manifest, scan, w)
+	case FormatTar:
+		return exportTar(manifest, scan, w)
+	default:
+		return fmt.Errorf("unsupported export format: %s", opts.Format)
+	}
+}
+
+func exportJSONL(manifest *Manifest, scan scanFunc, w io.Writer) error {
+	if err := json.NewEncoder(w).Encode(manifest); err != nil {
+		return fmt.Errorf("write manifest failed: %w", err)
+	}
+
+	_, err := writeChunks(scan, w)
+	return err
+}
+
+// exportTar 先把文档块写入临时文件以得到准确数量与大小，再打包
+func exportTar(manifest *Manifest, scan scanFunc, w io.Writer) error {
+	tmp, err := os.CreateTemp("", "go-agent-export-*.jsonl")
+	if err != nil {
+		return fmt.Errorf("create temp file failed: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	count, err := writeChunks(scan, tmp)
+	if err != nil {
+		return err
+	}
+	manifest.ChunkCount = count
+
+	size, err := tmp.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	manifestBytes, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+
+	if err := tw.WriteHeader(&tar.Header{Name: manifestFile, Mode: 0644, Size: int64(len(manifestBytes)), ModTime: manifest.ExportedAt}); err != nil {
+		return err
+	}
+	if _, err := tw.Write(manifestBytes); err != nil {
+		return err
+	}
+	if err := tw.WriteHeader(&tar.Header{Name: chunksFile, Mode: 0644, Size: size, ModTime: manifest.ExportedAt}); err != nil {
+		return err
+	}
+	if _, err := io.Copy(tw, tmp); err != nil {
+		return err
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gw.Close()
+}
+
+// writeChunks 逐行写入文档块，全部写完后写入 Trailer；遍历失败时不写 Trailer
+func writeChunks(scan scanFunc, w io.Writer) (int, error) {
+	cw := &chunkWriter{w: w, hash: sha256.New()}
+	if err := scan(func(chunks []*db.Chunk) error {
+		for _, chunk := range chunks {
+			if err := cw.write(chunk); err != nil {
+				return fmt.Errorf("write chunk failed: %w", err)
+			}
+		}
+		return nil
+	}); err != nil {
+		return cw.count, err
+	}
+
+	end, err := json.Marshal(trailerLine{End: &Trailer{ChunkCount: cw.count, SHA256: hex.EncodeToString(cw.hash.Sum(nil))}})
+	if err != nil {
+		return cw.count, err
+	}
+	if _, err := w.Write(append(end, '\n')); err != nil {
+		return cw.count, fmt.Errorf("write trailer failed: %w", err)
+	}
+	return cw.count, nil
+}
+
+// chunkWriter 写入文档块行并累计校验和
+type chunkWriter struct {
+	w     io.Writer
+	hash  hash.Hash
+	count int
+}
+
+func (cw *chunkWriter) write(chunk *db.Chunk) error {
+	line, err := json.Marshal(chunk)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+	cw.hash.Write(line)
+	if _, err := cw.w.Write(line); err != nil {
+		return err
+	}
+	cw.count++
+	return nil
+}
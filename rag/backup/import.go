@@ -0,0 +1,289 @@
+package backup
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-agent/model/embedding_model"
+	"go-agent/rag/tools/db"
+	"go-agent/rag/tools/indexer"
+	"io"
+	"log"
+	"os"
+
+	"github.com/cloudwego/eino/components/embedding"
+	indexer2 "github.com/cloudwego/eino/components/indexer"
+	"github.com/cloudwego/eino/schema"
+)
+
+// importBatchSize 每批写入的文档块数量
+const importBatchSize = 100
+
+// ImportResult 导入结果
+type ImportResult struct {
+	Manifest   *Manifest `json:"manifest"`
+	Imported   int       `json:"imported"`
+	Reembedded bool      `json:"reembedded"`
+}
+
+// Import 从 r 中读取备份（jsonl 或 tar.gz，自动识别）并恢复到 collection
+// 写入前先校验 Trailer，被截断或损坏的备份不会导入任何文档块；
+// 备份未包含向量、或建库嵌入模型与当前模型不一致时，会使用当前模型重新嵌入
+func Import(ctx context.Context, collection string, r io.Reader) (*ImportResult, error) {
+	manifest, chunks, cleanup, err := openBackup(r)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+	return restore(ctx, collection, manifest, chunks)
+}
+
+// openBackup 读取备份清单，校验文档块后返回可从头读取的文档块（每行一个，不含 Trailer）
+func openBackup(r io.Reader) (*Manifest, io.Reader, func(), error) {
+	br := bufio.NewReader(r)
+	magic, _ := br.Peek(2)
+	if len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		return openTar(br)
+	}
+
+	line, err := br.ReadBytes('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, nil, nil, fmt.Errorf("read manifest failed: %w", err)
+	}
+	var manifest Manifest
+	if err := json.Unmarshal(line, &manifest); err != nil {
+		return nil, nil, nil, fmt.Errorf("read manifest failed: %w", err)
+	}
+	chunks, cleanup, err := spoolChunks(&manifest, br)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return &manifest, chunks, cleanup, nil
+}
+
+func openTar(r io.Reader) (*Manifest, io.Reader, func(), error) {
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("open gzip failed: %w", err)
+	}
+	defer gr.Close()
+
+	tr := tar.NewReader(gr)
+	var manifest *Manifest
+	for {
+		hdr, err := tr.Next()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil, nil, nil, fmt.Errorf("archive missing %s", chunksFile)
+			}
+			return nil, nil, nil, fmt.Errorf("read archive failed: %w", err)
+		}
+
+		switch hdr.Name {
+		case manifestFile:
+			manifest = &Manifest{}
+			if err := json.NewDecoder(tr).Decode(manifest); err != nil {
+				return nil, nil, nil, fmt.Errorf("read manifest failed: %w", err)
+			}
+		case chunksFile:
+			if manifest == nil {
+				return nil, nil, nil, fmt.Errorf("%s must precede %s in archive", manifestFile, chunksFile)
+			}
+			chunks, cleanup, err := spoolChunks(manifest, tr)
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			return manifest, chunks, cleanup, nil
+		}
+	}
+}
+
+// spoolChunks 把文档块暂存到临时文件并校验 Trailer；版本 1 的备份没有 Trailer，无法校验，直接读取
+func spoolChunks(manifest *Manifest, r io.Reader) (io.Reader, func(), error) {
+	if manifest.Version > manifestVersion {
+		return nil, nil, fmt.Errorf("unsupported manifest version: %d", manifest.Version)
+	}
+	if manifest.Version < 2 {
+		log.Printf("备份版本 %d 没有结尾记录，无法校验是否完整", manifest.Version)
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return nil, nil, fmt.Errorf("read chunks failed: %w", err)
+		}
+		return bytes.NewReader(data), func() {}, nil
+	}
+
+	tmp, err := os.CreateTemp("", "go-agent-import-*.jsonl")
+	if err != nil {
+		return nil, nil, fmt.Errorf("create temp file failed: %w", err)
+	}
+	cleanup := func() {
+		tmp.Close()
+		os.Remove(tmp.Name())
+	}
+	if _, err := verifyChunks(r, tmp); err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	return tmp, cleanup, nil
+}
+
+// verifyChunks 把文档块行复制到 w，读到 Trailer 时校验数量与校验和
+// 没有 Trailer（导出中途失败或文件被截断）、或数量与校验和不一致时返回错误
+func verifyChunks(r io.Reader, w io.Writer) (int, error) {
+	br := bufio.NewReader(r)
+	h := sha256.New()
+	count := 0
+	for {
+		line, err := br.ReadBytes('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return count, fmt.Errorf("read chunks failed: %w", err)
+		}
+		if trailer := parseTrailer(line); trailer != nil {
+			if trailer.ChunkCount != count || trailer.SHA256 != hex.EncodeToString(h.Sum(nil)) {
+				return count, fmt.Errorf("备份已损坏: 结尾记录为 %d 个文档块，实际读取 %d 个或校验和不一致", trailer.ChunkCount, count)
+			}
+			return count, nil
+		}
+		if errors.Is(err, io.EOF) {
+			return count, fmt.Errorf("备份不完整: 读取 %d 个文档块后缺少结尾记录，可能在导出中途失败或被截断", count)
+		}
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		h.Write(line)
+		if _, err := w.Write(line); err != nil {
+			return count, fmt.Errorf("spool chunks failed: %w", err)
+		}
+		count++
+	}
+}
+
+func parseTrailer(line []byte) *Trailer {
+	if !bytes.HasPrefix(bytes.TrimSpace(line), []byte(`{"end"`)) {
+		return nil
+	}
+	var end trailerLine
+	if err := json.Unmarshal(line, &end); err != nil {
+		return nil
+	}
+	return end.End
+}
+
+// restore 准备目标集合与索引器后写入文档块
+func restore(ctx context.Context, collection string, manifest *Manifest, chunks io.Reader) (*ImportResult, error) {
+	currentModel := embedding_model.ModelName()
+	reembed := !manifest.IncludeVectors || manifest.EmbeddingModel == "" || manifest.EmbeddingModel != currentModel
+
+	dim := manifest.Dimension
+	if reembed {
+		var err error
+		dim, err = indexer.EmbeddingDim(ctx)
+		if err != nil {
+			return nil, err
+		}
+		log.Printf("导入集合 %s: 备份模型=%q 当前模型=%q，将重新嵌入", collection, manifest.EmbeddingModel, currentModel)
+	}
+
+	exists, err := db.Milvus.HasCollection(ctx, collection)
+	if err != nil {
+		return nil, fmt.Errorf("check collection exists failed: %w", err)
+	}
+	if exists {
+		coll, err := db.Milvus.DescribeCollection(ctx, collection)
+		if err != nil {
+			return nil, fmt.Errorf("describe collection failed: %w", err)
+		}
+		if existing := db.VectorDim(coll); existing != dim {
+			return nil, fmt.Errorf("目标集合维度不匹配: 现有维度=%d, 导入维度=%d", existing, dim)
+		}
+	}
+
+	idx, err := indexer.NewCollectionIndexer(ctx, collection, dim)
+	if err != nil {
+		return nil, fmt.Errorf("create indexer failed: %w", err)
+	}
+
+	result := &ImportResult{Manifest: manifest, Reembedded: reembed}
+	return result, load(ctx, idx, chunks, reembed, dim, result)
+}
+
+// load 分批写入文档块，不重新嵌入时回放备份中的向量
+func load(ctx context.Context, idx indexer2.Indexer, chunks io.Reader, reembed bool, dim int, result *ImportResult) error {
+	dec := json.NewDecoder(chunks)
+	docs := make([]*schema.Document, 0, importBatchSize)
+	vectors := make([][]float64, 0, importBatchSize)
+
+	flush := func() error {
+		if len(docs) == 0 {
+			return nil
+		}
+		var opts []indexer2.Option
+		if !reembed {
+			opts = append(opts, indexer2.WithEmbedding(&vectorReplayer{vectors: vectors}))
+		}
+		if _, err := idx.Store(ctx, docs, opts...); err != nil {
+			return fmt.Errorf("store chunks failed: %w", err)
+		}
+		result.Imported += len(docs)
+		docs = docs[:0]
+		vectors = vectors[:0]
+		return nil
+	}
+
+	for {
+		var chunk db.Chunk
+		if err := dec.Decode(&chunk); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return fmt.Errorf("read chunk failed: %w", err)
+		}
+
+		if !reembed {
+			if len(chunk.Vector) != dim {
+				return fmt.Errorf("chunk %s vector dim mismatch: want %d, got %d", chunk.ID, dim, len(chunk.Vector))
+			}
+			vec := make([]float64, len(chunk.Vector))
+			for i, v := range chunk.Vector {
+				vec[i] = float64(v)
+			}
+			vectors = append(vectors, vec)
+		}
+		docs = append(docs, &schema.Document{
+			ID:       chunk.ID,
+			Content:  chunk.Content,
+			MetaData: chunk.MetaData,
+		})
+
+		if len(docs) >= importBatchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+
+	return flush()
+}
+
+// vectorReplayer 按顺序回放备份中的向量，避免重复调用嵌入模型
+type vectorReplayer struct {
+	vectors [][]float64
+}
+
+func (v *vectorReplayer) EmbedStrings(ctx context.Context, texts []string, opts ...embedding.Option) ([][]float64, error) {
+	if len(texts) != len(v.vectors) {
+		return nil, fmt.Errorf("vector replay size mismatch, texts=%d vectors=%d", len(texts), len(v.vectors))
+	}
+	return v.vectors, nil
+}
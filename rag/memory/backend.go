@@ -0,0 +1,123 @@
+package memory
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"go-agent/config"
+	"go-agent/model/embedding_model"
+	"go-agent/rag/tools/db"
+	"go-agent/rag/tools/indexer"
+	"go-agent/rag/tools/retriever"
+	"strconv"
+
+	milvusretriever "github.com/cloudwego/eino-ext/components/retriever/milvus"
+	einoindexer "github.com/cloudwego/eino/components/indexer"
+	einoretriever "github.com/cloudwego/eino/components/retriever"
+	"github.com/cloudwego/eino/schema"
+)
+
+func newID() string {
+	return "mem_" + rand.Text()
+}
+
+// memoryBackend memory 模式下独立于知识库的进程内向量存储
+type memoryBackend struct {
+	store *db.MemoryStore
+}
+
+func (b *memoryBackend) search(ctx context.Context, userID, query string, topK int) ([]*schema.Document, error) {
+	if embedding_model.Embedding == nil {
+		return nil, fmt.Errorf("embedding not initialized")
+	}
+	vectors, err := embedding_model.Embedding.EmbedStrings(ctx, []string{query})
+	if err != nil {
+		return nil, err
+	}
+	if len(vectors) != 1 {
+		return nil, fmt.Errorf("invalid return length of vector, got=%d, expected=1", len(vectors))
+	}
+	return b.store.SearchFunc(vectors[0], topK, ownedBy(userID)), nil
+}
+
+func (b *memoryBackend) list(ctx context.Context, userID string) ([]*schema.Document, error) {
+	return b.store.List(ownedBy(userID)), nil
+}
+
+func (b *memoryBackend) delete(ctx context.Context, userID, id string) (bool, error) {
+	if len(b.store.List(func(doc *schema.Document) bool { return doc.ID == id && ownedBy(userID)(doc) })) == 0 {
+		return false, nil
+	}
+	return b.store.Delete(id) > 0, nil
+}
+
+func ownedBy(userID string) func(*schema.Document) bool {
+	return func(doc *schema.Document) bool {
+		owner, _ := doc.MetaData[MetaKeyUserID].(string)
+		return owner == userID
+	}
+}
+
+// milvusBackend 记忆集合，按元数据中的用户过滤
+type milvusBackend struct {
+	collection string
+	retriever  einoretriever.Retriever
+}
+
+// newMilvusBackend 创建（或打开）记忆集合及其索引器与召回器
+func newMilvusBackend(ctx context.Context, collection string) (*milvusBackend, einoindexer.Indexer, error) {
+	if collection == "" || collection == config.Cfg.MilvusConf.CollectionName {
+		return nil, nil, fmt.Errorf("MEMORY_COLLECTION 不能为空或与知识库集合相同")
+	}
+	dim, err := indexer.EmbeddingDim(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	// 索引器在集合不存在时创建集合，召回器需要读取集合的度量类型，必须在其后创建
+	idx, err := indexer.NewCollectionIndexer(ctx, collection, dim)
+	if err != nil {
+		return nil, nil, fmt.Errorf("create memory indexer failed: %w", err)
+	}
+	r, err := retriever.NewCollectionRetriever(ctx, collection, DefaultTopK)
+	if err != nil {
+		return nil, nil, fmt.Errorf("create memory retriever failed: %w", err)
+	}
+	return &milvusBackend{collection: collection, retriever: r}, idx, nil
+}
+
+func (b *milvusBackend) search(ctx context.Context, userID, query string, topK int) ([]*schema.Document, error) {
+	return b.retriever.Retrieve(ctx, query, einoretriever.WithTopK(topK), milvusretriever.WithFilter(userExpr(userID)))
+}
+
+func (b *milvusBackend) list(ctx context.Context, userID string) ([]*schema.Document, error) {
+	if err := db.LoadCollection(ctx, b.collection); err != nil {
+		return nil, err
+	}
+	var docs []*schema.Document
+	err := db.ScanChunks(ctx, b.collection, userExpr(userID), false, func(chunks []*db.Chunk) error {
+		for _, c := range chunks {
+			docs = append(docs, &schema.Document{ID: c.ID, Content: c.Content, MetaData: c.MetaData})
+		}
+		return nil
+	})
+	return docs, err
+}
+
+func (b *milvusBackend) delete(ctx context.Context, userID, id string) (bool, error) {
+	if err := db.LoadCollection(ctx, b.collection); err != nil {
+		return false, err
+	}
+	expr := fmt.Sprintf("id == %s && %s", strconv.Quote(id), userExpr(userID))
+	chunks, err := db.QueryChunks(ctx, b.collection, expr, 0, 1, false)
+	if err != nil || len(chunks) == 0 {
+		return false, err
+	}
+	if err := db.Milvus.Delete(ctx, b.collection, "", expr); err != nil {
+		return false, fmt.Errorf("delete memory failed: %w", err)
+	}
+	return true, nil
+}
+
+func userExpr(userID string) string {
+	return fmt.Sprintf(`metadata["%s"] == %s`, MetaKeyUserID, strconv.Quote(userID))
+}
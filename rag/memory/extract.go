@@ -0,0 +1,90 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+// maxKnownInPrompt 提取时最多列出的已有记忆条数
+const maxKnownInPrompt = 50
+
+const extractPrompt = `You maintain long-term memory about a user across conversations.
+Read the conversation and extract stable facts about the user that will still be useful in future conversations: preferences, background, their projects, tools and goals.
+Ignore one-off requests, facts about the world, anything the assistant said about itself, and anything already known.
+Write each memory as one short standalone sentence about the user, in the language of the conversation.
+
+Already known about the user:
+%s
+Reply with JSON only, no prose and no code fences:
+{"memories":[{"content":"the user prefers Go over Python","category":"preference"}]}
+category is one of preference, project, profile, other. Reply {"memories":[]} when there is nothing new.`
+
+// Item 从对话中提取的一条候选记忆
+type Item struct {
+	Content  string `json:"content"`
+	Category string `json:"category,omitempty"`
+}
+
+// Extract 让聊天模型从结束的对话中提取值得长期记住的用户信息，known 为已有记忆，用于避免重复提取
+func Extract(ctx context.Context, cm model.BaseChatModel, conversation []*schema.Message, known []Memory) ([]Item, error) {
+	var transcript strings.Builder
+	for _, msg := range conversation {
+		if (msg.Role != schema.User && msg.Role != schema.Assistant) || strings.TrimSpace(msg.Content) == "" {
+			continue
+		}
+		fmt.Fprintf(&transcript, "%s: %s\n", msg.Role, msg.Content)
+	}
+	if transcript.Len() == 0 {
+		return nil, nil
+	}
+
+	var knownText strings.Builder
+	for _, m := range known[max(len(known)-maxKnownInPrompt, 0):] {
+		fmt.Fprintf(&knownText, "- %s\n", m.Content)
+	}
+	if knownText.Len() == 0 {
+		knownText.WriteString("(nothing yet)\n")
+	}
+
+	out, err := cm.Generate(ctx, []*schema.Message{
+		schema.SystemMessage(fmt.Sprintf(extractPrompt, knownText.String())),
+		schema.UserMessage(transcript.String()),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("extract memories failed: %w", err)
+	}
+	return parseItems(out.Content)
+}
+
+// Remember 从对话中提取用户记忆并写入，返回新写入的记忆
+func (s *Store) Remember(ctx context.Context, cm model.BaseChatModel, userID string, conversation []*schema.Message) ([]Memory, error) {
+	known, err := s.List(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	items, err := Extract(ctx, cm, conversation, known)
+	if err != nil {
+		return nil, err
+	}
+	return s.Add(ctx, userID, items)
+}
+
+// parseItems 解析模型输出，容忍代码块与前后的说明文字
+func parseItems(content string) ([]Item, error) {
+	start, end := strings.Index(content, "{"), strings.LastIndex(content, "}")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("no JSON in model output: %q", content)
+	}
+	var out struct {
+		Memories []Item `json:"memories"`
+	}
+	if err := json.Unmarshal([]byte(content[start:end+1]), &out); err != nil {
+		return nil, fmt.Errorf("invalid memories JSON: %w", err)
+	}
+	return out.Memories, nil
+}
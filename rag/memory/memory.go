@@ -0,0 +1,260 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-agent/config"
+	"go-agent/model/embedding_model"
+	"go-agent/rag/tools/db"
+	"go-agent/rag/tools/indexer"
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	einoindexer "github.com/cloudwego/eino/components/indexer"
+	"github.com/cloudwego/eino/schema"
+)
+
+// 记忆文档的元数据键
+const (
+	MetaKeyUserID    = "user_id"
+	MetaKeyCategory  = "category"
+	MetaKeyCreatedAt = "created_at"
+)
+
+// 默认配置
+const (
+	DefaultTopK       = 5
+	DefaultDedupScore = 0.9
+)
+
+// Memories 全局记忆存储，未启用时为 nil
+var Memories *Store
+
+// ErrNotFound 记忆不存在或不属于该用户
+var ErrNotFound = errors.New("memory not found")
+
+// Memory 一条关于用户的长期记忆
+type Memory struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"user_id"`
+	Content   string    `json:"content"`
+	Category  string    `json:"category,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	Score     float64   `json:"score,omitempty"` // 检索时与问题的相似度
+}
+
+// backend 记忆集合的查询与删除，写入统一走索引器
+type backend interface {
+	search(ctx context.Context, userID, query string, topK int) ([]*schema.Document, error)
+	list(ctx context.Context, userID string) ([]*schema.Document, error)
+	delete(ctx context.Context, userID, id string) (bool, error)
+}
+
+// Store 记忆存储：每条记忆作为一个文档连同向量写入专用集合，元数据记录所属用户
+type Store struct {
+	indexer    einoindexer.Indexer
+	backend    backend
+	topK       int
+	minScore   float64
+	dedupScore float64
+}
+
+// NewStore 按配置创建记忆存储：milvus 模式写入 MEMORY_COLLECTION 集合，memory 模式使用独立的进程内向量存储
+func NewStore(ctx context.Context) (*Store, error) {
+	conf := config.Cfg.MemoryConf
+	s := &Store{topK: DefaultTopK, dedupScore: DefaultDedupScore}
+	if n, err := strconv.Atoi(conf.TopK); err == nil && n > 0 {
+		s.topK = n
+	}
+	if v, err := strconv.ParseFloat(conf.MinScore, 64); err == nil {
+		s.minScore = v
+	}
+	if v, err := strconv.ParseFloat(conf.DedupScore, 64); err == nil && v > 0 {
+		s.dedupScore = v
+	}
+
+	switch config.Cfg.VectorDBType {
+	case "memory":
+		store := db.NewMemoryStore()
+		s.indexer, s.backend = indexer.NewMemoryIndexer(store), &memoryBackend{store: store}
+	case "milvus":
+		b, idx, err := newMilvusBackend(ctx, conf.Collection)
+		if err != nil {
+			return nil, err
+		}
+		s.indexer, s.backend = idx, b
+	default:
+		return nil, fmt.Errorf("记忆存储不支持向量库类型: %s", config.Cfg.VectorDBType)
+	}
+	return s, nil
+}
+
+// Add 为用户写入记忆，与已有记忆或同批中靠前的条目高度相似（不低于 MEMORY_DEDUP_SCORE）的条目跳过，返回实际写入的记忆
+func (s *Store) Add(ctx context.Context, userID string, items []Item) ([]Memory, error) {
+	if userID == "" {
+		return nil, errors.New("user_id is required")
+	}
+	items, err := s.dedupBatch(ctx, items)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	var docs []*schema.Document
+	var added []Memory
+	for _, item := range items {
+		content := item.Content
+		similar, err := s.backend.search(ctx, userID, content, 1)
+		if err != nil {
+			return nil, err
+		}
+		if len(similar) > 0 && similar[0].Score() >= s.dedupScore {
+			continue
+		}
+
+		m := Memory{ID: newID(), UserID: userID, Content: content, Category: item.Category, CreatedAt: now}
+		docs = append(docs, &schema.Document{
+			ID:      m.ID,
+			Content: m.Content,
+			MetaData: map[string]any{
+				MetaKeyUserID:    m.UserID,
+				MetaKeyCategory:  m.Category,
+				MetaKeyCreatedAt: m.CreatedAt.Format(time.RFC3339),
+			},
+		})
+		added = append(added, m)
+	}
+	if len(docs) == 0 {
+		return nil, nil
+	}
+	if _, err := s.indexer.Store(ctx, docs); err != nil {
+		return nil, fmt.Errorf("store memories failed: %w", err)
+	}
+	return added, nil
+}
+
+// dedupBatch 去掉空条目，并在同一批内去重：内容相同或向量相似度不低于 dedupScore 的只保留第一条
+// 批内条目尚未写入，无法靠检索已有记忆发现彼此重复
+func (s *Store) dedupBatch(ctx context.Context, items []Item) ([]Item, error) {
+	var unique []Item
+	seen := make(map[string]bool)
+	for _, item := range items {
+		item.Content = strings.TrimSpace(item.Content)
+		key := strings.ToLower(strings.Join(strings.Fields(item.Content), " "))
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		unique = append(unique, item)
+	}
+	if len(unique) < 2 || embedding_model.Embedding == nil {
+		return unique, nil
+	}
+
+	texts := make([]string, len(unique))
+	for i, item := range unique {
+		texts[i] = item.Content
+	}
+	vectors, err := embedding_model.Embedding.EmbedStrings(ctx, texts)
+	if err != nil {
+		return nil, fmt.Errorf("embed memories failed: %w", err)
+	}
+	var kept []Item
+	var keptVectors [][]float64
+	for i, item := range unique {
+		if slices.ContainsFunc(keptVectors, func(v []float64) bool { return cosine(v, vectors[i]) >= s.dedupScore }) {
+			continue
+		}
+		kept = append(kept, item)
+		keptVectors = append(keptVectors, vectors[i])
+	}
+	return kept, nil
+}
+
+func cosine(a, b []float64) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += a[i] * b[i]
+		na += a[i] * a[i]
+		nb += b[i] * b[i]
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
+
+// Search 检索与 query 相关的用户记忆，按相似度从高到低排列，低于 MEMORY_MIN_SCORE 的不返回
+func (s *Store) Search(ctx context.Context, userID, query string) ([]Memory, error) {
+	docs, err := s.backend.search(ctx, userID, query, s.topK)
+	if err != nil {
+		return nil, err
+	}
+	memories := make([]Memory, 0, len(docs))
+	for _, doc := range docs {
+		if doc.Score() < s.minScore {
+			continue
+		}
+		m := fromDocument(doc)
+		m.Score = doc.Score()
+		memories = append(memories, m)
+	}
+	return memories, nil
+}
+
+// List 列出用户的全部记忆，按写入时间排列
+func (s *Store) List(ctx context.Context, userID string) ([]Memory, error) {
+	docs, err := s.backend.list(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	memories := make([]Memory, 0, len(docs))
+	for _, doc := range docs {
+		memories = append(memories, fromDocument(doc))
+	}
+	sort.SliceStable(memories, func(i, j int) bool { return memories[i].CreatedAt.Before(memories[j].CreatedAt) })
+	return memories, nil
+}
+
+// Delete 删除用户的一条记忆
+func (s *Store) Delete(ctx context.Context, userID, id string) error {
+	ok, err := s.backend.delete(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	return nil
+}
+
+// Prompt 把记忆整理为加入系统提示词的段落，没有记忆时返回空串
+func Prompt(memories []Memory) string {
+	if len(memories) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("关于该用户的已知信息（来自以往对话，仅在相关时参考）：\n")
+	for _, m := range memories {
+		fmt.Fprintf(&b, "- %s\n", m.Content)
+	}
+	return b.String()
+}
+
+func fromDocument(doc *schema.Document) Memory {
+	m := Memory{ID: doc.ID, Content: doc.Content}
+	m.UserID, _ = doc.MetaData[MetaKeyUserID].(string)
+	m.Category, _ = doc.MetaData[MetaKeyCategory].(string)
+	if ts, ok := doc.MetaData[MetaKeyCreatedAt].(string); ok {
+		m.CreatedAt, _ = time.Parse(time.RFC3339, ts)
+	}
+	return m
+}
@@ -0,0 +1,47 @@
+package pack
+
+import "github.com/cloudwego/eino/schema"
+
+// 文档块被过滤的原因
+const (
+	ReasonLowScore = "low_score"       // 低于逐块最低相似度
+	ReasonRelative = "relative_cutoff" // 与最高分的差距超过相对阈值
+)
+
+// FilterOptions 逐块相关性过滤参数
+type FilterOptions struct {
+	MinScore float64 // 逐块最低相似度
+	// RelativeCutoff 只保留分数不低于 最高分-RelativeCutoff 的块，0 表示不按相对分数过滤
+	RelativeCutoff float64
+	// Score 读取文档相似度，为空时使用 doc.Score()
+	Score func(doc *schema.Document) float64
+}
+
+// Filter 按逐块最低相似度与相对最高分的差距过滤检索结果，保持原有顺序，返回保留的块与被过滤的块
+func Filter(docs []*schema.Document, opts FilterOptions) ([]*schema.Document, []Dropped) {
+	score := opts.Score
+	if score == nil {
+		score = func(doc *schema.Document) float64 { return doc.Score() }
+	}
+	best := 0.0
+	for i, doc := range docs {
+		if s := score(doc); i == 0 || s > best {
+			best = s
+		}
+	}
+
+	var kept []*schema.Document
+	var dropped []Dropped
+	for _, doc := range docs {
+		s := score(doc)
+		switch {
+		case s < opts.MinScore:
+			dropped = append(dropped, Dropped{ID: doc.ID, Score: s, Reason: ReasonLowScore})
+		case opts.RelativeCutoff > 0 && s < best-opts.RelativeCutoff:
+			dropped = append(dropped, Dropped{ID: doc.ID, Score: s, Reason: ReasonRelative})
+		default:
+			kept = append(kept, doc)
+		}
+	}
+	return kept, dropped
+}
@@ -0,0 +1,239 @@
+package pack
+
+import (
+	"go-agent/model/token"
+	"go-agent/rag/tools/db"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+// 文档块被丢弃的原因
+const (
+	ReasonDuplicate = "duplicate" // 内容与分数更高的块相同或被其包含
+	ReasonBudget    = "budget"    // 超出上下文预算
+)
+
+// minOverlap 相邻块首尾重叠少于该字节数时不视为切分重叠，直接换行拼接
+const minOverlap = 16
+
+// passageOverhead 每段文档在提示词中的标题（序号、相似度）估算 token 数
+const passageOverhead = 12
+
+// Options 打包参数
+type Options struct {
+	Budget  int           // 文档部分可用的 token 数，0 表示不限制
+	Counter token.Counter // 按应答提供方估算 token，为空时使用 token.Default
+	// Score 读取文档相似度，为空时使用 doc.Score()
+	Score func(doc *schema.Document) float64
+}
+
+// Passage 打包后的一段上下文，由同一文档中相邻的一个或多个块合并而成
+type Passage struct {
+	ChunkIDs  []string `json:"chunk_ids"` // 按文档中的顺序
+	Source    string   `json:"source,omitempty"`
+	Score     float64  `json:"score"` // 合并块中的最高相似度
+	Content   string   `json:"-"`
+	Tokens    int      `json:"tokens"`
+	Truncated bool     `json:"truncated,omitempty"` // 单个块超出预算，只保留了开头部分
+}
+
+// Dropped 未放入上下文的块
+type Dropped struct {
+	ID     string  `json:"id"`
+	Score  float64 `json:"score"`
+	Reason string  `json:"reason"`
+}
+
+// Result 打包结果，Passages 按相似度从高到低排列
+type Result struct {
+	Passages []Passage `json:"passages"`
+	Dropped  []Dropped `json:"dropped,omitempty"`
+	Tokens   int       `json:"tokens"`
+}
+
+// ChunkIDs 返回放入上下文的全部块 ID
+func (r *Result) ChunkIDs() []string {
+	var ids []string
+	for _, p := range r.Passages {
+		ids = append(ids, p.ChunkIDs...)
+	}
+	return ids
+}
+
+type chunk struct {
+	doc    *schema.Document
+	score  float64
+	group  string // 所属文档，无法识别切分序号时为空
+	index  int
+	tokens int // 放入上下文时增加的 token 数
+
+	truncated bool
+}
+
+// Pack 把检索到的块打包为提示词上下文：按相似度从高到低依次选入，去掉重复的块，
+// 与已选块相邻的块只计算去掉切分重叠后新增的部分，超出预算时停止，后续的块记为丢弃
+// 最后把同一文档中相邻的块合并为一段
+func Pack(docs []*schema.Document, opts Options) *Result {
+	if opts.Counter == (token.Counter{}) {
+		opts.Counter = token.Default
+	}
+	score := opts.Score
+	if score == nil {
+		score = func(doc *schema.Document) float64 { return doc.Score() }
+	}
+	chunks := make([]*chunk, 0, len(docs))
+	for _, doc := range docs {
+		group, index := chunkPosition(doc)
+		chunks = append(chunks, &chunk{doc: doc, score: score(doc), group: group, index: index})
+	}
+	sort.SliceStable(chunks, func(i, j int) bool { return chunks[i].score > chunks[j].score })
+
+	res := &Result{}
+	var selected []*chunk
+	for i, c := range chunks {
+		if isDuplicate(c, selected) {
+			res.Dropped = append(res.Dropped, Dropped{ID: c.doc.ID, Score: c.score, Reason: ReasonDuplicate})
+			continue
+		}
+
+		c.tokens = marginalTokens(c, selected, opts.Counter)
+		if opts.Budget > 0 && res.Tokens+c.tokens > opts.Budget {
+			if len(selected) == 0 {
+				// 最相关的块本身超出预算时截断保留，避免没有任何上下文
+				if t := truncate(c, opts.Budget, opts.Counter); t.doc.Content != "" {
+					selected = append(selected, t)
+					res.Tokens = t.tokens
+					continue
+				}
+			}
+			for _, rest := range chunks[i:] {
+				reason := ReasonBudget
+				if isDuplicate(rest, selected) {
+					reason = ReasonDuplicate
+				}
+				res.Dropped = append(res.Dropped, Dropped{ID: rest.doc.ID, Score: rest.score, Reason: reason})
+			}
+			break
+		}
+		selected = append(selected, c)
+		res.Tokens += c.tokens
+	}
+
+	res.Passages, res.Tokens = merge(selected, opts.Counter), 0
+	for _, p := range res.Passages {
+		res.Tokens += p.Tokens
+	}
+	return res
+}
+
+// chunkPosition 从切分器生成的 ID（原始ID_chunk_序号）与来源元数据识别块所属文档及序号
+func chunkPosition(doc *schema.Document) (string, int) {
+	i := strings.LastIndex(doc.ID, "_chunk_")
+	if i < 0 {
+		return "", 0
+	}
+	index, err := strconv.Atoi(doc.ID[i+len("_chunk_"):])
+	if err != nil {
+		return "", 0
+	}
+	source, _ := doc.MetaData[db.MetaKeySource].(string)
+	return source + "\x00" + doc.ID[:i], index
+}
+
+// isDuplicate 块 ID 已选入，或内容与已选块相同、被已选块包含
+func isDuplicate(c *chunk, selected []*chunk) bool {
+	content := strings.TrimSpace(c.doc.Content)
+	for _, s := range selected {
+		if s.doc.ID == c.doc.ID || strings.Contains(s.doc.Content, content) {
+			return true
+		}
+	}
+	return false
+}
+
+// marginalTokens 块放入上下文增加的 token 数，与已选块相邻时扣除切分重叠部分，否则计入段落标题
+func marginalTokens(c *chunk, selected []*chunk, counter token.Counter) int {
+	if c.group != "" {
+		for _, s := range selected {
+			if s.group != c.group {
+				continue
+			}
+			switch s.index - c.index {
+			case -1:
+				return counter.Count(c.doc.Content[overlap(s.doc.Content, c.doc.Content):])
+			case 1:
+				return counter.Count(c.doc.Content[:len(c.doc.Content)-overlap(c.doc.Content, s.doc.Content)])
+			}
+		}
+	}
+	return passageOverhead + counter.Count(c.doc.Content)
+}
+
+// truncate 截取块的开头部分使其不超出预算，截断后的块不再与相邻块合并
+func truncate(c *chunk, budget int, counter token.Counter) *chunk {
+	runes := []rune(c.doc.Content)
+	lo, hi := 0, len(runes)
+	for lo < hi {
+		mid := (lo + hi + 1) / 2
+		if passageOverhead+counter.Count(string(runes[:mid])) <= budget {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+	doc := *c.doc
+	doc.Content = string(runes[:lo])
+	return &chunk{doc: &doc, score: c.score, tokens: passageOverhead + counter.Count(doc.Content), truncated: true}
+}
+
+// merge 把同一文档中序号连续的块合并为一段，段落按最高相似度排列
+func merge(selected []*chunk, counter token.Counter) []Passage {
+	ordered := append([]*chunk(nil), selected...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		if ordered[i].group != ordered[j].group {
+			return ordered[i].group < ordered[j].group
+		}
+		return ordered[i].index < ordered[j].index
+	})
+
+	var passages []Passage
+	var prev *chunk
+	for _, c := range ordered {
+		source, _ := c.doc.MetaData[db.MetaKeySource].(string)
+		if prev != nil && c.group != "" && c.group == prev.group && c.index == prev.index+1 {
+			p := &passages[len(passages)-1]
+			p.Content += joinOverlap(p.Content, c.doc.Content)
+			p.ChunkIDs = append(p.ChunkIDs, c.doc.ID)
+			p.Score = max(p.Score, c.score)
+		} else {
+			passages = append(passages, Passage{ChunkIDs: []string{c.doc.ID}, Source: source, Score: c.score, Content: c.doc.Content, Truncated: c.truncated})
+		}
+		prev = c
+	}
+	for i := range passages {
+		passages[i].Tokens = passageOverhead + counter.Count(passages[i].Content)
+	}
+	sort.SliceStable(passages, func(i, j int) bool { return passages[i].Score > passages[j].Score })
+	return passages
+}
+
+// joinOverlap 返回把 next 接在 text 之后需要追加的部分：去掉两者首尾重叠的内容，没有重叠时换行拼接
+func joinOverlap(text, next string) string {
+	if n := overlap(text, next); n > 0 {
+		return next[n:]
+	}
+	return "\n" + next
+}
+
+// overlap 返回 a 的结尾与 b 的开头重叠的字节数，少于 minOverlap 时视为没有重叠
+func overlap(a, b string) int {
+	for n := min(len(a), len(b)); n >= minOverlap; n-- {
+		if strings.HasSuffix(a, b[:n]) {
+			return n
+		}
+	}
+	return 0
+}
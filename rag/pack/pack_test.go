@@ -0,0 +1,284 @@
+package pack
+
+import (
+	"go-agent/model/token"
+	"go-agent/rag/tools/db"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+func doc(id, source, content string, score float64) *schema.Document {
+	d := &schema.Document{ID: id, Content: content, MetaData: map[string]any{}}
+	if source != "" {
+		d.MetaData[db.MetaKeySource] = source
+	}
+	return d.WithScore(score)
+}
+
+// text 生成 n 个字符的 ASCII 文本，按默认计数每 4 个字符 1 token
+func text(prefix string, n int) string {
+	return prefix + strings.Repeat("x", n-len(prefix))
+}
+
+func TestPack(t *testing.T) {
+	overlapText := text("shared overlap ", 20)
+	first := text("first chunk ", 40) + overlapText
+	second := overlapText + text("second chunk ", 40)
+	merged := first + text("second chunk ", 40)
+
+	cases := []struct {
+		name         string
+		docs         []*schema.Document
+		budget       int
+		wantPassages [][]string
+		wantDropped  []Dropped
+		check        func(t *testing.T, res *Result)
+	}{
+		{
+			name: "zero budget keeps everything",
+			docs: []*schema.Document{
+				doc("b", "", text("b", 400), 0.5),
+				doc("a", "", text("a", 400), 0.9),
+			},
+			wantPassages: [][]string{{"a"}, {"b"}},
+		},
+		{
+			name: "budget exhausted",
+			docs: []*schema.Document{
+				doc("a", "", text("a", 40), 0.9),
+				doc("b", "", text("b", 40), 0.8),
+				doc("c", "", text("c", 40), 0.7),
+				doc("d", "", text("a", 20), 0.6),
+			},
+			// 每块 10 token 加 12 token 标题，只放得下两块
+			budget:       50,
+			wantPassages: [][]string{{"a"}, {"b"}},
+			wantDropped: []Dropped{
+				{ID: "c", Score: 0.7, Reason: ReasonBudget},
+				{ID: "d", Score: 0.6, Reason: ReasonDuplicate},
+			},
+			check: func(t *testing.T, res *Result) {
+				if res.Tokens != 44 {
+					t.Fatalf("tokens = %d, want 44", res.Tokens)
+				}
+			},
+		},
+		{
+			name: "duplicates dropped",
+			docs: []*schema.Document{
+				doc("a", "", "  "+text("same content ", 40)+"\n", 0.9),
+				doc("copy", "", text("same content ", 40), 0.8),
+				doc("part", "", "same content", 0.7),
+				doc("a", "", text("other", 40), 0.6),
+			},
+			wantPassages: [][]string{{"a"}},
+			wantDropped: []Dropped{
+				{ID: "copy", Score: 0.8, Reason: ReasonDuplicate},
+				{ID: "part", Score: 0.7, Reason: ReasonDuplicate},
+				{ID: "a", Score: 0.6, Reason: ReasonDuplicate},
+			},
+		},
+		{
+			name: "overlapping adjacent chunks merged",
+			docs: []*schema.Document{
+				doc("doc_chunk_1", "guide.md", second, 0.7),
+				doc("doc_chunk_0", "guide.md", first, 0.9),
+			},
+			// 只有扣除重叠部分后才放得下两块
+			budget:       passageOverhead + token.Default.Count(merged),
+			wantPassages: [][]string{{"doc_chunk_0", "doc_chunk_1"}},
+			check: func(t *testing.T, res *Result) {
+				p := res.Passages[0]
+				if p.Content != merged || p.Score != 0.9 || p.Source != "guide.md" {
+					t.Fatalf("passage = %+v", p)
+				}
+				if p.Tokens != passageOverhead+token.Default.Count(merged) || res.Tokens != p.Tokens {
+					t.Fatalf("passage tokens = %d, result tokens = %d", p.Tokens, res.Tokens)
+				}
+			},
+		},
+		{
+			name: "adjacent chunks without overlap joined by newline",
+			docs: []*schema.Document{
+				doc("doc_chunk_0", "guide.md", "alpha", 0.9),
+				doc("doc_chunk_1", "guide.md", "beta", 0.8),
+			},
+			wantPassages: [][]string{{"doc_chunk_0", "doc_chunk_1"}},
+			check: func(t *testing.T, res *Result) {
+				if res.Passages[0].Content != "alpha\nbeta" {
+					t.Fatalf("content = %q", res.Passages[0].Content)
+				}
+			},
+		},
+		{
+			name: "gaps and other sources not merged",
+			docs: []*schema.Document{
+				doc("doc_chunk_0", "guide.md", "alpha", 0.9),
+				doc("doc_chunk_2", "guide.md", "gamma", 0.8),
+				doc("doc_chunk_1", "other.md", "beta", 0.7),
+			},
+			wantPassages: [][]string{{"doc_chunk_0"}, {"doc_chunk_2"}, {"doc_chunk_1"}},
+		},
+		{
+			name: "top chunk truncated",
+			docs: []*schema.Document{
+				doc("big", "", text("big", 400), 0.9),
+				doc("small", "", text("small", 8), 0.5),
+			},
+			budget:       30,
+			wantPassages: [][]string{{"big"}},
+			wantDropped:  []Dropped{{ID: "small", Score: 0.5, Reason: ReasonBudget}},
+			check: func(t *testing.T, res *Result) {
+				p := res.Passages[0]
+				if !p.Truncated || p.Tokens > 30 || res.Tokens != p.Tokens || !strings.HasPrefix(text("big", 400), p.Content) || len(p.Content) != 72 {
+					t.Fatalf("passage = %+v (%d chars)", p, len(p.Content))
+				}
+			},
+		},
+		{
+			name:         "budget below passage overhead",
+			docs:         []*schema.Document{doc("a", "", text("a", 40), 0.9)},
+			budget:       passageOverhead,
+			wantPassages: [][]string{},
+			wantDropped:  []Dropped{{ID: "a", Score: 0.9, Reason: ReasonBudget}},
+		},
+		{
+			name:         "empty input",
+			budget:       100,
+			wantPassages: [][]string{},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			res := Pack(tc.docs, Options{Budget: tc.budget})
+
+			passages := [][]string{}
+			for _, p := range res.Passages {
+				passages = append(passages, p.ChunkIDs)
+			}
+			if !reflect.DeepEqual(passages, tc.wantPassages) {
+				t.Fatalf("passages = %v, want %v", passages, tc.wantPassages)
+			}
+			if !reflect.DeepEqual(res.Dropped, tc.wantDropped) {
+				t.Fatalf("dropped = %+v, want %+v", res.Dropped, tc.wantDropped)
+			}
+			if tc.budget > 0 && res.Tokens > tc.budget {
+				t.Fatalf("tokens = %d exceed budget %d", res.Tokens, tc.budget)
+			}
+			if tc.check != nil {
+				tc.check(t, res)
+			}
+		})
+	}
+}
+
+func TestPackCustomScoreAndCounter(t *testing.T) {
+	docs := []*schema.Document{
+		{ID: "low", Content: "你好世界", MetaData: map[string]any{"rank": 0.1}},
+		{ID: "high", Content: "你好", MetaData: map[string]any{"rank": 0.9}},
+	}
+	counter := token.ForProvider("ark")
+	res := Pack(docs, Options{
+		Counter: counter,
+		Score:   func(doc *schema.Document) float64 { return doc.MetaData["rank"].(float64) },
+	})
+	if ids := res.ChunkIDs(); !reflect.DeepEqual(ids, []string{"high", "low"}) {
+		t.Fatalf("chunk ids = %v", ids)
+	}
+	if want := 2*passageOverhead + counter.Count("你好") + counter.Count("你好世界"); res.Tokens != want {
+		t.Fatalf("tokens = %d, want %d", res.Tokens, want)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	docs := []*schema.Document{
+		doc("a", "", "a", 0.6),
+		doc("b", "", "b", 0.9),
+		doc("c", "", "c", 0.75),
+		doc("d", "", "d", 0.3),
+	}
+	cases := []struct {
+		name        string
+		docs        []*schema.Document
+		opts        FilterOptions
+		wantKept    []string
+		wantDropped []Dropped
+	}{
+		{
+			name:     "no filtering",
+			docs:     docs,
+			wantKept: []string{"a", "b", "c", "d"},
+		},
+		{
+			name:        "min score only",
+			docs:        docs,
+			opts:        FilterOptions{MinScore: 0.5},
+			wantKept:    []string{"a", "b", "c"},
+			wantDropped: []Dropped{{ID: "d", Score: 0.3, Reason: ReasonLowScore}},
+		},
+		{
+			name:     "relative cutoff only",
+			docs:     docs,
+			opts:     FilterOptions{RelativeCutoff: 0.2},
+			wantKept: []string{"b", "c"},
+			wantDropped: []Dropped{
+				{ID: "a", Score: 0.6, Reason: ReasonRelative},
+				{ID: "d", Score: 0.3, Reason: ReasonRelative},
+			},
+		},
+		{
+			name:     "both",
+			docs:     docs,
+			opts:     FilterOptions{MinScore: 0.5, RelativeCutoff: 0.2},
+			wantKept: []string{"b", "c"},
+			wantDropped: []Dropped{
+				{ID: "a", Score: 0.6, Reason: ReasonRelative},
+				{ID: "d", Score: 0.3, Reason: ReasonLowScore},
+			},
+		},
+		{
+			name:     "relative cutoff with negative scores",
+			docs:     []*schema.Document{doc("x", "", "x", -0.2), doc("y", "", "y", -0.5)},
+			opts:     FilterOptions{MinScore: -1, RelativeCutoff: 0.1},
+			wantKept: []string{"x"},
+			wantDropped: []Dropped{
+				{ID: "y", Score: -0.5, Reason: ReasonRelative},
+			},
+		},
+		{
+			name: "custom score",
+			docs: docs,
+			opts: FilterOptions{MinScore: 0.5, Score: func(doc *schema.Document) float64 {
+				if doc.ID == "d" {
+					return 1
+				}
+				return doc.Score()
+			}},
+			wantKept: []string{"a", "b", "c", "d"},
+		},
+		{
+			name: "empty input",
+			opts: FilterOptions{MinScore: 0.5, RelativeCutoff: 0.2},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			kept, dropped := Filter(tc.docs, tc.opts)
+			var ids []string
+			for _, d := range kept {
+				ids = append(ids, d.ID)
+			}
+			if !reflect.DeepEqual(ids, tc.wantKept) {
+				t.Fatalf("kept = %v, want %v", ids, tc.wantKept)
+			}
+			if !reflect.DeepEqual(dropped, tc.wantDropped) {
+				t.Fatalf("dropped = %+v, want %+v", dropped, tc.wantDropped)
+			}
+		})
+	}
+}
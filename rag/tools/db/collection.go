@@ -0,0 +1,168 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/milvus-io/milvus-sdk-go/v2/client"
+	"github.com/milvus-io/milvus-sdk-go/v2/entity"
+)
+
+// embeddingDescPrefix 集合描述中记录嵌入模型的前缀
+const embeddingDescPrefix = "embedding:"
+
+// Chunk 集合中存储的单个文档块
+type Chunk struct {
+	ID       string         `json:"id"`
+	Content  string         `json:"content"`
+	MetaData map[string]any `json:"metadata,omitempty"`
+	Vector   []float32      `json:"vector,omitempty"`
+}
+
+// EmbeddingDescription 生成记录嵌入模型的集合描述
+func EmbeddingDescription(model string) string {
+	return embeddingDescPrefix + model
+}
+
+// CollectionEmbeddingModel 从集合描述中解析建库时使用的嵌入模型，未记录时返回空串
+func CollectionEmbeddingModel(coll *entity.Collection) string {
+	if coll == nil || coll.Schema == nil {
+		return ""
+	}
+	model, ok := strings.CutPrefix(coll.Schema.Description, embeddingDescPrefix)
+	if !ok {
+		return ""
+	}
+	return model
+}
+
+// VectorDim 返回集合中向量字段的维度，未找到时返回 0
+func VectorDim(coll *entity.Collection) int {
+	if coll == nil || coll.Schema == nil {
+		return 0
+	}
+	for _, field := range coll.Schema.Fields {
+		if field.DataType != entity.FieldTypeFloatVector {
+			continue
+		}
+		dim, err := strconv.Atoi(field.TypeParams["dim"])
+		if err == nil {
+			return dim
+		}
+	}
+	return 0
+}
+
+// LoadCollection 确保集合已加载，查询前必须调用
+func LoadCollection(ctx context.Context, collection string) error {
+	state, err := Milvus.GetLoadState(ctx, collection, nil)
+	if err != nil {
+		return fmt.Errorf("get load state failed: %w", err)
+	}
+	if state == entity.LoadStateLoaded {
+		return nil
+	}
+	return Milvus.LoadCollection(ctx, collection, false)
+}
+
+// ScanChunks 按主键顺序分批遍历集合中满足 expr 的全部文档块
+func ScanChunks(ctx context.Context, collection, expr string, withVector bool, fn func([]*Chunk) error) error {
+	opt := client.NewQueryIteratorOption(collection).
+		WithExpr(expr).
+		WithOutputFields(chunkFields(withVector)...).
+		WithBatchSize(500)
+
+	itr, err := Milvus.QueryIterator(ctx, opt)
+	if err != nil {
+		return fmt.Errorf("create query iterator failed: %w", err)
+	}
+
+	for {
+		rs, err := itr.Next(ctx)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("query iterator failed: %w", err)
+		}
+
+		chunks, err := toChunks(rs)
+		if err != nil {
+			return err
+		}
+		if err := fn(chunks); err != nil {
+			return err
+		}
+	}
+}
+
+// QueryChunks 分页查询集合中满足 expr 的文档块
+func QueryChunks(ctx context.Context, collection, expr string, offset, limit int64, withVector bool) ([]*Chunk, error) {
+	rs, err := Milvus.Query(ctx, collection, nil, expr, chunkFields(withVector),
+		client.WithOffset(offset), client.WithLimit(limit))
+	if err != nil {
+		return nil, fmt.Errorf("query chunks failed: %w", err)
+	}
+	return toChunks(rs)
+}
+
+func chunkFields(withVector bool) []string {
+	fields := []string{"id", "content", "metadata"}
+	if withVector {
+		fields = append(fields, "vector")
+	}
+	return fields
+}
+
+func toChunks(rs client.ResultSet) ([]*Chunk, error) {
+	chunks := make([]*Chunk, rs.Len())
+	for i := range chunks {
+		chunks[i] = &Chunk{MetaData: map[string]any{}}
+	}
+
+	for _, column := range rs {
+		switch column.Name() {
+		case "id":
+			for i := range chunks {
+				id, err := column.GetAsString(i)
+				if err != nil {
+					return nil, err
+				}
+				chunks[i].ID = id
+			}
+		case "content":
+			for i := range chunks {
+				content, err := column.GetAsString(i)
+				if err != nil {
+					return nil, err
+				}
+				chunks[i].Content = content
+			}
+		case "metadata":
+			for i := range chunks {
+				raw, err := column.Get(i)
+				if err != nil {
+					return nil, err
+				}
+				if b, ok := raw.([]byte); ok {
+					_ = json.Unmarshal(b, &chunks[i].MetaData)
+				}
+			}
+		case "vector":
+			vectors, ok := column.(*entity.ColumnFloatVector)
+			if !ok {
+				return nil, fmt.Errorf("unexpected vector column type: %T", column)
+			}
+			for i, v := range vectors.Data() {
+				chunks[i].Vector = v
+			}
+		}
+	}
+
+	return chunks, nil
+}
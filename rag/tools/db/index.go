@@ -0,0 +1,179 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go-agent/config"
+	"maps"
+	"strconv"
+	"strings"
+
+	"github.com/milvus-io/milvus-sdk-go/v2/entity"
+)
+
+// supportedIndexTypes 允许配置的浮点向量索引类型
+var supportedIndexTypes = map[string]bool{
+	"AUTOINDEX": true,
+	"FLAT":      true,
+	"IVF_FLAT":  true,
+	"IVF_SQ8":   true,
+	"IVF_PQ":    true,
+	"HNSW":      true,
+	"DISKANN":   true,
+	"SCANN":     true,
+}
+
+// defaultSearchParams 未配置检索参数时各索引类型的默认值
+var defaultSearchParams = map[string]map[string]any{
+	"AUTOINDEX": {"level": 1},
+	"IVF_FLAT":  {"nprobe": 16},
+	"IVF_SQ8":   {"nprobe": 16},
+	"IVF_PQ":    {"nprobe": 16},
+	"SCANN":     {"nprobe": 16},
+	"HNSW":      {"ef": 64},
+	"DISKANN":   {"search_list": 100},
+}
+
+// IndexSpec 向量索引配置
+type IndexSpec struct {
+	IndexType    string         `json:"index_type,omitempty"`
+	MetricType   string         `json:"metric_type,omitempty"`
+	BuildParams  map[string]any `json:"build_params,omitempty"` // 数值与字符串均可，如 {"M":16} 或 {"M":"16"}
+	SearchParams map[string]any `json:"search_params,omitempty"`
+}
+
+// IndexSpecFor 返回集合的索引配置：全局默认值叠加 MILVUS_COLLECTION_INDEXES 中该集合的覆盖项
+func IndexSpecFor(collection string) (IndexSpec, error) {
+	conf := config.Cfg.MilvusConf
+	spec := IndexSpec{
+		IndexType:  strings.ToUpper(conf.IndexType),
+		MetricType: strings.ToUpper(conf.MetricType),
+	}
+	if conf.IndexParams != "" {
+		if err := json.Unmarshal([]byte(conf.IndexParams), &spec.BuildParams); err != nil {
+			return spec, fmt.Errorf("invalid MILVUS_INDEX_PARAMS: %w", err)
+		}
+	}
+	if conf.SearchParams != "" {
+		if err := json.Unmarshal([]byte(conf.SearchParams), &spec.SearchParams); err != nil {
+			return spec, fmt.Errorf("invalid MILVUS_SEARCH_PARAMS: %w", err)
+		}
+	}
+
+	if conf.CollectionIndexes != "" {
+		var overrides map[string]IndexSpec
+		if err := json.Unmarshal([]byte(conf.CollectionIndexes), &overrides); err != nil {
+			return spec, fmt.Errorf("invalid MILVUS_COLLECTION_INDEXES: %w", err)
+		}
+		if o, ok := overrides[collection]; ok {
+			if o.IndexType != "" {
+				spec.IndexType = strings.ToUpper(o.IndexType)
+				// 索引类型变化时全局构建/检索参数不再适用
+				spec.BuildParams, spec.SearchParams = nil, nil
+			}
+			if o.MetricType != "" {
+				spec.MetricType = strings.ToUpper(o.MetricType)
+			}
+			if o.BuildParams != nil {
+				spec.BuildParams = o.BuildParams
+			}
+			if o.SearchParams != nil {
+				spec.SearchParams = o.SearchParams
+			}
+		}
+	}
+
+	return spec, spec.Validate()
+}
+
+// Validate 校验索引类型与度量类型
+func (s IndexSpec) Validate() error {
+	if !supportedIndexTypes[s.IndexType] {
+		return fmt.Errorf("unsupported index type: %s", s.IndexType)
+	}
+	switch entity.MetricType(s.MetricType) {
+	case entity.COSINE, entity.IP, entity.L2:
+		return nil
+	default:
+		return fmt.Errorf("unsupported metric type: %s (COSINE/IP/L2)", s.MetricType)
+	}
+}
+
+// Index 生成建索引所需的 entity.Index，构建参数统一转为字符串，与 SDK 内置索引的传参方式一致
+func (s IndexSpec) Index() (entity.Index, error) {
+	buildParams := make(map[string]string, len(s.BuildParams))
+	for k, v := range s.BuildParams {
+		switch v := v.(type) {
+		case string:
+			buildParams[k] = v
+		case float64:
+			buildParams[k] = strconv.FormatFloat(v, 'f', -1, 64)
+		default:
+			bs, err := json.Marshal(v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid build param %s: %w", k, err)
+			}
+			buildParams[k] = string(bs)
+		}
+	}
+	bs, err := json.Marshal(buildParams)
+	if err != nil {
+		return nil, err
+	}
+
+	return entity.NewGenericIndex("", entity.IndexType(s.IndexType), map[string]string{
+		"metric_type": s.MetricType,
+		"params":      string(bs),
+	}), nil
+}
+
+// SearchParam 合并默认值、配置与单次查询覆盖项，生成检索参数
+func (s IndexSpec) SearchParam(overrides map[string]any) entity.SearchParam {
+	params := make(map[string]any)
+	maps.Copy(params, defaultSearchParams[s.IndexType])
+	maps.Copy(params, s.SearchParams)
+	maps.Copy(params, overrides)
+	return &searchParam{params: params}
+}
+
+// searchParam 通用检索参数，直接透传给 Milvus
+type searchParam struct {
+	params map[string]any
+}
+
+func (sp *searchParam) Params() map[string]any {
+	return maps.Clone(sp.params)
+}
+
+func (sp *searchParam) AddRadius(radius float64) {
+	sp.params["radius"] = radius
+}
+
+func (sp *searchParam) AddRangeFilter(rangeFilter float64) {
+	sp.params["range_filter"] = rangeFilter
+}
+
+// CollectionMetric 读取集合向量索引实际使用的度量类型，集合未建索引时返回空
+func CollectionMetric(ctx context.Context, collection string) (entity.MetricType, error) {
+	indexes, err := Milvus.DescribeIndex(ctx, collection, "vector")
+	if err != nil {
+		return "", fmt.Errorf("describe index failed: %w", err)
+	}
+	if len(indexes) == 0 {
+		return "", nil
+	}
+	return entity.MetricType(indexes[0].Params()["metric_type"]), nil
+}
+
+// Similarity 把 Milvus 返回的分数按度量类型换算为越大越相似的相似度
+// COSINE/IP 本身即相似度；L2 返回的是平方欧氏距离，嵌入向量不一定归一化，按 1/(1+d) 单调换算到 (0,1]，
+// 距离越小越接近 1，与余弦相似度的刻度不同，L2 集合的阈值需按该刻度配置
+func Similarity(metric entity.MetricType, score float32) float64 {
+	switch metric {
+	case entity.L2:
+		return 1 / (1 + max(float64(score), 0))
+	default:
+		return float64(score)
+	}
+}
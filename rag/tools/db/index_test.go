@@ -0,0 +1,147 @@
+package db
+
+import (
+	"encoding/json"
+	"go-agent/config"
+	"reflect"
+	"testing"
+
+	"github.com/milvus-io/milvus-sdk-go/v2/entity"
+)
+
+func TestIndexSpecFor(t *testing.T) {
+	cases := []struct {
+		name    string
+		conf    config.MilvusConfig
+		want    IndexSpec
+		wantErr bool
+	}{
+		{
+			name: "global defaults",
+			conf: config.MilvusConfig{IndexType: "hnsw", MetricType: "cosine", IndexParams: `{"M":16,"efConstruction":"200"}`, SearchParams: `{"ef":64}`},
+			want: IndexSpec{IndexType: "HNSW", MetricType: "COSINE", BuildParams: map[string]any{"M": 16.0, "efConstruction": "200"}, SearchParams: map[string]any{"ef": 64.0}},
+		},
+		{
+			name: "override metric keeps global params",
+			conf: config.MilvusConfig{IndexType: "HNSW", MetricType: "COSINE", IndexParams: `{"M":16}`, CollectionIndexes: `{"docs":{"metric_type":"ip"}}`},
+			want: IndexSpec{IndexType: "HNSW", MetricType: "IP", BuildParams: map[string]any{"M": 16.0}},
+		},
+		{
+			name: "override index type drops global params",
+			conf: config.MilvusConfig{IndexType: "HNSW", MetricType: "COSINE", IndexParams: `{"M":16}`, SearchParams: `{"ef":64}`, CollectionIndexes: `{"docs":{"index_type":"ivf_pq","build_params":{"nlist":1024,"m":16,"nbits":8}}}`},
+			want: IndexSpec{IndexType: "IVF_PQ", MetricType: "COSINE", BuildParams: map[string]any{"nlist": 1024.0, "m": 16.0, "nbits": 8.0}},
+		},
+		{
+			name: "other collection not affected",
+			conf: config.MilvusConfig{IndexType: "AUTOINDEX", MetricType: "L2", CollectionIndexes: `{"other":{"index_type":"FLAT"}}`},
+			want: IndexSpec{IndexType: "AUTOINDEX", MetricType: "L2"},
+		},
+		{
+			name:    "invalid build params",
+			conf:    config.MilvusConfig{IndexType: "HNSW", MetricType: "COSINE", IndexParams: `{"M":`},
+			wantErr: true,
+		},
+		{
+			name:    "unsupported index type",
+			conf:    config.MilvusConfig{IndexType: "GPU_CAGRA", MetricType: "COSINE"},
+			wantErr: true,
+		},
+		{
+			name:    "unsupported metric type",
+			conf:    config.MilvusConfig{IndexType: "HNSW", MetricType: "JACCARD"},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			config.Cfg = &config.Config{MilvusConf: tc.conf}
+			got, err := IndexSpecFor("docs")
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("want error, got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("got %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestIndexSpecIndexParams(t *testing.T) {
+	spec := IndexSpec{IndexType: "HNSW", MetricType: "COSINE", BuildParams: map[string]any{"M": 16.0, "efConstruction": "200", "mmap.enabled": true}}
+	idx, err := spec.Index()
+	if err != nil {
+		t.Fatal(err)
+	}
+	params := idx.Params()
+	if params["metric_type"] != "COSINE" || string(idx.IndexType()) != "HNSW" {
+		t.Fatalf("params = %v, type = %s", params, idx.IndexType())
+	}
+
+	var build map[string]string
+	if err := json.Unmarshal([]byte(params["params"]), &build); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"M": "16", "efConstruction": "200", "mmap.enabled": "true"}
+	if !reflect.DeepEqual(build, want) {
+		t.Fatalf("build params = %v, want %v", build, want)
+	}
+}
+
+func TestIndexSpecSearchParam(t *testing.T) {
+	cases := []struct {
+		name      string
+		spec      IndexSpec
+		overrides map[string]any
+		want      map[string]any
+	}{
+		{"default", IndexSpec{IndexType: "HNSW"}, nil, map[string]any{"ef": 64}},
+		{"configured", IndexSpec{IndexType: "HNSW", SearchParams: map[string]any{"ef": 128.0}}, nil, map[string]any{"ef": 128.0}},
+		{"override", IndexSpec{IndexType: "IVF_FLAT", SearchParams: map[string]any{"nprobe": 32.0}}, map[string]any{"nprobe": 8}, map[string]any{"nprobe": 8}},
+		{"extra keys merged", IndexSpec{IndexType: "DISKANN"}, map[string]any{"radius": 0.5}, map[string]any{"search_list": 100, "radius": 0.5}},
+		{"no default", IndexSpec{IndexType: "FLAT"}, nil, map[string]any{}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			sp := tc.spec.SearchParam(tc.overrides)
+			if got := sp.Params(); !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+
+	// 修改返回的参数不影响默认值
+	IndexSpec{IndexType: "HNSW"}.SearchParam(nil).Params()["ef"] = 1
+	if defaultSearchParams["HNSW"]["ef"] != 64 {
+		t.Fatal("default search params mutated")
+	}
+}
+
+func TestSimilarity(t *testing.T) {
+	cases := []struct {
+		metric entity.MetricType
+		score  float32
+		want   float64
+	}{
+		{entity.COSINE, 0.8, float64(float32(0.8))},
+		{entity.IP, 42, 42},
+		{entity.L2, 0, 1},
+		{entity.L2, 1, 0.5},
+		{entity.L2, 3, 0.25},
+		// 未归一化的向量距离可以很大，换算结果仍在 (0,1] 内且单调递减
+		{entity.L2, 399, 0.0025},
+		{entity.L2, -0.0001, 1},
+	}
+	for _, tc := range cases {
+		if got := Similarity(tc.metric, tc.score); got != tc.want {
+			t.Fatalf("Similarity(%s, %v) = %v, want %v", tc.metric, tc.score, got, tc.want)
+		}
+	}
+}
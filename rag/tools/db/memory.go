@@ -0,0 +1,144 @@
+package db
+
+import (
+	"maps"
+	"math"
+	"slices"
+	"sort"
+	"sync"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+// Memory 进程内向量存储，VECTOR_DB_TYPE=memory 时使用，适合离线测试与本地体验
+var Memory = NewMemoryStore()
+
+// MemoryStore 基于暴力余弦检索的进程内向量存储，重启后数据丢失
+type MemoryStore struct {
+	mu   sync.RWMutex
+	ids  []string
+	rows map[string]*memoryRow
+}
+
+type memoryRow struct {
+	doc    *schema.Document
+	vector []float64
+}
+
+// NewMemoryStore 创建空的进程内向量存储
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{rows: make(map[string]*memoryRow)}
+}
+
+// Upsert 写入文档与向量，ID 已存在时覆盖
+func (s *MemoryStore) Upsert(docs []*schema.Document, vectors [][]float64) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ids := make([]string, 0, len(docs))
+	for i, doc := range docs {
+		if _, ok := s.rows[doc.ID]; !ok {
+			s.ids = append(s.ids, doc.ID)
+		}
+		s.rows[doc.ID] = &memoryRow{doc: doc, vector: vectors[i]}
+		ids = append(ids, doc.ID)
+	}
+	return ids
+}
+
+// Search 返回与 vector 余弦相似度最高的 topK 个文档（副本，分数写入 Score）
+func (s *MemoryStore) Search(vector []float64, topK int) []*schema.Document {
+	return s.SearchFunc(vector, topK, nil)
+}
+
+// SearchFunc 同 Search，只在 keep 返回 true 的文档中检索，keep 为 nil 时检索全部
+func (s *MemoryStore) SearchFunc(vector []float64, topK int, keep func(*schema.Document) bool) []*schema.Document {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	type hit struct {
+		row   *memoryRow
+		score float64
+	}
+	hits := make([]hit, 0, len(s.ids))
+	for _, id := range s.ids {
+		row := s.rows[id]
+		if keep != nil && !keep(row.doc) {
+			continue
+		}
+		hits = append(hits, hit{row: row, score: cosine(vector, row.vector)})
+	}
+	sort.SliceStable(hits, func(i, j int) bool {
+		return hits[i].score > hits[j].score
+	})
+	if len(hits) > topK {
+		hits = hits[:topK]
+	}
+
+	docs := make([]*schema.Document, 0, len(hits))
+	for _, h := range hits {
+		metadata := make(map[string]any, len(h.row.doc.MetaData)+1)
+		for k, v := range h.row.doc.MetaData {
+			metadata[k] = v
+		}
+		doc := &schema.Document{ID: h.row.doc.ID, Content: h.row.doc.Content, MetaData: metadata}
+		docs = append(docs, doc.WithScore(h.score))
+	}
+	return docs
+}
+
+// List 按写入顺序返回 keep 返回 true 的文档（副本），keep 为 nil 时返回全部
+func (s *MemoryStore) List(keep func(*schema.Document) bool) []*schema.Document {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var docs []*schema.Document
+	for _, id := range s.ids {
+		doc := s.rows[id].doc
+		if keep != nil && !keep(doc) {
+			continue
+		}
+		docs = append(docs, &schema.Document{ID: doc.ID, Content: doc.Content, MetaData: maps.Clone(doc.MetaData)})
+	}
+	return docs
+}
+
+// Delete 删除指定 ID 的文档，返回实际删除的数量
+func (s *MemoryStore) Delete(ids ...string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := 0
+	for _, id := range ids {
+		if _, ok := s.rows[id]; !ok {
+			continue
+		}
+		delete(s.rows, id)
+		s.ids = slices.DeleteFunc(s.ids, func(v string) bool { return v == id })
+		n++
+	}
+	return n
+}
+
+// Len 返回文档数量
+func (s *MemoryStore) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.ids)
+}
+
+func cosine(a, b []float64) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += a[i] * b[i]
+		na += a[i] * a[i]
+		nb += b[i] * b[i]
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
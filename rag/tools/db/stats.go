@@ -0,0 +1,109 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/milvus-io/milvus-sdk-go/v2/entity"
+)
+
+// MetaKeySource 文件加载器写入的文档来源元数据键
+const MetaKeySource = "_source"
+
+// SourceStat 单个来源文档的统计
+type SourceStat struct {
+	Source string `json:"source"`
+	Chunks int    `json:"chunks"`
+}
+
+// CollectionStats 集合统计信息
+type CollectionStats struct {
+	Name           string `json:"name"`
+	RowCount       int64  `json:"row_count"`
+	Dimension      int    `json:"dimension"`
+	MetricType     string `json:"metric_type,omitempty"`
+	IndexType      string `json:"index_type,omitempty"`
+	EmbeddingModel string `json:"embedding_model,omitempty"` // 建库时的嵌入模型，旧集合可能未记录
+	Loaded         bool   `json:"loaded"`
+	// SourceCount、Sources 与 EstimatedSizeBytes 只在遍历集合（withSources）时填写，未统计时不出现，空集合统计结果为 0
+	SourceCount *int         `json:"source_count,omitempty"`
+	Sources     []SourceStat `json:"sources,omitempty"`
+	// EstimatedSizeBytes 按原始数据估算的存储大小（内容 + 元数据 + 向量），不含索引与压缩
+	EstimatedSizeBytes *int64 `json:"estimated_size_bytes,omitempty"`
+}
+
+// GetCollectionStats 汇总集合的结构与索引统计，行数取自 Milvus 统计信息
+// withSources 为 true 时遍历集合全部文档块，统计来源文档与估算存储大小，大集合上开销较高
+func GetCollectionStats(ctx context.Context, collection string, withSources bool) (*CollectionStats, error) {
+	coll, err := Milvus.DescribeCollection(ctx, collection)
+	if err != nil {
+		return nil, fmt.Errorf("describe collection failed: %w", err)
+	}
+
+	stats := &CollectionStats{
+		Name:           collection,
+		Dimension:      VectorDim(coll),
+		EmbeddingModel: CollectionEmbeddingModel(coll),
+	}
+
+	statistics, err := Milvus.GetCollectionStatistics(ctx, collection)
+	if err != nil {
+		return nil, fmt.Errorf("get collection statistics failed: %w", err)
+	}
+	stats.RowCount, _ = strconv.ParseInt(statistics["row_count"], 10, 64)
+
+	indexes, err := Milvus.DescribeIndex(ctx, collection, "vector")
+	if err == nil && len(indexes) > 0 {
+		stats.IndexType = string(indexes[0].IndexType())
+		stats.MetricType = indexes[0].Params()["metric_type"]
+	}
+
+	state, err := Milvus.GetLoadState(ctx, collection, nil)
+	if err != nil {
+		return nil, fmt.Errorf("get load state failed: %w", err)
+	}
+	stats.Loaded = state == entity.LoadStateLoaded
+	if !withSources {
+		return stats, nil
+	}
+
+	if err := LoadCollection(ctx, collection); err != nil {
+		return nil, fmt.Errorf("load collection failed: %w", err)
+	}
+	stats.Loaded = true
+
+	sources := make(map[string]int)
+	var size int64
+	err = ScanChunks(ctx, collection, "", false, func(chunks []*Chunk) error {
+		for _, chunk := range chunks {
+			source, _ := chunk.MetaData[MetaKeySource].(string)
+			sources[source]++
+
+			metadata, _ := json.Marshal(chunk.MetaData)
+			size += int64(len(chunk.ID) + len(chunk.Content) + len(metadata) + stats.Dimension*4)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for source, count := range sources {
+		stats.Sources = append(stats.Sources, SourceStat{Source: source, Chunks: count})
+	}
+	sort.Slice(stats.Sources, func(i, j int) bool {
+		return stats.Sources[i].Source < stats.Sources[j].Source
+	})
+	count := len(stats.Sources)
+	stats.SourceCount, stats.EstimatedSizeBytes = &count, &size
+
+	return stats, nil
+}
+
+// SourceExpr 生成按来源文档过滤的查询表达式
+func SourceExpr(source string) string {
+	return fmt.Sprintf(`metadata["%s"] == %s`, MetaKeySource, strconv.Quote(source))
+}
@@ -0,0 +1,33 @@
+package db
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestCollectionStatsJSON(t *testing.T) {
+	zero, size := 0, int64(0)
+	cases := []struct {
+		name    string
+		stats   CollectionStats
+		present bool
+	}{
+		{"not scanned", CollectionStats{Name: "docs"}, false},
+		{"empty collection scanned", CollectionStats{Name: "docs", SourceCount: &zero, EstimatedSizeBytes: &size}, true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			b, err := json.Marshal(tc.stats)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, field := range []string{`"source_count":0`, `"estimated_size_bytes":0`} {
+				if strings.Contains(string(b), field) != tc.present {
+					t.Fatalf("%s in %s: want present %v", field, b, tc.present)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"go-agent/model/embedding_model"
+	"go-agent/rag/tools/db"
+
+	"github.com/cloudwego/eino/components/indexer"
+	"github.com/cloudwego/eino/schema"
+)
+
+// memoryIndexer 写入进程内向量存储的索引器
+type memoryIndexer struct {
+	store *db.MemoryStore
+}
+
+func (m *memoryIndexer) Store(ctx context.Context, docs []*schema.Document, opts ...indexer.Option) ([]string, error) {
+	co := indexer.GetCommonOptions(&indexer.Options{Embedding: embedding_model.Embedding}, opts...)
+	if co.Embedding == nil {
+		return nil, fmt.Errorf("embedding not initialized")
+	}
+
+	texts := make([]string, 0, len(docs))
+	for _, doc := range docs {
+		texts = append(texts, doc.Content)
+	}
+	vectors, err := co.Embedding.EmbedStrings(ctx, texts)
+	if err != nil {
+		return nil, err
+	}
+	if len(vectors) != len(docs) {
+		return nil, fmt.Errorf("vector size mismatch, docs=%d vectors=%d", len(docs), len(vectors))
+	}
+
+	return m.store.Upsert(docs, vectors), nil
+}
+
+// NewMemoryIndexer 创建写入指定进程内向量存储的索引器
+func NewMemoryIndexer(store *db.MemoryStore) indexer.Indexer {
+	return &memoryIndexer{store: store}
+}
+
+func initMemory() {
+	registerIndexer("memory", func(ctx context.Context) (indexer.Indexer, error) {
+		return NewMemoryIndexer(db.Memory), nil
+	})
+}
@@ -15,6 +15,7 @@ import (
 	"github.com/cloudwego/eino-ext/components/indexer/milvus"
 	"github.com/cloudwego/eino/components/indexer"
 	"github.com/cloudwego/eino/schema"
+	"github.com/milvus-io/milvus-sdk-go/v2/client"
 	"github.com/milvus-io/milvus-sdk-go/v2/entity"
 )
 
@@ -27,7 +28,7 @@ type floatSchema struct {
 
 func initMilvus() {
 	registerIndexer("milvus", func(ctx context.Context) (indexer.Indexer, error) {
-		dim, err := getEmbeddingDim(ctx)
+		dim, err := EmbeddingDim(ctx)
 		if err != nil {
 			return nil, err
 		}
@@ -38,7 +39,7 @@ func initMilvus() {
 			log.Printf("检查集合维度失败: %v", err)
 		}
 
-		indexer, err := milvus.NewIndexer(ctx, buildIndexerConfig(dim))
+		indexer, err := newMilvusIndexer(ctx, config.Cfg.MilvusConf.CollectionName, dim)
 		if err != nil {
 			// 自动处理 schema 不匹配：删除旧集合并重建
 			if strings.Contains(err.Error(), "collection schema not match") {
@@ -53,7 +54,7 @@ func initMilvus() {
 					log.Printf("旧集合仍存在，改用新集合: %s", newName)
 					config.Cfg.MilvusConf.CollectionName = newName
 				}
-				indexer, err = milvus.NewIndexer(ctx, buildIndexerConfig(dim))
+				indexer, err = newMilvusIndexer(ctx, config.Cfg.MilvusConf.CollectionName, dim)
 				if err != nil {
 					return nil, err
 				}
@@ -66,12 +67,68 @@ func initMilvus() {
 	})
 }
 
-func buildIndexerConfig(dim int) *milvus.IndexerConfig {
+// NewCollectionIndexer 为指定集合创建索引器，集合不存在时自动创建
+func NewCollectionIndexer(ctx context.Context, collection string, dim int) (indexer.Indexer, error) {
+	return newMilvusIndexer(ctx, collection, dim)
+}
+
+func newMilvusIndexer(ctx context.Context, collection string, dim int) (*milvus.Indexer, error) {
+	spec, err := db.IndexSpecFor(collection)
+	if err != nil {
+		return nil, err
+	}
+
+	conf := buildIndexerConfig(collection, dim, spec)
+	if err := createCollectionIfMissing(ctx, conf, spec); err != nil {
+		return nil, err
+	}
+
+	return milvus.NewIndexer(ctx, conf)
+}
+
+// createCollectionIfMissing 按索引配置建集合和索引
+// eino 的 milvus 索引器只会创建 AUTOINDEX，因此需要在它之前自行建好索引
+func createCollectionIfMissing(ctx context.Context, conf *milvus.IndexerConfig, spec db.IndexSpec) error {
+	exists, err := db.Milvus.HasCollection(ctx, conf.Collection)
+	if err != nil {
+		return fmt.Errorf("check collection exists failed: %w", err)
+	}
+	if exists {
+		if metric, err := db.CollectionMetric(ctx, conf.Collection); err == nil && metric != "" && string(metric) != spec.MetricType {
+			log.Printf("警告: 集合 %s 现有索引度量为 %s，与配置 %s 不一致，检索将沿用现有索引", conf.Collection, metric, spec.MetricType)
+		}
+		return nil
+	}
+
+	schema := entity.NewSchema().
+		WithName(conf.Collection).
+		WithDescription(conf.Description)
+	for _, field := range conf.Fields {
+		schema.WithField(field)
+	}
+	if err := db.Milvus.CreateCollection(ctx, schema, 1, client.WithConsistencyLevel(entity.ClBounded)); err != nil {
+		return fmt.Errorf("create collection failed: %w", err)
+	}
+
+	idx, err := spec.Index()
+	if err != nil {
+		return err
+	}
+	if err := db.Milvus.CreateIndex(ctx, conf.Collection, "vector", idx, false); err != nil {
+		return fmt.Errorf("create %s index failed: %w", spec.IndexType, err)
+	}
+	log.Printf("已创建集合 %s，索引类型=%s 度量=%s", conf.Collection, spec.IndexType, spec.MetricType)
+
+	return nil
+}
+
+func buildIndexerConfig(collection string, dim int, spec db.IndexSpec) *milvus.IndexerConfig {
 	return &milvus.IndexerConfig{
-		Client:     db.Milvus,
-		Embedding:  embedding_model.Embedding,
-		Collection: config.Cfg.MilvusConf.CollectionName,
-		MetricType: milvus.COSINE,
+		Client:      db.Milvus,
+		Embedding:   embedding_model.Embedding,
+		Collection:  collection,
+		Description: db.EmbeddingDescription(embedding_model.ModelName()),
+		MetricType:  milvus.MetricType(spec.MetricType),
 		Fields: []*entity.Field{
 			entity.NewField().
 				WithName("id").
@@ -140,7 +197,8 @@ func waitCollectionDropped(ctx context.Context, name string, timeout time.Durati
 	return fmt.Errorf("collection still exists after drop: %s", name)
 }
 
-func getEmbeddingDim(ctx context.Context) (int, error) {
+// EmbeddingDim 通过一次试探调用获取当前嵌入模型的向量维度
+func EmbeddingDim(ctx context.Context) (int, error) {
 	if embedding_model.Embedding == nil {
 		return 0, fmt.Errorf("embedding not initialized")
 	}
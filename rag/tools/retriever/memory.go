@@ -0,0 +1,49 @@
+package retriever
+
+import (
+	"context"
+	"fmt"
+	"go-agent/config"
+	"go-agent/model/embedding_model"
+	"go-agent/rag/tools/db"
+	"strconv"
+
+	"github.com/cloudwego/eino/components/retriever"
+	"github.com/cloudwego/eino/schema"
+)
+
+// memoryRetriever 从进程内向量存储召回
+type memoryRetriever struct {
+	store *db.MemoryStore
+	topK  int
+}
+
+func (m *memoryRetriever) Retrieve(ctx context.Context, query string, opts ...retriever.Option) ([]*schema.Document, error) {
+	co := retriever.GetCommonOptions(&retriever.Options{
+		TopK:      &m.topK,
+		Embedding: embedding_model.Embedding,
+	}, opts...)
+	if co.Embedding == nil {
+		return nil, fmt.Errorf("embedding not initialized")
+	}
+
+	vectors, err := co.Embedding.EmbedStrings(ctx, []string{query})
+	if err != nil {
+		return nil, err
+	}
+	if len(vectors) != 1 {
+		return nil, fmt.Errorf("invalid return length of vector, got=%d, expected=1", len(vectors))
+	}
+
+	return m.store.Search(vectors[0], *co.TopK), nil
+}
+
+func initMemory() {
+	registerRetriever("memory", func(ctx context.Context) (retriever.Retriever, error) {
+		topK, err := strconv.Atoi(config.Cfg.MilvusConf.TopK)
+		if err != nil || topK <= 0 {
+			topK = 10
+		}
+		return &memoryRetriever{store: db.Memory, topK: topK}, nil
+	})
+}
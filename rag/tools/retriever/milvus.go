@@ -15,86 +15,158 @@ import (
 	"github.com/milvus-io/milvus-sdk-go/v2/entity"
 )
 
+// ImplOptions Milvus 召回器的单次查询选项
+type ImplOptions struct {
+	// SearchParams 覆盖本次检索参数，如 {"ef":128}、{"nprobe":32}
+	SearchParams map[string]any
+}
+
+// WithSearchParams 覆盖单次查询的检索参数
+func WithSearchParams(params map[string]any) retriever.Option {
+	return retriever.WrapImplSpecificOptFn(func(o *ImplOptions) {
+		o.SearchParams = params
+	})
+}
+
+// milvusRetriever 在 eino milvus 召回器外包一层，支持按查询覆盖检索参数
+type milvusRetriever struct {
+	collection string
+	topK       int
+	spec       db.IndexSpec
+	metric     entity.MetricType
+	base       retriever.Retriever
+}
+
+func (r *milvusRetriever) Retrieve(ctx context.Context, query string, opts ...retriever.Option) ([]*schema.Document, error) {
+	io := retriever.GetImplSpecificOptions(&ImplOptions{}, opts...)
+	if len(io.SearchParams) == 0 {
+		return r.base.Retrieve(ctx, query, opts...)
+	}
+
+	// eino 召回器的检索参数在创建时固定，有覆盖项时按需新建
+	base, err := newBaseRetriever(ctx, r.collection, r.topK, r.metric, r.spec.SearchParam(io.SearchParams))
+	if err != nil {
+		return nil, err
+	}
+	return base.Retrieve(ctx, query, opts...)
+}
+
+func (r *milvusRetriever) GetType() string {
+	return "Milvus"
+}
+
+func (r *milvusRetriever) IsCallbacksEnabled() bool {
+	return true
+}
+
 func initMilvus() {
 	registerRetriever("milvus", func(ctx context.Context) (retriever.Retriever, error) {
 		topK, err := strconv.Atoi(config.Cfg.MilvusConf.TopK)
 		if err != nil || topK <= 0 {
 			topK = 10
 		}
-		sp, _ := entity.NewIndexAUTOINDEXSearchParam(1)
-		ret, err := milvus.NewRetriever(ctx, &milvus.RetrieverConfig{
-			Client:       db.Milvus,
-			Embedding:    embedding_model.Embedding,
-			TopK:         topK,
-			Collection:   config.Cfg.MilvusConf.CollectionName,
-			VectorField:  "vector",
-			OutputFields: []string{"id", "content", "metadata"},
-			MetricType:   entity.COSINE,
-			Sp:           sp,
-			VectorConverter: func(ctx context.Context, vectors [][]float64) ([]entity.Vector, error) {
-				vecs := make([]entity.Vector, 0, len(vectors))
-				for _, v := range vectors {
-					v32 := make([]float32, len(v))
-					for i, val := range v {
-						v32[i] = float32(val)
-					}
-					vecs = append(vecs, entity.FloatVector(v32))
-				}
-				return vecs, nil
-			},
-			DocumentConverter: func(ctx context.Context, result client.SearchResult) ([]*schema.Document, error) {
-				docs := make([]*schema.Document, result.IDs.Len())
-				for i := range docs {
-					docs[i] = &schema.Document{MetaData: map[string]any{}}
+		return NewCollectionRetriever(ctx, config.Cfg.MilvusConf.CollectionName, topK)
+	})
+}
+
+// NewCollectionRetriever 为指定集合创建召回器，度量类型以集合现有索引为准
+func NewCollectionRetriever(ctx context.Context, collection string, topK int) (retriever.Retriever, error) {
+	spec, err := db.IndexSpecFor(collection)
+	if err != nil {
+		return nil, err
+	}
+
+	metric, err := db.CollectionMetric(ctx, collection)
+	if err != nil {
+		return nil, err
+	}
+	if metric == "" {
+		metric = entity.MetricType(spec.MetricType)
+	}
+
+	base, err := newBaseRetriever(ctx, collection, topK, metric, spec.SearchParam(nil))
+	if err != nil {
+		return nil, err
+	}
+
+	return &milvusRetriever{
+		collection: collection,
+		topK:       topK,
+		spec:       spec,
+		metric:     metric,
+		base:       base,
+	}, nil
+}
+
+func newBaseRetriever(ctx context.Context, collection string, topK int, metric entity.MetricType, sp entity.SearchParam) (retriever.Retriever, error) {
+	return milvus.NewRetriever(ctx, &milvus.RetrieverConfig{
+		Client:       db.Milvus,
+		Embedding:    embedding_model.Embedding,
+		TopK:         topK,
+		Collection:   collection,
+		VectorField:  "vector",
+		OutputFields: []string{"id", "content", "metadata"},
+		MetricType:   metric,
+		Sp:           sp,
+		VectorConverter: func(ctx context.Context, vectors [][]float64) ([]entity.Vector, error) {
+			vecs := make([]entity.Vector, 0, len(vectors))
+			for _, v := range vectors {
+				v32 := make([]float32, len(v))
+				for i, val := range v {
+					v32[i] = float32(val)
 				}
+				vecs = append(vecs, entity.FloatVector(v32))
+			}
+			return vecs, nil
+		},
+		DocumentConverter: func(ctx context.Context, result client.SearchResult) ([]*schema.Document, error) {
+			docs := make([]*schema.Document, result.IDs.Len())
+			for i := range docs {
+				docs[i] = &schema.Document{MetaData: map[string]any{}}
+			}
 
-				for _, field := range result.Fields {
-					switch field.Name() {
-					case "id":
-						for i := range docs {
-							id, err := result.IDs.GetAsString(i)
-							if err != nil {
-								return nil, err
-							}
-							docs[i].ID = id
+			for _, field := range result.Fields {
+				switch field.Name() {
+				case "id":
+					for i := range docs {
+						id, err := result.IDs.GetAsString(i)
+						if err != nil {
+							return nil, err
+						}
+						docs[i].ID = id
+					}
+				case "content":
+					for i := range docs {
+						content, err := field.GetAsString(i)
+						if err != nil {
+							return nil, err
 						}
-					case "content":
-						for i := range docs {
-							content, err := field.GetAsString(i)
-							if err != nil {
-								return nil, err
-							}
-							docs[i].Content = content
+						docs[i].Content = content
+					}
+				case "metadata":
+					for i := range docs {
+						raw, err := field.Get(i)
+						if err != nil {
+							return nil, err
 						}
-					case "metadata":
-						for i := range docs {
-							raw, err := field.Get(i)
-							if err != nil {
-								return nil, err
-							}
-							if b, ok := raw.([]byte); ok {
-								_ = json.Unmarshal(b, &docs[i].MetaData)
-							}
+						if b, ok := raw.([]byte); ok {
+							_ = json.Unmarshal(b, &docs[i].MetaData)
 						}
 					}
 				}
+			}
 
-				// 写入相似度分数（Milvus 返回的是 distance）
-				for i := range docs {
-					if i < len(result.Scores) {
-						distance := float64(result.Scores[i])
-						docs[i].MetaData["distance"] = distance
-						docs[i].WithScore(1 - distance)
-					}
+			// 写入相似度分数：COSINE/IP 返回的是相似度，L2 返回的是距离，统一换算为越大越相似
+			for i := range docs {
+				if i < len(result.Scores) {
+					raw := result.Scores[i]
+					docs[i].MetaData["metric_type"] = string(metric)
+					docs[i].MetaData["raw_score"] = float64(raw)
+					docs[i].WithScore(db.Similarity(metric, raw))
 				}
+			}
 
-				return docs, nil
-			},
-		})
-		if err != nil {
-			return nil, err
-		}
-
-		return ret, nil
+			return docs, nil
+		},
 	})
 }
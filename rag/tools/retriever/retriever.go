@@ -15,6 +15,7 @@ var Retriever retriever.Retriever
 
 func NewRetriever(ctx context.Context) (retriever.Retriever, error) {
 	initMilvus()
+	initMemory()
 	dbType := config.Cfg.VectorDBType
 	create, ok := retrieverRegistry[dbType]
 	if !ok {